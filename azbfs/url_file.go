@@ -126,6 +126,25 @@ func (f FileURL) Delete(ctx context.Context) (*PathDeleteResponse, error) {
 		nil, nil, nil)
 }
 
+// Rename moves the file to the given path, in the same filesystem. The parent directory of the destination must already exist.
+// For more information, see https://docs.microsoft.com/en-us/rest/api/storageservices/datalakestoragegen2/path/create.
+func (f FileURL) Rename(ctx context.Context, destination FileURL) (*PathCreateResponse, error) {
+	sourceURL := f.URL()
+	renameSource := sourceURL.EscapedPath()
+	if sourceURL.RawQuery != "" {
+		// the source may need to be authorized on its own, with its SAS
+		renameSource += "?" + sourceURL.RawQuery
+	}
+
+	return destination.fileClient.Create(ctx, destination.fileSystemName, destination.path, PathResourceNone,
+		nil, PathRenameModeNone, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		&renameSource, nil, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		nil)
+}
+
 // GetProperties returns the file's metadata and properties.
 // For more information, see https://docs.microsoft.com/rest/api/storageservices/get-file-properties.
 func (f FileURL) GetProperties(ctx context.Context) (*PathGetPropertiesResponse, error) {
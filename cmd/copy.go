@@ -30,6 +30,7 @@ import (
 	"math"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -75,6 +76,7 @@ type rawCopyCmdArgs struct {
 	includeFileAttributes string
 	excludeFileAttributes string
 	includeAfter          string
+	includeBlobTags       string
 	legacyInclude         string // used only for warnings
 	legacyExclude         string // used only for warnings
 	listOfVersionIDs      string
@@ -92,6 +94,7 @@ type rawCopyCmdArgs struct {
 	// options from flags
 	blockSizeMB              float64
 	metadata                 string
+	blobTags                 string
 	contentType              string
 	contentEncoding          string
 	contentDisposition       string
@@ -102,6 +105,13 @@ type rawCopyCmdArgs struct {
 	putMd5                   bool
 	md5ValidationOption      string
 	CheckLength              bool
+	clientSideEncryption     bool // encrypt uploads before they're sent, with the key from the key file or the environment
+	clientEncryptionKeyFile  string
+	cpkByValue               bool // use the customer-provided key in the environment for server-side encryption
+	cpkByName                string
+	encryptionScope          string
+	compress                 string
+	compressionExtension     bool
 	deleteSnapshotsOption    string
 	// defines the type of the blob at the destination in case of upload / account to account copy
 	blobType      string
@@ -130,6 +140,9 @@ type rawCopyCmdArgs struct {
 	// In such cases, use s2sPreserveAccessTier=false to bypass the access tier copy.
 	// For more details, please refer to https://docs.microsoft.com/en-us/azure/storage/blobs/storage-blob-storage-tiers
 	s2sPreserveAccessTier bool
+	// whether user wants to preserve the blob index tags of blobs (or the tags of S3 objects) during service to service copy
+	// to Blob Storage, the default value is true. Getting the tags needs permission to read them at the source.
+	s2sPreserveBlobTags bool
 	// whether user wants to check if source has changed after enumerating, the default value is true.
 	// For S2S copy, as source is a remote resource, validating whether source has changed need additional request costs.
 	s2sSourceChangeValidation bool
@@ -141,6 +154,9 @@ type rawCopyCmdArgs struct {
 
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
+
+	// whether to only report what would be transferred (or, for remove, deleted)
+	dryrun bool
 }
 
 func (raw *rawCopyCmdArgs) parsePatterns(pattern string) (cookedPatterns []string) {
@@ -246,7 +262,8 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	}
 
 	// Check if source has a trailing wildcard on a URL
-	if fromTo.From().IsRemote() {
+	// Plain web servers can't be listed, so wildcards mean nothing there.
+	if fromTo.From().IsRemote() && fromTo.From() != common.ELocation.Http() {
 		tempSrc, cooked.stripTopDir, err = raw.stripTrailingWildcardOnRemoteSource(fromTo.From())
 
 		if err != nil {
@@ -285,9 +302,9 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if err != nil {
 		return cooked, err
 	}
-	allowAutoDecompress := fromTo == common.EFromTo.BlobLocal() || fromTo == common.EFromTo.FileLocal()
+	allowAutoDecompress := fromTo == common.EFromTo.BlobLocal() || fromTo == common.EFromTo.FileLocal() || fromTo == common.EFromTo.S3Local()
 	if raw.autoDecompress && !allowAutoDecompress {
-		return cooked, errors.New("automatic decompression is only supported for downloads from Blob and Azure Files") // as at Sept 2019, our ADLS Gen 2 Swagger does not include content-encoding for directory (path) listings so we can't support it there
+		return cooked, errors.New("automatic decompression is only supported for downloads from Blob, Azure Files and S3") // as at Sept 2019, our ADLS Gen 2 Swagger does not include content-encoding for directory (path) listings so we can't support it there
 	}
 	cooked.autoDecompress = raw.autoDecompress
 
@@ -315,6 +332,18 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		return cooked, fmt.Errorf("block size cannot be greater than 4MB for AppendBlob blob type")
 	}
 
+	cooked.clientSideEncryption = raw.clientSideEncryption
+	cooked.clientEncryptionKeyFile = raw.clientEncryptionKeyFile
+	cooked.clientEncryptionKeyHash, err = validateClientSideEncryption(cooked.clientSideEncryption, cooked.clientEncryptionKeyFile, fromTo, cooked.blobType)
+	if err != nil {
+		return cooked, err
+	}
+
+	cooked.cpkOptions, err = validateCpkOptions(raw.cpkByValue, raw.cpkByName, raw.encryptionScope, fromTo)
+	if err != nil {
+		return cooked, err
+	}
+
 	err = cooked.blockBlobTier.Parse(raw.blockBlobTier)
 	if err != nil {
 		return cooked, err
@@ -405,6 +434,16 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 					headerLineNum++
 				}
 
+				// Entries for plain web servers may be given as full URLs, which are made relative to the source here
+				if fromTo.From() == common.ELocation.Http() {
+					relativePath, err := relativePathFromHttpListEntry(cooked.source, v)
+					if err != nil {
+						glcm.Info(fmt.Sprintf("Skipping %s: %s", v, err))
+						continue
+					}
+					v = relativePath
+				}
+
 				addToChannel(v, "list-of-files")
 			}
 		}
@@ -479,9 +518,30 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	cooked.metadata = raw.metadata
 	cooked.contentType = raw.contentType
 	cooked.contentEncoding = raw.contentEncoding
+
+	if err = cooked.compression.Parse(raw.compress); err != nil {
+		return cooked, err
+	}
+	cooked.compressionExtension = raw.compressionExtension
+	if err = validateCompression(cooked.compression, cooked.compressionExtension, cooked.fromTo, cooked.blobType, cooked.contentEncoding, cooked.clientSideEncryption); err != nil {
+		return cooked, err
+	}
+	if cooked.compression != common.ECompressionType.None() {
+		if common.MaxCompressedSize(cooked.blockSize) > common.MaxBlockBlobBlockSize {
+			return cooked, fmt.Errorf("block-size-mb is too large to compress with: a block that doesn't compress grows slightly, and must still be no more than %d MiB", common.MaxBlockBlobBlockSize/(1024*1024))
+		}
+		cooked.contentEncoding = cooked.compression.ContentEncoding()
+	}
 	cooked.contentLanguage = raw.contentLanguage
 	cooked.contentDisposition = raw.contentDisposition
 	cooked.cacheControl = raw.cacheControl
+
+	if cooked.blobTags, err = common.ToCommonBlobTags(raw.blobTags); err != nil {
+		return cooked, fmt.Errorf("invalid blob-tags: %w", err)
+	}
+	if len(cooked.blobTags) > 0 && cooked.fromTo.To() != common.ELocation.Blob() {
+		return cooked, errors.New("blob-tags can only be set when the destination is Blob Storage")
+	}
 	cooked.noGuessMimeType = raw.noGuessMimeType
 	cooked.preserveLastModifiedTime = raw.preserveLastModifiedTime
 	cooked.includeDirectoryStubs = raw.includeDirectoryStubs
@@ -514,6 +574,17 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		glcm.SetOutputFormat(common.EOutputFormat.None())
 	}
 
+	if raw.dryrun {
+		if cooked.isRedirection() {
+			return cooked, errors.New("dry-run is not supported when piping, since there is nothing to list")
+		}
+		if cooked.fromTo == common.EFromTo.BlobFSTrash() {
+			// removal through the dfs endpoint deletes a whole directory in one request, without listing it
+			return cooked, errors.New("dry-run is not supported when removing through the ADLS Gen2 (dfs) endpoint; please use the blob endpoint instead")
+		}
+		cooked.dryrunReporter = newDryrunReporter()
+	}
+
 	if err = validatePreserveSMBPropertyOption(raw.preserveSMBPermissions, cooked.fromTo, &cooked.forceWrite, "preserve-smb-permissions"); err != nil {
 		return cooked, err
 	}
@@ -582,6 +653,58 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		if cooked.s2sSourceChangeValidation {
 			return cooked, fmt.Errorf("s2s-detect-source-changed is not supported while uploading to Blob Storage")
 		}
+	case common.EFromTo.LocalS3():
+		if cooked.preserveLastModifiedTime {
+			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while uploading")
+		}
+		if cooked.blobType != common.EBlobType.Detect() {
+			return cooked, fmt.Errorf("blob-type is not supported on Amazon S3")
+		}
+		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+			cooked.pageBlobTier != common.EPageBlobTier.None() {
+			return cooked, fmt.Errorf("blob-tier is not supported while uploading to Amazon S3")
+		}
+		if cooked.s2sPreserveProperties {
+			return cooked, fmt.Errorf("s2s-preserve-properties is not supported while uploading")
+		}
+		if cooked.s2sPreserveAccessTier {
+			return cooked, fmt.Errorf("s2s-preserve-access-tier is not supported while uploading")
+		}
+		if cooked.s2sInvalidMetadataHandleOption != common.DefaultInvalidMetadataHandleOption {
+			return cooked, fmt.Errorf("s2s-handle-invalid-metadata is not supported while uploading")
+		}
+		if cooked.s2sSourceChangeValidation {
+			return cooked, fmt.Errorf("s2s-detect-source-changed is not supported while uploading")
+		}
+	case common.EFromTo.LocalLocal():
+		if cooked.blobType != common.EBlobType.Detect() {
+			return cooked, fmt.Errorf("blob-type is not supported while copying between local file systems")
+		}
+		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+			cooked.pageBlobTier != common.EPageBlobTier.None() {
+			return cooked, fmt.Errorf("blob-tier is not supported while copying between local file systems")
+		}
+		if cooked.noGuessMimeType {
+			return cooked, fmt.Errorf("no-guess-mime-type is not supported while copying between local file systems")
+		}
+		if len(cooked.contentType) > 0 || len(cooked.contentEncoding) > 0 || len(cooked.contentLanguage) > 0 || len(cooked.contentDisposition) > 0 || len(cooked.cacheControl) > 0 || len(cooked.metadata) > 0 {
+			return cooked, fmt.Errorf("content-type, content-encoding, content-language, content-disposition, cache-control, or metadata is not supported while copying between local file systems")
+		}
+		if cooked.s2sPreserveProperties {
+			return cooked, fmt.Errorf("s2s-preserve-properties is not supported while copying between local file systems")
+		}
+		if cooked.s2sPreserveAccessTier {
+			return cooked, fmt.Errorf("s2s-preserve-access-tier is not supported while copying between local file systems")
+		}
+		if cooked.s2sInvalidMetadataHandleOption != common.DefaultInvalidMetadataHandleOption {
+			return cooked, fmt.Errorf("s2s-handle-invalid-metadata is not supported while copying between local file systems")
+		}
+		if cooked.s2sSourceChangeValidation {
+			return cooked, fmt.Errorf("s2s-detect-source-changed is not supported while copying between local file systems")
+		}
+		if err = validateLocalCopyPaths(cooked.source.ValueLocal(), cooked.destination.ValueLocal(), cooked.recursive); err != nil {
+			return cooked, err
+		}
 	case common.EFromTo.LocalFile():
 		if cooked.preserveLastModifiedTime {
 			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while uploading")
@@ -607,7 +730,8 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		}
 	case common.EFromTo.BlobLocal(),
 		common.EFromTo.FileLocal(),
-		common.EFromTo.BlobFSLocal():
+		common.EFromTo.BlobFSLocal(),
+		common.EFromTo.S3Local():
 		if cooked.followSymlinks {
 			return cooked, fmt.Errorf("follow-symlinks flag is not supported while downloading")
 		}
@@ -635,6 +759,12 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		}
 	case common.EFromTo.BlobFile(),
 		common.EFromTo.S3Blob(),
+		common.EFromTo.BlobS3(),
+		common.EFromTo.GCPBlob(),
+		common.EFromTo.HttpBlob(),
+		common.EFromTo.BlobFSBlob(),
+		common.EFromTo.BlobFSBlobFS(),
+		common.EFromTo.BlobBlobFS(),
 		common.EFromTo.BlobBlob(),
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile():
@@ -673,6 +803,7 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	cooked.s2sPreserveProperties = raw.s2sPreserveProperties
 	cooked.s2sGetPropertiesInBackend = raw.s2sGetPropertiesInBackend
 	cooked.s2sPreserveAccessTier = raw.s2sPreserveAccessTier
+	cooked.s2sPreserveBlobTags = raw.s2sPreserveBlobTags
 	cooked.s2sSourceChangeValidation = raw.s2sSourceChangeValidation
 
 	// If the user has provided some input with excludeBlobType flag, parse the input.
@@ -705,6 +836,13 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	cooked.includeFileAttributes = raw.parsePatterns(raw.includeFileAttributes)
 	cooked.excludeFileAttributes = raw.parsePatterns(raw.excludeFileAttributes)
 
+	if cooked.includeBlobTags, err = common.ToCommonBlobTags(raw.includeBlobTags); err != nil {
+		return cooked, fmt.Errorf("invalid include-blob-tags: %w", err)
+	}
+	if len(cooked.includeBlobTags) > 0 && fromTo.From() != common.ELocation.Blob() {
+		return cooked, errors.New("include-blob-tags can only be used when the source is Blob Storage")
+	}
+
 	return cooked, nil
 }
 
@@ -745,6 +883,67 @@ func validateForceIfReadOnly(toForce bool, fromTo common.FromTo) error {
 	return nil
 }
 
+// validateLocalCopyPaths makes sure that a copy between local file systems won't write over its own source.
+// The destination is created, empty, before the source is read, and deleted if the transfer fails, so the source would be lost.
+// Nor can a recursive copy go into a folder inside its source, since it would copy what it has already copied.
+func validateLocalCopyPaths(source, destination string, recursive bool) error {
+	// a wildcard copies the contents of the folder that it's in, straight into the destination
+	wildcard := false
+	if i := strings.Index(source, "*"); i >= 0 {
+		source = filepath.Dir(source[:i+1])
+		wildcard = true
+	}
+
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+	destination, err = filepath.Abs(destination)
+	if err != nil {
+		return err
+	}
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		// the enumerator reports the missing source
+		return nil
+	}
+	// symlinks in either path mustn't hide that they're the same
+	if resolved, err := filepath.EvalSymlinks(source); err == nil {
+		source = resolved
+	}
+	destination = resolveExistingSymlinks(destination)
+
+	isSource := func(path string) bool {
+		info, err := os.Stat(path)
+		return path == source || (err == nil && os.SameFile(info, sourceInfo))
+	}
+	if isSource(destination) {
+		return errors.New("the source and the destination are the same, so the copy would overwrite the source")
+	}
+	if destinationInfo, err := os.Stat(destination); err == nil && destinationInfo.IsDir() && !wildcard && isSource(filepath.Join(destination, filepath.Base(source))) {
+		return errors.New("the destination is the folder that the source is in, so the copy would overwrite the source")
+	}
+
+	if sourceInfo.IsDir() && recursive {
+		if rel, err := filepath.Rel(source, destination); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return errors.New("the destination is inside the source, so the copy would copy the source into itself")
+		}
+	}
+	return nil
+}
+
+// resolveExistingSymlinks resolves the symlinks in as much of the path as exists
+func resolveExistingSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(resolveExistingSymlinks(parent), filepath.Base(path))
+}
+
 func validatePreserveSMBPropertyOption(toPreserve bool, fromTo common.FromTo, overwrite *common.OverwriteOption, flagName string) error {
 	if toPreserve && !(fromTo == common.EFromTo.LocalFile() ||
 		fromTo == common.EFromTo.FileLocal() ||
@@ -806,6 +1005,105 @@ func validatePutMd5(putMd5 bool, fromTo common.FromTo) error {
 	return nil
 }
 
+// validateClientSideEncryption loads the client-side encryption key, if one is needed, so that a missing or bad key is reported up front.
+// The hash of the key is returned, since only the hash is persisted.
+func validateClientSideEncryption(encrypt bool, keyFile string, fromTo common.FromTo, blobType common.BlobType) (common.ClientEncryptionKeyHash, error) {
+	if encrypt {
+		if fromTo != common.EFromTo.LocalBlob() {
+			return common.ClientEncryptionKeyHash{}, errors.New("client-side encryption is only supported when uploading to Blob storage")
+		}
+		if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+			return common.ClientEncryptionKeyHash{}, errors.New("client-side encryption is only supported for block blobs")
+		}
+	} else if !fromTo.IsDownload() {
+		if keyFile != "" {
+			return common.ClientEncryptionKeyHash{}, errors.New("client-encryption-key-file is only used with client-side-encryption, or when downloading")
+		}
+		return common.ClientEncryptionKeyHash{}, nil
+	}
+
+	// when downloading, the key is optional; it's only needed if some of the blobs were encrypted
+	key, err := common.LoadClientEncryptionKey(keyFile)
+	if err != nil {
+		return common.ClientEncryptionKeyHash{}, err
+	}
+	if key == nil {
+		if encrypt {
+			return common.ClientEncryptionKeyHash{}, fmt.Errorf("client-side encryption needs a key. Give the path of a key file with client-encryption-key-file, or set %s", common.EEnvironmentVariable.ClientEncryptionKey().Name)
+		}
+		return common.ClientEncryptionKeyHash{}, nil
+	}
+	return key.Hash(), nil
+}
+
+// validateCpkOptions loads the customer-provided key, if one is to be used, so that a missing or bad key is reported up front.
+// The options are for whichever side of the job is in Blob storage: the destination if there is one there, otherwise the source.
+func validateCpkOptions(byValue bool, byName string, encryptionScope string, fromTo common.FromTo) (common.CpkOptions, error) {
+	// cpk-by-name is just another name for the encryption scope
+	if byName != "" && encryptionScope != "" && byName != encryptionScope {
+		return common.CpkOptions{}, errors.New("cpk-by-name and encryption-scope are two names for the same thing, so they cannot be given different values")
+	}
+	if encryptionScope == "" {
+		encryptionScope = byName
+	}
+
+	options := common.CpkOptions{EncryptionScope: encryptionScope}
+	if !byValue && encryptionScope == "" {
+		return options, nil
+	}
+
+	if fromTo.From() != common.ELocation.Blob() && fromTo.To() != common.ELocation.Blob() {
+		return common.CpkOptions{}, errors.New("customer-provided keys and encryption scopes are only supported for Blob storage")
+	}
+	if encryptionScope != "" && fromTo.To() != common.ELocation.Blob() {
+		// the scope is only needed to write blobs, since the service knows which scope a blob was written with
+		return common.CpkOptions{}, errors.New("an encryption scope can only be given when the destination is Blob storage")
+	}
+	if byValue {
+		if fromTo == common.EFromTo.BlobFile() {
+			// Files are copied by the service from the blob's URL, which can't be read without the key
+			return common.CpkOptions{}, errors.New("blobs encrypted with a customer-provided key cannot be copied to Azure Files")
+		}
+		key, err := common.LoadCpkKey()
+		if err != nil {
+			return common.CpkOptions{}, err
+		}
+		if key == nil {
+			return common.CpkOptions{}, fmt.Errorf("cpk-by-value needs a key. Set %s to the base64-encoded 256-bit key", common.EEnvironmentVariable.CpkEncryptionKey().Name)
+		}
+		options.Key = key
+	}
+
+	return options, options.Validate()
+}
+
+func validateCompression(compression common.CompressionType, addExtension bool, fromTo common.FromTo, blobType common.BlobType, contentEncoding string, clientSideEncryption bool) error {
+	if compression == common.ECompressionType.None() {
+		if addExtension {
+			return errors.New("compression-extension is only used with compress")
+		}
+		return nil
+	}
+
+	if !compression.CanConcatenate() {
+		return fmt.Errorf("cannot compress with %s; the supported compressions are gzip and zstd", compression.String())
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return errors.New("compression is only supported when uploading to Blob storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+		return errors.New("compression is only supported for block blobs")
+	}
+	if contentEncoding != "" && !strings.EqualFold(contentEncoding, compression.ContentEncoding()) {
+		return fmt.Errorf("the content-encoding of compressed blobs is %s, so it cannot be set to %s", compression.ContentEncoding(), contentEncoding)
+	}
+	if clientSideEncryption {
+		// encrypted chunks must all be the same size, which compressed chunks aren't
+		return errors.New("compression cannot be used together with client-side encryption")
+	}
+	return nil
+}
+
 func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo) error {
 	hasMd5Validation := option != common.DefaultHashValidationOption
 	if hasMd5Validation && !fromTo.IsDownload() {
@@ -830,6 +1128,7 @@ type cookedCopyCmdArgs struct {
 	includeFileAttributes []string
 	excludeFileAttributes []string
 	includeAfter          *time.Time
+	includeBlobTags       common.BlobTags
 
 	// list of version ids
 	listOfVersionIDs chan string
@@ -850,6 +1149,7 @@ type cookedCopyCmdArgs struct {
 	blockBlobTier            common.BlockBlobTier
 	pageBlobTier             common.PageBlobTier
 	metadata                 string
+	blobTags                 common.BlobTags
 	contentType              string
 	contentEncoding          string
 	contentLanguage          string
@@ -861,6 +1161,12 @@ type cookedCopyCmdArgs struct {
 	putMd5                   bool
 	md5ValidationOption      common.HashValidationOption
 	CheckLength              bool
+	clientSideEncryption     bool
+	clientEncryptionKeyFile  string
+	clientEncryptionKeyHash  common.ClientEncryptionKeyHash // identifies the key, which the STE loads again, since the key itself is never persisted
+	cpkOptions               common.CpkOptions
+	compression              common.CompressionType
+	compressionExtension     bool // add the compression's extension to the blob names
 	logVerbosity             common.LogLevel
 	// commandString hold the user given command which is logged to the Job log file
 	commandString string
@@ -904,6 +1210,8 @@ type cookedCopyCmdArgs struct {
 	// In such cases, use s2sPreserveAccessTier=false to bypass the access tier copy.
 	// For more details, please refer to https://docs.microsoft.com/en-us/azure/storage/blobs/storage-blob-storage-tiers
 	s2sPreserveAccessTier bool
+	// whether user wants to preserve blob index tags during service to service copy to Blob Storage
+	s2sPreserveBlobTags bool
 	// whether user wants to check if source has changed after enumerating, the default value is true.
 	// For S2S copy, as source is a remote resource, validating whether source has changed need additional request costs.
 	s2sSourceChangeValidation bool
@@ -919,6 +1227,9 @@ type cookedCopyCmdArgs struct {
 
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
+
+	// set in a dry run, which reports the transfers (or deletions) instead of performing them
+	dryrunReporter *dryrunReporter
 }
 
 func (cca *cookedCopyCmdArgs) isRedirection() bool {
@@ -996,7 +1307,7 @@ func (cca *cookedCopyCmdArgs) processRedirectionDownload(blobResource common.Res
 
 	// step 3: start download
 	blobURL := azblob.NewBlobURL(*u, p)
-	blobStream, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	blobStream, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, cca.cpkOptions.ToClientProvidedKeyOptions())
 	if err != nil {
 		return fmt.Errorf("fatal: cannot download blob due to error: %s", err.Error())
 	}
@@ -1043,8 +1354,9 @@ func (cca *cookedCopyCmdArgs) processRedirectionUpload(blobResource common.Resou
 	// step 2: leverage high-level call in Blob SDK to upload stdin in parallel
 	blockBlobUrl := azblob.NewBlockBlobURL(*u, p)
 	_, err = azblob.UploadStreamToBlockBlob(ctx, os.Stdin, blockBlobUrl, azblob.UploadStreamToBlockBlobOptions{
-		BufferSize: int(blockSize),
-		MaxBuffers: pipingUploadParallelism,
+		BufferSize:               int(blockSize),
+		MaxBuffers:               pipingUploadParallelism,
+		ClientProvidedKeyOptions: cca.cpkOptions.ToClientProvidedKeyOptions(),
 	})
 
 	return err
@@ -1106,14 +1418,23 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 			BlockBlobTier:            cca.blockBlobTier,
 			PageBlobTier:             cca.pageBlobTier,
 			Metadata:                 cca.metadata,
+			BlobTagsString:           cca.blobTags.ToString(),
 			NoGuessMimeType:          cca.noGuessMimeType,
 			PreserveLastModifiedTime: cca.preserveLastModifiedTime,
 			PutMd5:                   cca.putMd5,
+			Compression:              cca.compression,
 			MD5ValidationOption:      cca.md5ValidationOption,
 			DeleteSnapshotsOption:    cca.deleteSnapshotsOption,
 		},
-		CommandString:  cca.commandString,
-		CredentialInfo: cca.credentialInfo,
+		CommandString:           cca.commandString,
+		CredentialInfo:          cca.credentialInfo,
+		ClientSideEncrypt:       cca.clientSideEncryption,
+		ClientEncryptionKeyFile: cca.clientEncryptionKeyFile,
+		ClientEncryptionKeyHash: cca.clientEncryptionKeyHash,
+		EncryptionScope:         cca.cpkOptions.EncryptionScope,
+	}
+	if cca.cpkOptions.Key != nil {
+		jobPartOrder.CpkKeyHash = cca.cpkOptions.Key.Hash()
 	}
 
 	from := cca.fromTo.From()
@@ -1142,14 +1463,23 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 	case common.EFromTo.LocalBlob(),
 		common.EFromTo.LocalBlobFS(),
 		common.EFromTo.LocalFile(),
+		common.EFromTo.LocalS3(),
+		common.EFromTo.LocalLocal(),
 		common.EFromTo.BlobLocal(),
 		common.EFromTo.FileLocal(),
 		common.EFromTo.BlobFSLocal(),
+		common.EFromTo.S3Local(),
 		common.EFromTo.BlobBlob(),
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile(),
 		common.EFromTo.BlobFile(),
 		common.EFromTo.S3Blob(),
+		common.EFromTo.BlobS3(),
+		common.EFromTo.GCPBlob(),
+		common.EFromTo.HttpBlob(),
+		common.EFromTo.BlobFSBlob(),
+		common.EFromTo.BlobFSBlobFS(),
+		common.EFromTo.BlobBlobFS(),
 		common.EFromTo.BenchmarkBlob(),
 		common.EFromTo.BenchmarkBlobFS(),
 		common.EFromTo.BenchmarkFile():
@@ -1562,6 +1892,8 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Include only those files modified on or after the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. As at AzCopy 10.5, this flag applies only to files, not folders, so folder properties won't be copied when using this flag with --preserve-smb-info or --preserve-smb-permissions.")
 	cpCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only these files when copying. "+
 		"This option supports wildcard characters (*). Separate files by using a ';'.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeBlobTags, "include-blob-tags", "", "Include only those blobs that have all of these blob index tags. "+
+		"The tags are given in the same format as for --blob-tags, e.g. 'project=apollo&stage=final'. Only available when the source is Blob Storage.")
 	cpCmd.PersistentFlags().StringVar(&raw.includePath, "include-path", "", "Include only these paths when copying. "+
 		"This option does not support wildcard characters (*). Checks relative path prefix (For example: myFolder;myFolder/subDirName/file.pdf).")
 	cpCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when copying. "+ // Currently, only exclude-path is supported alongside account traversal.
@@ -1570,7 +1902,7 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of text file which has the list of only files to be copied.")
 	cpCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude these files when copying. This option supports wildcard characters (*)")
 	cpCmd.PersistentFlags().StringVar(&raw.forceWrite, "overwrite", "true", "Overwrite the conflicting files and blobs at the destination if this flag is set to true. (default 'true') Possible values include 'true', 'false', 'prompt', and 'ifSourceNewer'. For destinations that support folders, conflicting folder-level properties will be overwritten this flag is 'true' or if a positive response is provided to the prompt.")
-	cpCmd.PersistentFlags().BoolVar(&raw.autoDecompress, "decompress", false, "Automatically decompress files when downloading, if their content-encoding indicates that they are compressed. The supported content-encoding values are 'gzip' and 'deflate'. File extensions of '.gz'/'.gzip' or '.zz' aren't necessary, but will be removed if present.")
+	cpCmd.PersistentFlags().BoolVar(&raw.autoDecompress, "decompress", false, "Automatically decompress files when downloading, if their content-encoding indicates that they are compressed. The supported content-encoding values are 'gzip', 'deflate', 'zstd' and 'br'. File extensions of '.gz'/'.gzip', '.zz', '.zst' or '.br' aren't necessary, but will be removed if present.")
 	cpCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Look into sub-directories recursively when uploading from local file system.")
 	cpCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalBlobFS. Piping: BlobPipe, PipeBlob")
 	cpCmd.PersistentFlags().StringVar(&raw.excludeBlobType, "exclude-blob-type", "", "Optionally specifies the type of blob (BlockBlob/ PageBlob/ AppendBlob) to exclude when copying blobs from the container "+
@@ -1583,6 +1915,8 @@ func init() {
 	cpCmd.PersistentFlags().StringVar(&raw.blockBlobTier, "block-blob-tier", "None", "upload block blob to Azure Storage using this blob tier.")
 	cpCmd.PersistentFlags().StringVar(&raw.pageBlobTier, "page-blob-tier", "None", "Upload page blob to Azure Storage using this blob tier. (default 'None').")
 	cpCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata.")
+	cpCmd.PersistentFlags().StringVar(&raw.blobTags, "blob-tags", "", "Set these blob index tags on the blobs at the destination, instead of any tags that the sources have. "+
+		"Give them as URL-encoded key=value pairs separated by '&', e.g. 'project=apollo&stage=final'. Only available when the destination is Blob Storage.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentType, "content-type", "", "Specifies the content type of the file. Implies no-guess-mime-type. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentEncoding, "content-encoding", "", "Set the content-encoding header. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentDisposition, "content-disposition", "", "Set the content-disposition header. Returned on download.")
@@ -1596,15 +1930,33 @@ func init() {
 	cpCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
 	cpCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 	cpCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
+	cpCmd.PersistentFlags().BoolVar(&raw.clientSideEncryption, "client-side-encryption", false, "Encrypt each file before it's uploaded, with AES-256-GCM, under a key of its own. That key is in turn encrypted with the key "+
+		"given by --client-encryption-key-file or the "+common.EEnvironmentVariable.ClientEncryptionKey().Name+" environment variable, and saved in the blob's metadata. Only available when uploading to block blobs. "+
+		"Encrypted blobs are decrypted automatically when they're downloaded with the same key.")
+	cpCmd.PersistentFlags().StringVar(&raw.clientEncryptionKeyFile, "client-encryption-key-file", "", "The file that holds the 256-bit key for client-side encryption and decryption, either as 32 bytes or base64-encoded. "+
+		"The key itself is never saved in the job's plan files, so the file must still be there if the job is resumed.")
+	cpCmd.PersistentFlags().BoolVar(&raw.cpkByValue, "cpk-by-value", false, "Have the service encrypt and decrypt blobs with a customer-provided key, given base64-encoded in the "+
+		common.EEnvironmentVariable.CpkEncryptionKey().Name+" environment variable. The key is sent on every request to the Blob storage side of the job. "+
+		"Only a hash of the key is saved in the job's plan files, so the variable must still be set if the job is resumed.")
+	cpCmd.PersistentFlags().StringVar(&raw.cpkByName, "cpk-by-name", "", "Have the service encrypt blobs with the customer-managed key of this encryption scope. The same as --encryption-scope, so it is only available when the destination is Blob storage.")
+	cpCmd.PersistentFlags().StringVar(&raw.encryptionScope, "encryption-scope", "", "Have the service encrypt blobs with the key of this encryption scope. Only available when the destination is Blob storage.")
+	cpCmd.PersistentFlags().StringVar(&raw.compress, "compress", "", "Compress files while uploading them to block blobs, and set their content-encoding to say so. Available options: gzip, zstd. "+
+		"Since the files are compressed as they're sent, no temporary copies are needed. Use --decompress to decompress them again when downloading.")
+	cpCmd.PersistentFlags().BoolVar(&raw.compressionExtension, "compression-extension", false, "Add the usual extension for the compression (.gz or .zst) to the name of each blob. Only used with --compress.")
 	cpCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. Only available when downloading. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent')")
 	cpCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include files whose attributes match the attribute list. For example: A;S;R")
 	cpCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
+	cpCmd.PersistentFlags().BoolVar(&raw.dryrun, "dry-run", false, "Print the files and blobs that would be copied, with totals, without transferring anything. "+
+		"Since the destination isn't listed, files that would overwrite existing ones are reported as copies too.")
 	cpCmd.PersistentFlags().BoolVar(&raw.CheckLength, "check-length", true, "Check the length of a file on the destination after the transfer. If there is a mismatch between source and destination, the transfer is marked as failed.")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveProperties, "s2s-preserve-properties", true, "Preserve full properties during service to service copy. "+
 		"For AWS S3 and Azure File non-single file source, the list operation doesn't return full properties of objects and files. To preserve full properties, AzCopy needs to send one additional request per object or file.")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveAccessTier, "s2s-preserve-access-tier", true, "Preserve access tier during service to service copy. "+
 		"Please refer to [Azure Blob storage: hot, cool, and archive access tiers](https://docs.microsoft.com/azure/storage/blobs/storage-blob-storage-tiers) to ensure destination storage account supports setting access tier. "+
 		"In the cases that setting access tier is not supported, please use s2sPreserveAccessTier=false to bypass copying access tier. (default true). ")
+	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveBlobTags, "s2s-preserve-blob-tags", false, "Preserve blob index tags during service to service copy to Blob Storage. "+
+		"For an AWS S3 source, the tags of the objects are preserved, which needs one additional request per object. "+
+		"The tags must be readable at the source, and the destination must allow them to be set (for a SAS, with the 't' permission), or the transfers fail. (default false)")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sSourceChangeValidation, "s2s-detect-source-changed", false, "Detect if the source file/blob changes while it is being read. (This parameter only applies to service to service copies, because the corresponding check is permanently enabled for uploads and downloads.)")
 	cpCmd.PersistentFlags().StringVar(&raw.s2sInvalidMetadataHandleOption, "s2s-handle-invalid-metadata", common.DefaultInvalidMetadataHandleOption.String(), "Specifies how invalid metadata keys are handled. Available options: ExcludeIfInvalid, FailIfInvalid, RenameIfInvalid. (default 'ExcludeIfInvalid').")
 	cpCmd.PersistentFlags().StringVar(&raw.listOfVersionIDs, "list-of-versions", "", "Specifies a file where each version id is listed on a separate line. Ensure that the source must point to a single blob and all the version ids specified in the file using this flag must belong to the source blob only. AzCopy will download the specified versions in the destination folder provided.")
@@ -16,7 +16,9 @@ import (
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/azure-storage-file-go/azfile"
+	"github.com/minio/minio-go"
 
+	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-azcopy/ste"
 )
@@ -30,9 +32,10 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 	if srcCredInfo, isPublic, err = getCredentialInfoForLocation(ctx, cca.fromTo.From(), cca.source.Value, cca.source.SAS, true); err != nil {
 		return nil, err
-		// If S2S and source takes OAuthToken as its cred type (OR) source takes anonymous as its cred type, but it's not public and there's no SAS
+		// If S2S and source takes OAuthToken (or, for ADLS Gen2, SharedKey) as its cred type (OR) source takes anonymous as its cred type, but it's not public and there's no SAS
 	} else if cca.fromTo.From().IsRemote() && cca.fromTo.To().IsRemote() &&
 		(srcCredInfo.CredentialType == common.ECredentialType.OAuthToken() ||
+			srcCredInfo.CredentialType == common.ECredentialType.SharedKey() ||
 			(srcCredInfo.CredentialType == common.ECredentialType.Anonymous() && !isPublic && cca.source.SAS == "")) {
 		// TODO: Generate a SAS token if it's blob -> *
 		return nil, errors.New("a SAS token (or S3 access key) is required as a part of the source in S2S transfers, unless the source is a public resource")
@@ -47,14 +50,31 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 	// If source change validation is enabled on files to remote, turn it on (consider a separate flag entirely?)
 	getRemoteProperties := cca.forceWrite == common.EOverwriteOption.IfSourceNewer() ||
 		(cca.fromTo.From() == common.ELocation.File() && !cca.fromTo.To().IsRemote()) || // If download, we still need LMT and MD5 from files.
+		(cca.fromTo.From() == common.ELocation.S3() && !cca.fromTo.To().IsRemote()) || // Likewise for S3 downloads, we need content encoding and MD5, which listings don't return.
 		(cca.fromTo.From() == common.ELocation.File() && cca.fromTo.To().IsRemote() && (cca.s2sSourceChangeValidation || cca.includeAfter != nil)) || // If S2S from File to *, and sourceChangeValidation is enabled, we get properties so that we have LMTs. Likewise if we are using includeAfter, which requires LMTs.
 		(cca.fromTo.From().IsRemote() && cca.fromTo.To().IsRemote() && cca.s2sPreserveProperties && !cca.s2sGetPropertiesInBackend) // If S2S and preserve properties AND get properties in backend is on, turn this off, as properties will be obtained in the backend.
 	jobPartOrder.S2SGetPropertiesInBackend = cca.s2sPreserveProperties && !getRemoteProperties && cca.s2sGetPropertiesInBackend // Infer GetProperties if GetPropertiesInBackend is enabled.
+	if cca.fromTo.From() == common.ELocation.BlobFS() && cca.fromTo.To().IsRemote() {
+		// The ADLS Gen2 listing doesn't return full properties or metadata, so the backend is the only place they can be obtained.
+		jobPartOrder.S2SGetPropertiesInBackend = cca.s2sPreserveProperties
+	}
 	jobPartOrder.S2SSourceChangeValidation = cca.s2sSourceChangeValidation
 	jobPartOrder.DestLengthValidation = cca.CheckLength
 	jobPartOrder.S2SInvalidMetadataHandleOption = cca.s2sInvalidMetadataHandleOption
+	jobPartOrder.S2SPreserveBlobTags = cca.s2sPreserveBlobTags
+
+	// Blob listings only include the tags when asked for them, which needs permission to read them.
+	// So only ask when they are to be copied (and weren't given for the whole job), or filtered on.
+	getBlobTags := (cca.fromTo == common.EFromTo.BlobBlob() && cca.s2sPreserveBlobTags && len(cca.blobTags) == 0) ||
+		len(cca.includeBlobTags) > 0
+
+	// In a copy between blobs, the customer-provided key is the destination's, since the service can't copy from blobs encrypted with one.
+	srcCpkOptions := common.CpkOptions{}
+	if cca.fromTo.To() != common.ELocation.Blob() {
+		srcCpkOptions = cca.cpkOptions
+	}
 
-	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, cca.listOfFilesChannel, cca.recursive, getRemoteProperties, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
+	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, cca.listOfFilesChannel, cca.recursive, getRemoteProperties, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs, getBlobTags, srcCpkOptions)
 
 	if err != nil {
 		return nil, err
@@ -240,13 +260,26 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 			jobPartOrder.Fpo,
 		)
 
-		if shouldSendToSte {
-			return addTransfer(&jobPartOrder, transfer, cca)
-		} else {
+		if !shouldSendToSte {
+			return nil
+		}
+		if cca.compressionExtension && transfer.EntityType == common.EEntityType.File() && transfer.Destination != "" {
+			// (an empty destination means the blob was named by the user, so we leave its name alone)
+			transfer.Destination += cca.compression.Extension()
+		}
+		if cca.dryrunReporter != nil {
+			cca.dryrunReporter.reportTransfer(object,
+				common.GenerateFullPath(jobPartOrder.SourceRoot.Value, transfer.Source),
+				common.GenerateFullPath(jobPartOrder.DestinationRoot.Value, transfer.Destination))
 			return nil
 		}
+		return addTransfer(&jobPartOrder, transfer, cca)
 	}
 	finalizer := func() error {
+		if cca.dryrunReporter != nil {
+			cca.dryrunReporter.exit()
+			return nil
+		}
 		return dispatchFinalPart(&jobPartOrder, cca)
 	}
 
@@ -267,7 +300,12 @@ func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *co
 		return false
 	}
 
-	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, nil, nil, false, false, false, func(common.EntityType) {}, cca.listOfVersionIDs)
+	dstCpkOptions := common.CpkOptions{}
+	if cca.fromTo.To() == common.ELocation.Blob() {
+		dstCpkOptions = cca.cpkOptions
+	}
+
+	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, nil, nil, false, false, false, func(common.EntityType) {}, cca.listOfVersionIDs, false, dstCpkOptions)
 
 	if err != nil {
 		return false
@@ -303,6 +341,10 @@ func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 		}
 	}
 
+	if len(cca.includeBlobTags) != 0 {
+		filters = append(filters, &includeBlobTagsFilter{tags: cca.includeBlobTags})
+	}
+
 	if len(cca.excludeBlobType) != 0 {
 		excludeSet := map[azblob.BlobType]bool{}
 
@@ -332,6 +374,9 @@ func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 }
 
 func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSAS common.ResourceString, ctx context.Context, existingContainers map[string]bool) (err error) {
+	if cca.dryrunReporter != nil {
+		return // a dry run creates nothing
+	}
 	if _, ok := existingContainers[containerName]; ok {
 		return
 	}
@@ -349,7 +394,7 @@ func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 	}
 
 	// Because the only use-cases for createDstContainer will be on service-level S2S and service-level download
-	// We only need to create "containers" on local and blob.
+	// We only need to create "containers" on local, blob, file, ADLS Gen2 and S3.
 	// TODO: Reduce code dupe somehow
 	switch cca.fromTo.To() {
 	case common.ELocation.Local():
@@ -417,6 +462,73 @@ func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 		} else {
 			return err
 		}
+	case common.ELocation.BlobFS():
+		accountRoot, err := GetAccountRoot(dstWithSAS, cca.fromTo.To())
+
+		if err != nil {
+			return err
+		}
+
+		dstURL, err := url.Parse(accountRoot)
+
+		if err != nil {
+			return err
+		}
+
+		fsu := azbfs.NewServiceURL(*dstURL, dstPipeline).NewFileSystemURL(containerName)
+		_, err = fsu.GetProperties(ctx)
+
+		if err == nil {
+			return err // File system already exists, return gracefully
+		}
+
+		_, err = fsu.Create(ctx)
+
+		if stgErr, ok := err.(azbfs.StorageError); ok {
+			if stgErr.ServiceCode() != azbfs.ServiceCodeFileSystemAlreadyExists {
+				return err
+			}
+		} else {
+			return err
+		}
+	case common.ELocation.S3():
+		dstURL, err := url.Parse(cca.destination.Value)
+
+		if err != nil {
+			return err
+		}
+
+		s3URLParts, err := common.NewS3URLParts(*dstURL)
+
+		if err != nil {
+			return err
+		}
+
+		s3Client, err := common.CreateS3Client(
+			ctx,
+			common.CredentialInfo{
+				CredentialType:   common.ECredentialType.S3AccessKey(),
+				S3CredentialInfo: s3URLParts.S3CredentialInfo(),
+			},
+			common.CredentialOpOptions{
+				LogError: glcm.Error,
+			})
+
+		if err != nil {
+			return err
+		}
+
+		if exists, err := s3Client.BucketExists(containerName); err != nil || exists {
+			return err // Bucket already exists (or we can't tell), return gracefully
+		}
+
+		err = s3Client.MakeBucket(containerName, s3URLParts.Region)
+
+		if errResp := minio.ToErrorResponse(err); err != nil && errResp.Code != "BucketAlreadyOwnedByYou" {
+			return err
+		}
+
+		return nil
 	default:
 		panic(fmt.Sprintf("cannot create a destination container at location %s.", cca.fromTo.To()))
 	}
@@ -131,7 +131,7 @@ func getBlobCredentialType(ctx context.Context, blobResourceURL string, canBePub
 		if !isContainer {
 			// Scenario 3: When resourceURL points to a blob
 			blobURL := azblob.NewBlobURL(*resourceURL, p)
-			if _, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}); err == nil {
+			if _, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{}); err == nil {
 				return true
 			}
 		}
@@ -338,13 +338,16 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 		// and the parsing of s3 URL is non-trivial.  E.g. can't just look for the ending since
 		// something like https://someApi.execute-api.someRegion.amazonaws.com is AWS but is a customer-
 		// written code, not S3.
+		// The only exception is the S3-compatible endpoint that the user has explicitly named in AZCOPY_S3_ENDPOINT.
 		ok := false
 		host := "<unparseable url>"
 		u, err := url.Parse(resource)
 		if err == nil {
 			host = u.Host
 			parts, err := common.NewS3URLParts(*u) // strip any leading bucket name from URL, to get an endpoint we can pass to s3utils
-			if err == nil {
+			if err == nil && parts.IsCustomEndpoint() {
+				ok = true
+			} else if err == nil {
 				u, err := url.Parse("https://" + parts.Endpoint)
 				ok = err == nil && s3utils.IsAmazonEndpoint(*u)
 			}
@@ -355,6 +358,24 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 				"s3 authentication to %s is not currently suported in AzCopy", host)
 		}
 
+	case common.ECredentialType.GCPHMACKey(), common.ECredentialType.GCPServiceAccount():
+		if resourceType != common.ELocation.GCP() {
+			//noinspection ALL
+			return fmt.Errorf("GCP authentication to %s is not enabled in AzCopy", resourceType.String())
+		}
+
+		// the HMAC key (or OAuth token) is only ever sent to the Google Cloud Storage endpoint, whatever the URL,
+		// but check the URL anyway so that we never claim to be authenticating somewhere else.
+		host := "<unparseable url>"
+		u, err := url.Parse(resource)
+		if err == nil {
+			host = u.Host
+		}
+		if err != nil || !common.IsGCPURL(*u) {
+			return fmt.Errorf(
+				"GCP authentication to %s is not currently supported in AzCopy", host)
+		}
+
 	default:
 		panic("unknown credential type")
 	}
@@ -398,10 +419,14 @@ func getCredentialTypeForLocation(ctx context.Context, location common.Location,
 func doGetCredentialTypeForLocation(ctx context.Context, location common.Location, resource, resourceSAS string, isSource bool, getForcedCredType func() common.CredentialType) (credType common.CredentialType, isPublic bool, err error) {
 	if resourceSAS != "" {
 		credType = common.ECredentialType.Anonymous()
-	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() {
+	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() || location == common.ELocation.GCP() || location == common.ELocation.Http() {
 		switch location {
 		case common.ELocation.Local(), common.ELocation.Benchmark():
 			credType = common.ECredentialType.Anonymous()
+		case common.ELocation.Http():
+			// Never send credentials to an arbitrary web server. Whatever it serves is, as far as we're concerned, public.
+			credType = common.ECredentialType.Anonymous()
+			isPublic = true
 		case common.ELocation.Blob():
 			if credType, isPublic, err = getBlobCredentialType(ctx, resource, isSource, resourceSAS != ""); err != nil {
 				return common.ECredentialType.Unknown(), false, err
@@ -421,6 +446,10 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 				return common.ECredentialType.Unknown(), false, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set before creating the S3 AccessKey credential")
 			}
 			credType = common.ECredentialType.S3AccessKey()
+		case common.ELocation.GCP():
+			if credType, err = common.GetGCPCredentialType(); err != nil {
+				return common.ECredentialType.Unknown(), false, err
+			}
 		}
 	}
 
@@ -446,8 +475,9 @@ func getCredentialInfoForLocation(ctx context.Context, location common.Location,
 		} else {
 			credInfo.OAuthTokenInfo = *tokenInfo
 		}
-	} else if credInfo.CredentialType == common.ECredentialType.S3AccessKey() {
-		// nothing to do here. The extra fields for S3 are fleshed out at the time
+	} else if credInfo.CredentialType == common.ECredentialType.S3AccessKey() || credInfo.CredentialType == common.ECredentialType.GCPHMACKey() ||
+		credInfo.CredentialType == common.ECredentialType.GCPServiceAccount() {
+		// nothing to do here. The extra fields for S3 (and GCP) are fleshed out at the time
 		// we make the S3Client
 	}
 
@@ -472,6 +502,9 @@ func getCredentialType(ctx context.Context, raw rawFromToInfo) (credType common.
 	case raw.fromTo.From().IsRemote() && raw.fromTo.To().IsLocal():
 		// we authenticate to the source.
 		credType, _, err = getCredentialTypeForLocation(ctx, raw.fromTo.From(), raw.source, raw.sourceSAS, true)
+	case raw.fromTo == common.EFromTo.LocalLocal():
+		// there's nothing to authenticate to
+		credType = common.ECredentialType.Anonymous()
 	default:
 		credType = common.ECredentialType.Anonymous()
 		// Log the FromTo types which getCredentialType hasn't solved, in case of miss-use.
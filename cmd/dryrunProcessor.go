@@ -0,0 +1,156 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// dryrunReporter stands in for the transfer scheduler and the delete processors when --dry-run is given.
+// It reports each transfer and deletion that would have happened, and totals them up,
+// so that the enumeration, filters and comparisons all run as normal, but nothing is sent to the transfer engine and nothing is deleted.
+type dryrunReporter struct {
+	copies     uint64
+	overwrites uint64
+	deletions  uint64
+	totalBytes uint64
+
+	// a sync notes which source object has a counterpart at the destination, just before that object is scheduled,
+	// so that its transfer can be reported as an overwrite rather than a copy
+	overwritePending bool
+	overwritePath    string
+}
+
+// the JSON form of one operation that a dry run would have performed
+type dryrunOperation struct {
+	Operation   string // Copy, Overwrite or Delete
+	EntityType  string // File or Folder
+	Source      string `json:",omitempty"`
+	Destination string `json:",omitempty"`
+	Size        int64  `json:",omitempty"`
+}
+
+// the JSON form of the totals, which are given when the dry run exits
+type dryrunSummary struct {
+	Copies     uint64
+	Overwrites uint64
+	Deletions  uint64
+	TotalBytes uint64
+}
+
+func newDryrunReporter() *dryrunReporter {
+	return &dryrunReporter{}
+}
+
+// noteOverwrite wraps a change detector, so that the transfers of the objects it finds changed are reported as overwrites
+func (r *dryrunReporter) noteOverwrite(inner syncChangeDetector) syncChangeDetector {
+	return dryrunChangeDetector{inner: inner, reporter: r}
+}
+
+type dryrunChangeDetector struct {
+	inner    syncChangeDetector
+	reporter *dryrunReporter
+}
+
+func (d dryrunChangeDetector) hasChanged(sourceObject, destinationObject storedObject) bool {
+	changed := d.inner.hasChanged(sourceObject, destinationObject)
+	if changed {
+		d.reporter.overwritePending = true
+		d.reporter.overwritePath = sourceObject.relativePath
+	}
+	return changed
+}
+
+// reportTransfer reports the transfer of an object, given the full paths of its source and destination
+func (r *dryrunReporter) reportTransfer(object storedObject, source, destination string) {
+	operation := "Copy"
+	if r.overwritePending && r.overwritePath == object.relativePath {
+		operation = "Overwrite"
+		r.overwrites++
+	} else {
+		r.copies++
+	}
+	r.overwritePending = false
+
+	if object.entityType == common.EEntityType.File() {
+		r.totalBytes += uint64(object.size)
+	}
+
+	glcm.Dryrun(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			return dryrunJson(dryrunOperation{Operation: operation, EntityType: object.entityType.String(), Source: source, Destination: destination, Size: object.size})
+		}
+		if operation == "Overwrite" {
+			return fmt.Sprintf("DRYRUN: overwrite %s with %s", destination, source)
+		}
+		return fmt.Sprintf("DRYRUN: copy %s to %s", source, destination)
+	})
+}
+
+// reportDeletion reports the deletion of an object, given its full path
+func (r *dryrunReporter) reportDeletion(object storedObject, target string) {
+	r.deletions++
+	glcm.Dryrun(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			return dryrunJson(dryrunOperation{Operation: "Delete", EntityType: object.entityType.String(), Source: target})
+		}
+		return fmt.Sprintf("DRYRUN: delete %s", target)
+	})
+}
+
+// deletionProcessor reports the deletion of each object given to it, from under the given root
+func (r *dryrunReporter) deletionProcessor(root common.ResourceString) objectProcessor {
+	return func(object storedObject) error {
+		target := common.GenerateFullPath(root.Value, object.relativePath)
+		if object.blobVersionID != "" {
+			target += "?versionid=" + object.blobVersionID
+		}
+		r.reportDeletion(object, target)
+		return nil
+	}
+}
+
+// exit prints the totals, and ends the run
+func (r *dryrunReporter) exit() {
+	glcm.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			return dryrunJson(dryrunSummary{Copies: r.copies, Overwrites: r.overwrites, Deletions: r.deletions, TotalBytes: r.totalBytes})
+		}
+
+		var sb strings.Builder
+		sb.WriteString("Dry run complete. Nothing was transferred or deleted.\n")
+		sb.WriteString(fmt.Sprintf("Copies: %v\n", r.copies))
+		sb.WriteString(fmt.Sprintf("Overwrites: %v\n", r.overwrites))
+		sb.WriteString(fmt.Sprintf("Deletions: %v\n", r.deletions))
+		sb.WriteString(fmt.Sprintf("Total size to transfer: %s", byteSizeToString(int64(r.totalBytes))))
+		return sb.String()
+	}, common.EExitCode.Success())
+}
+
+func dryrunJson(v interface{}) string {
+	jsonOutput, err := json.Marshal(v)
+	common.PanicIfErr(err)
+	return string(jsonOutput)
+}
@@ -28,6 +28,13 @@ Copies source data to a destination location. The supported directions are:
   - Azure Files (SAS) -> Azure Files (SAS)
   - Azure Files (SAS) -> Azure Blob (SAS or OAuth authentication)
   - AWS S3 (Access Key) -> Azure Block Blob (SAS or OAuth authentication)
+  - local <-> AWS S3 (Access Key)
+  - Azure Blob (SAS or public) -> AWS S3 (Access Key)
+  - Google Cloud Storage (HMAC Key or service account) -> Azure Block Blob (SAS or OAuth authentication)
+  - HTTP(S) web server (public) -> Azure Blob (SAS or OAuth authentication)
+  - ADLS Gen 2 (SAS) -> Azure Blob (SAS or OAuth authentication)
+  - ADLS Gen 2 (SAS) -> ADLS Gen 2 (SAS, OAuth, or SharedKey authentication)
+  - local <-> local
 
 Please refer to the examples for more information.
 
@@ -158,6 +165,58 @@ Copy all buckets to Blob Storage from an Amazon Web Services (AWS) region by usi
 Copy a subset of buckets by using a wildcard symbol (*) in the bucket name. Like the previous examples, you'll need an access key and a SAS token. Make sure to set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for AWS S3 source.
 
   - azcopy cp "https://s3.amazonaws.com/[bucket*name]/" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
+
+Copy a bucket to Blob Storage from an S3-compatible service such as MinIO, Ceph or Wasabi. First, set the environment variable AZCOPY_S3_ENDPOINT to the host name (and port) of the service, and optionally AZCOPY_S3_REGION, along with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+
+  - azcopy cp "http://minio.contoso.local:9000/[bucket]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive=true
+
+Copy a bucket to Blob Storage from Google Cloud Storage by using an HMAC key and a SAS token. First, set the environment variable GOOGLE_CLOUD_HMAC_ACCESS_ID and GOOGLE_CLOUD_HMAC_SECRET for the GCS source.
+
+  - azcopy cp "https://storage.googleapis.com/[bucket]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive=true
+
+Copy a bucket to Blob Storage from Google Cloud Storage by using a service account and a SAS token. First, set the environment variable GOOGLE_APPLICATION_CREDENTIALS to the path of the service account's JSON key file. (An HMAC key is used instead, if one is set.)
+
+  - azcopy cp "https://storage.googleapis.com/[bucket]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive=true
+
+Copy all buckets to Blob Storage from Google Cloud Storage by using an HMAC key and a SAS token.
+
+  - azcopy cp "https://storage.googleapis.com/" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
+
+Copy a single file from a public web server to Blob Storage. The file must be reachable by the Blob service itself, as the data is copied server-side.
+
+  - azcopy cp "https://[server]/[path]/[file]" "https://[destaccount].blob.core.windows.net/[container]/[path]/[file]?[SAS]"
+
+Copy a single file from a web server that's addressed by IP. Since Azure Stack and the storage emulator are addressed that way too, say that it's a web server with --from-to.
+
+  - azcopy cp "https://[IP address]/[path]/[file]" "https://[destaccount].blob.core.windows.net/[container]/[path]/[file]?[SAS]" --from-to=HttpBlob
+
+Copy many files from a web server to Blob Storage in parallel, by listing them in a file (one per line). The entries may be paths relative to the source, or full URLs under it.
+
+  - azcopy cp "https://[server]/[path]/" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --list-of-files=/path/to/list.txt
+
+Download a single object from AWS S3 by using an access key. First, set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for AWS S3 source.
+
+  - azcopy cp "https://s3.amazonaws.com/[bucket]/[object]" "/path/to/file.txt"
+
+Download an entire directory from AWS S3 by using an access key.
+
+  - azcopy cp "https://s3.amazonaws.com/[bucket]/[folder]" "/path/to/dir" --recursive=true
+
+Upload an entire directory to AWS S3 by using an access key. Files larger than the block size are sent as multipart uploads.
+
+  - azcopy cp "/path/to/dir" "https://s3.amazonaws.com/[bucket]/[folder]" --recursive=true --block-size-mb=16
+
+Copy a container from Blob Storage to AWS S3 by using a SAS token and an access key. First, set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for AWS S3 destination.
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "https://s3.amazonaws.com/[bucket]" --recursive=true
+
+Copy a directory between two ADLS Gen 2 accounts, including any empty directories, by using a SAS token for the source.
+
+  - azcopy cp "https://[srcaccount].dfs.core.windows.net/[filesystem]/[path/to/directory]?[SAS]" "https://[destaccount].dfs.core.windows.net/[filesystem]/[path/to/directory]" --recursive=true
+
+Copy a directory between two local paths (e.g. from a local disk to a mounted network share) in parallel, resumably.
+
+  - azcopy cp "/path/to/dir" "/mnt/share/path/to/dir" --recursive=true
 `
 
 // ===================================== ENV COMMAND ===================================== //
@@ -316,6 +375,10 @@ Remove specific blobs and virtual directories by putting their relative paths (N
 	   blob1
 	   blob2
 
+List the blobs that would be removed from a virtual directory, without removing anything:
+
+   - azcopy rm "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --dry-run
+
 Remove a single file from a Blob Storage account that has a hierarchical namespace (include/exclude not supported):
 
    - azcopy rm "https://[account].dfs.core.windows.net/[container]/[path/to/file]?[SAS]"
@@ -329,11 +392,16 @@ Remove a single directory from a Blob Storage account that has a hierarchical na
 const syncCmdShortDescription = "Replicate source to the destination location"
 
 const syncCmdLongDescription = `
-The last modified times are used for comparison. The file is skipped if the last modified time in the destination is more recent. The supported pairs are:
+By default, the last modified times are used for comparison. The file is skipped if the last modified time in the destination is more recent.
+Use --compare-hash=MD5 to compare the content hashes instead, or --size-only to compare only the sizes. The supported pairs are:
   
   - local <-> Azure Blob (either SAS or OAuth authentication can be used)
+  - local <-> Azure File (SAS authentication should be used)
   - Azure Blob <-> Azure Blob (Source must include a SAS or is publicly accessible; either SAS or OAuth authentication can be used for destination)
   - Azure File <-> Azure File (Source must include a SAS or is publicly accessible; SAS authentication should be used for destination)
+  - Azure File <-> Azure Blob, and Azure Blob <-> Azure Data Lake Storage Gen2, in either direction (Source must include a SAS or is publicly accessible;
+    the destination is authorized on its own, in the same way as it would be for a copy to it)
+  - Amazon S3 -> Azure Blob (the source is authorized with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY; either SAS or OAuth authentication can be used for destination)
 
 The sync command differs from the copy command in several ways:
 
@@ -353,8 +421,49 @@ The built-in lookup table is small but on Unix it is augmented by the local syst
 
 On Windows, MIME types are extracted from the registry.
 
-Please also note that sync works off of the last modified times exclusively. So in the case of Azure File <-> Azure File,
+Please also note that, unless --compare-hash or --size-only is used, sync works off of the last modified times exclusively. So in the case of Azure File <-> Azure File,
 the header field Last-Modified is used instead of x-ms-file-change-time, which means that metadata changes at the source can also trigger a full copy.
+
+Between a local directory and an Azure File share, --preserve-smb-info and --preserve-smb-permissions (Windows only) carry the SMB attributes, times and ACLs across,
+as they would for a copy. With --force-if-read-only, files that have the read-only attribute set at the destination are overwritten and deleted too,
+which is usually wanted when a share is kept as a mirror of an on-premises file server.
+
+With --compare-hash=MD5, a file is transferred if its size or MD5 differs from the destination's, or if either MD5 is not known.
+The MD5s of remote files are taken from their Content-MD5 properties, so they should have been uploaded with --put-md5.
+The MD5s of local files are computed, and remembered in the job plan folder so that unchanged files need not be read again on the next sync.
+
+By default, one side is indexed before the other is listed. If that index grows past AZCOPY_SYNC_INDEX_GB, the rest of it is kept on disk in the job plan folder.
+Local folders, Blob containers and S3 buckets can also be listed in order, so with --compare-in-order, sync between them compares the two listings as they arrive, and starts transferring straight away.
+
+With --bidirectional, between a local directory and Blob storage, the changes made at either end since the last sync are applied to the other.
+What each file looked like after the last sync is recorded in the job plan folder, so that a file deleted from one end can be told apart from a file created at the other.
+Deletions are only applied if --delete-destination is true or prompt. A file changed at both ends is a conflict, which is settled by --conflict-policy.
+The transfers from the destination to the source run as a second job, since a job only ever transfers in one direction; both jobs are reported together.
+A two-way sync that was interrupted should be run again, rather than resumed, so that its record can be brought up to date.
+
+With --incremental, when uploading to Blob storage, a manifest of what was uploaded is kept in the job plan folder, and the next sync compares the local files against it
+instead of listing the destination. Use --full-check-interval to have the destination listed every so often anyway, in case something else has changed it.
+
+From Amazon S3, an object is transferred if its size differs from the destination's. Otherwise, if the MD5s of both are known, they decide, and if not the last modified times do.
+The MD5 of an S3 object is its Content-MD5, or failing that its ETag, which is an MD5 for objects uploaded in one part without SSE-KMS or SSE-C.
+
+With --dry-run, the source and destination are listed and compared as usual, but instead of transferring or deleting anything,
+sync prints what it would copy, overwrite and delete, followed by the totals. With --output-type=json, each of these is a separate message.
+
+With --backup-destination, each file that sync is about to delete or overwrite at the destination is first kept under a folder named for the time of the sync
+(e.g. backups/20201017T093000Z/path/to/file), so that it can be recovered. Blobs and Azure Files are copied server-side within the same container or share,
+while local files and ADLS Gen2 files are moved. If the backups are inside the destination, they are left out of the comparison.
+
+With --follow-symlinks, symlinks at a local source are followed. The contents of a symlinked folder are only synced once, under one path that leads to them,
+which is chosen the same way every time, so that what's found where doesn't change from one sync to the next: the folder's own path, if it's inside the source,
+or else the first symlink to it in order of path, where symlinks that are only reached through other symlinks come after those that aren't.
+Symlinks to folders that have already been synced are skipped.
+If a symlink's target moves away, what was synced through the symlink is no longer at the source, and so is deleted at the destination (with --delete-destination=true).
+
+With --watch (Linux only), once the destination matches a local source, sync carries on watching the source. Files that are written to it are transferred,
+and files that are removed from it are deleted from the destination if --delete-destination is true, once the source has been quiet for --watch-debounce.
+Each batch of changes is a part of the current job. After 1000 parts, or a day, the watch moves on to a new job, with a log of its own,
+and the plan files of the old job are deleted once its transfers are done. Press Ctrl-C once to stop watching and let the transfers finish, or twice to cancel them.
 `
 
 const syncCmdExample = `
@@ -372,6 +481,22 @@ Sync an entire directory including its subdirectories (note that recursive is by
 or
   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --put-md5
 
+Sync a directory, transferring only the files whose content differs from the destination's (as judged by MD5):
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --compare-hash=MD5
+
+Sync a directory in both directions, keeping both versions of any file that was changed at both ends:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --bidirectional --conflict-policy=KeepBoth --delete-destination=true
+
+Sync a directory without listing the destination, except for a full check once a day:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --incremental --full-check-interval=24h
+
+Sync an Azure Files share into a Blob container:
+
+   - azcopy sync "https://[account].file.core.windows.net/[share]?[SAS]" "https://[account].blob.core.windows.net/[container]?[SAS]" --recursive=true
+
 Sync only the files inside of a directory but not subdirectories or the files inside of subdirectories:
 
    - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --recursive=false
@@ -400,6 +525,26 @@ Sync an Azure File directory (same syntax as Blob):
 
    - azcopy sync "https://[account].file.core.windows.net/[share]/[path/to/dir]?[SAS]" "https://[account].file.core.windows.net/[share]/[path/to/dir]" --recursive=true
 
+Mirror a directory of a Windows file server into an Azure File share, with its SMB properties and ACLs, even where files are read-only:
+
+   - azcopy sync "D:\shares\finance" "https://[account].file.core.windows.net/[share]/finance?[SAS]" --preserve-smb-info --preserve-smb-permissions --force-if-read-only --delete-destination=true
+
+Preview what a sync would copy, overwrite and delete, without changing anything at the destination:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --delete-destination=true --dry-run
+
+Sync a directory to a container, keeping whatever is deleted or overwritten under "backups" in the same container:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true --backup-destination=backups
+
+Keep a container up to date with a local directory that files are being added to, until stopped with Ctrl-C:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --watch --watch-debounce=5s
+
+Sync an S3 bucket to a Blob container, deleting the blobs whose objects are no longer in the bucket (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY first):
+
+   - azcopy sync "https://s3.amazonaws.com/[bucket]" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true
+
 Note: if include and exclude flags are used together, only files matching the include patterns are used, but those matching the exclude patterns are ignored.
 `
 
@@ -57,8 +57,8 @@ func init() {
 			// the expected argument in input is the container sas / or path of virtual directory in the container.
 			// verifying the location type
 			location := inferArgumentLocation(sourcePath)
-			// Only support listing for Azure locations
-			if location != location.Blob() && location != location.File() && location != location.BlobFS() {
+			// Only support listing for Azure locations, S3 and GCP
+			if location != location.Blob() && location != location.File() && location != location.BlobFS() && location != location.S3() && location != location.GCP() {
 				glcm.Error("invalid path passed for listing. given source is of type " + location.String() + " while expect is container / container path ")
 			}
 
@@ -119,7 +119,7 @@ func HandleListContainerCommand(unparsedSource string, location common.Location)
 		}
 	}
 
-	traverser, err := initResourceTraverser(source, location, &ctx, &credentialInfo, nil, nil, true, false, false, func(common.EntityType) {}, nil)
+	traverser, err := initResourceTraverser(source, location, &ctx, &credentialInfo, nil, nil, true, false, false, func(common.EntityType) {}, nil, false, common.CpkOptions{})
 
 	if err != nil {
 		return fmt.Errorf("failed to initialize traverser: %s", err.Error())
@@ -56,10 +56,25 @@ func determineLocationLevel(location string, locationType common.Location, sourc
 	case common.ELocation.Benchmark():
 		return ELocationLevel.Object(), nil // we always benchmark to a subfolder, not the container root
 
+	case common.ELocation.Http():
+		URL, err := url.Parse(location)
+
+		if err != nil {
+			return ELocationLevel.Service(), err
+		}
+
+		// A web server has no containers, but a directory URL (e.g. one used with list-of-files) acts as the root of the transfer,
+		// just as a container would
+		if URL.Path == "" || strings.HasSuffix(URL.Path, "/") {
+			return ELocationLevel.Container(), nil
+		}
+		return ELocationLevel.Object(), nil
+
 	case common.ELocation.Blob(),
 		common.ELocation.File(),
 		common.ELocation.BlobFS(),
-		common.ELocation.S3():
+		common.ELocation.S3(),
+		common.ELocation.GCP():
 		URL, err := url.Parse(location)
 
 		if err != nil {
@@ -99,7 +114,8 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 	// todo: reduce code-delicateness, maybe?
 	switch location {
 	case common.ELocation.Unknown(),
-		common.ELocation.Benchmark(): // do nothing
+		common.ELocation.Benchmark(),
+		common.ELocation.Http(): // do nothing, there are no wildcards to eliminate
 		return resource, nil
 	case common.ELocation.Local():
 		return cleanLocalPath(getPathBeforeFirstWildcard(resource)), nil
@@ -164,6 +180,22 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 
 		s3URL := s3URLParts.URL()
 		return s3URL.String(), nil
+
+	// noinspection GoNilness
+	case common.ELocation.GCP():
+		gcpURLParts, err := common.NewGCPURLParts(*resourceURL)
+		common.PanicIfErr(err)
+
+		if gcpURLParts.BucketName == "" || strings.Contains(gcpURLParts.BucketName, "*") {
+			if gcpURLParts.ObjectKey != "" {
+				return resource, errors.New("cannot combine account-level traversal and specific object names")
+			}
+
+			gcpURLParts.BucketName = ""
+		}
+
+		gcpURL := gcpURLParts.URL()
+		return gcpURL.String(), nil
 	default:
 		panic(fmt.Sprintf("Location %s is missing from GetResourceRoot", location))
 	}
@@ -205,8 +237,10 @@ func splitAuthTokenFromResource(resource string, location common.Location) (reso
 		*baseURL = common.URLExtension{URL: *baseURL}.URLWithPlusDecodedInPath()
 		return baseURL.String(), "", nil
 	case common.ELocation.Benchmark(), // cover for benchmark as we generate data for that
+		common.ELocation.GCP(),     // GCP is authenticated with an HMAC key from the environment
+		common.ELocation.Http(),    // any query string on a plain web server URL is kept as-is, since we can't know what it means
 		common.ELocation.Unknown(): // cover for unknown as we treat that as garbage
-		// Local and S3 don't feature URL-embedded tokens
+		// Local, S3 and GCP don't feature URL-embedded tokens
 		return resource, "", nil
 
 	// Use resource-specific APIs that all mostly do the same thing, just on the off-chance they end up doing something slightly different in the future.
@@ -295,6 +329,38 @@ func splitQueryFromSaslessResource(resource string, loc common.Location) (mainUr
 	}
 }
 
+// Lets list-of-files entries for plain web servers be given as full URLs, which is natural when mirroring a site.
+// Such entries must fall under the source URL, and are returned relative to it (not URL-encoded, like any other list entry).
+// Entries that aren't URLs are assumed to be relative already.
+func relativePathFromHttpListEntry(source common.ResourceString, entry string) (string, error) {
+	entryURL, err := url.Parse(entry)
+	if err != nil || entryURL.Scheme == "" || entryURL.Host == "" {
+		return entry, nil
+	}
+
+	sourceURL, err := url.Parse(source.Value)
+	if err != nil {
+		return "", err
+	}
+
+	sourcePrefix := sourceURL.Path
+	if !strings.HasSuffix(sourcePrefix, "/") {
+		sourcePrefix += "/"
+	}
+
+	if !strings.EqualFold(entryURL.Scheme, sourceURL.Scheme) || !strings.EqualFold(entryURL.Host, sourceURL.Host) ||
+		!strings.HasPrefix(entryURL.Path, sourcePrefix) {
+		return "", fmt.Errorf("the URL is not under the source %s", source.Value)
+	}
+
+	// Every transfer shares the query string of the source, so we can't honour one given per entry
+	if entryURL.RawQuery != "" && entryURL.RawQuery != source.ExtraQuery {
+		return "", errors.New("query strings are only supported on the source URL, not on the entries of list-of-files")
+	}
+
+	return strings.TrimPrefix(entryURL.Path, sourcePrefix), nil
+}
+
 // All of the below functions only really do one thing at the moment.
 // They've been separated from copyEnumeratorInit.go in order to make the code more maintainable, should we want more destinations in the future.
 func getPathBeforeFirstWildcard(path string) string {
@@ -364,6 +430,20 @@ func GetContainerName(path string, location common.Location) (string, error) {
 		}
 
 		return s3URLParts.BucketName, nil
+	case common.ELocation.GCP():
+		baseURL, err := url.Parse(path)
+
+		if err != nil {
+			return "", err
+		}
+
+		gcpURLParts, err := common.NewGCPURLParts(*baseURL)
+
+		if err != nil {
+			return "", err
+		}
+
+		return gcpURLParts.BucketName, nil
 	default:
 		return "", fmt.Errorf("cannot get container name on location type %s", location.String())
 	}
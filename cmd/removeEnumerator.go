@@ -47,7 +47,7 @@ func newRemoveEnumerator(cca *cookedCopyCmdArgs) (enumerator *copyEnumerator, er
 
 	// Include-path is handled by ListOfFilesChannel.
 	sourceTraverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &cca.credentialInfo, nil,
-		cca.listOfFilesChannel, cca.recursive, false, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
+		cca.listOfFilesChannel, cca.recursive, false, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs, false, common.CpkOptions{})
 
 	// report failure to create traverser
 	if err != nil {
@@ -71,6 +71,15 @@ func newRemoveEnumerator(cca *cookedCopyCmdArgs) (enumerator *copyEnumerator, er
 		ste.JobsAdmin.LogToJobLog(message, pipeline.LogInfo)
 	}
 
+	if cca.dryrunReporter != nil {
+		reportDeletion := newFpoAwareProcessor(fpo, cca.dryrunReporter.deletionProcessor(cca.source))
+		finalize := func() error {
+			cca.dryrunReporter.exit()
+			return nil
+		}
+		return newCopyEnumerator(sourceTraverser, filters, reportDeletion, finalize), nil
+	}
+
 	transferScheduler := newRemoveTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
 
 	finalize := func() error {
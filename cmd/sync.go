@@ -24,6 +24,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"runtime"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -59,6 +61,9 @@ type rawSyncCmdArgs struct {
 	backupMode             bool
 	putMd5                 bool
 	md5ValidationOption    string
+	cpkByValue             bool // use the customer-provided key in the environment for server-side encryption
+	cpkByName              string
+	encryptionScope        string
 	// this flag indicates the user agreement with respect to deleting the extra files at the destination
 	// which do not exists at source. With this flag turned on/off, users will not be asked for permission.
 	// otherwise the user is prompted to make a decision
@@ -67,6 +72,31 @@ type rawSyncCmdArgs struct {
 	s2sPreserveAccessTier bool
 
 	forceIfReadOnly bool
+
+	// how to decide whether a file that exists at both ends has changed
+	compareHash string
+	sizeOnly    bool
+
+	// whether to apply the changes made at the destination to the source as well, and how to settle conflicts between them
+	bidirectional  bool
+	conflictPolicy string
+
+	// whether to compare against the manifest of the last sync, instead of listing the destination, and how often to list it anyway
+	incremental       bool
+	fullCheckInterval string
+
+	// whether to compare the two listings as they arrive, in order, rather than indexing one side first
+	compareInOrder bool
+
+	// whether to only report what would be transferred and deleted
+	dryrun bool
+
+	// where to keep the destination files that would otherwise be deleted or overwritten
+	backupDestination string
+
+	// whether to carry on once the destination matches the source, watching the source for changes, and how long it must be quiet before they're transferred
+	watch         bool
+	watchDebounce string
 }
 
 func (raw *rawSyncCmdArgs) parsePatterns(pattern string) (cookedPatterns []string) {
@@ -108,13 +138,16 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	var err error
 	if cooked.fromTo == common.EFromTo.Unknown() {
 		return cooked, fmt.Errorf("Unable to infer the source '%s' / destination '%s'. ", raw.src, raw.dst)
-	} else if cooked.fromTo == common.EFromTo.LocalBlob() {
+	} else if cooked.fromTo == common.EFromTo.LocalBlob() || cooked.fromTo == common.EFromTo.LocalFile() {
 		cooked.destination, err = SplitResourceString(raw.dst, cooked.fromTo.To())
 		common.PanicIfErr(err)
-	} else if cooked.fromTo == common.EFromTo.BlobLocal() {
+	} else if cooked.fromTo == common.EFromTo.BlobLocal() || cooked.fromTo == common.EFromTo.FileLocal() {
 		cooked.source, err = SplitResourceString(raw.src, cooked.fromTo.From())
 		common.PanicIfErr(err)
-	} else if cooked.fromTo == common.EFromTo.BlobBlob() || cooked.fromTo == common.EFromTo.FileFile() {
+	} else if cooked.fromTo == common.EFromTo.BlobBlob() || cooked.fromTo == common.EFromTo.FileFile() ||
+		cooked.fromTo == common.EFromTo.FileBlob() || cooked.fromTo == common.EFromTo.BlobFile() ||
+		cooked.fromTo == common.EFromTo.BlobBlobFS() || cooked.fromTo == common.EFromTo.BlobFSBlob() || cooked.fromTo == common.EFromTo.BlobFSBlobFS() ||
+		cooked.fromTo == common.EFromTo.S3Blob() {
 		cooked.destination, err = SplitResourceString(raw.dst, cooked.fromTo.To())
 		common.PanicIfErr(err)
 		cooked.source, err = SplitResourceString(raw.src, cooked.fromTo.From())
@@ -155,8 +188,8 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	}
 
 	cooked.followSymlinks = raw.followSymlinks
-	if err = crossValidateSymlinksAndPermissions(cooked.followSymlinks, true /* replace with real value when available */); err != nil {
-		return cooked, err
+	if cooked.followSymlinks && cooked.fromTo.From() != common.ELocation.Local() {
+		return cooked, fmt.Errorf("follow-symlinks is only supported when syncing from a local directory")
 	}
 	cooked.recursive = raw.recursive
 	cooked.forceIfReadOnly = raw.forceIfReadOnly
@@ -197,11 +230,13 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if err = validatePreserveSMBPropertyOption(raw.preserveSMBPermissions, cooked.fromTo, nil, "preserve-smb-permissions"); err != nil {
 		return cooked, err
 	}
-	// TODO: the check on raw.preserveSMBPermissions on the next line can be removed once we have full support for these properties in sync
-	//if err = validatePreserveOwner(raw.preserveOwner, cooked.fromTo); raw.preserveSMBPermissions && err != nil {
-	//	return cooked, err
-	//}
+	if err = validatePreserveOwner(raw.preserveOwner, cooked.fromTo); err != nil {
+		return cooked, err
+	}
 	cooked.preserveSMBPermissions = common.NewPreservePermissionsOption(raw.preserveSMBPermissions, raw.preserveOwner, cooked.fromTo)
+	if err = crossValidateSymlinksAndPermissions(cooked.followSymlinks, cooked.preserveSMBPermissions.IsTruthy()); err != nil {
+		return cooked, err
+	}
 
 	cooked.preserveSMBInfo = raw.preserveSMBInfo
 	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.fromTo, nil, "preserve-smb-info"); err != nil {
@@ -221,13 +256,131 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, err
 	}
 
+	cooked.cpkOptions, err = validateCpkOptions(raw.cpkByValue, raw.cpkByName, raw.encryptionScope, cooked.fromTo)
+	if err != nil {
+		return cooked, err
+	}
+
 	if cooked.fromTo.IsS2S() {
 		cooked.preserveAccessTier = raw.s2sPreserveAccessTier
 	}
 
+	err = cooked.compareHash.Parse(raw.compareHash)
+	if err != nil {
+		return cooked, err
+	}
+	cooked.sizeOnly = raw.sizeOnly
+	if cooked.sizeOnly && cooked.compareHash != common.ECompareHashType.None() {
+		return cooked, fmt.Errorf("compare-hash and size-only cannot be used together")
+	}
+
+	cooked.bidirectional = raw.bidirectional
+	err = cooked.conflictPolicy.Parse(raw.conflictPolicy)
+	if err != nil {
+		return cooked, err
+	}
+	if cooked.bidirectional {
+		if cooked.fromTo != common.EFromTo.LocalBlob() && cooked.fromTo != common.EFromTo.BlobLocal() {
+			return cooked, fmt.Errorf("two-way sync is only supported between a local directory and Blob storage")
+		}
+		if cooked.sizeOnly || cooked.compareHash != common.ECompareHashType.None() {
+			return cooked, fmt.Errorf("two-way sync cannot be used with compare-hash or size-only, since it decides what changed from the state of the last sync")
+		}
+		if cooked.followSymlinks {
+			return cooked, fmt.Errorf("two-way sync cannot follow symlinks, since the changes from Blob storage would be made wherever the symlinks point")
+		}
+
+		// the hashes of uploaded blobs are what tell us, next time, whether they've been changed remotely
+		cooked.putMd5 = true
+		cooked.returnJobID = common.NewJobID()
+	}
+
+	cooked.incremental = raw.incremental
+	if cooked.incremental {
+		if cooked.fromTo != common.EFromTo.LocalBlob() {
+			return cooked, fmt.Errorf("incremental sync is only supported when uploading to Blob storage")
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("incremental sync cannot be used with two-way sync, since it doesn't notice changes made at the destination")
+		}
+		if cooked.followSymlinks {
+			return cooked, fmt.Errorf("incremental sync cannot follow symlinks, since the contents of symlinked folders can't be listed in order")
+		}
+	}
+	if raw.fullCheckInterval != "" {
+		if !cooked.incremental {
+			return cooked, fmt.Errorf("full-check-interval can only be used with incremental sync")
+		}
+		cooked.fullCheckInterval, err = time.ParseDuration(raw.fullCheckInterval)
+		if err != nil {
+			return cooked, fmt.Errorf("invalid full-check-interval: %s", err)
+		}
+	}
+
+	cooked.compareInOrder = raw.compareInOrder
+	if cooked.compareInOrder && cooked.followSymlinks {
+		return cooked, fmt.Errorf("compare-in-order cannot be used with follow-symlinks, since the contents of symlinked folders can't be listed in order")
+	}
+
+	if raw.dryrun {
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("dry-run cannot be used with two-way sync, since settling conflicts can rename local files")
+		}
+		cooked.dryrunReporter = newDryrunReporter()
+	}
+
+	if raw.backupDestination != "" {
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("backup-destination cannot be used with two-way sync")
+		}
+		cooked.backupDestination, cooked.backupExcludedPath, err = cookBackupDestination(raw.backupDestination, cooked.destination, cooked.fromTo.To())
+		if err != nil {
+			return cooked, err
+		}
+	}
+
+	if raw.watch {
+		if cooked.fromTo.From() != common.ELocation.Local() {
+			return cooked, fmt.Errorf("watch is only supported when syncing from a local directory")
+		}
+		if runtime.GOOS != "linux" {
+			return cooked, fmt.Errorf("watch is only supported on Linux")
+		}
+		if cooked.bidirectional || cooked.incremental || cooked.dryrunReporter != nil {
+			return cooked, fmt.Errorf("watch cannot be used with two-way sync, incremental sync or dry-run")
+		}
+		if cooked.followSymlinks {
+			return cooked, fmt.Errorf("watch cannot be used with follow-symlinks, since changes made in symlinked folders aren't noticed")
+		}
+		if cooked.deleteDestination == common.EDeleteDestination.Prompt() {
+			return cooked, fmt.Errorf("watch cannot be used with delete-destination=prompt, since there may be nobody to answer the prompts; use true or false")
+		}
+
+		debounce := defaultSyncWatchDebounce
+		if raw.watchDebounce != "" {
+			debounce, err = time.ParseDuration(raw.watchDebounce)
+			if err != nil || debounce <= 0 {
+				return cooked, fmt.Errorf("invalid watch-debounce '%s': it must be a positive duration, e.g. 5s", raw.watchDebounce)
+			}
+		}
+		cooked.watch = newSyncWatch(debounce)
+	} else if raw.watchDebounce != "" {
+		return cooked, fmt.Errorf("watch-debounce can only be used with watch")
+	}
+
 	return cooked, nil
 }
 
+// persistentSyncRecord is something that a sync remembers for the next sync of the same source and destination,
+// which can only be saved once the transfers are over, since what it records depends on whether they succeeded
+type persistentSyncRecord interface {
+	// commit saves the record. Unless all the transfers succeeded, failedTransfers holds the paths of those that failed, relative to the root they were copied from,
+	// or is nil if that isn't known (e.g. if the job was cancelled, so that some transfers never ran)
+	commit(transfersSucceeded bool, failedTransfers map[string]bool) error
+	// discard throws away what has been gathered for the record, when the sync fails before there's a job to wait for
+	discard() error
+}
+
 type cookedSyncCmdArgs struct {
 	// NOTE: for the 64 bit atomic functions to work on a 32 bit system, we have to guarantee the right 64-bit alignment
 	// so the 64 bit integers are placed first in the struct to avoid future breaks
@@ -242,10 +395,15 @@ type cookedSyncCmdArgs struct {
 	// defines whether first part has been ordered or not.
 	// 0 means first part is not ordered and 1 means first part is ordered.
 	atomicFirstPartOrdered uint32
+	// the same, for the job that holds the return leg of a two-way sync
+	atomicReturnFirstPartOrdered uint32
 
 	// deletion count keeps track of how many extra files from the destination were removed
 	atomicDeletionCount uint32
 
+	// conflict count keeps track of how many files were changed at both ends, in a two-way sync
+	atomicConflictCount uint32
+
 	source         common.ResourceString
 	destination    common.ResourceString
 	fromTo         common.FromTo
@@ -265,10 +423,35 @@ type cookedSyncCmdArgs struct {
 	preserveSMBInfo        bool
 	putMd5                 bool
 	md5ValidationOption    common.HashValidationOption
+	cpkOptions             common.CpkOptions
 	blockSize              int64
 	logVerbosity           common.LogLevel
 	forceIfReadOnly        bool
 	backupMode             bool
+	compareHash            common.CompareHashType
+	sizeOnly               bool
+	bidirectional          bool
+	conflictPolicy         common.SyncConflictPolicy
+	incremental            bool
+	fullCheckInterval      time.Duration
+	compareInOrder         bool
+
+	// what this sync remembers for next time, which is saved once the job is done
+	syncRecord persistentSyncRecord
+
+	// set in a dry run, which reports the transfers and deletions instead of performing them
+	dryrunReporter *dryrunReporter
+
+	// where the destination files that would otherwise be deleted or overwritten are kept, if anywhere,
+	// and the path of the backups relative to the destination, if they're inside it
+	backupDestination  string
+	backupExcludedPath string
+
+	// set up from backupDestination when the sync starts, so that all of its backups go in the same folder
+	backup syncBackup
+
+	// set with --watch, to carry on once the destination matches the source, feeding the changes to the source into a series of jobs
+	watch *syncWatch
 
 	// commandString hold the user given command which is logged to the Job log file
 	commandString string
@@ -276,6 +459,10 @@ type cookedSyncCmdArgs struct {
 	// generated
 	jobID common.JobID
 
+	// the job for the transfers from the destination to the source, in a two-way sync.
+	// A job only ever transfers in one direction, so these can't go in the same job as the rest.
+	returnJobID common.JobID
+
 	// variables used to calculate progress
 	// intervalStartTime holds the last time value when the progress summary was fetched
 	// the value of this variable is used to calculate the throughput
@@ -299,6 +486,15 @@ type cookedSyncCmdArgs struct {
 	preserveAccessTier bool
 }
 
+// cpkOptionsFor gets the customer-provided key options for listing one side of the sync. As in copy, they're the destination's
+// if it's in Blob storage, since the service can't copy from blobs encrypted with a customer-provided key, and otherwise the source's.
+func (cca *cookedSyncCmdArgs) cpkOptionsFor(isSource bool) common.CpkOptions {
+	if isSource == (cca.fromTo.To() != common.ELocation.Blob()) {
+		return cca.cpkOptions
+	}
+	return common.CpkOptions{}
+}
+
 func (cca *cookedSyncCmdArgs) incrementDeletionCount() {
 	atomic.AddUint32(&cca.atomicDeletionCount, 1)
 }
@@ -317,6 +513,101 @@ func (cca *cookedSyncCmdArgs) firstPartOrdered() bool {
 	return atomic.LoadUint32(&cca.atomicFirstPartOrdered) > 0
 }
 
+// setReturnFirstPartOrdered sets the value of atomicReturnFirstPartOrdered to 1
+func (cca *cookedSyncCmdArgs) setReturnFirstPartOrdered() {
+	atomic.StoreUint32(&cca.atomicReturnFirstPartOrdered, 1)
+}
+
+// returnFirstPartOrdered returns the value of atomicReturnFirstPartOrdered.
+func (cca *cookedSyncCmdArgs) returnFirstPartOrdered() bool {
+	return atomic.LoadUint32(&cca.atomicReturnFirstPartOrdered) > 0
+}
+
+// orderedJobIDs returns the jobs that have been started so far: the sync's own job, and the job for the return leg of a two-way sync
+func (cca *cookedSyncCmdArgs) orderedJobIDs() []common.JobID {
+	if cca.watch != nil {
+		return cca.watch.orderedJobIDs()
+	}
+
+	jobIDs := make([]common.JobID, 0, 2)
+	if cca.firstPartOrdered() {
+		jobIDs = append(jobIDs, cca.jobID)
+	}
+	if cca.returnFirstPartOrdered() {
+		jobIDs = append(jobIDs, cca.returnJobID)
+	}
+	return jobIDs
+}
+
+// getJobSummary asks the STE for the summary of a job
+func getJobSummary(jobID common.JobID) (summary common.ListJobSummaryResponse) {
+	Rpc(common.ERpcCmd.ListJobSummary(), &jobID, &summary)
+	return
+}
+
+// summarizeJobs adds up the summaries of the given jobs, and tells whether they're all done.
+// It returns false if there are no jobs.
+func summarizeJobs(jobIDs []common.JobID, getSummary func(jobID common.JobID) common.ListJobSummaryResponse) (summary common.ListJobSummaryResponse, done bool, ok bool) {
+	if len(jobIDs) == 0 {
+		return summary, false, false
+	}
+
+	done = true
+	for i, jobID := range jobIDs {
+		jobSummary := getSummary(jobID)
+		done = done && jobSummary.JobStatus.IsJobDone()
+		if i == 0 {
+			summary = jobSummary
+		} else {
+			summary = combineJobSummaries(summary, jobSummary)
+		}
+	}
+	return summary, done, true
+}
+
+// failedTransferPaths lists the transfers of the sync's jobs that failed, by their paths relative to the root they were copied from.
+// It returns nil if they can't be listed.
+func (cca *cookedSyncCmdArgs) failedTransferPaths() map[string]bool {
+	failed := make(map[string]bool)
+	for _, jobID := range cca.orderedJobIDs() {
+		// the return leg of a two-way sync copies from the destination
+		root, rootLocation := cca.source, cca.fromTo.From()
+		if jobID == cca.returnJobID {
+			root, rootLocation = cca.destination, cca.fromTo.To()
+		}
+		rootPath := root.CloneWithConsolidatedSeparators().Value
+
+		var response common.ListJobTransfersResponse
+		Rpc(common.ERpcCmd.ListJobTransfers(), common.ListJobTransfersRequest{JobID: jobID, OfStatus: common.ETransferStatus.Failed()}, &response)
+		if response.ErrorMsg != "" {
+			return nil
+		}
+		for _, transfer := range response.Details {
+			failed[transferRelativePath(transfer.Src, rootPath, rootLocation)] = true
+		}
+	}
+	return failed
+}
+
+// transferRelativePath turns the full path of a transfer's source back into the relative path that it was scheduled with
+func transferRelativePath(fullPath, rootPath string, location common.Location) string {
+	relativePath := strings.TrimPrefix(fullPath, rootPath)
+	if location == common.ELocation.Local() {
+		if runtime.GOOS == "windows" {
+			relativePath = strings.ReplaceAll(relativePath, `\`, common.AZCOPY_PATH_SEPARATOR_STRING)
+		}
+	} else {
+		// remote paths carry any extra query, and are escaped
+		if queryStart := strings.Index(relativePath, "?"); queryStart >= 0 {
+			relativePath = relativePath[:queryStart]
+		}
+		if unescaped, err := url.PathUnescape(relativePath); err == nil {
+			relativePath = unescaped
+		}
+	}
+	return strings.Trim(relativePath, common.AZCOPY_PATH_SEPARATOR_STRING)
+}
+
 // setScanningComplete sets the value of atomicScanningStatus to 1.
 func (cca *cookedSyncCmdArgs) setScanningComplete() {
 	atomic.StoreUint32(&cca.atomicScanningStatus, 1)
@@ -333,6 +624,10 @@ func (cca *cookedSyncCmdArgs) scanningComplete() bool {
 func (cca *cookedSyncCmdArgs) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
 	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), false, ""))
+	if cca.bidirectional {
+		glcm.Info(fmt.Sprintf("Transfers from the destination to the source are in job %s, with log file %s%s%s.log",
+			cca.returnJobID, azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.returnJobID))
+	}
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
@@ -350,6 +645,12 @@ func (cca *cookedSyncCmdArgs) waitUntilJobCompletion(blocking bool) {
 }
 
 func (cca *cookedSyncCmdArgs) Cancel(lcm common.LifecycleMgr) {
+	// when watching, the first request only stops the watch, so that the changes seen so far can still be transferred
+	if cca.watch != nil && cca.watch.requestStop() {
+		lcm.Info("Stopping the watch. The changes seen so far will still be transferred; press Ctrl-C again to cancel them.")
+		return
+	}
+
 	// prompt for confirmation, except when enumeration is complete
 	if !cca.isEnumerationComplete {
 		answer := lcm.Prompt("The enumeration (source/destination comparison) is not complete, "+
@@ -368,9 +669,23 @@ func (cca *cookedSyncCmdArgs) Cancel(lcm common.LifecycleMgr) {
 		}
 	}
 
-	err := cookedCancelCmdArgs{jobID: cca.jobID}.process()
-	if err != nil {
-		lcm.Error("error occurred while cancelling the job " + cca.jobID.String() + ". Failed with error " + err.Error())
+	jobIDs := []common.JobID{cca.jobID}
+	if cca.returnFirstPartOrdered() {
+		jobIDs = append(jobIDs, cca.returnJobID)
+	}
+	if cca.watch != nil {
+		// the watch's current job is the last one; any before it may still be running
+		jobIDs = cca.orderedJobIDs()
+		for i, j := 0, len(jobIDs)-1; i < j; i, j = i+1, j-1 {
+			jobIDs[i], jobIDs[j] = jobIDs[j], jobIDs[i]
+		}
+	}
+
+	for _, jobID := range jobIDs {
+		err := cookedCancelCmdArgs{jobID: jobID}.process()
+		if err != nil {
+			lcm.Error("error occurred while cancelling the job " + jobID.String() + ". Failed with error " + err.Error())
+		}
 	}
 }
 
@@ -409,6 +724,7 @@ func (cca *cookedSyncCmdArgs) getJsonOfSyncJobSummary(summary common.ListJobSumm
 	wrapped := common.ListSyncJobSummaryResponse{ListJobSummaryResponse: summary}
 	wrapped.DeleteTotalTransfers = cca.getDeletionCount()
 	wrapped.DeleteTransfersCompleted = cca.getDeletionCount()
+	wrapped.ConflictCount = cca.getConflictCount()
 	jsonOutput, err := json.Marshal(wrapped)
 	common.PanicIfErr(err)
 	return string(jsonOutput)
@@ -420,11 +736,19 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 	var throughput float64
 	var jobDone bool
 
+	// check this before looking for jobs, since every job has been ordered by the time scanning is complete
+	scanningComplete := cca.scanningComplete()
+
 	// fetch a job status and compute throughput if the first part was dispatched
-	if cca.firstPartOrdered() {
-		Rpc(common.ERpcCmd.ListJobSummary(), &cca.jobID, &summary)
-		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &lcm)
-		jobDone = summary.JobStatus.IsJobDone()
+	// a two-way sync may have two jobs, and a watched one a series of them, whose summaries are added together
+	var haveSummary bool
+	if cca.watch != nil {
+		summary, jobDone, haveSummary = cca.watch.summarizeJobs(getJobSummary)
+	} else {
+		summary, jobDone, haveSummary = summarizeJobs(cca.orderedJobIDs(), getJobSummary)
+	}
+	if haveSummary {
+		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &summary.JobID, &lcm)
 		totalKnownCount = summary.TotalTransfers
 
 		// compute the average throughput for the last time interval
@@ -439,7 +763,7 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 
 	// first part not dispatched, and we are still scanning
 	// so a special message is outputted to notice the user that we are not stalling
-	if !cca.scanningComplete() {
+	if !scanningComplete {
 		cca.reportScanningProgress(lcm, throughput)
 		return
 	}
@@ -450,15 +774,38 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			exitCode = common.EExitCode.Error()
 		}
 
+		// what the sync left the pair in is only known once all its transfers are over
+		if cca.syncRecord != nil {
+			transfersSucceeded := summary.TransfersFailed == 0 && summary.JobStatus == common.EJobStatus.Completed()
+			var failedTransfers map[string]bool
+			if !transfersSucceeded && summary.JobStatus != common.EJobStatus.Cancelled() {
+				failedTransfers = cca.failedTransferPaths()
+			}
+			if err := cca.syncRecord.commit(transfersSucceeded, failedTransfers); err != nil {
+				glcm.Info("Failed to save the record of this sync, which the next sync of the same source and destination relies on: " + err.Error())
+				exitCode = common.EExitCode.Error()
+			}
+		}
+
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
 				return cca.getJsonOfSyncJobSummary(summary)
 			}
+			jobHeading := "Job " + summary.JobID.String()
+			if cca.watch != nil && cca.watch.jobsOrdered() > 1 {
+				jobHeading = fmt.Sprintf("Watch (%d jobs, the last being %s)", cca.watch.jobsOrdered(), cca.jobID)
+			} else if jobIDs := cca.orderedJobIDs(); len(jobIDs) == 2 {
+				jobHeading = fmt.Sprintf("Jobs %s and %s", jobIDs[0], jobIDs[1])
+			}
 			screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
+			conflictStats := ""
+			if cca.bidirectional {
+				conflictStats = fmt.Sprintf("\nNumber of Conflicts: %v", cca.getConflictCount())
+			}
 
 			output := fmt.Sprintf(
 				`
-Job %s Summary
+%s Summary
 Files Scanned at Source: %v
 Files Scanned at Destination: %v
 Elapsed Time (Minutes): %v
@@ -467,12 +814,12 @@ Number of Copy Transfers for Folder Properties: %v
 Total Number Of Copy Transfers: %v
 Number of Copy Transfers Completed: %v
 Number of Copy Transfers Failed: %v
-Number of Deletions at Destination: %v
+Number of Deletions at Destination: %v%s
 Total Number of Bytes Transferred: %v
 Total Number of Bytes Enumerated: %v
 Final Job Status: %v%s%s
 `,
-				summary.JobID.String(),
+				jobHeading,
 				atomic.LoadUint64(&cca.atomicSourceFilesScanned),
 				atomic.LoadUint64(&cca.atomicDestinationFilesScanned),
 				ste.ToFixed(duration.Minutes(), 4),
@@ -482,15 +829,18 @@ Final Job Status: %v%s%s
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				cca.atomicDeletionCount,
+				conflictStats,
 				summary.TotalBytesTransferred,
 				summary.TotalBytesEnumerated,
 				summary.JobStatus,
 				screenStats,
 				formatPerfAdvice(summary.PerformanceAdvice))
 
-			jobMan, exists := ste.JobsAdmin.JobMgr(summary.JobID)
-			if exists {
-				jobMan.Log(pipeline.LogInfo, logStats+"\n"+output)
+			for _, jobID := range cca.orderedJobIDs() {
+				jobMan, exists := ste.JobsAdmin.JobMgr(jobID)
+				if exists {
+					jobMan.Log(pipeline.LogInfo, logStats+"\n"+output)
+				}
 			}
 
 			return output
@@ -562,11 +912,17 @@ func (cca *cookedSyncCmdArgs) process() (err error) {
 	}
 
 	// trigger the progress reporting
-	cca.waitUntilJobCompletion(false)
+	// (there's nothing to report in a dry run, and its output is easier to read without the progress line)
+	if cca.dryrunReporter == nil {
+		cca.waitUntilJobCompletion(false)
+	}
 
 	// trigger the enumeration
 	err = enumerator.enumerate()
 	if err != nil {
+		if cca.syncRecord != nil {
+			_ = cca.syncRecord.discard()
+		}
 		return err
 	}
 	return nil
@@ -613,14 +969,13 @@ func init() {
 	syncCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", true, "True by default, look into sub-directories recursively when syncing between directories. (default true).")
 
 	// TODO: enable for copy with IfSourceNewer
-	// smb info/permissions can be persisted in the scenario of File -> File
+	// smb info/permissions can be persisted in the scenarios of File -> File, Local -> File and File -> Local
 	syncCmd.PersistentFlags().BoolVar(&raw.preserveSMBPermissions, "preserve-smb-permissions", false, "False by default. Preserves SMB ACLs between aware resources (Azure Files). This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
 	syncCmd.PersistentFlags().BoolVar(&raw.preserveSMBInfo, "preserve-smb-info", false, "False by default. Preserves SMB property info (last write time, creation time, attribute bits) between SMB-aware resources (Azure Files). This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is not preserved for folders. ")
 
-	// TODO: enable when we support local <-> File
-	//syncCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
-	//syncCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
-	//syncCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
+	syncCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
+	syncCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
+	syncCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 
 	syncCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage or downloading from Azure Storage. Default is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
 	syncCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
@@ -634,6 +989,37 @@ func init() {
 		"If set to prompt, the user will be asked a question before scheduling files and blobs for deletion. (default 'false').")
 	syncCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
 	syncCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. This option is only available when downloading. Available values include: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent').")
+	syncCmd.PersistentFlags().BoolVar(&raw.cpkByValue, "cpk-by-value", false, "Have the service encrypt and decrypt blobs with a customer-provided key, given base64-encoded in the "+
+		common.EEnvironmentVariable.CpkEncryptionKey().Name+" environment variable. The key is sent on every request to the Blob storage side of the sync (the destination, if both sides are). "+
+		"Only a hash of the key is saved in the job's plan files, so the variable must still be set if the job is resumed.")
+	syncCmd.PersistentFlags().StringVar(&raw.cpkByName, "cpk-by-name", "", "Have the service encrypt blobs with the customer-managed key of this encryption scope. The same as --encryption-scope, so it is only available when the destination is Blob storage.")
+	syncCmd.PersistentFlags().StringVar(&raw.encryptionScope, "encryption-scope", "", "Have the service encrypt blobs with the key of this encryption scope. Only available when the destination is Blob storage.")
+	syncCmd.PersistentFlags().StringVar(&raw.compareHash, "compare-hash", common.ECompareHashType.None().String(), "Compare the sizes and content hashes of files that exist at both ends, instead of their last modified times, "+
+		"so that only files whose content differs are transferred. Available values include: None, MD5. "+
+		"The hashes of local files are computed (and remembered for next time); remote files that have no stored hash are always transferred, so consider using --put-md5 when uploading.")
+	syncCmd.PersistentFlags().BoolVar(&raw.sizeOnly, "size-only", false, "Compare only the sizes of files that exist at both ends, instead of their last modified times, and transfer those whose sizes differ.")
+	syncCmd.PersistentFlags().BoolVar(&raw.bidirectional, "bidirectional", false, "Sync in both directions, between a local directory and Blob storage: changes made at either end since the last sync are applied to the other. "+
+		"Deletions are only applied if --delete-destination is true or prompt; otherwise deleted files are restored from the other end.")
+	syncCmd.PersistentFlags().BoolVar(&raw.incremental, "incremental", false, "Compare the local source against a manifest of what the last sync put at the destination, instead of listing the destination. "+
+		"This is much faster for large destinations, but doesn't notice changes made to the destination by anything else. Only available when uploading to Blob storage. "+
+		"The destination is listed anyway when there is no manifest yet.")
+	syncCmd.PersistentFlags().StringVar(&raw.fullCheckInterval, "full-check-interval", "", "With --incremental, list the destination anyway if it hasn't been listed for this long, e.g. 24h, "+
+		"to catch changes made to it by anything else. (By default, the manifest is always trusted once it exists.)")
+	syncCmd.PersistentFlags().BoolVar(&raw.compareInOrder, "compare-in-order", false, "List the source and the destination in order, and compare them as they are listed, instead of indexing one of them first. "+
+		"This keeps memory use flat, and starts transfers straight away, but Blob containers are listed one page at a time rather than in parallel, so it can be slower for large containers. "+
+		"Only available between local directories, Blob storage and S3.")
+	syncCmd.PersistentFlags().StringVar(&raw.conflictPolicy, "conflict-policy", common.ESyncConflictPolicy.NewerWins().String(), "How to settle conflicts in a two-way sync, i.e. files changed at both ends since the last sync. "+
+		"Available values include: NewerWins (keep the most recently modified version), SourceWins, and KeepBoth (rename the local version, and keep both versions at both ends).")
+	syncCmd.PersistentFlags().BoolVar(&raw.dryrun, "dry-run", false, "Print the files and blobs that would be copied, overwritten or deleted, with totals, without transferring or deleting anything. "+
+		"Deletions are only reported if --delete-destination is true or prompt. Cannot be used with --bidirectional.")
+	syncCmd.PersistentFlags().StringVar(&raw.backupDestination, "backup-destination", "", "Before deleting or overwriting a file at the destination, move or copy it into a folder named for the time of the sync, under this location. "+
+		"For a local destination this is a directory, on the same volume; for a remote one it's a path within the destination's container, share or filesystem. "+
+		"If it's inside the destination, it's left out of the comparison. Cannot be used with --bidirectional.")
+	syncCmd.PersistentFlags().BoolVar(&raw.watch, "watch", false, "(Linux only) Once the destination matches the local source, carry on watching the source, and transfer the files that are written to it, "+
+		"and delete the ones that are removed from it (if --delete-destination is true), until stopped with Ctrl-C. "+
+		"Every 1000 batches of changes, or every day, the watch moves on to a new job, and the plan files of the old one are deleted once it's done. "+
+		"Cannot be used with --bidirectional, --incremental or --dry-run.")
+	syncCmd.PersistentFlags().StringVar(&raw.watchDebounce, "watch-debounce", "", "With --watch, how long the source must go without changes before the changes seen are transferred, e.g. 5s. (default 2s)")
 	syncCmd.PersistentFlags().BoolVar(&raw.s2sPreserveAccessTier, "s2s-preserve-access-tier", true, "Preserve access tier during service to service copy. "+
 		"Please refer to [Azure Blob storage: hot, cool, and archive access tiers](https://docs.microsoft.com/azure/storage/blobs/storage-blob-storage-tiers) to ensure destination storage account supports setting access tier. "+
 		"In the cases that setting access tier is not supported, please use s2sPreserveAccessTier=false to bypass copying access tier. (default true). ")
@@ -644,8 +1030,8 @@ func init() {
 	syncCmd.PersistentFlags().MarkHidden("include")
 	syncCmd.PersistentFlags().MarkHidden("exclude")
 
-	// TODO follow sym link is not implemented, clarify behavior first
-	//syncCmd.PersistentFlags().BoolVar(&raw.followSymlinks, "follow-symlinks", false, "follow symbolic links when performing sync from local file system.")
+	syncCmd.PersistentFlags().BoolVar(&raw.followSymlinks, "follow-symlinks", false, "Follow symbolic links when syncing from the local file system. "+
+		"The contents of a symlinked folder are synced once, under the same one of the paths that lead to them every time.")
 
 	// TODO sync does not support all BlobAttributes on the command line, this functionality should be added
 }
@@ -0,0 +1,413 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// syncBackup keeps what a sync would otherwise destroy at the destination, like rsync's --backup-dir.
+// Each object that's about to be deleted or overwritten is first moved or copied under a folder of --backup-destination,
+// named for when the sync started, so that there's a window in which it can be recovered, without soft delete being turned on for the whole account.
+type syncBackup interface {
+	// preserve keeps the destination object, which is about to be overwritten, in the backup
+	preserve(object storedObject) error
+
+	// remove moves the destination object, which is extra, into the backup, in place of deleting it
+	remove(object storedObject) error
+}
+
+// how often to check whether a server-side copy into the backup has finished
+const syncBackupCopyPollInterval = time.Second
+
+// the biggest blob that Copy Blob From URL will copy synchronously
+const syncBackupMaxSyncCopyBytes = 256 * 1024 * 1024
+
+// syncBackupFolderName names the folder that one sync puts its backups in, so that different syncs don't overwrite each other's backups
+func syncBackupFolderName(syncStartTime time.Time) string {
+	return syncStartTime.UTC().Format("20060102T150405Z")
+}
+
+// backupRelativePath is where the object goes, relative to the backup folder of the sync
+func backupRelativePath(object storedObject) string {
+	// when syncing a single file, the root is the file itself
+	if object.relativePath == "" {
+		return object.name
+	}
+	return object.relativePath
+}
+
+// cookBackupDestination checks the --backup-destination given for the destination,
+// and returns it in the form that the backups are made with: an absolute path for local destinations,
+// and a path within the destination's container, share or filesystem for remote ones.
+// Since later syncs must not treat the backups as extra files, it also returns the path of the backups relative to the destination,
+// if they are inside the destination, so that they can be left out of the comparison.
+func cookBackupDestination(rawBackupDestination string, destination common.ResourceString, location common.Location) (backupDestination, excludedPath string, err error) {
+	if location == common.ELocation.Local() {
+		backupDestination, err = filepath.Abs(rawBackupDestination)
+		if err != nil {
+			return "", "", err
+		}
+		destinationPath, err := filepath.Abs(common.ToShortPath(destination.ValueLocal()))
+		if err != nil {
+			return "", "", err
+		}
+
+		if relativePath, err := filepath.Rel(destinationPath, backupDestination); err == nil && !strings.HasPrefix(relativePath, "..") {
+			if relativePath == "." {
+				return "", "", fmt.Errorf("the backup-destination cannot be the destination itself")
+			}
+			excludedPath = filepath.ToSlash(relativePath)
+		}
+		return backupDestination, excludedPath, nil
+	}
+
+	if strings.Contains(rawBackupDestination, "://") {
+		return "", "", fmt.Errorf("the backup-destination of a sync to %s must be a path within the destination's %s, not a URL", location, remoteContainerTerm(location))
+	}
+	backupDestination = strings.Trim(rawBackupDestination, "/")
+	if backupDestination == "" || backupDestination != path.Clean(backupDestination) || backupDestination == ".." || strings.HasPrefix(backupDestination, "../") {
+		return "", "", fmt.Errorf("invalid backup-destination '%s': it must be a path within the destination's %s, e.g. backups/myfolder", rawBackupDestination, remoteContainerTerm(location))
+	}
+
+	destinationURL, err := destination.FullURL()
+	if err != nil {
+		return "", "", err
+	}
+	var destinationPath string
+	switch location {
+	case common.ELocation.Blob():
+		destinationPath = azblob.NewBlobURLParts(*destinationURL).BlobName
+	case common.ELocation.File():
+		destinationPath = azfile.NewFileURLParts(*destinationURL).DirectoryOrFilePath
+	case common.ELocation.BlobFS():
+		destinationPath = azbfs.NewBfsURLParts(*destinationURL).DirectoryOrFilePath
+	default:
+		return "", "", fmt.Errorf("backup-destination is not supported when syncing to %s", location)
+	}
+	destinationPath = strings.Trim(destinationPath, "/")
+
+	switch {
+	case backupDestination == destinationPath:
+		return "", "", fmt.Errorf("the backup-destination cannot be the destination itself")
+	case destinationPath == "":
+		excludedPath = backupDestination
+	case strings.HasPrefix(backupDestination, destinationPath+"/"):
+		excludedPath = strings.TrimPrefix(backupDestination, destinationPath+"/")
+	}
+	return backupDestination, excludedPath, nil
+}
+
+// remoteContainerTerm is what the location calls the top level that everything sits in, for messages
+func remoteContainerTerm(location common.Location) string {
+	switch location {
+	case common.ELocation.File():
+		return "share"
+	case common.ELocation.BlobFS():
+		return "filesystem"
+	default:
+		return "container"
+	}
+}
+
+// newSyncBackup sets up the backup of the destination, in a new folder under --backup-destination
+func (cca *cookedSyncCmdArgs) newSyncBackup() (syncBackup, error) {
+	folderName := syncBackupFolderName(time.Now())
+	if cca.fromTo.To() == common.ELocation.Local() {
+		return &localSyncBackup{rootPath: cca.destination.ValueLocal(), backupPath: filepath.Join(cca.backupDestination, folderName)}, nil
+	}
+
+	rawURL, err := cca.destination.FullURL()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+	p, err := initPipeline(ctx, cca.fromTo.To(), cca.credentialInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteSyncBackup{
+		rootURL:        *rawURL,
+		backupPath:     path.Join(cca.backupDestination, folderName),
+		p:              p,
+		ctx:            ctx,
+		targetLocation: cca.fromTo.To(),
+		cpkOptions:     cca.cpkOptionsFor(false),
+	}, nil
+}
+
+// backs up a local destination, by renaming its files into the backup folder, which must be on the same volume
+type localSyncBackup struct {
+	rootPath   string
+	backupPath string
+}
+
+func (l *localSyncBackup) preserve(object storedObject) error {
+	// the transfer writes a new file, so the old one can simply be moved out of its way
+	return l.move(object)
+}
+
+func (l *localSyncBackup) remove(object storedObject) error {
+	if object.entityType == common.EEntityType.File() {
+		glcm.Info("Moving extra file to the backup: " + object.relativePath)
+	}
+	return l.move(object)
+}
+
+func (l *localSyncBackup) move(object storedObject) error {
+	if object.entityType != common.EEntityType.File() {
+		// folders are left where they are, just as sync doesn't delete them
+		return nil
+	}
+
+	currentPath := common.GenerateFullPath(l.rootPath, object.relativePath)
+	backupPath := common.GenerateFullPath(l.backupPath, backupRelativePath(object))
+	if err := os.MkdirAll(filepath.Dir(backupPath), os.ModePerm); err != nil {
+		return fmt.Errorf("cannot create the backup folder for %s, due to error: %s", object.relativePath, err)
+	}
+	if err := os.Rename(currentPath, backupPath); err != nil {
+		if os.IsNotExist(err) {
+			// there's nothing to keep
+			return nil
+		}
+		return fmt.Errorf("cannot move %s to the backup at %s, due to error: %s", object.relativePath, backupPath, err)
+	}
+	return nil
+}
+
+// backs up a remote destination, within its own container, share or filesystem.
+// Blobs and Azure Files are copied server-side, whereas ADLS Gen2 files are simply renamed.
+type remoteSyncBackup struct {
+	rootURL        url.URL
+	backupPath     string
+	p              pipeline.Pipeline
+	ctx            context.Context
+	targetLocation common.Location
+	// for reading the properties of blobs encrypted with a customer-provided key
+	cpkOptions common.CpkOptions
+}
+
+func (b *remoteSyncBackup) preserve(object storedObject) error {
+	if object.entityType != common.EEntityType.File() {
+		return nil
+	}
+
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog("Backing up "+object.relativePath+" before it's overwritten", pipeline.LogInfo)
+	}
+	return b.backUp(object, false)
+}
+
+func (b *remoteSyncBackup) remove(object storedObject) error {
+	if object.entityType != common.EEntityType.File() {
+		if shouldSyncRemoveFolders() {
+			panic("folder deletion enabled but not implemented")
+		}
+		return nil
+	}
+
+	glcm.Info("Moving extra object to the backup: " + object.relativePath)
+	return b.backUp(object, true)
+}
+
+// backUp copies (or moves, if andRemove) the object into the backup
+func (b *remoteSyncBackup) backUp(object storedObject, andRemove bool) error {
+	backupPath := path.Join(b.backupPath, backupRelativePath(object))
+
+	var err error
+	switch b.targetLocation {
+	case common.ELocation.Blob():
+		blobURLParts := azblob.NewBlobURLParts(b.rootURL)
+		blobURLParts.BlobName = path.Join(blobURLParts.BlobName, object.relativePath)
+		blobURL := azblob.NewBlobURL(blobURLParts.URL(), b.p)
+		blobURLParts.BlobName = backupPath
+		backupBlobURL := azblob.NewBlobURL(blobURLParts.URL(), b.p)
+
+		err = b.copyBlob(object, blobURL, backupBlobURL)
+		if err == nil && andRemove {
+			_, err = blobURL.Delete(b.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+		}
+	case common.ELocation.File():
+		fileURLParts := azfile.NewFileURLParts(b.rootURL)
+		fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, object.relativePath)
+		fileURL := azfile.NewFileURL(fileURLParts.URL(), b.p)
+		fileURLParts.DirectoryOrFilePath = backupPath
+		backupFileURL := azfile.NewFileURL(fileURLParts.URL(), b.p)
+
+		err = b.copyFile(fileURL, backupFileURL)
+		if err == nil && andRemove {
+			_, err = fileURL.Delete(b.ctx)
+		}
+	case common.ELocation.BlobFS():
+		bfsURLParts := azbfs.NewBfsURLParts(b.rootURL)
+		bfsURLParts.DirectoryOrFilePath = path.Join(bfsURLParts.DirectoryOrFilePath, object.relativePath)
+		fileURL := azbfs.NewFileURL(bfsURLParts.URL(), b.p)
+		bfsURLParts.DirectoryOrFilePath = backupPath
+		backupFileURL := azbfs.NewFileURL(bfsURLParts.URL(), b.p)
+
+		// the transfer writes a new file, so even when it's about to be overwritten, the old one can simply be moved out of its way
+		err = b.renameFile(fileURL, backupFileURL)
+	default:
+		panic("not implemented, check your code")
+	}
+
+	if isNotFoundError(err) {
+		// there's nothing to keep
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("cannot back up %s to %s, due to error: %s", object.relativePath, backupPath, err)
+	}
+	return nil
+}
+
+func (b *remoteSyncBackup) copyBlob(object storedObject, blobURL, backupBlobURL azblob.BlobURL) error {
+	// the backup happens on the goroutine that's comparing the source and the destination, so rather than polling for the copy to finish,
+	// copy synchronously whenever Copy Blob From URL can. It takes block blobs of up to 256 MiB, whose URL authorizes reading them,
+	// since unlike the asynchronous copy, it doesn't accept the request's own authorization for a source in the same account.
+	sourceURLParts := azblob.NewBlobURLParts(blobURL.URL())
+	if object.blobType == azblob.BlobBlockBlob && object.size <= syncBackupMaxSyncCopyBytes && sourceURLParts.SAS.Signature() != "" {
+		copyResponse, err := backupBlobURL.ToBlockBlobURL().CopyFromURL(b.ctx, blobURL.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, nil, azblob.DefaultAccessTier, nil)
+		if err != nil {
+			return err
+		}
+		if copyStatus := copyResponse.CopyStatus(); copyStatus != azblob.SyncCopyStatusSuccess {
+			return fmt.Errorf("the copy finished with status %s", copyStatus)
+		}
+		return nil
+	}
+
+	copyResponse, err := backupBlobURL.StartCopyFromURL(b.ctx, blobURL.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil)
+	if err != nil {
+		return err
+	}
+
+	// the copy must be finished before the original is deleted or overwritten
+	copyStatus := copyResponse.CopyStatus()
+	for copyStatus == azblob.CopyStatusPending {
+		time.Sleep(syncBackupCopyPollInterval)
+		properties, err := backupBlobURL.GetProperties(b.ctx, azblob.BlobAccessConditions{}, b.cpkOptions.ToClientProvidedKeyOptions())
+		if err != nil {
+			return err
+		}
+		copyStatus = properties.CopyStatus()
+	}
+	if copyStatus != azblob.CopyStatusSuccess {
+		return fmt.Errorf("the copy finished with status %s", copyStatus)
+	}
+	return nil
+}
+
+func (b *remoteSyncBackup) copyFile(fileURL, backupFileURL azfile.FileURL) error {
+	// unlike blob names, file paths need their directories to exist
+	err := ste.AzureFileParentDirCreator{}.CreateParentDirToRoot(b.ctx, backupFileURL, b.p, common.NewFolderCreationTracker(common.EFolderPropertiesOption.NoFolders()))
+	if err != nil {
+		return err
+	}
+
+	copyResponse, err := backupFileURL.StartCopy(b.ctx, fileURL.URL(), nil)
+	if err != nil {
+		return err
+	}
+
+	copyStatus := copyResponse.CopyStatus()
+	for copyStatus == azfile.CopyStatusPending {
+		time.Sleep(syncBackupCopyPollInterval)
+		properties, err := backupFileURL.GetProperties(b.ctx)
+		if err != nil {
+			return err
+		}
+		copyStatus = properties.CopyStatus()
+	}
+	if copyStatus != azfile.CopyStatusSuccess {
+		return fmt.Errorf("the copy finished with status %s", copyStatus)
+	}
+	return nil
+}
+
+func (b *remoteSyncBackup) renameFile(fileURL, backupFileURL azbfs.FileURL) error {
+	parentDirURL, err := backupFileURL.GetParentDir()
+	if err != nil {
+		return err
+	}
+	// creating a directory also creates its parents, and it's fine if it already exists
+	if _, err = parentDirURL.Create(b.ctx, false); err != nil {
+		if stgErr, ok := err.(azbfs.StorageError); !ok || stgErr.Response() == nil || stgErr.Response().StatusCode != http.StatusConflict {
+			return err
+		}
+	}
+
+	_, err = fileURL.Rename(b.ctx, backupFileURL)
+	return err
+}
+
+func isNotFoundError(err error) bool {
+	resp, ok := err.(pipeline.Response)
+	return ok && resp.Response() != nil && resp.Response().StatusCode == http.StatusNotFound
+}
+
+// backupExcludingTraverser leaves the backups out of the listing of the destination, when they're inside it,
+// so that they aren't taken for extra files
+type backupExcludingTraverser struct {
+	resourceTraverser
+	excludedPath string
+}
+
+func newBackupExcludingTraverser(destinationTraverser resourceTraverser, excludedPath string) resourceTraverser {
+	return &backupExcludingTraverser{resourceTraverser: destinationTraverser, excludedPath: excludedPath}
+}
+
+func (t *backupExcludingTraverser) withExclusion(filters []objectFilter) []objectFilter {
+	// the trailing separator keeps files that merely start with the same name
+	exclusion := buildExcludeFilters([]string{t.excludedPath + common.AZCOPY_PATH_SEPARATOR_STRING}, true)
+	return append(append(make([]objectFilter, 0, len(filters)+1), filters...), exclusion...)
+}
+
+func (t *backupExcludingTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	return t.resourceTraverser.traverse(preprocessor, processor, t.withExclusion(filters))
+}
+
+func (t *backupExcludingTraverser) canTraverseSorted() bool {
+	_, ok := asSortedTraverser(t.resourceTraverser)
+	return ok
+}
+
+func (t *backupExcludingTraverser) traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	sorted, _ := asSortedTraverser(t.resourceTraverser)
+	return sorted.traverseSorted(preprocessor, processor, t.withExclusion(filters))
+}
@@ -20,6 +20,104 @@
 
 package cmd
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// decides whether the source object needs to be transferred, given its counterpart at the destination
+type syncChangeDetector interface {
+	hasChanged(sourceObject, destinationObject storedObject) bool
+}
+
+// the default: the source is transferred if it was modified after the destination was
+type lmtChangeDetector struct{}
+
+func (lmtChangeDetector) hasChanged(sourceObject, destinationObject storedObject) bool {
+	return sourceObject.isMoreRecentThan(destinationObject)
+}
+
+// for when the last modified times can't be trusted, e.g. when the clocks differ,
+// or when a tool rewrites the files without changing their content
+type sizeOnlyChangeDetector struct{}
+
+func (sizeOnlyChangeDetector) hasChanged(sourceObject, destinationObject storedObject) bool {
+	return sourceObject.size != destinationObject.size
+}
+
+// supplies the MD5 of an object, which is nil if it isn't known
+type md5Getter func(object storedObject) ([]byte, error)
+
+// the MD5 of a remote object is whatever the traverser found for it
+func remoteMd5Getter(object storedObject) ([]byte, error) {
+	return object.md5, nil
+}
+
+// compares sizes, and then (if they are the same) MD5s
+// An object is considered changed if either hash is unknown, since there's no way to tell that it isn't.
+type md5ChangeDetector struct {
+	sourceMd5      md5Getter
+	destinationMd5 md5Getter
+}
+
+func (d md5ChangeDetector) hasChanged(sourceObject, destinationObject storedObject) bool {
+	if sourceObject.entityType == common.EEntityType.Folder() {
+		return false // folders have no content, so there's nothing to compare
+	}
+	if sourceObject.size != destinationObject.size {
+		return true
+	}
+
+	getHash := func(get md5Getter, object storedObject, side string) []byte {
+		hash, err := get(object)
+		if err != nil && ste.JobsAdmin != nil {
+			ste.JobsAdmin.LogToJobLog(fmt.Sprintf("cannot get the MD5 of %s %s, so it will be treated as changed: %s", side, object.relativePath, err), pipeline.LogWarning)
+		}
+		return hash
+	}
+
+	sourceHash := getHash(d.sourceMd5, sourceObject, "source")
+	destinationHash := getHash(d.destinationMd5, destinationObject, "destination")
+	if len(sourceHash) == 0 || len(destinationHash) == 0 {
+		return true
+	}
+
+	return !bytes.Equal(sourceHash, destinationHash)
+}
+
+// the MD5 of an S3 object is its Content-MD5 if that's known, and otherwise its ETag where that is an MD5 (a single-part upload without SSE-KMS)
+func s3Md5Getter(object storedObject) ([]byte, error) {
+	if len(object.md5) != 0 {
+		return object.md5, nil
+	}
+	return object.etagMd5, nil
+}
+
+// the default for an S3 source, whose last modified times are from another service's clock
+// A difference in size always counts as a change. If both MD5s are known they decide; otherwise the last modified times do.
+type s3ChangeDetector struct{}
+
+func (s3ChangeDetector) hasChanged(sourceObject, destinationObject storedObject) bool {
+	if sourceObject.entityType == common.EEntityType.Folder() {
+		return false
+	}
+	if sourceObject.size != destinationObject.size {
+		return true
+	}
+
+	sourceHash, _ := s3Md5Getter(sourceObject)
+	if len(sourceHash) != 0 && len(destinationObject.md5) != 0 {
+		return !bytes.Equal(sourceHash, destinationObject.md5)
+	}
+
+	return sourceObject.isMoreRecentThan(destinationObject)
+}
+
 // with the help of an objectIndexer containing the source objects
 // find out the destination objects that should be transferred
 // in other words, this should be used when destination is being enumerated secondly
@@ -32,10 +130,16 @@ type syncDestinationComparator struct {
 
 	// storing the source objects
 	sourceIndex *objectIndexer
+
+	// deciding whether a source object that's also at the destination should be transferred
+	changeDetector syncChangeDetector
+
+	// if set, it's given each destination object that's about to be overwritten, e.g. to back it up
+	beforeOverwrite objectProcessor
 }
 
-func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor) *syncDestinationComparator {
-	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner}
+func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor, changeDetector syncChangeDetector) *syncDestinationComparator {
+	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, changeDetector: changeDetector}
 }
 
 // it will only schedule transfers for destination objects that are present in the indexer but stale compared to the entry in the map
@@ -44,14 +148,19 @@ func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objec
 // if file x from the destination exists at the source, then we'd only transfer it if it is considered stale compared to its counterpart at the source
 // if file x does not exist at the source, then it is considered extra, and will be deleted
 func (f *syncDestinationComparator) processIfNecessary(destinationObject storedObject) error {
-	sourceObjectInMap, present := f.sourceIndex.indexMap[destinationObject.relativePath]
+	sourceObjectInMap, present, err := f.sourceIndex.lookup(destinationObject.relativePath)
+	if err != nil {
+		return err
+	}
 
 	// if the destinationObject is present at source and stale, we transfer the up-to-date version from source
 	if present {
-		defer delete(f.sourceIndex.indexMap, destinationObject.relativePath)
+		if err = f.sourceIndex.remove(destinationObject.relativePath); err != nil {
+			return err
+		}
 
-		if sourceObjectInMap.isMoreRecentThan(destinationObject) {
-			err := f.copyTransferScheduler(sourceObjectInMap)
+		if f.changeDetector.hasChanged(sourceObjectInMap, destinationObject) {
+			err := scheduleOverwrite(sourceObjectInMap, destinationObject, f.beforeOverwrite, f.copyTransferScheduler)
 			if err != nil {
 				return err
 			}
@@ -74,26 +183,37 @@ type syncSourceComparator struct {
 
 	// storing the destination objects
 	destinationIndex *objectIndexer
+
+	// deciding whether a source object that's also at the destination should be transferred
+	changeDetector syncChangeDetector
+
+	// if set, it's given each destination object that's about to be overwritten, e.g. to back it up
+	beforeOverwrite objectProcessor
 }
 
-func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor) *syncSourceComparator {
-	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler}
+func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor, changeDetector syncChangeDetector) *syncSourceComparator {
+	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, changeDetector: changeDetector}
 }
 
 // it will only transfer source items that are:
 //	1. not present in the map
-//  2. present but is more recent than the entry in the map
+//  2. present but changed (by default, more recent) compared to the entry in the map
 // note: we remove the storedObject if it is present so that when we have finished
 // the index will contain all objects which exist at the destination but were NOT seen at the source
 func (f *syncSourceComparator) processIfNecessary(sourceObject storedObject) error {
-	destinationObjectInMap, present := f.destinationIndex.indexMap[sourceObject.relativePath]
+	destinationObjectInMap, present, err := f.destinationIndex.lookup(sourceObject.relativePath)
+	if err != nil {
+		return err
+	}
 
 	if present {
-		defer delete(f.destinationIndex.indexMap, sourceObject.relativePath)
+		if err = f.destinationIndex.remove(sourceObject.relativePath); err != nil {
+			return err
+		}
 
 		// if destination is stale, schedule source for transfer
-		if sourceObject.isMoreRecentThan(destinationObjectInMap) {
-			return f.copyTransferScheduler(sourceObject)
+		if f.changeDetector.hasChanged(sourceObject, destinationObjectInMap) {
+			return scheduleOverwrite(sourceObject, destinationObjectInMap, f.beforeOverwrite, f.copyTransferScheduler)
 
 		} else {
 			// skip if source is more recent
@@ -104,3 +224,63 @@ func (f *syncSourceComparator) processIfNecessary(sourceObject storedObject) err
 	// if source does not exist at the destination, then schedule it for transfer
 	return f.copyTransferScheduler(sourceObject)
 }
+
+// decides what to do with each path, when the source and the destination are enumerated side by side
+type syncMergeProcessor interface {
+	processSourceOnly(sourceObject storedObject) error
+	processDestinationOnly(destinationObject storedObject) error
+	processPair(sourceObject, destinationObject storedObject) error
+}
+
+// with both the source and the destination being enumerated at the same time, in ascending order of relative path,
+// decide what to do with each object as soon as we know whether its counterpart exists, so that neither side needs to be indexed
+type syncMergeComparator struct {
+	// the processor responsible for scheduling copy transfers
+	copyTransferScheduler objectProcessor
+
+	// the processor responsible for removing extra objects from the destination
+	destinationCleaner objectProcessor
+
+	// deciding whether a source object that's also at the destination should be transferred
+	changeDetector syncChangeDetector
+
+	// if set, it's given each destination object that's about to be overwritten, e.g. to back it up
+	beforeOverwrite objectProcessor
+}
+
+func newSyncMergeComparator(copyScheduler, cleaner objectProcessor, changeDetector syncChangeDetector) *syncMergeComparator {
+	return &syncMergeComparator{copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, changeDetector: changeDetector}
+}
+
+// the source object does not exist at the destination, so it must be transferred
+func (f *syncMergeComparator) processSourceOnly(sourceObject storedObject) error {
+	return f.copyTransferScheduler(sourceObject)
+}
+
+// the destination object does not exist at the source, so it is extra and will be deleted
+func (f *syncMergeComparator) processDestinationOnly(destinationObject storedObject) error {
+	// purposefully ignore the error from destinationCleaner
+	// it's a tolerable error, since it just means some extra destination object might hang around a bit longer
+	_ = f.destinationCleaner(destinationObject)
+	return nil
+}
+
+// the object exists on both sides, so it's only transferred if it has changed
+func (f *syncMergeComparator) processPair(sourceObject, destinationObject storedObject) error {
+	if f.changeDetector.hasChanged(sourceObject, destinationObject) {
+		return scheduleOverwrite(sourceObject, destinationObject, f.beforeOverwrite, f.copyTransferScheduler)
+	}
+	return nil
+}
+
+// scheduleOverwrite schedules the source object to replace its counterpart at the destination,
+// after giving the destination object to beforeOverwrite, if there is one.
+// Unlike a failed deletion, a failure there is not tolerated, since it would mean destroying something that was meant to be kept.
+func scheduleOverwrite(sourceObject, destinationObject storedObject, beforeOverwrite, copyTransferScheduler objectProcessor) error {
+	if beforeOverwrite != nil {
+		if err := beforeOverwrite(destinationObject); err != nil {
+			return err
+		}
+	}
+	return copyTransferScheduler(sourceObject)
+}
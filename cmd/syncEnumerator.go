@@ -22,9 +22,13 @@ package cmd
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -43,30 +47,35 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 
 	if cca.fromTo.IsS2S() {
 		if cca.fromTo.From() != common.ELocation.S3() {
+			// The destination service reads the source by its URL, so whatever the two locations are, the source can't use the destination's credential.
+			// Each side is authorized on its own: the source here, and the destination in process().
 			// Adding files here seems like an odd case, but since files can't be public
 			// the second half of this if statement does not hurt.
-			if cca.fromTo.From() != cca.fromTo.To() {
-				panic(fmt.Sprintf("The semantics of authorization for an S2S transfer (within sync) is unknown for %s->%s transfers", cca.fromTo.From(), cca.fromTo.To()))
-			}
-
 			if srcCredInfo.CredentialType != common.ECredentialType.Anonymous() && !srcIsPublic {
 				return nil, fmt.Errorf("the source of a %s->%s sync must either be public, or authorized with a SAS token", cca.fromTo.From(), cca.fromTo.To())
 			}
 		}
 	}
 
-	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
+	// The exception is S3, where it would cost a HEAD request per object: the listing already has the size, LMT and ETag we compare on,
+	// and the transfer engine fetches the full properties itself (S2SGetPropertiesInBackend).
+	getSourceProperties := cca.fromTo.From() != common.ELocation.S3()
+	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, nil, cca.recursive, getSourceProperties, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
 		}
-	}, nil)
+	}, nil, false, cca.cpkOptionsFor(true))
 
 	if err != nil {
 		return nil, err
 	}
+	if localSource, ok := sourceTraverser.(*localTraverser); ok {
+		// what isn't found at the same path as last time gets deleted, so the paths that the contents of symlinked folders
+		// are found under mustn't depend on which symlink the walk happens upon first
+		localSource.stableLinkedPaths = true
+	}
 
 	// Because we can't trust cca.credinfo, given that it's for the overall job, not the individual traversers, we get cred info again here.
 	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
@@ -75,14 +84,14 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		return nil, err
 	}
 
-	// TODO: enable symlink support in a future release after evaluating the implications
+	// symlinks are only followed at the source, since following them at the destination would sync into wherever they point
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
 	destinationTraverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicDestinationFilesScanned, 1)
 		}
-	}, nil)
+	}, nil, false, cca.cpkOptionsFor(false))
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +101,22 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		return nil, errors.New("sync must happen between source and destination of the same type, e.g. either file <-> file, or directory/container <-> directory/container")
 	}
 
+	if cca.watch != nil && !sourceTraverser.isDirectory(true) {
+		return nil, errors.New("only a directory can be watched")
+	}
+
+	if cca.backupExcludedPath != "" {
+		destinationTraverser = newBackupExcludingTraverser(destinationTraverser, cca.backupExcludedPath)
+	}
+	// a dry run doesn't touch the destination, so there's nothing to back up.
+	// (A watched sync may get here more than once, but all its backups go in the same folder.)
+	if cca.backupDestination != "" && cca.dryrunReporter == nil && cca.backup == nil {
+		cca.backup, err = cca.newSyncBackup()
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up the backup of the destination due to: %s", err.Error())
+		}
+	}
+
 	// set up the filters in the right order
 	// Note: includeFilters and includeAttrFilters are ANDed
 	// They must both pass to get the file included
@@ -122,35 +147,119 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		ste.JobsAdmin.LogToJobLog(folderMessage, pipeline.LogInfo)
 	}
 
+	if cca.bidirectional {
+		return cca.initTwoWayEnumerator(sourceTraverser, destinationTraverser, filters, fpo)
+	}
+
 	transferScheduler := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	if cca.watch != nil {
+		cca.orderIntoWatchJobs(transferScheduler)
+	}
+
+	changeDetector, localHashCache := cca.newChangeDetector()
+	if cca.dryrunReporter != nil {
+		changeDetector = cca.dryrunReporter.noteOverwrite(changeDetector)
+	}
+	saveLocalHashCache := func() {
+		// the cache is purely an optimization, so failing to save it is no reason to fail the sync
+		if localHashCache != nil {
+			if err := localHashCache.save(); err != nil && ste.JobsAdmin != nil {
+				ste.JobsAdmin.LogToJobLog("failed to save the cache of local file hashes: "+err.Error(), pipeline.LogWarning)
+			}
+		}
+	}
+
+	if cca.incremental {
+		return cca.initIncrementalEnumerator(sourceTraverser, destinationTraverser, filters, fpo, transferScheduler, changeDetector, localHashCache)
+	}
+
+	// if asked to, compare both sides as they are being listed in order, so that memory use stays flat,
+	// and transfers start straight away rather than after one side has been indexed.
+	// It's not the default, since listing in order means giving up the parallel listing of Blob containers.
+	if cca.compareInOrder {
+		sortedSource, sourceIsSorted := asSortedTraverser(sourceTraverser)
+		sortedDestination, destinationIsSorted := asSortedTraverser(destinationTraverser)
+		if !sourceIsSorted || !destinationIsSorted {
+			return nil, errors.New("compare-in-order requires both the source and the destination to be listed in order, which isn't possible here")
+		}
+
+		// since we see both sides of each path at once, extra destination objects can be deleted as soon as they're seen
+		destinationCleaner, err := cca.newDestinationCleaner(fpo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
+		}
+
+		mergeComparator := newSyncMergeComparator(transferScheduler.scheduleCopyTransfer, destinationCleaner, changeDetector)
+		mergeComparator.beforeOverwrite = cca.beforeOverwrite()
+		finalize := func() error {
+			// every comparison has been made by now
+			saveLocalHashCache()
+
+			if cca.watch != nil {
+				return cca.watchSource(transferScheduler, filters, fpo)
+			}
+
+			jobInitiated, err := transferScheduler.dispatchFinalPart()
+			// sync cleanly exits if nothing is scheduled.
+			if err != nil && err != NothingScheduledError {
+				return err
+			}
+
+			quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+			cca.setScanningComplete()
+			return nil
+		}
+
+		return newSyncMergeEnumerator(sortedSource, sortedDestination, filters, mergeComparator, finalize), nil
+	}
 
-	// set up the comparator so that the source/destination can be compared
-	indexer := newObjectIndexer()
+	// otherwise, set up the comparator so that the source/destination can be compared
+	// the index spills to disk when it gets too big, so that syncing huge trees doesn't run out of RAM
+	indexMemoryLimit, err := getSyncIndexMemoryLimit()
+	if err != nil {
+		return nil, err
+	}
+	spillFolder := azcopyJobPlanFolder
+	if spillFolder == "" {
+		spillFolder = os.TempDir()
+	}
+	indexer := newSpillingObjectIndexer(indexMemoryLimit, filepath.Join(spillFolder, cca.jobID.String()+".syncindex"))
 	var comparator objectProcessor
 	var finalize func() error
 
 	switch cca.fromTo {
-	case common.EFromTo.LocalBlob():
+	case common.EFromTo.LocalBlob(), common.EFromTo.LocalFile():
 		// upload implies transferring from a local disk to a remote resource
 		// in this scenario, the local disk (source) is scanned/indexed first
 		// then the destination is scanned and filtered based on what the destination contains
 		// we do the local one first because it is assumed that local file systems will be faster to enumerate than remote resources
-		destinationCleaner, err := newSyncDeleteProcessor(cca)
+		destCleanerFunc, err := cca.newDestinationCleaner(fpo)
 		if err != nil {
 			return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
 		}
-		destCleanerFunc := newFpoAwareProcessor(fpo, destinationCleaner.removeImmediately)
 
 		// when uploading, we can delete remote objects immediately, because as we traverse the remote location
 		// we ALREADY have available a complete map of everything that exists locally
 		// so as soon as we see a remote destination object we can know whether it exists in the local source
-		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc).processIfNecessary
+		destinationComparator := newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc, changeDetector)
+		destinationComparator.beforeOverwrite = cca.beforeOverwrite()
+		comparator = destinationComparator.processIfNecessary
 		finalize = func() error {
+			// every comparison has been made by now
+			saveLocalHashCache()
+
 			// schedule every local file that doesn't exist at the destination
 			err = indexer.traverse(transferScheduler.scheduleCopyTransfer, filters)
 			if err != nil {
 				return err
 			}
+			if err = indexer.close(); err != nil {
+				return err
+			}
+
+			if cca.watch != nil {
+				return cca.watchSource(transferScheduler, filters, fpo)
+			}
 
 			jobInitiated, err := transferScheduler.dispatchFinalPart()
 			// sync cleanly exits if nothing is scheduled.
@@ -167,28 +276,33 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	default:
 		// in all other cases (download and S2S), the destination is scanned/indexed first
 		// then the source is scanned and filtered based on what the destination contains
-		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer).processIfNecessary
+		sourceComparator := newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer, changeDetector)
+		sourceComparator.beforeOverwrite = cca.beforeOverwrite()
+		comparator = sourceComparator.processIfNecessary
 
 		finalize = func() error {
+			// every comparison has been made by now
+			saveLocalHashCache()
+
 			// remove the extra files at the destination that were not present at the source
 			// we can only know what needs to be deleted when we have FINISHED traversing the remote source
 			// since only then can we know which local files definitely don't exist remotely
-			var deleteScheduler objectProcessor
-			switch cca.fromTo.To() {
-			case common.ELocation.Blob(), common.ELocation.File():
-				deleter, err := newSyncDeleteProcessor(cca)
-				if err != nil {
-					return err
-				}
-				deleteScheduler = newFpoAwareProcessor(fpo, deleter.removeImmediately)
-			default:
-				deleteScheduler = newFpoAwareProcessor(fpo, newSyncLocalDeleteProcessor(cca).removeImmediately)
+			deleteScheduler, err := cca.newDestinationCleaner(fpo)
+			if err != nil {
+				return err
 			}
 
 			err = indexer.traverse(deleteScheduler, nil)
 			if err != nil {
 				return err
 			}
+			if err = indexer.close(); err != nil {
+				return err
+			}
+
+			if cca.watch != nil {
+				return cca.watchSource(transferScheduler, filters, fpo)
+			}
 
 			// let the deletions happen first
 			// otherwise if the final part is executed too quickly, we might quit before deletions could finish
@@ -207,7 +321,83 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	}
 }
 
+// newDestinationCleaner returns the processor that deletes (or prompts to delete) the extra objects at the destination
+func (cca *cookedSyncCmdArgs) newDestinationCleaner(fpo common.FolderPropertyOption) (objectProcessor, error) {
+	if cca.dryrunReporter != nil {
+		if cca.deleteDestination == common.EDeleteDestination.False() {
+			return func(storedObject) error { return nil }, nil
+		}
+
+		// like the real deleters, leave folders alone, unless sync ever learns to remove them
+		reportDeletion := cca.dryrunReporter.deletionProcessor(cca.destination)
+		return newFpoAwareProcessor(fpo, func(object storedObject) error {
+			if object.entityType == common.EEntityType.File() || shouldSyncRemoveFolders() {
+				return reportDeletion(object)
+			}
+			return nil
+		}), nil
+	}
+
+	switch cca.fromTo.To() {
+	case common.ELocation.Blob(), common.ELocation.File(), common.ELocation.BlobFS():
+		deleter, err := newSyncDeleteProcessor(cca)
+		if err != nil {
+			return nil, err
+		}
+		return newFpoAwareProcessor(fpo, deleter.removeImmediately), nil
+	default:
+		return newFpoAwareProcessor(fpo, newSyncLocalDeleteProcessor(cca).removeImmediately), nil
+	}
+}
+
+// beforeOverwrite returns what must be done with each destination object before it's overwritten, if anything
+func (cca *cookedSyncCmdArgs) beforeOverwrite() objectProcessor {
+	if cca.backup != nil {
+		return cca.backup.preserve
+	}
+	return nil
+}
+
+// newChangeDetector builds the change detector for the comparison that the user asked for.
+// When comparing MD5s with a local end, it also returns the cache of local hashes, which must be saved once the comparison is over.
+func (cca *cookedSyncCmdArgs) newChangeDetector() (syncChangeDetector, *localMd5Cache) {
+	switch {
+	case cca.sizeOnly:
+		return sizeOnlyChangeDetector{}, nil
+	case cca.compareHash == common.ECompareHashType.MD5():
+		detector := md5ChangeDetector{sourceMd5: remoteMd5Getter, destinationMd5: remoteMd5Getter}
+		var cache *localMd5Cache
+		if cca.fromTo.From() == common.ELocation.S3() {
+			detector.sourceMd5 = s3Md5Getter
+		} else if cca.fromTo.From() == common.ELocation.Local() {
+			cache = newLocalMd5Cache(cca.source.ValueLocal(), azcopyJobPlanFolder)
+			detector.sourceMd5 = cache.getMd5
+		} else if cca.fromTo.To() == common.ELocation.Local() {
+			cache = newLocalMd5Cache(cca.destination.ValueLocal(), azcopyJobPlanFolder)
+			detector.destinationMd5 = cache.getMd5
+		}
+		return detector, cache
+	case cca.fromTo.From() == common.ELocation.S3():
+		return s3ChangeDetector{}, nil
+	default:
+		return lmtChangeDetector{}, nil
+	}
+}
+
+// syncPairFilePath names a file, in the given folder, that belongs to one source/destination pair,
+// for the things that a sync of the pair remembers from one run to the next
+func syncPairFilePath(folder, prefix, extension string, fromTo common.FromTo, source, destination common.ResourceString) string {
+	pairHash := sha256.Sum256([]byte(fromTo.String() + "\n" + source.Value + "\n" + destination.Value))
+	return filepath.Join(folder, prefix+"-"+hex.EncodeToString(pairHash[:8])+extension)
+}
+
 func quitIfInSync(transferJobInitiated, anyDestinationFileDeleted bool, cca *cookedSyncCmdArgs) {
+	if cca.dryrunReporter != nil {
+		cca.reportScanningProgress(glcm, 0)
+		cca.dryrunReporter.exit()
+		return
+	}
+
 	if !transferJobInitiated && !anyDestinationFileDeleted {
 		cca.reportScanningProgress(glcm, 0)
 		glcm.Exit(func(format common.OutputFormat) string {
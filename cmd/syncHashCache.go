@@ -0,0 +1,135 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// localMd5Cache computes the MD5s of local files, for sync's hash comparison.
+// The hashes are remembered from one run to the next (in a file in the job plan folder), so that
+// unchanged files needn't be read again. An entry is only reused if the file still has the size and
+// last modified time that it had when it was hashed.
+type localMd5Cache struct {
+	rootPath  string
+	cacheFile string // empty if the hashes aren't to be persisted
+
+	mu       *sync.Mutex
+	previous map[string]localMd5CacheEntry // what was loaded from the cache file
+	current  map[string]localMd5CacheEntry // what this run has used, which is all that gets saved
+}
+
+type localMd5CacheEntry struct {
+	Size int64     `json:"size"`
+	LMT  time.Time `json:"lmt"`
+	MD5  []byte    `json:"md5"`
+}
+
+func newLocalMd5Cache(rootPath string, cacheFolder string) *localMd5Cache {
+	c := &localMd5Cache{
+		rootPath: rootPath,
+		mu:       &sync.Mutex{},
+		previous: make(map[string]localMd5CacheEntry),
+		current:  make(map[string]localMd5CacheEntry),
+	}
+
+	if cacheFolder != "" {
+		// one cache per local root, so that syncs of different folders don't trample each other's entries
+		rootHash := sha256.Sum256([]byte(rootPath))
+		c.cacheFile = filepath.Join(cacheFolder, "syncmd5-"+hex.EncodeToString(rootHash[:8])+".json")
+
+		// a missing or unreadable cache just means we have to hash everything
+		if data, err := ioutil.ReadFile(c.cacheFile); err == nil {
+			_ = json.Unmarshal(data, &c.previous)
+		}
+	}
+
+	return c
+}
+
+// getMd5 is an md5Getter for objects under the cache's root
+func (c *localMd5Cache) getMd5(object storedObject) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.previous[object.relativePath]
+	c.mu.Unlock()
+
+	if !ok || entry.Size != object.size || !entry.LMT.Equal(object.lastModifiedTime) {
+		hash, err := c.computeMd5(common.GenerateFullPath(c.rootPath, object.relativePath))
+		if err != nil {
+			return nil, err
+		}
+		entry = localMd5CacheEntry{Size: object.size, LMT: object.lastModifiedTime, MD5: hash}
+	}
+
+	c.mu.Lock()
+	c.current[object.relativePath] = entry
+	c.mu.Unlock()
+
+	return entry.MD5, nil
+}
+
+func (c *localMd5Cache) computeMd5(fullPath string) ([]byte, error) {
+	f, err := common.OSOpenFile(fullPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := md5.New()
+	if _, err = io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// save persists the hashes used in this run. Entries for files that were not looked at are dropped,
+// so that the cache doesn't grow without bound as files come and go.
+func (c *localMd5Cache) save() error {
+	if c.cacheFile == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.Marshal(c.current)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a crash can't leave a half-written cache behind
+	tempFile := c.cacheFile + ".tmp"
+	if err = ioutil.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, c.cacheFile)
+}
@@ -20,31 +20,126 @@
 
 package cmd
 
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
 // the objectIndexer is essential for the generic sync enumerator to work
 // it can serve as a:
 // 		1. objectProcessor: accumulate a lookup map with given storedObjects
 //		2. resourceTraverser: go through the entities in the map like a traverser
+// Once the objects in memory reach the memory limit (if there is one), any further objects are spilled to a file on disk,
+// so that very large trees can be synced without running out of RAM.
 type objectIndexer struct {
 	indexMap map[string]storedObject
 	counter  int
+
+	// approximately how many bytes the objects in indexMap take, and how many they may take before we spill to disk
+	// a limit of 0 means the index is kept entirely in memory
+	memoryUsed  int64
+	memoryLimit int64
+
+	spillFilePath string
+	spill         *spilledIndex // nil until we have had to spill
 }
 
 func newObjectIndexer() *objectIndexer {
 	return &objectIndexer{indexMap: make(map[string]storedObject)}
 }
 
+// newSpillingObjectIndexer returns an indexer that keeps up to memoryLimit bytes of objects in memory,
+// and the rest in the file at spillFilePath, which is only created if needed
+func newSpillingObjectIndexer(memoryLimit int64, spillFilePath string) *objectIndexer {
+	i := newObjectIndexer()
+	i.memoryLimit = memoryLimit
+	i.spillFilePath = spillFilePath
+	return i
+}
+
+// the default for how much memory sync's index may use before it spills to disk
+const defaultSyncIndexGB = 2
+
+// getSyncIndexMemoryLimit returns the number of bytes that sync's index may keep in memory, as set by AZCOPY_SYNC_INDEX_GB
+func getSyncIndexMemoryLimit() (int64, error) {
+	envVar := common.EEnvironmentVariable.SyncIndexGB()
+	overrideString := glcm.GetEnvironmentVariable(envVar)
+	if overrideString == "" {
+		return defaultSyncIndexGB * 1024 * 1024 * 1024, nil
+	}
+
+	overrideValue, err := strconv.ParseFloat(overrideString, 64)
+	if err != nil || overrideValue <= 0 {
+		return 0, fmt.Errorf("cannot parse environment variable %s, it must be a positive number of GB", envVar.Name)
+	}
+	return int64(overrideValue * 1024 * 1024 * 1024), nil
+}
+
 // process the given stored object by indexing it using its relative path
 func (i *objectIndexer) store(storedObject storedObject) (err error) {
-	// TODO we might buffer too much data in memory, figure out whether we should limit the max number of files
-	// TODO previously we used 10M as the max, but it was proven to be too small for some users
-
 	// It is safe to index all storedObjects just by relative path, regardless of their entity type, because
 	// no filesystem allows a file and a folder to have the exact same full path.  This is true of
 	// Linux file systems, Windows, Azure Files and ADLS Gen 2 (and logically should be true of all file systems).
 
-	i.indexMap[storedObject.relativePath] = storedObject
 	i.counter += 1
-	return
+
+	if existing, inMemory := i.indexMap[storedObject.relativePath]; inMemory {
+		// replace it where it is, so that there is only ever one entry per path
+		i.memoryUsed += storedObject.approximateMemorySize() - existing.approximateMemorySize()
+		i.indexMap[storedObject.relativePath] = storedObject
+		return
+	}
+
+	size := storedObject.approximateMemorySize()
+	if i.spill == nil && (i.memoryLimit <= 0 || i.memoryUsed+size <= i.memoryLimit) {
+		i.memoryUsed += size
+		i.indexMap[storedObject.relativePath] = storedObject
+		return
+	}
+
+	if i.spill == nil {
+		i.spill, err = newSpilledIndex(i.spillFilePath)
+		if err != nil {
+			return
+		}
+	}
+	return i.spill.store(storedObject)
+}
+
+// lookup finds the stored object with the given relative path, if there is one
+func (i *objectIndexer) lookup(relativePath string) (storedObject, bool, error) {
+	if object, present := i.indexMap[relativePath]; present {
+		return object, true, nil
+	}
+	if i.spill == nil {
+		return storedObject{}, false, nil
+	}
+	return i.spill.lookup(relativePath)
+}
+
+// remove drops the stored object with the given relative path from the index, so that traverse won't see it
+func (i *objectIndexer) remove(relativePath string) error {
+	if object, present := i.indexMap[relativePath]; present {
+		i.memoryUsed -= object.approximateMemorySize()
+		delete(i.indexMap, relativePath)
+		return nil
+	}
+	if i.spill == nil {
+		return nil
+	}
+	return i.spill.remove(relativePath)
 }
 
 // go through the remaining stored objects in the map to process them
@@ -56,5 +151,434 @@ func (i *objectIndexer) traverse(processor objectProcessor, filters []objectFilt
 			return
 		}
 	}
+
+	if i.spill != nil {
+		err = i.spill.traverse(func(value storedObject) error {
+			err := processIfPassedFilters(filters, value, processor)
+			_, err = getProcessingError(err)
+			return err
+		})
+	}
 	return
 }
+
+// close deletes the spill files, if there are any. The index must not be used afterwards, but may be closed again.
+func (i *objectIndexer) close() error {
+	if i.spill == nil {
+		return nil
+	}
+	spill := i.spill
+	i.spill = nil
+	return spill.close()
+}
+
+// a rough estimate of the memory used by the object when it is held in the index
+// (it needn't be exact, it's only used to decide when to spill)
+func (s *storedObject) approximateMemorySize() int64 {
+	const fixedOverhead = 400 // the struct itself, plus the map entry and key header
+	size := fixedOverhead + 2*len(s.relativePath) + len(s.name) + len(s.md5) + len(s.etagMd5) +
+		len(s.contentDisposition) + len(s.cacheControl) + len(s.contentLanguage) + len(s.contentEncoding) + len(s.contentType) +
+		len(s.containerName) + len(s.dstContainerName) + len(s.blobAccessTier) + len(s.blobVersionID)
+	for k, v := range s.Metadata {
+		size += 64 + len(k) + len(v)
+	}
+	for k, v := range s.blobTags {
+		size += 64 + len(k) + len(v)
+	}
+	return int64(size)
+}
+
+// spilledIndex is the on-disk part of an objectIndexer: a hash table that lives entirely on disk, so that its memory use doesn't grow with the number of objects.
+// The objects are appended to one file, and a second file holds the offset of the (most recent) record in each bucket of the table.
+// Each record holds the offset of the previous record in its bucket, so that the records of a bucket form a chain through the file.
+// When there are too many records per bucket, the number of buckets is doubled, and the chains are relinked.
+//
+// Record layout: the offset of the previous record in the chain, plus one (8 bytes, 0 for none), the hash of the relative path (8 bytes),
+// the length of the payload (4 bytes), then the payload. Comparing the hashes first means that only matching payloads need reading.
+type spilledIndex struct {
+	file    *os.File
+	writer  *bufio.Writer
+	fileEnd int64 // where the next record will go
+
+	// the offset of the head of each bucket's chain, plus one (0 for an empty bucket)
+	heads      *os.File
+	bucketBits uint
+	count      int64 // how many objects are in the index
+}
+
+const spilledRecordHeaderSize = 20
+
+// the table starts with this many buckets (as a power of two), and doubles when the chains average more than spilledMaxLoad records
+const spilledInitialBucketBits = 16
+const spilledMaxLoad = 4
+
+// how much of the heads file to read at a time, when going through every bucket
+const spilledHeadsReadSize = 64 * 1024
+
+// the on-disk form of a storedObject, whose fields have to be exported for JSON
+type spilledObject struct {
+	Name               string
+	EntityType         common.EntityType
+	LastModifiedTime   time.Time
+	Size               int64
+	MD5                []byte
+	EtagMD5            []byte
+	BlobType           azblob.BlobType
+	ContentDisposition string
+	CacheControl       string
+	ContentLanguage    string
+	ContentEncoding    string
+	ContentType        string
+	RelativePath       string
+	ContainerName      string
+	DstContainerName   string
+	BlobAccessTier     azblob.AccessTierType
+	Metadata           common.Metadata
+	BlobVersionID      string
+	BlobTags           common.BlobTags
+}
+
+func newSpilledIndex(filePath string) (*spilledIndex, error) {
+	return newSpilledIndexWithBuckets(filePath, spilledInitialBucketBits)
+}
+
+func newSpilledIndexWithBuckets(filePath string, bucketBits uint) (*spilledIndex, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spilledIndex{file: file, writer: bufio.NewWriterSize(file, 1024*1024)}
+	if s.heads, err = newSpilledHeads(filePath, bucketBits); err != nil {
+		_ = file.Close()
+		_ = os.Remove(filePath)
+		return nil, err
+	}
+	s.bucketBits = bucketBits
+	return s, nil
+}
+
+// newSpilledHeads creates a heads file with every bucket empty
+func newSpilledHeads(filePath string, bucketBits uint) (*os.File, error) {
+	heads, err := os.OpenFile(filePath+".heads", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = heads.Truncate(int64(8) << bucketBits); err != nil {
+		_ = heads.Close()
+		_ = os.Remove(heads.Name())
+		return nil, err
+	}
+	return heads, nil
+}
+
+func hashRelativePath(relativePath string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(relativePath))
+	return h.Sum64()
+}
+
+func (s *spilledIndex) bucketOf(hash uint64) int64 {
+	return int64(hash & (1<<s.bucketBits - 1))
+}
+
+// readHead returns the offset of the head of the bucket's chain, or -1 if the bucket is empty
+func (s *spilledIndex) readHead(bucket int64) (int64, error) {
+	buffer := make([]byte, 8)
+	if _, err := s.heads.ReadAt(buffer, bucket*8); err != nil {
+		return -1, err
+	}
+	return int64(binary.LittleEndian.Uint64(buffer)) - 1, nil
+}
+
+func writeSpilledLink(file *os.File, at int64, offset int64) error {
+	buffer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buffer, uint64(offset+1))
+	_, err := file.WriteAt(buffer, at)
+	return err
+}
+
+func (s *spilledIndex) store(object storedObject) error {
+	// if it's already here, drop the old one, so that lookups can't find a stale copy
+	if err := s.remove(object.relativePath); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(spilledObject{
+		Name:               object.name,
+		EntityType:         object.entityType,
+		LastModifiedTime:   object.lastModifiedTime,
+		Size:               object.size,
+		MD5:                object.md5,
+		EtagMD5:            object.etagMd5,
+		BlobType:           object.blobType,
+		ContentDisposition: object.contentDisposition,
+		CacheControl:       object.cacheControl,
+		ContentLanguage:    object.contentLanguage,
+		ContentEncoding:    object.contentEncoding,
+		ContentType:        object.contentType,
+		RelativePath:       object.relativePath,
+		ContainerName:      object.containerName,
+		DstContainerName:   object.dstContainerName,
+		BlobAccessTier:     object.blobAccessTier,
+		Metadata:           object.Metadata,
+		BlobVersionID:      object.blobVersionID,
+		BlobTags:           object.blobTags,
+	})
+	if err != nil {
+		return err
+	}
+
+	hash := hashRelativePath(object.relativePath)
+	bucket := s.bucketOf(hash)
+	previous, err := s.readHead(bucket)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, spilledRecordHeaderSize)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(previous+1))
+	binary.LittleEndian.PutUint64(header[8:16], hash)
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(payload)))
+	if _, err = s.writer.Write(header); err != nil {
+		return err
+	}
+	if _, err = s.writer.Write(payload); err != nil {
+		return err
+	}
+
+	if err = writeSpilledLink(s.heads, bucket*8, s.fileEnd); err != nil {
+		return err
+	}
+	s.fileEnd += int64(len(header) + len(payload))
+	s.count++
+
+	if s.count > spilledMaxLoad<<s.bucketBits {
+		return s.grow()
+	}
+	return nil
+}
+
+// grow doubles the number of buckets. Each bucket splits in two, according to the next bit of the hash,
+// so relinking one old chain only ever touches two new ones.
+func (s *spilledIndex) grow() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	oldHeads := s.heads
+	oldBuckets := int64(1) << s.bucketBits
+	_ = oldHeads.Close()
+	oldHeadsPath := oldHeads.Name() + ".old"
+	if err := os.Rename(oldHeads.Name(), oldHeadsPath); err != nil {
+		return err
+	}
+	defer os.Remove(oldHeadsPath)
+	old, err := os.Open(oldHeadsPath)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	newHeads, err := newSpilledHeads(strings.TrimSuffix(oldHeads.Name(), ".heads"), s.bucketBits+1)
+	if err != nil {
+		return err
+	}
+	s.heads = newHeads
+	s.bucketBits++
+
+	err = forEachSpilledHead(old, oldBuckets, func(bucket int64, offset int64) error {
+		lowHead, highHead := int64(-1), int64(-1)
+		for offset >= 0 {
+			previous, hash, _, err := s.readHeader(offset)
+			if err != nil {
+				return err
+			}
+
+			newPrevious := &lowHead
+			if s.bucketOf(hash) != bucket {
+				newPrevious = &highHead
+			}
+			if err = writeSpilledLink(s.file, offset, *newPrevious); err != nil {
+				return err
+			}
+			*newPrevious = offset
+			offset = previous
+		}
+
+		if err := writeSpilledLink(s.heads, bucket*8, lowHead); err != nil {
+			return err
+		}
+		return writeSpilledLink(s.heads, (bucket+oldBuckets)*8, highHead)
+	})
+	return err
+}
+
+// forEachSpilledHead calls the func with the head of each bucket's chain that isn't empty, in order of bucket
+func forEachSpilledHead(heads *os.File, buckets int64, f func(bucket int64, offset int64) error) error {
+	buffer := make([]byte, spilledHeadsReadSize)
+	for start := int64(0); start < buckets; start += spilledHeadsReadSize / 8 {
+		n, err := heads.ReadAt(buffer, start*8)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		for i := 0; i+8 <= n; i += 8 {
+			if head := int64(binary.LittleEndian.Uint64(buffer[i:i+8])) - 1; head >= 0 {
+				if err = f(start+int64(i/8), head); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// makes sure that everything written so far can be read back
+func (s *spilledIndex) flush() error {
+	if s.writer.Buffered() == 0 {
+		return nil
+	}
+	return s.writer.Flush()
+}
+
+// flushes only if the record at the given offset hasn't reached the file yet, so that looking up recent objects
+// doesn't cost a write for every store
+func (s *spilledIndex) flushIfBuffered(offset int64) error {
+	if offset < s.fileEnd-int64(s.writer.Buffered()) {
+		return nil
+	}
+	return s.flush()
+}
+
+// readHeader returns the offset of the previous record in the chain of the record at the given offset, along with the hash of its path and the length of its payload
+func (s *spilledIndex) readHeader(offset int64) (previous int64, hash uint64, length uint32, err error) {
+	if err = s.flushIfBuffered(offset); err != nil {
+		return -1, 0, 0, err
+	}
+	header := make([]byte, spilledRecordHeaderSize)
+	if _, err = s.file.ReadAt(header, offset); err != nil {
+		return -1, 0, 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(header[0:8])) - 1, binary.LittleEndian.Uint64(header[8:16]), binary.LittleEndian.Uint32(header[16:20]), nil
+}
+
+// reads the payload of the record at the given offset
+func (s *spilledIndex) readRecord(offset int64, length uint32) (storedObject, error) {
+	payload := make([]byte, length)
+	if _, err := s.file.ReadAt(payload, offset+spilledRecordHeaderSize); err != nil && err != io.EOF {
+		return storedObject{}, err
+	}
+
+	var spilled spilledObject
+	if err := json.Unmarshal(payload, &spilled); err != nil {
+		return storedObject{}, err
+	}
+
+	return storedObject{
+		name:               spilled.Name,
+		entityType:         spilled.EntityType,
+		lastModifiedTime:   spilled.LastModifiedTime,
+		size:               spilled.Size,
+		md5:                spilled.MD5,
+		etagMd5:            spilled.EtagMD5,
+		blobType:           spilled.BlobType,
+		contentDisposition: spilled.ContentDisposition,
+		cacheControl:       spilled.CacheControl,
+		contentLanguage:    spilled.ContentLanguage,
+		contentEncoding:    spilled.ContentEncoding,
+		contentType:        spilled.ContentType,
+		relativePath:       spilled.RelativePath,
+		containerName:      spilled.ContainerName,
+		dstContainerName:   spilled.DstContainerName,
+		blobAccessTier:     spilled.BlobAccessTier,
+		Metadata:           spilled.Metadata,
+		blobVersionID:      spilled.BlobVersionID,
+		blobTags:           spilled.BlobTags,
+	}, nil
+}
+
+// find walks the chain for the given path, returning the matching object along with
+// the offset of its record, the offset of the record after it in the chain (-1 if it's the head), and the offset of the one before it
+func (s *spilledIndex) find(relativePath string) (object storedObject, found bool, offset, next, previous int64, err error) {
+	hash := hashRelativePath(relativePath)
+	if offset, err = s.readHead(s.bucketOf(hash)); err != nil {
+		return
+	}
+
+	next = -1
+	for offset >= 0 {
+		var recordHash uint64
+		var length uint32
+		if previous, recordHash, length, err = s.readHeader(offset); err != nil {
+			return
+		}
+		if recordHash == hash {
+			if object, err = s.readRecord(offset, length); err != nil {
+				return
+			}
+			if object.relativePath == relativePath {
+				found = true
+				return
+			}
+		}
+		next = offset
+		offset = previous
+	}
+	return
+}
+
+func (s *spilledIndex) lookup(relativePath string) (storedObject, bool, error) {
+	object, found, _, _, _, err := s.find(relativePath)
+	return object, found, err
+}
+
+func (s *spilledIndex) remove(relativePath string) error {
+	_, found, _, next, previous, err := s.find(relativePath)
+	if err != nil || !found {
+		return err
+	}
+	s.count--
+
+	if next < 0 {
+		// it's the head of its chain
+		return writeSpilledLink(s.heads, s.bucketOf(hashRelativePath(relativePath))*8, previous)
+	}
+
+	// unlink it from the middle of the chain, by pointing the record after it at the one before it
+	// (readHeader has already made sure that record is in the file, rather than the writer's buffer)
+	return writeSpilledLink(s.file, next, previous)
+}
+
+func (s *spilledIndex) traverse(processor func(storedObject) error) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	return forEachSpilledHead(s.heads, int64(1)<<s.bucketBits, func(_ int64, offset int64) error {
+		for offset >= 0 {
+			previous, _, length, err := s.readHeader(offset)
+			if err != nil {
+				return err
+			}
+			object, err := s.readRecord(offset, length)
+			if err != nil {
+				return err
+			}
+			if err = processor(object); err != nil {
+				return err
+			}
+			offset = previous
+		}
+		return nil
+	})
+}
+
+func (s *spilledIndex) close() error {
+	_ = s.file.Close()
+	_ = s.heads.Close()
+	err := os.Remove(s.file.Name())
+	if headsErr := os.Remove(s.heads.Name()); err == nil {
+		err = headsErr
+	}
+	return err
+}
@@ -0,0 +1,406 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// Incremental sync remembers what it last put at the destination, in a manifest alongside the job plan files,
+// so that later syncs can compare the source against the manifest, instead of listing the destination.
+// That only holds for as long as nothing else changes the destination, which is why a full check can be asked for every so often.
+//
+// The manifest is a header line, followed by one line per file, in ascending order of relative path.
+// Since it is written in the order that the sync compares the files in, it can be streamed back in the same order next time.
+
+const syncManifestVersion = 1
+
+type syncManifestHeader struct {
+	Version       int       `json:"version"`
+	LastFullCheck time.Time `json:"lastFullCheck"`
+}
+
+// syncManifestEntry describes the source file that a destination file was last synced from
+type syncManifestEntry struct {
+	RelativePath string    `json:"path"`
+	Size         int64     `json:"size"`
+	LMT          time.Time `json:"lmt"`
+	MD5          []byte    `json:"md5,omitempty"`
+
+	// set while the file is being transferred, since it's only at the destination if the transfer succeeds
+	Pending bool `json:"pending,omitempty"`
+}
+
+func syncManifestFilePath(folder string, fromTo common.FromTo, source, destination common.ResourceString) string {
+	return syncPairFilePath(folder, "syncmanifest", ".jsonl", fromTo, source, destination)
+}
+
+// readSyncManifestHeader reads the header of the manifest, if there is a usable one
+func readSyncManifestHeader(filePath string) (header syncManifestHeader, exists bool, err error) {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return header, false, nil
+	} else if err != nil {
+		return header, false, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return header, false, err
+	}
+
+	// a manifest that can't be understood is as good as none, which just means a full check
+	if json.Unmarshal(line, &header) != nil || header.Version != syncManifestVersion {
+		return syncManifestHeader{}, false, nil
+	}
+	return header, true, nil
+}
+
+// syncManifestTraverser lists the files in the manifest, as if they were the destination
+type syncManifestTraverser struct {
+	filePath                    string
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func newSyncManifestTraverser(filePath string, incrementEnumerationCounter enumerationCounterFunc) *syncManifestTraverser {
+	return &syncManifestTraverser{filePath: filePath, incrementEnumerationCounter: incrementEnumerationCounter}
+}
+
+func (t *syncManifestTraverser) isDirectory(bool) bool {
+	return true
+}
+
+func (t *syncManifestTraverser) canTraverseSorted() bool {
+	return true
+}
+
+// the manifest is always written in order, so there's nothing extra to do
+func (t *syncManifestTraverser) traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	return t.traverse(preprocessor, processor, filters)
+}
+
+func (t *syncManifestTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	return readSyncManifestEntries(t.filePath, func(entry syncManifestEntry) error {
+		if t.incrementEnumerationCounter != nil {
+			t.incrementEnumerationCounter(common.EEntityType.File())
+		}
+
+		object := newStoredObject(
+			preprocessor,
+			path.Base(entry.RelativePath),
+			entry.RelativePath,
+			common.EEntityType.File(),
+			entry.LMT,
+			entry.Size,
+			noContentProps,
+			noBlobProps,
+			noMetdata,
+			"")
+		object.md5 = entry.MD5
+
+		if err := processIfPassedFilters(filters, object, processor); err != nil && err != ignoredError {
+			return err
+		}
+		return nil
+	})
+}
+
+// readSyncManifestEntries reads the entries of the manifest in order, skipping its header
+func readSyncManifestEntries(filePath string, process func(entry syncManifestEntry) error) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // allow for very long paths
+	for lineNumber := 0; scanner.Scan(); lineNumber++ {
+		if lineNumber == 0 {
+			continue
+		}
+
+		var entry syncManifestEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("cannot read line %d of the sync manifest %s, due to error: %s", lineNumber+1, filePath, err)
+		}
+		if err = process(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// syncManifestWriter writes the next manifest while the sync is comparing, to a temporary file that replaces the manifest once the job is done
+type syncManifestWriter struct {
+	filePath string
+	tempPath string
+
+	file       *os.File
+	writer     *bufio.Writer
+	encoder    *json.Encoder
+	hasPending bool
+}
+
+func newSyncManifestWriter(filePath string, header syncManifestHeader) (*syncManifestWriter, error) {
+	w := &syncManifestWriter{filePath: filePath, tempPath: filePath + ".new"}
+
+	var err error
+	w.file, err = os.Create(w.tempPath)
+	if err != nil {
+		return nil, err
+	}
+	w.writer = bufio.NewWriter(w.file)
+	w.encoder = json.NewEncoder(w.writer) // which puts each entry on a line of its own
+
+	header.Version = syncManifestVersion
+	if err = w.encoder.Encode(header); err != nil {
+		_ = w.file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// record adds an entry, which must come after all the previous ones in order of relative path
+func (w *syncManifestWriter) record(entry syncManifestEntry) error {
+	w.hasPending = w.hasPending || entry.Pending
+	return w.encoder.Encode(entry)
+}
+
+// close must be called once every entry has been recorded
+func (w *syncManifestWriter) close() error {
+	if err := w.writer.Flush(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// discard throws the new manifest away, leaving the old one as it was
+func (w *syncManifestWriter) discard() error {
+	_ = w.file.Close() // in case the sync failed before close was called
+	return os.Remove(w.tempPath)
+}
+
+// commit replaces the manifest with the new one.
+// The files that were being transferred are kept in it, unless their transfers failed, so that they'll be transferred again next time.
+// If failedTransfers is nil, we can't tell which transfers failed (or never ran), so unless they all succeeded, they're all left out.
+// Either way, the new manifest's temporary file is gone afterwards.
+func (w *syncManifestWriter) commit(transfersSucceeded bool, failedTransfers map[string]bool) (err error) {
+	defer func() {
+		if err != nil {
+			_ = os.Remove(w.tempPath)
+		}
+	}()
+
+	if !w.hasPending {
+		return os.Rename(w.tempPath, w.filePath)
+	}
+
+	header, _, err := readSyncManifestHeader(w.tempPath)
+	if err != nil {
+		return err
+	}
+	final, err := newSyncManifestWriter(w.filePath+".tmp", header)
+	if err != nil {
+		return err
+	}
+
+	err = readSyncManifestEntries(w.tempPath, func(entry syncManifestEntry) error {
+		if entry.Pending {
+			if !transfersSucceeded && (failedTransfers == nil || failedTransfers[entry.RelativePath]) {
+				return nil
+			}
+			entry.Pending = false
+		}
+		return final.record(entry)
+	})
+	if closeErr := final.close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(final.tempPath, w.filePath)
+	}
+	if err != nil {
+		_ = os.Remove(final.tempPath)
+		return err
+	}
+	return os.Remove(w.tempPath)
+}
+
+// syncManifestRecorder wraps the comparator of an incremental sync, to note what ends up at the destination
+type syncManifestRecorder struct {
+	syncMergeProcessor // which decides what to do with each file
+
+	writer *syncManifestWriter
+	md5    md5Getter // nil unless hashes are being compared
+
+	scheduled bool // whether the pair being compared was scheduled for transfer
+}
+
+// recordTransfers wraps the transfer scheduler, so that the files that it is given are recorded as pending
+func (r *syncManifestRecorder) recordTransfers(scheduler objectProcessor) objectProcessor {
+	return func(object storedObject) error {
+		r.scheduled = true
+		if err := r.record(object, true); err != nil {
+			return err
+		}
+		return scheduler(object)
+	}
+}
+
+// files that are already in step keep their place in the manifest
+// (extra files at the destination don't, since they are either deleted, or no longer ours to keep track of)
+func (r *syncManifestRecorder) processPair(sourceObject, destinationObject storedObject) error {
+	r.scheduled = false
+	if err := r.syncMergeProcessor.processPair(sourceObject, destinationObject); err != nil || r.scheduled {
+		return err
+	}
+	return r.record(sourceObject, false)
+}
+
+func (r *syncManifestRecorder) record(sourceObject storedObject, pending bool) error {
+	if sourceObject.entityType != common.EEntityType.File() {
+		return nil
+	}
+
+	entry := syncManifestEntry{RelativePath: sourceObject.relativePath, Size: sourceObject.size, LMT: sourceObject.lastModifiedTime, Pending: pending}
+	if r.md5 != nil {
+		// the hash is already known if the file was compared, and otherwise it's worth knowing for next time
+		if hash, err := r.md5(sourceObject); err == nil {
+			entry.MD5 = hash
+		}
+	}
+	return r.writer.record(entry)
+}
+
+// initIncrementalEnumerator sets up a sync that compares the source against the manifest of the last sync,
+// or against the destination itself, when there's no manifest yet, or when it's time for a full check
+func (cca *cookedSyncCmdArgs) initIncrementalEnumerator(sourceTraverser, destinationTraverser resourceTraverser, filters []objectFilter, fpo common.FolderPropertyOption,
+	transferScheduler *copyTransferProcessor, changeDetector syncChangeDetector, localHashCache *localMd5Cache) (*syncEnumerator, error) {
+	sortedSource, sourceIsSorted := asSortedTraverser(sourceTraverser)
+	sortedDestination, destinationIsSorted := asSortedTraverser(destinationTraverser)
+	if !sourceIsSorted || !destinationIsSorted {
+		return nil, errors.New("incremental sync requires both the source and the destination to be listed in order, which isn't possible here")
+	}
+
+	manifestFolder := azcopyJobPlanFolder
+	if manifestFolder == "" {
+		manifestFolder = os.TempDir()
+	}
+	manifestPath := syncManifestFilePath(manifestFolder, cca.fromTo, cca.source, cca.destination)
+	header, exists, err := readSyncManifestHeader(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !exists:
+		glcm.Info("There is no manifest of a previous sync, so the destination will be listed in full.")
+		header.LastFullCheck = time.Now()
+	case cca.fullCheckInterval > 0 && time.Since(header.LastFullCheck) >= cca.fullCheckInterval:
+		glcm.Info("It's time for a full check, so the destination will be listed, rather than the manifest of the previous sync being trusted.")
+		header.LastFullCheck = time.Now()
+	default:
+		sortedDestination = newSyncManifestTraverser(manifestPath, func(entityType common.EntityType) {
+			if entityType == common.EEntityType.File() {
+				atomic.AddUint64(&cca.atomicDestinationFilesScanned, 1)
+			}
+		})
+	}
+
+	writer, err := newSyncManifestWriter(manifestPath, header)
+	if err != nil {
+		return nil, err
+	}
+	cca.syncRecord = writer
+
+	destinationCleaner, err := cca.newDestinationCleaner(fpo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
+	}
+
+	recorder := &syncManifestRecorder{writer: writer}
+	if localHashCache != nil {
+		recorder.md5 = localHashCache.getMd5
+	}
+	mergeComparator := newSyncMergeComparator(recorder.recordTransfers(transferScheduler.scheduleCopyTransfer), destinationCleaner, changeDetector)
+	mergeComparator.beforeOverwrite = cca.beforeOverwrite()
+	recorder.syncMergeProcessor = mergeComparator
+
+	finalize := func() (err error) {
+		// the new manifest is only kept once the job is done, so it's no use if the job can't be started
+		defer func() {
+			if err != nil {
+				_ = writer.discard()
+			}
+		}()
+
+		if err = writer.close(); err != nil {
+			return err
+		}
+
+		// the cache is purely an optimization, so failing to save it is no reason to fail the sync
+		if localHashCache != nil {
+			if err := localHashCache.save(); err != nil && ste.JobsAdmin != nil {
+				ste.JobsAdmin.LogToJobLog("failed to save the cache of local file hashes: "+err.Error(), pipeline.LogWarning)
+			}
+		}
+
+		jobInitiated, err := transferScheduler.dispatchFinalPart()
+		// sync cleanly exits if nothing is scheduled.
+		if err != nil && err != NothingScheduledError {
+			return err
+		}
+
+		if cca.dryrunReporter != nil {
+			// nothing was transferred, so the manifest stays as it was
+			if err = writer.discard(); err != nil {
+				return err
+			}
+		} else if !jobInitiated {
+			// if there's no job, there's nothing to wait for before the manifest can be saved
+			if err = writer.commit(true, nil); err != nil {
+				return fmt.Errorf("cannot save the sync manifest, due to error: %s", err)
+			}
+		}
+
+		quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+		cca.setScanningComplete()
+		return nil
+	}
+
+	return newSyncMergeEnumerator(sortedSource, sortedDestination, filters, recorder, finalize), nil
+}
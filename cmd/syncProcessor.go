@@ -22,6 +22,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -30,6 +31,7 @@ import (
 	"github.com/Azure/azure-storage-file-go/azfile"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-azcopy/ste"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -37,13 +39,19 @@ import (
 
 // extract the right info from cooked arguments and instantiate a generic copy transfer processor from it
 func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int, fpo common.FolderPropertyOption) *copyTransferProcessor {
+	return newSyncTransferProcessorForDirection(cca, cca.fromTo, cca.source, cca.destination, numOfTransfersPerPart, fpo)
+}
+
+// the same, but for transfers in the given direction, which is the other way round for the return leg of a two-way sync
+func newSyncTransferProcessorForDirection(cca *cookedSyncCmdArgs, fromTo common.FromTo, source, destination common.ResourceString,
+	numOfTransfersPerPart int, fpo common.FolderPropertyOption) *copyTransferProcessor {
 	copyJobTemplate := &common.CopyJobPartOrderRequest{
 		JobID:           cca.jobID,
 		CommandString:   cca.commandString,
-		FromTo:          cca.fromTo,
+		FromTo:          fromTo,
 		Fpo:             fpo,
-		SourceRoot:      cca.source.CloneWithConsolidatedSeparators(),
-		DestinationRoot: cca.destination.CloneWithConsolidatedSeparators(),
+		SourceRoot:      source.CloneWithConsolidatedSeparators(),
+		DestinationRoot: destination.CloneWithConsolidatedSeparators(),
 		CredentialInfo:  cca.credentialInfo,
 
 		// flags
@@ -62,14 +70,23 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 		S2SGetPropertiesInBackend:      true,
 		S2SInvalidMetadataHandleOption: common.EInvalidMetadataHandleOption.RenameIfInvalid(),
 	}
+	if cca.cpkOptions.Key != nil {
+		copyJobTemplate.CpkKeyHash = cca.cpkOptions.Key.Hash()
+	}
+	if fromTo.To() == common.ELocation.Blob() {
+		// the return leg of a two-way sync only reads blobs, and the service knows which scope they were written with
+		copyJobTemplate.EncryptionScope = cca.cpkOptions.EncryptionScope
+	}
 
 	reportFirstPart := func(jobStarted bool) { cca.setFirstPartOrdered() } // for compatibility with the way sync has always worked, we don't check jobStarted here
 	reportFinalPart := func() { cca.isEnumerationComplete = true }
 
 	// note that the source and destination, along with the template are given to the generic processor's constructor
 	// this means that given an object with a relative path, this processor already knows how to schedule the right kind of transfers
-	return newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
+	processor := newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, source, destination,
 		reportFirstPart, reportFinalPart, cca.preserveAccessTier)
+	processor.dryrunReporter = cca.dryrunReporter
+	return processor
 }
 
 // base for delete processors targeting different resources
@@ -91,6 +108,11 @@ type interactiveDeleteProcessor struct {
 	// examples: a directory path, or url to container
 	objectLocationToDisplay string
 
+	// used for prompt message: which end the object is missing from, and which end it would be deleted from
+	// these are only the other way round when a two-way sync deletes from the source
+	missingFromToDisplay string
+	deleteFromToDisplay  string
+
 	// count the deletions that happened
 	incrementDeletionCount func()
 }
@@ -116,9 +138,9 @@ func (d *interactiveDeleteProcessor) removeImmediately(object storedObject) (err
 }
 
 func (d *interactiveDeleteProcessor) promptForConfirmation(object storedObject) (shouldDelete bool, keepPrompting bool) {
-	answer := glcm.Prompt(fmt.Sprintf("The %s '%s' does not exist at the source. "+
-		"Do you wish to delete it from the destination(%s)?",
-		d.objectTypeToDisplay, object.relativePath, d.objectLocationToDisplay),
+	answer := glcm.Prompt(fmt.Sprintf("The %s '%s' does not exist at the %s. "+
+		"Do you wish to delete it from the %s(%s)?",
+		d.objectTypeToDisplay, object.relativePath, d.missingFromToDisplay, d.deleteFromToDisplay, d.objectLocationToDisplay),
 		common.PromptDetails{
 			PromptType:   common.EPromptType.DeleteDestination(),
 			PromptTarget: object.relativePath,
@@ -156,6 +178,8 @@ func newInteractiveDeleteProcessor(deleter objectProcessor, deleteDestination co
 		deleter:                 deleter,
 		objectTypeToDisplay:     objectTypeToDisplay,
 		objectLocationToDisplay: objectLocationToDisplay.Value,
+		missingFromToDisplay:    "source",
+		deleteFromToDisplay:     "destination",
 		incrementDeletionCount:  incrementDeletionCounter,
 		shouldPromptUser:        deleteDestination == common.EDeleteDestination.Prompt(),
 		shouldDelete:            deleteDestination == common.EDeleteDestination.True(), // if shouldPromptUser is true, this will start as false, but we will determine its value later
@@ -163,12 +187,18 @@ func newInteractiveDeleteProcessor(deleter objectProcessor, deleteDestination co
 }
 
 func newSyncLocalDeleteProcessor(cca *cookedSyncCmdArgs) *interactiveDeleteProcessor {
-	localDeleter := localFileDeleter{rootPath: cca.destination.ValueLocal()}
-	return newInteractiveDeleteProcessor(localDeleter.deleteFile, cca.deleteDestination, "local file", cca.destination, cca.incrementDeletionCount)
+	localDeleter := localFileDeleter{rootPath: cca.destination.ValueLocal(), forceIfReadOnly: cca.forceIfReadOnly}
+	deleter := localDeleter.deleteFile
+	if cca.backup != nil {
+		// the extra files are moved into the backup, rather than deleted
+		deleter = cca.backup.remove
+	}
+	return newInteractiveDeleteProcessor(deleter, cca.deleteDestination, "local file", cca.destination, cca.incrementDeletionCount)
 }
 
 type localFileDeleter struct {
-	rootPath string
+	rootPath        string
+	forceIfReadOnly bool
 }
 
 // As at version 10.4.0, we intentionally don't delete directories in sync,
@@ -190,7 +220,17 @@ func shouldSyncRemoveFolders() bool {
 func (l *localFileDeleter) deleteFile(object storedObject) error {
 	if object.entityType == common.EEntityType.File() {
 		glcm.Info("Deleting extra file: " + object.relativePath)
-		return os.Remove(common.GenerateFullPath(l.rootPath, object.relativePath))
+		fullPath := common.GenerateFullPath(l.rootPath, object.relativePath)
+		err := os.Remove(fullPath)
+		if err != nil && os.IsPermission(err) && l.forceIfReadOnly {
+			// On Windows, a file with the read-only attribute can't be deleted until the attribute is cleared,
+			// and making the file writable is what clears it. (Elsewhere, it's the folder's permissions that matter,
+			// so this won't help, and the retry will fail the same way.)
+			if chmodErr := os.Chmod(fullPath, common.DEFAULT_FILE_PERM); chmodErr == nil {
+				err = os.Remove(fullPath)
+			}
+		}
+		return err
 	} else {
 		if shouldSyncRemoveFolders() {
 			panic("folder deletion enabled but not implemented")
@@ -200,6 +240,12 @@ func (l *localFileDeleter) deleteFile(object storedObject) error {
 }
 
 func newSyncDeleteProcessor(cca *cookedSyncCmdArgs) (*interactiveDeleteProcessor, error) {
+	if cca.backup != nil {
+		// the extra objects are moved into the backup, rather than deleted
+		return newInteractiveDeleteProcessor(cca.backup.remove,
+			cca.deleteDestination, cca.fromTo.To().String(), cca.destination, cca.incrementDeletionCount), nil
+	}
+
 	rawURL, err := cca.destination.FullURL()
 	if err != nil {
 		return nil, err
@@ -212,7 +258,7 @@ func newSyncDeleteProcessor(cca *cookedSyncCmdArgs) (*interactiveDeleteProcessor
 		return nil, err
 	}
 
-	return newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To()).delete,
+	return newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To(), cca.forceIfReadOnly).delete,
 		cca.deleteDestination, cca.fromTo.To().String(), cca.destination, cca.incrementDeletionCount), nil
 }
 
@@ -221,14 +267,17 @@ type remoteResourceDeleter struct {
 	p              pipeline.Pipeline
 	ctx            context.Context
 	targetLocation common.Location
+	// only applies to Azure Files, where a file with the read-only attribute set can't otherwise be deleted
+	forceIfReadOnly bool
 }
 
-func newRemoteResourceDeleter(rawRootURL *url.URL, p pipeline.Pipeline, ctx context.Context, targetLocation common.Location) *remoteResourceDeleter {
+func newRemoteResourceDeleter(rawRootURL *url.URL, p pipeline.Pipeline, ctx context.Context, targetLocation common.Location, forceIfReadOnly bool) *remoteResourceDeleter {
 	return &remoteResourceDeleter{
-		rootURL:        rawRootURL,
-		p:              p,
-		ctx:            ctx,
-		targetLocation: targetLocation,
+		rootURL:         rawRootURL,
+		p:               p,
+		ctx:             ctx,
+		targetLocation:  targetLocation,
+		forceIfReadOnly: forceIfReadOnly,
 	}
 }
 
@@ -247,6 +296,11 @@ func (b *remoteResourceDeleter) delete(object storedObject) error {
 			fileURLParts := azfile.NewFileURLParts(*b.rootURL)
 			fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, object.relativePath)
 			fileURL := azfile.NewFileURL(fileURLParts.URL(), b.p)
+			return b.deleteAzureFile(fileURL)
+		case common.ELocation.BlobFS():
+			bfsURLParts := azbfs.NewBfsURLParts(*b.rootURL)
+			bfsURLParts.DirectoryOrFilePath = path.Join(bfsURLParts.DirectoryOrFilePath, object.relativePath)
+			fileURL := azbfs.NewFileURL(bfsURLParts.URL(), b.p)
 			_, err := fileURL.Delete(b.ctx)
 			return err
 		default:
@@ -259,3 +313,25 @@ func (b *remoteResourceDeleter) delete(object storedObject) error {
 		return nil
 	}
 }
+
+// deleteAzureFile deletes the file, first clearing its attributes if it is read-only and we've been told to force the deletion
+func (b *remoteResourceDeleter) deleteAzureFile(fileURL azfile.FileURL) error {
+	_, err := fileURL.Delete(b.ctx)
+	if stgErr, ok := err.(azfile.StorageError); !ok || stgErr.ServiceCode() != azfile.ServiceCodeReadOnlyAttribute {
+		return err
+	}
+
+	if !b.forceIfReadOnly {
+		return errors.New("target is readonly. To force the deletion to proceed, add --force-if-read-only to the command line")
+	}
+
+	// the file is about to go, so there's no harm in losing its attributes
+	none := azfile.FileAttributeNone
+	h := azfile.FileHTTPHeaders{}
+	h.FileAttributes = &none
+	if _, err = fileURL.SetHTTPHeaders(b.ctx, h); err != nil {
+		return err
+	}
+	_, err = fileURL.Delete(b.ctx)
+	return err
+}
@@ -62,10 +62,9 @@ func newLocalTraverserForSync(cca *cookedSyncCmdArgs, isSource bool) (*localTrav
 		}
 	}
 
-	// TODO: Implement this flag (followSymlinks).
-	// It's extra work and would require testing at the moment, hence why I didn't do it.
-	// Though in hindsight, copy is already getting this testing so, your choice.
-	traverser := newLocalTraverser(fullPath, cca.recursive, false, incrementEnumerationCounter)
+	// symlinks are only followed at the source, and, as in initEnumerator, the same way every time
+	traverser := newLocalTraverser(fullPath, cca.recursive, cca.followSymlinks && isSource, incrementEnumerationCounter)
+	traverser.stableLinkedPaths = true
 
 	return traverser, nil
 }
@@ -109,5 +108,5 @@ func newBlobTraverserForSync(cca *cookedSyncCmdArgs, isSource bool) (t *blobTrav
 		}
 	}
 
-	return newBlobTraverser(rawURL, p, ctx, cca.recursive, false, incrementEnumerationCounter), nil
+	return newBlobTraverser(rawURL, p, ctx, cca.recursive, false, false, cca.cpkOptionsFor(isSource), incrementEnumerationCounter), nil
 }
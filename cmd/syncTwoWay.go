@@ -0,0 +1,599 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// Two-way sync keeps a local folder and a Blob container (or virtual directory) in step, by applying the changes made at each end to the other.
+// To tell which end a change was made at, it remembers what each path looked like at the end of the last sync,
+// in a state file that lives alongside the job plan files. There is one state file per source/destination pair.
+
+// twoWaySyncEntry records what a path looked like, at both ends, when it was last synced
+type twoWaySyncEntry struct {
+	Size int64  `json:"size"`
+	MD5  []byte `json:"md5,omitempty"`
+
+	// The last modified times at each end.
+	// One of them is zero if it isn't known yet, i.e. when we have just uploaded the file, since the service decides what it is.
+	SourceLMT      time.Time `json:"sourceLmt"`
+	DestinationLMT time.Time `json:"destinationLmt"`
+}
+
+// hasChanged tells whether the object has changed since the entry was recorded, given the last modified time recorded for the object's end.
+// If that can't be told, it's taken to have changed, and known is false. That's when we uploaded it last time, so its time isn't known,
+// and it has no MD5 to compare. Since two-way sync always gives the blobs it uploads an MD5, something else has most likely rewritten it.
+func (e twoWaySyncEntry) hasChanged(object storedObject, recordedLMT time.Time) (changed bool, known bool) {
+	if object.size != e.Size {
+		return true, true
+	}
+	if len(object.md5) > 0 && len(e.MD5) > 0 {
+		return !bytes.Equal(object.md5, e.MD5), true
+	}
+	if recordedLMT.IsZero() {
+		return true, false
+	}
+	return !object.lastModifiedTime.Equal(recordedLMT), true
+}
+
+// changed is hasChanged for when it doesn't matter whether the change is certain, since a change always wins over a deletion
+func (e twoWaySyncEntry) changed(object storedObject, recordedLMT time.Time) bool {
+	changed, _ := e.hasChanged(object, recordedLMT)
+	return changed
+}
+
+type twoWaySyncState struct {
+	filePath string
+
+	mu       *sync.Mutex
+	previous map[string]twoWaySyncEntry // as loaded from the state file
+	next     map[string]twoWaySyncEntry // for paths that this run found to be in step
+	pending  map[string]twoWaySyncEntry // for paths that are being transferred, which only hold if the transfers succeed
+	removed  map[string]bool            // for paths that were deleted from one end because they had been deleted from the other
+}
+
+// the state file of a source/destination pair
+func twoWaySyncStateFilePath(folder string, fromTo common.FromTo, source, destination common.ResourceString) string {
+	return syncPairFilePath(folder, "syncstate", ".json", fromTo, source, destination)
+}
+
+func loadTwoWaySyncState(filePath string) (*twoWaySyncState, error) {
+	state := &twoWaySyncState{
+		filePath: filePath,
+		mu:       &sync.Mutex{},
+		previous: make(map[string]twoWaySyncEntry),
+		next:     make(map[string]twoWaySyncEntry),
+		pending:  make(map[string]twoWaySyncEntry),
+		removed:  make(map[string]bool),
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return state, nil // this is the first sync of the pair
+	} else if err != nil {
+		return nil, err
+	}
+
+	// unlike the hash cache, the state can't just be thrown away if it's unreadable,
+	// since without it every deletion would be undone and every difference would be a conflict
+	if err = json.Unmarshal(data, &state.previous); err != nil {
+		return nil, fmt.Errorf("cannot read the two-way sync state file %s, due to error: %s", filePath, err)
+	}
+	return state, nil
+}
+
+func (s *twoWaySyncState) lookup(relativePath string) (twoWaySyncEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.previous[relativePath]
+	return entry, ok
+}
+
+func (s *twoWaySyncState) recordInStep(relativePath string, entry twoWaySyncEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[relativePath] = entry
+}
+
+func (s *twoWaySyncState) recordPending(relativePath string, entry twoWaySyncEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[relativePath] = entry
+}
+
+func (s *twoWaySyncState) recordRemoved(relativePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removed[relativePath] = true
+}
+
+// commit saves the state that the pair is left in.
+// If any transfer failed, the paths that were being transferred keep their previous entries. The transfers that failed aren't singled out,
+// since a path may have been transferred under another name (when a conflict is settled by keeping both). Keeping the previous entries is safe,
+// because the next sync will then see the change again, and a change always wins over a deletion.
+// Entries for paths that weren't seen at all (e.g. because they were filtered out) are kept as they were.
+func (s *twoWaySyncState) commit(transfersSucceeded bool, failedTransfers map[string]bool) error {
+	s.mu.Lock()
+	result := make(map[string]twoWaySyncEntry, len(s.previous))
+	for relativePath, entry := range s.previous {
+		if !s.removed[relativePath] {
+			result[relativePath] = entry
+		}
+	}
+	for relativePath, entry := range s.next {
+		result[relativePath] = entry
+	}
+	if transfersSucceeded {
+		for relativePath, entry := range s.pending {
+			result[relativePath] = entry
+		}
+	}
+	data, err := json.Marshal(result)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a crash can't leave a half-written state behind
+	tempFile := s.filePath + ".tmp"
+	if err = ioutil.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, s.filePath)
+}
+
+// the state is only written by commit, so there's nothing to throw away
+func (s *twoWaySyncState) discard() error {
+	return nil
+}
+
+// twoWaySyncComparator reconciles each path, given what it looks like at each end, and what it looked like after the last sync.
+// Extra objects at one end have either been created there, or deleted from the other end,
+// and the state tells which. Objects at both ends may have changed at either of them, or at both, in which case it's a conflict.
+type twoWaySyncComparator struct {
+	state  *twoWaySyncState
+	policy common.SyncConflictPolicy
+
+	sourceIsLocal bool
+	localRoot     string
+	localMd5      md5Getter
+
+	copyForward        func(object storedObject, destinationRelativePath string) error // from the source to the destination
+	copyBackward       func(object storedObject, destinationRelativePath string) error // from the destination to the source
+	sourceCleaner      objectProcessor
+	destinationCleaner objectProcessor
+	propagateDeletions bool
+
+	conflictTime           time.Time // goes into the names of the local files that are renamed to keep both versions
+	incrementConflictCount func()
+}
+
+func (c *twoWaySyncComparator) processSourceOnly(sourceObject storedObject) error {
+	if sourceObject.entityType == common.EEntityType.Folder() {
+		return nil // folders are created as needed by the transfers into them
+	}
+
+	entry, known := c.state.lookup(sourceObject.relativePath)
+	if known && !entry.changed(sourceObject, entry.SourceLMT) && c.propagateDeletions {
+		// it was deleted from the destination since the last sync
+		c.state.recordRemoved(sourceObject.relativePath)
+		return c.sourceCleaner(sourceObject)
+	}
+
+	// it's new, or it was changed at the source after being deleted from the destination, in which case the change wins
+	return c.transferForward(sourceObject, sourceObject.relativePath)
+}
+
+func (c *twoWaySyncComparator) processDestinationOnly(destinationObject storedObject) error {
+	if destinationObject.entityType == common.EEntityType.Folder() {
+		return nil
+	}
+
+	entry, known := c.state.lookup(destinationObject.relativePath)
+	if known && !entry.changed(destinationObject, entry.DestinationLMT) && c.propagateDeletions {
+		// it was deleted from the source since the last sync
+		c.state.recordRemoved(destinationObject.relativePath)
+		return c.destinationCleaner(destinationObject)
+	}
+
+	return c.transferBackward(destinationObject, destinationObject.relativePath)
+}
+
+func (c *twoWaySyncComparator) processPair(sourceObject, destinationObject storedObject) error {
+	if sourceObject.entityType == common.EEntityType.Folder() {
+		return nil
+	}
+
+	entry, known := c.state.lookup(sourceObject.relativePath)
+	sourceChanged, sourceKnown, destinationChanged, destinationKnown := true, true, true, true
+	if known {
+		sourceChanged, sourceKnown = entry.hasChanged(sourceObject, entry.SourceLMT)
+		destinationChanged, destinationKnown = entry.hasChanged(destinationObject, entry.DestinationLMT)
+	}
+
+	switch {
+	case !sourceChanged && !destinationChanged:
+		// still in step, but note the last modified times, since one of them may only have just become known
+		md5 := entry.MD5
+		if len(md5) == 0 {
+			md5 = c.md5Of(destinationObject, !c.sourceIsLocal)
+		}
+		c.state.recordInStep(sourceObject.relativePath, twoWaySyncEntry{Size: entry.Size, MD5: md5,
+			SourceLMT: sourceObject.lastModifiedTime, DestinationLMT: destinationObject.lastModifiedTime})
+		return nil
+	case sourceChanged && sourceKnown && !destinationChanged:
+		return c.transferForward(sourceObject, sourceObject.relativePath)
+	case !sourceChanged && destinationChanged && destinationKnown:
+		return c.transferBackward(destinationObject, destinationObject.relativePath)
+	default:
+		// changed at both ends, or seen at both ends for the first time, or perhaps changed at an end where it can't be told,
+		// which is only a conflict if the contents differ
+		if md5, same := c.sameContent(sourceObject, destinationObject); same {
+			c.state.recordInStep(sourceObject.relativePath, twoWaySyncEntry{Size: sourceObject.size, MD5: md5,
+				SourceLMT: sourceObject.lastModifiedTime, DestinationLMT: destinationObject.lastModifiedTime})
+			return nil
+		}
+		change := "was changed at both the source and the destination since the last sync"
+		if !sourceKnown || !destinationKnown {
+			change = "may have been changed at both the source and the destination since the last sync, which can't be told for sure"
+		}
+		return c.resolveConflict(sourceObject, destinationObject, change)
+	}
+}
+
+// resolveConflict settles a conflict by the policy, and reports it, saying what the change was
+func (c *twoWaySyncComparator) resolveConflict(sourceObject, destinationObject storedObject, change string) error {
+	c.incrementConflictCount()
+
+	switch c.policy {
+	case common.ESyncConflictPolicy.SourceWins():
+		c.reportConflict(sourceObject.relativePath, change, "the source's version was kept")
+		return c.transferForward(sourceObject, sourceObject.relativePath)
+	case common.ESyncConflictPolicy.KeepBoth():
+		return c.keepBoth(sourceObject, destinationObject, change)
+	default:
+		if destinationObject.lastModifiedTime.After(sourceObject.lastModifiedTime) {
+			c.reportConflict(sourceObject.relativePath, change, "the destination's version was newer, so it was kept")
+			return c.transferBackward(destinationObject, destinationObject.relativePath)
+		}
+		c.reportConflict(sourceObject.relativePath, change, "the source's version was newer, so it was kept")
+		return c.transferForward(sourceObject, sourceObject.relativePath)
+	}
+}
+
+// keepBoth renames the local version, and copies it to the remote end under its new name, while the remote version takes its old place locally.
+// The local rename happens first, so that the download can't overwrite the local version before it has been moved out of the way.
+func (c *twoWaySyncComparator) keepBoth(sourceObject, destinationObject storedObject, change string) error {
+	localObject, remoteObject := sourceObject, destinationObject
+	if !c.sourceIsLocal {
+		localObject, remoteObject = destinationObject, sourceObject
+	}
+
+	renamedPath := conflictRelativePath(localObject.relativePath, c.conflictTime)
+	err := os.Rename(common.GenerateFullPath(c.localRoot, localObject.relativePath), common.GenerateFullPath(c.localRoot, renamedPath))
+	if err != nil {
+		return fmt.Errorf("cannot rename the local version of conflicting file %s, due to error: %s", localObject.relativePath, err)
+	}
+	c.reportConflict(localObject.relativePath, change, "the local version was renamed to "+renamedPath)
+
+	renamedObject := localObject
+	renamedObject.relativePath = renamedPath
+	renamedObject.name = path.Base(renamedPath)
+
+	if c.sourceIsLocal {
+		if err = c.transferForward(renamedObject, renamedPath); err != nil {
+			return err
+		}
+		return c.transferBackward(remoteObject, remoteObject.relativePath)
+	}
+
+	if err = c.transferBackward(renamedObject, renamedPath); err != nil {
+		return err
+	}
+	return c.transferForward(remoteObject, remoteObject.relativePath)
+}
+
+// transferForward copies the source object to the destination, and notes what both ends will look like afterwards
+func (c *twoWaySyncComparator) transferForward(sourceObject storedObject, destinationRelativePath string) error {
+	c.state.recordPending(destinationRelativePath, twoWaySyncEntry{
+		Size:           sourceObject.size,
+		MD5:            c.md5Of(sourceObject, c.sourceIsLocal),
+		SourceLMT:      sourceObject.lastModifiedTime,
+		DestinationLMT: lmtAfterTransfer(sourceObject, !c.sourceIsLocal),
+	})
+	return c.copyForward(sourceObject, destinationRelativePath)
+}
+
+// transferBackward copies the destination object to the source, and notes what both ends will look like afterwards
+func (c *twoWaySyncComparator) transferBackward(destinationObject storedObject, sourceRelativePath string) error {
+	c.state.recordPending(sourceRelativePath, twoWaySyncEntry{
+		Size:           destinationObject.size,
+		MD5:            c.md5Of(destinationObject, !c.sourceIsLocal),
+		SourceLMT:      lmtAfterTransfer(destinationObject, c.sourceIsLocal),
+		DestinationLMT: destinationObject.lastModifiedTime,
+	})
+	return c.copyBackward(destinationObject, sourceRelativePath)
+}
+
+// downloads keep the last modified time of the blob, but uploads get a new one from the service, which isn't known until the next listing
+func lmtAfterTransfer(object storedObject, toLocal bool) time.Time {
+	if toLocal {
+		return object.lastModifiedTime
+	}
+	return time.Time{}
+}
+
+// md5Of gets the MD5 of the object, which is nil if it isn't known
+func (c *twoWaySyncComparator) md5Of(object storedObject, isLocal bool) []byte {
+	if !isLocal {
+		return object.md5
+	}
+
+	hash, err := c.localMd5(object)
+	if err != nil && ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(fmt.Sprintf("cannot get the MD5 of local file %s: %s", object.relativePath, err), pipeline.LogWarning)
+	}
+	return hash
+}
+
+// sameContent tells whether the two ends have the same content, which can only be known for sure if both MD5s are known
+func (c *twoWaySyncComparator) sameContent(sourceObject, destinationObject storedObject) ([]byte, bool) {
+	if sourceObject.size != destinationObject.size {
+		return nil, false
+	}
+
+	sourceMd5 := c.md5Of(sourceObject, c.sourceIsLocal)
+	destinationMd5 := c.md5Of(destinationObject, !c.sourceIsLocal)
+	if len(sourceMd5) == 0 || len(destinationMd5) == 0 {
+		return nil, false
+	}
+	return sourceMd5, bytes.Equal(sourceMd5, destinationMd5)
+}
+
+func (c *twoWaySyncComparator) reportConflict(relativePath string, change string, resolution string) {
+	message := fmt.Sprintf("Conflict: %s %s; %s.", relativePath, change, resolution)
+	glcm.Info(message)
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(message, pipeline.LogWarning)
+	}
+}
+
+// the name that a conflicting local file is renamed to, e.g. dir/report.conflict-20200102T030405.docx
+func conflictRelativePath(relativePath string, conflictTime time.Time) string {
+	dir, file := path.Split(relativePath)
+	extension := path.Ext(file)
+	return dir + strings.TrimSuffix(file, extension) + ".conflict-" + conflictTime.Format("20060102T150405") + extension
+}
+
+// twoWayTransferScheduler feeds the transfers of each direction into a job of its own, since the transfer engine expects all the parts of a job to go the same way,
+// e.g. for its performance stats, for how it treats case-insensitive destinations, and for the credentials needed to resume.
+// The two processors take turns at numbering the parts, and between them, the last part is marked as final.
+type twoWayTransferScheduler struct {
+	forward  *copyTransferProcessor
+	backward *copyTransferProcessor
+}
+
+func newTwoWayTransferScheduler(forward, backward *copyTransferProcessor) *twoWayTransferScheduler {
+	return &twoWayTransferScheduler{forward: forward, backward: backward}
+}
+
+// dispatchFinalPart finishes both jobs, reporting NothingScheduledError only if neither of them has any transfers
+func (s *twoWayTransferScheduler) dispatchFinalPart() (copyJobInitiated bool, err error) {
+	forwardInitiated, err := s.forward.dispatchFinalPart()
+	if err != nil && err != NothingScheduledError {
+		return false, err
+	}
+
+	backwardInitiated, err := s.backward.dispatchFinalPart()
+	if err != nil && err != NothingScheduledError {
+		return forwardInitiated, err
+	}
+
+	if !forwardInitiated && !backwardInitiated {
+		return false, NothingScheduledError
+	}
+	return true, nil
+}
+
+// combineJobSummaries adds up the summaries of the two jobs of a two-way sync, for reporting them as one
+func combineJobSummaries(a, b common.ListJobSummaryResponse) common.ListJobSummaryResponse {
+	combined := a
+	combined.ActiveConnections += b.ActiveConnections
+	combined.CompleteJobOrdered = a.CompleteJobOrdered && b.CompleteJobOrdered
+	combined.TotalTransfers += b.TotalTransfers
+	combined.FileTransfers += b.FileTransfers
+	combined.FolderPropertyTransfers += b.FolderPropertyTransfers
+	combined.TransfersCompleted += b.TransfersCompleted
+	combined.TransfersFailed += b.TransfersFailed
+	combined.TransfersSkipped += b.TransfersSkipped
+	combined.BytesOverWire += b.BytesOverWire
+	combined.TotalBytesTransferred += b.TotalBytesTransferred
+	combined.TotalBytesEnumerated += b.TotalBytesEnumerated
+	combined.TotalBytesExpected += b.TotalBytesExpected
+	combined.AverageIOPS += b.AverageIOPS
+	if b.AverageE2EMilliseconds > combined.AverageE2EMilliseconds {
+		combined.AverageE2EMilliseconds = b.AverageE2EMilliseconds
+	}
+	if b.ServerBusyPercentage > combined.ServerBusyPercentage {
+		combined.ServerBusyPercentage = b.ServerBusyPercentage
+	}
+	if b.NetworkErrorPercentage > combined.NetworkErrorPercentage {
+		combined.NetworkErrorPercentage = b.NetworkErrorPercentage
+	}
+	combined.FailedTransfers = append(append([]common.TransferDetail{}, a.FailedTransfers...), b.FailedTransfers...)
+	combined.SkippedTransfers = append(append([]common.TransferDetail{}, a.SkippedTransfers...), b.SkippedTransfers...)
+	combined.PerformanceAdvice = append(append([]common.PerformanceAdvice{}, a.PerformanceAdvice...), b.PerformanceAdvice...)
+
+	if combined.TotalBytesExpected == 0 {
+		combined.PercentComplete = 100
+	} else {
+		combined.PercentComplete = 100 * float32(combined.TotalBytesTransferred) / float32(combined.TotalBytesExpected)
+	}
+
+	// the pair is only as done as the job that's furthest behind
+	switch {
+	case !a.JobStatus.IsJobDone():
+		combined.JobStatus = a.JobStatus
+	case !b.JobStatus.IsJobDone():
+		combined.JobStatus = b.JobStatus
+	case a.JobStatus == common.EJobStatus.Cancelled() || b.JobStatus == common.EJobStatus.Cancelled():
+		combined.JobStatus = common.EJobStatus.Cancelled()
+	default:
+		combined.JobStatus = combined.JobStatus.EnhanceJobStatusInfo(combined.TransfersSkipped > 0, combined.TransfersFailed > 0, combined.TransfersCompleted > 0)
+	}
+	return combined
+}
+
+// newSourceCleaner builds the processor that deletes objects from the source, which two-way sync does when they've been deleted from the destination
+func (cca *cookedSyncCmdArgs) newSourceCleaner(fpo common.FolderPropertyOption) (objectProcessor, error) {
+	var deleter *interactiveDeleteProcessor
+	switch cca.fromTo.From() {
+	case common.ELocation.Local():
+		localDeleter := localFileDeleter{rootPath: cca.source.ValueLocal()}
+		deleter = newInteractiveDeleteProcessor(localDeleter.deleteFile, cca.deleteDestination, "local file", cca.source, cca.incrementDeletionCount)
+	default:
+		rawURL, err := cca.source.FullURL()
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+		p, err := initPipeline(ctx, cca.fromTo.From(), cca.credentialInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		deleter = newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.From(), cca.forceIfReadOnly).delete,
+			cca.deleteDestination, cca.fromTo.From().String(), cca.source, cca.incrementDeletionCount)
+	}
+
+	deleter.missingFromToDisplay = "destination"
+	deleter.deleteFromToDisplay = "source"
+	return newFpoAwareProcessor(fpo, deleter.removeImmediately), nil
+}
+
+// initTwoWayEnumerator sets up a two-way sync, which compares both ends as they are listed, in order
+func (cca *cookedSyncCmdArgs) initTwoWayEnumerator(sourceTraverser, destinationTraverser resourceTraverser, filters []objectFilter,
+	fpo common.FolderPropertyOption) (*syncEnumerator, error) {
+	sortedSource, sourceIsSorted := asSortedTraverser(sourceTraverser)
+	sortedDestination, destinationIsSorted := asSortedTraverser(destinationTraverser)
+	if !sourceIsSorted || !destinationIsSorted {
+		return nil, errors.New("two-way sync requires both the source and the destination to be listed in order, which isn't possible here")
+	}
+
+	stateFolder := azcopyJobPlanFolder
+	if stateFolder == "" {
+		stateFolder = os.TempDir()
+	}
+	state, err := loadTwoWaySyncState(twoWaySyncStateFilePath(stateFolder, cca.fromTo, cca.source, cca.destination))
+	if err != nil {
+		return nil, err
+	}
+	cca.syncRecord = state
+
+	sourceCleaner, err := cca.newSourceCleaner(fpo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate source cleaner due to: %s", err.Error())
+	}
+	destinationCleaner, err := cca.newDestinationCleaner(fpo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
+	}
+
+	forward := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	backward := newSyncTransferProcessorForDirection(cca, cca.fromTo.Reverse(), cca.destination, cca.source, NumOfFilesPerDispatchJobPart, fpo)
+	backward.copyJobTemplate.JobID = cca.returnJobID
+	backward.reportFirstPartDispatched = func(jobStarted bool) { cca.setReturnFirstPartOrdered() }
+	transferScheduler := newTwoWayTransferScheduler(forward, backward)
+
+	sourceIsLocal := cca.fromTo.From() == common.ELocation.Local()
+	localRoot := cca.destination.ValueLocal()
+	if sourceIsLocal {
+		localRoot = cca.source.ValueLocal()
+	}
+	localHashCache := newLocalMd5Cache(localRoot, azcopyJobPlanFolder)
+
+	comparator := &twoWaySyncComparator{
+		state:                  state,
+		policy:                 cca.conflictPolicy,
+		sourceIsLocal:          sourceIsLocal,
+		localRoot:              localRoot,
+		localMd5:               localHashCache.getMd5,
+		copyForward:            forward.scheduleCopyTransferAs,
+		copyBackward:           backward.scheduleCopyTransferAs,
+		sourceCleaner:          sourceCleaner,
+		destinationCleaner:     destinationCleaner,
+		propagateDeletions:     cca.deleteDestination != common.EDeleteDestination.False(),
+		conflictTime:           time.Now(),
+		incrementConflictCount: cca.incrementConflictCount,
+	}
+
+	finalize := func() error {
+		// the cache is purely an optimization, so failing to save it is no reason to fail the sync
+		if err := localHashCache.save(); err != nil && ste.JobsAdmin != nil {
+			ste.JobsAdmin.LogToJobLog("failed to save the cache of local file hashes: "+err.Error(), pipeline.LogWarning)
+		}
+
+		jobInitiated, err := transferScheduler.dispatchFinalPart()
+		// sync cleanly exits if nothing is scheduled.
+		if err != nil && err != NothingScheduledError {
+			return err
+		}
+
+		// if there's no job, there's nothing to wait for before the state can be saved
+		if !jobInitiated {
+			if err = state.commit(true, nil); err != nil {
+				return fmt.Errorf("cannot save the two-way sync state, due to error: %s", err)
+			}
+		}
+
+		quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+		cca.setScanningComplete()
+		return nil
+	}
+
+	return newSyncMergeEnumerator(sortedSource, sortedDestination, filters, comparator, finalize), nil
+}
+
+func (cca *cookedSyncCmdArgs) incrementConflictCount() {
+	atomic.AddUint32(&cca.atomicConflictCount, 1)
+}
+
+func (cca *cookedSyncCmdArgs) getConflictCount() uint32 {
+	return atomic.LoadUint32(&cca.atomicConflictCount)
+}
@@ -0,0 +1,478 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// With --watch, a sync from a local directory carries on once the destination matches the source.
+// It watches the source for changes, and feeds them into its current job, a batch at a time, as parts of their own,
+// so that new files are transferred within seconds, without the tree being listed again.
+// Since every part has a plan file of its own, no job is allowed to grow without end: after so many parts, or so many hours,
+// the job is ended and the watch carries on in a new one, and once the old job is done, its plan files are deleted (its log is kept).
+// The last job only ends once the user stops the watch.
+
+// how long the source must be quiet before a batch of changes is scheduled, unless --watch-debounce says otherwise
+const defaultSyncWatchDebounce = 2 * time.Second
+
+// however busy the source is, its changes are scheduled at least this many debounce periods after the first of them
+const syncWatchMaxDelayFactor = 10
+
+// the watch moves on to a new job once its current one has this many parts, or has been going this long
+const syncWatchMaxJobParts = 1000
+const syncWatchMaxJobAge = 24 * time.Hour
+
+// syncWatch is the state of a watched sync, which lasts over all of its passes over the source
+type syncWatch struct {
+	debounce time.Duration
+
+	// every pass over the whole source, and every batch of changes, goes into the current job, so their parts are numbered in one sequence
+	nextPartNum common.PartNumber
+
+	// when the current job was begun, and the limits past which the watch moves on to a new one
+	jobStartTime time.Time
+	maxJobParts  common.PartNumber
+	maxJobAge    time.Duration
+
+	// the jobs that have had a part ordered, oldest first, and haven't been cleaned up yet,
+	// along with the summary of those that have, since they can no longer be asked for one.
+	// The lock is held while the jobs are summarized, so that none is cleaned up meanwhile.
+	jobsLock         sync.Mutex
+	jobIDs           []common.JobID
+	cleanedUpSummary *common.ListJobSummaryResponse
+	jobCount         int
+
+	// whether the first pass is over, and the source is being watched
+	started bool
+
+	// closed when the user asks for the watch to stop
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSyncWatch(debounce time.Duration) *syncWatch {
+	return &syncWatch{
+		debounce:     debounce,
+		jobStartTime: time.Now(),
+		maxJobParts:  syncWatchMaxJobParts,
+		maxJobAge:    syncWatchMaxJobAge,
+		stop:         make(chan struct{}),
+	}
+}
+
+// requestStop asks the watch to stop, once it has scheduled the changes seen so far.
+// It returns false if the watch had already been asked to stop.
+func (w *syncWatch) requestStop() (stopping bool) {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		stopping = true
+	})
+	return
+}
+
+// addJob records that the first part of a job has been ordered
+func (w *syncWatch) addJob(jobID common.JobID) {
+	w.jobsLock.Lock()
+	defer w.jobsLock.Unlock()
+	w.jobIDs = append(w.jobIDs, jobID)
+	w.jobCount++
+}
+
+// jobsOrdered returns how many jobs have had a part ordered, including the ones already cleaned up
+func (w *syncWatch) jobsOrdered() int {
+	w.jobsLock.Lock()
+	defer w.jobsLock.Unlock()
+	return w.jobCount
+}
+
+// orderedJobIDs returns the jobs that have had a part ordered and haven't been cleaned up, oldest first
+func (w *syncWatch) orderedJobIDs() []common.JobID {
+	w.jobsLock.Lock()
+	defer w.jobsLock.Unlock()
+	return append([]common.JobID{}, w.jobIDs...)
+}
+
+// shouldMoveToNewJob tells whether the current job has had enough parts ordered, or has been going for long enough,
+// that the watch should end it and carry on in a new one
+func (w *syncWatch) shouldMoveToNewJob(now time.Time) bool {
+	if w.nextPartNum == 0 {
+		// nothing has been ordered in the current job yet
+		return false
+	}
+	return w.nextPartNum >= w.maxJobParts || now.Sub(w.jobStartTime) >= w.maxJobAge
+}
+
+// summarizeJobs adds up the summaries of all the watch's jobs, including the ones already cleaned up.
+// It returns false if there are none yet.
+func (w *syncWatch) summarizeJobs(getSummary func(jobID common.JobID) common.ListJobSummaryResponse) (summary common.ListJobSummaryResponse, done bool, ok bool) {
+	w.jobsLock.Lock()
+	defer w.jobsLock.Unlock()
+
+	summary, done, ok = summarizeJobs(w.jobIDs, getSummary)
+	if w.cleanedUpSummary != nil {
+		if ok {
+			summary = combineJobSummaries(summary, *w.cleanedUpSummary)
+		} else {
+			summary, done, ok = *w.cleanedUpSummary, true, true
+		}
+	}
+	return
+}
+
+// cleanUpFinishedJobs cleans up the jobs that are done, other than the current one, once their summaries have been kept
+func (w *syncWatch) cleanUpFinishedJobs(currentJobID common.JobID, getSummary func(jobID common.JobID) common.ListJobSummaryResponse, cleanUp func(jobID common.JobID)) {
+	w.jobsLock.Lock()
+	defer w.jobsLock.Unlock()
+
+	remaining := make([]common.JobID, 0, len(w.jobIDs))
+	for _, jobID := range w.jobIDs {
+		if jobID == currentJobID {
+			remaining = append(remaining, jobID)
+			continue
+		}
+		summary := getSummary(jobID)
+		if !summary.JobStatus.IsJobDone() {
+			remaining = append(remaining, jobID)
+			continue
+		}
+
+		// these describe what's going on now, which the job no longer has any part in
+		summary.BytesOverWire = 0
+		summary.ActiveConnections = 0
+		summary.AverageIOPS = 0
+		if w.cleanedUpSummary != nil {
+			summary = combineJobSummaries(*w.cleanedUpSummary, summary)
+		}
+		w.cleanedUpSummary = &summary
+		cleanUp(jobID)
+	}
+	w.jobIDs = remaining
+}
+
+// localChange is a change seen by a localChangeWatcher
+type localChange struct {
+	// the path that changed, relative to the watched directory
+	relativePath string
+
+	// whether the path was created by this change, which means that it wasn't at the destination before
+	appeared bool
+
+	// whether changes were missed, or can't be followed, so that the whole source must be compared against the destination again
+	rescan bool
+
+	// set if the watch can't carry on
+	err error
+}
+
+// localChangeWatcher watches a local directory, and gives the changes to it on its channel
+type localChangeWatcher interface {
+	changes() <-chan localChange
+	close() error
+}
+
+// syncWatchBatch is what changed in the source, between one batch and the next
+type syncWatchBatch struct {
+	// each path that changed, and whether it appeared during the batch
+	paths map[string]bool
+
+	// whether the whole source must be compared against the destination again
+	rescan bool
+}
+
+func (b syncWatchBatch) isEmpty() bool {
+	return len(b.paths) == 0 && !b.rescan
+}
+
+// collectChanges gathers changes until none have been seen for the debounce period,
+// so that e.g. a file that's written in several goes is only transferred once, or until the watch is stopped
+func collectChanges(changes <-chan localChange, debounce time.Duration, stop <-chan struct{}) (batch syncWatchBatch, stopped bool, err error) {
+	batch.paths = make(map[string]bool)
+	var quiet, deadline <-chan time.Time // both nil, so not waited on, until the first change
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return batch, false, errors.New("the watch of the source ended unexpectedly")
+			}
+			if change.err != nil {
+				return batch, false, change.err
+			}
+
+			if change.rescan {
+				batch.rescan = true
+			} else if _, seen := batch.paths[change.relativePath]; !seen {
+				// it's the first change in the batch that tells whether the path was there before
+				batch.paths[change.relativePath] = change.appeared
+			}
+
+			quiet = time.After(debounce)
+			if deadline == nil {
+				deadline = time.After(debounce * syncWatchMaxDelayFactor)
+			}
+		case <-quiet:
+			return batch, false, nil
+		case <-deadline:
+			return batch, false, nil
+		case <-stop:
+			return batch, true, nil
+		}
+	}
+}
+
+// syncWatchScheduler schedules the batches of changes to a watched source
+type syncWatchScheduler struct {
+	rootPath  string
+	recursive bool
+	filters   []objectFilter
+
+	scheduleTransfer   objectProcessor
+	beforeOverwrite    objectProcessor // nil unless something must be done before a file is overwritten
+	destinationCleaner objectProcessor
+
+	incrementFilesScanned func()
+}
+
+// schedule transfers the files that were written, and removes the ones that were deleted from the destination
+// (if the destination cleaner does so), in order of relative path
+func (s *syncWatchScheduler) schedule(batch syncWatchBatch) error {
+	relativePaths := make([]string, 0, len(batch.paths))
+	for relativePath := range batch.paths {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	sort.Strings(relativePaths)
+
+	for _, relativePath := range relativePaths {
+		appeared := batch.paths[relativePath]
+		fileInfo, err := os.Lstat(common.GenerateFullPath(s.rootPath, relativePath))
+
+		switch {
+		case os.IsNotExist(err):
+			if appeared {
+				// it came and went within the batch, so it never reached the destination
+				continue
+			}
+			err = s.process(newStoredObject(noPreProccessor, path.Base(relativePath), relativePath, common.EEntityType.File(),
+				time.Time{}, 0, noContentProps, noBlobProps, noMetdata, ""), s.destinationCleaner)
+		case err != nil:
+			glcm.Info("Skipping the changes to " + relativePath + ", since it can't be read: " + err.Error())
+			continue
+		case fileInfo.IsDir():
+			if s.recursive {
+				err = s.scheduleFolder(relativePath)
+			}
+		case fileInfo.Mode().IsRegular():
+			err = s.scheduleFile(relativePath, fileInfo)
+		default:
+			// symlinks and the like are left alone, as they are by the sync itself
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleFolder schedules the folder, and everything in it, since nothing in it can have been seen before it appeared
+func (s *syncWatchScheduler) scheduleFolder(relativePath string) error {
+	folderPath := common.GenerateFullPath(s.rootPath, relativePath)
+	return filepath.Walk(folderPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// it's already gone, which will be seen in a later batch
+				return nil
+			}
+			return err
+		}
+
+		relativeToFolder, err := filepath.Rel(folderPath, filePath)
+		if err != nil {
+			return err
+		}
+		objectRelativePath := path.Join(relativePath, filepath.ToSlash(relativeToFolder))
+
+		switch {
+		case fileInfo.IsDir():
+			// folders are only transferred if the folder property option says so, which the transfer scheduler knows
+			return s.process(newStoredObject(noPreProccessor, fileInfo.Name(), objectRelativePath, common.EEntityType.Folder(),
+				fileInfo.ModTime(), 0, noContentProps, noBlobProps, noMetdata, ""), s.scheduleTransfer)
+		case fileInfo.Mode().IsRegular():
+			return s.scheduleFile(objectRelativePath, fileInfo)
+		default:
+			return nil
+		}
+	})
+}
+
+func (s *syncWatchScheduler) scheduleFile(relativePath string, fileInfo os.FileInfo) error {
+	if s.incrementFilesScanned != nil {
+		s.incrementFilesScanned()
+	}
+
+	object := newStoredObject(noPreProccessor, fileInfo.Name(), relativePath, common.EEntityType.File(),
+		fileInfo.ModTime(), fileInfo.Size(), noContentProps, noBlobProps, noMetdata, "")
+	return s.process(object, func(object storedObject) error {
+		// the destination isn't listed, so whatever must be done before an overwrite is done in case there's something there
+		return scheduleOverwrite(object, object, s.beforeOverwrite, s.scheduleTransfer)
+	})
+}
+
+func (s *syncWatchScheduler) process(object storedObject, processor objectProcessor) error {
+	_, err := getProcessingError(processIfPassedFilters(s.filters, object, processor))
+	return err
+}
+
+// watchSource carries on a watched sync, once a pass over the whole source has been made:
+// it sends the transfers of the pass straight away, and then feeds the changes to the source into the current job, until the user stops the watch.
+// Only then is the final part of the last job dispatched.
+func (cca *cookedSyncCmdArgs) watchSource(transferScheduler *copyTransferProcessor, filters []objectFilter, fpo common.FolderPropertyOption) error {
+	if err := transferScheduler.dispatchPendingPart(); err != nil {
+		return err
+	}
+	if cca.watch.started {
+		// this was a pass that the watch itself asked for, and the watch carries on once it's done
+		return nil
+	}
+	cca.watch.started = true
+	cca.setScanningComplete()
+
+	destinationCleaner, err := cca.newDestinationCleaner(fpo)
+	if err != nil {
+		return err
+	}
+	scheduler := &syncWatchScheduler{
+		rootPath:           cca.source.ValueLocal(),
+		recursive:          cca.recursive,
+		filters:            filters,
+		scheduleTransfer:   transferScheduler.scheduleCopyTransfer,
+		beforeOverwrite:    cca.beforeOverwrite(),
+		destinationCleaner: destinationCleaner,
+		incrementFilesScanned: func() {
+			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
+		},
+	}
+
+	watcher, err := newLocalChangeWatcher(scheduler.rootPath, cca.recursive)
+	if err != nil {
+		return err
+	}
+	defer watcher.close()
+
+	select {
+	case <-cca.watch.stop:
+		// the user asked for the watch to stop before it began
+	default:
+		glcm.Info("Watching the source for changes. Press Ctrl-C to stop watching, once the changes seen so far have been transferred.")
+		for stopped := false; !stopped; {
+			var batch syncWatchBatch
+			batch, stopped, err = collectChanges(watcher.changes(), cca.watch.debounce, cca.watch.stop)
+			if err != nil {
+				return err
+			}
+
+			if batch.rescan {
+				glcm.Info("Some changes to the source could not be followed, so it is being compared against the destination again.")
+				if err = cca.rescanSource(); err != nil {
+					return err
+				}
+			} else if !batch.isEmpty() {
+				if err = scheduler.schedule(batch); err != nil {
+					return err
+				}
+			}
+
+			if err = transferScheduler.dispatchPendingPart(); err != nil {
+				return err
+			}
+
+			if cca.watch.shouldMoveToNewJob(time.Now()) {
+				if err = cca.moveToNewJob(transferScheduler); err != nil {
+					return err
+				}
+			}
+			cca.watch.cleanUpFinishedJobs(cca.jobID, getJobSummary, ste.JobsAdmin.CleanUpJob)
+		}
+	}
+
+	jobInitiated, err := transferScheduler.dispatchFinalPart()
+	// sync cleanly exits if nothing is scheduled.
+	if err != nil && err != NothingScheduledError {
+		return err
+	}
+
+	quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+	return nil
+}
+
+// orderIntoWatchJobs has the processor order its parts into the watch's current job, whichever that is
+func (cca *cookedSyncCmdArgs) orderIntoWatchJobs(transferScheduler *copyTransferProcessor) {
+	// every pass over the source of a watched sync goes into the current job, so its parts are numbered in one sequence
+	transferScheduler.sharedNextPartNum = &cca.watch.nextPartNum
+	transferScheduler.reportFirstPartDispatched = func(jobStarted bool) {
+		// the template names the job being ordered, which changes each time the watch moves on to a new one
+		cca.watch.addJob(transferScheduler.copyJobTemplate.JobID)
+		cca.setFirstPartOrdered()
+	}
+}
+
+// moveToNewJob ends the current job with its final part, and has the watch carry on in a new one
+func (cca *cookedSyncCmdArgs) moveToNewJob(transferScheduler *copyTransferProcessor) error {
+	if _, err := transferScheduler.dispatchFinalPart(); err != nil {
+		return err
+	}
+	// the enumeration of the watch as a whole isn't over
+	cca.isEnumerationComplete = false
+
+	// passes over the source made from now on are ordered in the new job too, since they take their job ID from cca
+	cca.jobID = common.NewJobID()
+	cca.watch.nextPartNum = 0
+	cca.watch.jobStartTime = time.Now()
+	transferScheduler.copyJobTemplate.JobID = cca.jobID
+	transferScheduler.copyJobTemplate.IsFinalPart = false
+
+	glcm.Info(fmt.Sprintf("The watch carries on in a new job, %s, with its log file at %s%s%s.log",
+		cca.jobID, azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID))
+	return nil
+}
+
+// rescanSource makes another pass over the whole source, comparing it against the destination, for the watch to carry on from
+func (cca *cookedSyncCmdArgs) rescanSource() error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+	enumerator, err := cca.initEnumerator(ctx)
+	if err != nil {
+		return err
+	}
+	return enumerator.enumerate()
+}
@@ -0,0 +1,226 @@
+// +build linux
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// the events that each watched directory reports
+const inotifyWatchMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_DELETE |
+	unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR | unix.IN_DONT_FOLLOW
+
+// inotifyWatcher watches a local directory with inotify, which watches one directory at a time,
+// so each directory under the root gets a watch of its own, including the ones that are created while it's being watched
+type inotifyWatcher struct {
+	fd        int
+	file      *os.File // the inotify descriptor, so that reads wait in the runtime's poller, and are cut short when it's closed
+	rootPath  string
+	recursive bool
+
+	// the relative paths of the watched directories, by their watch descriptors. Only the reading goroutine uses it, once that has started.
+	directories map[int32]string
+
+	output chan localChange
+	done   chan struct{}
+}
+
+func newLocalChangeWatcher(rootPath string, recursive bool) (localChangeWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start watching %s, due to error: %s", rootPath, err)
+	}
+
+	w := &inotifyWatcher{
+		fd:          fd,
+		file:        os.NewFile(uintptr(fd), "inotify"),
+		rootPath:    rootPath,
+		recursive:   recursive,
+		directories: make(map[int32]string),
+		output:      make(chan localChange, 1000),
+		done:        make(chan struct{}),
+	}
+	if err = w.watchTree(""); err != nil {
+		_ = w.file.Close()
+		return nil, err
+	}
+
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) changes() <-chan localChange {
+	return w.output
+}
+
+func (w *inotifyWatcher) close() error {
+	close(w.done)
+	return w.file.Close()
+}
+
+// watchTree watches the directory, and if the watch is recursive, the directories under it
+func (w *inotifyWatcher) watchTree(relativePath string) error {
+	if !w.recursive {
+		return w.watchDirectory(relativePath)
+	}
+
+	return filepath.Walk(common.GenerateFullPath(w.rootPath, relativePath), func(directoryPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// it's already gone, which its parent will report
+				return nil
+			}
+			return err
+		}
+		if !fileInfo.IsDir() {
+			return nil
+		}
+
+		directoryRelativePath, err := filepath.Rel(w.rootPath, directoryPath)
+		if err != nil {
+			return err
+		}
+		if directoryRelativePath == "." {
+			directoryRelativePath = ""
+		}
+		return w.watchDirectory(filepath.ToSlash(directoryRelativePath))
+	})
+}
+
+func (w *inotifyWatcher) watchDirectory(relativePath string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, common.GenerateFullPath(w.rootPath, relativePath), inotifyWatchMask)
+	switch {
+	case err == unix.ENOENT || err == unix.ENOTDIR:
+		// it's already gone, which its parent will report
+		return nil
+	case err == unix.ENOSPC:
+		return errors.New("cannot watch all the directories of the source, since there are more than fs.inotify.max_user_watches allows")
+	case err != nil:
+		return fmt.Errorf("cannot watch %s, due to error: %s", relativePath, err)
+	}
+
+	w.directories[int32(wd)] = relativePath
+	return nil
+}
+
+// forgetTree stops watching the directory, and the directories under it, since they're not where they were
+func (w *inotifyWatcher) forgetTree(relativePath string) {
+	for wd, directory := range w.directories {
+		if directory == relativePath || strings.HasPrefix(directory, relativePath+"/") {
+			_, _ = unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.directories, wd)
+		}
+	}
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.output)
+
+	buffer := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buffer)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.send(localChange{err: fmt.Errorf("cannot read the changes to the source, due to error: %s", err)})
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buffer[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if err = w.handle(event.Wd, event.Mask, name); err != nil {
+				w.send(localChange{err: err})
+				return
+			}
+		}
+	}
+}
+
+// handle turns an inotify event into the change that it means for the sync, if any
+func (w *inotifyWatcher) handle(wd int32, mask uint32, name string) error {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		w.send(localChange{rescan: true})
+		return nil
+	}
+
+	directory, known := w.directories[wd]
+	if !known {
+		return nil
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.directories, wd)
+		return nil
+	}
+	if mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+		if directory == "" {
+			return errors.New("the source directory was deleted or moved")
+		}
+		// the parent reports it
+		return nil
+	}
+
+	relativePath := path.Join(directory, name)
+	appeared := mask&unix.IN_CREATE != 0
+	if mask&unix.IN_ISDIR == 0 {
+		w.send(localChange{relativePath: relativePath, appeared: appeared})
+		return nil
+	}
+
+	switch {
+	case !w.recursive:
+		// sub-directories aren't synced
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+		if err := w.watchTree(relativePath); err != nil {
+			return err
+		}
+		w.send(localChange{relativePath: relativePath, appeared: appeared})
+	case mask&unix.IN_MOVED_FROM != 0:
+		// what was in it isn't known here, so the destination has to be compared against the whole source to know what went with it
+		w.forgetTree(relativePath)
+		w.send(localChange{rescan: true})
+	default:
+		// when a directory is deleted, what was in it has already been reported, one by one
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) send(change localChange) {
+	select {
+	case w.output <- change:
+	case <-w.done:
+	}
+}
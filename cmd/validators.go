@@ -35,6 +35,10 @@ func validateFromTo(src, dst string, userSpecifiedFromTo string) (common.FromTo,
 
 		// If user didn't explicitly specify FromTo, use what was inferred (if possible)
 		if inferredFromTo == common.EFromTo.Unknown() {
+			if inferArgumentLocation(src) == common.ELocation.Unknown() || inferArgumentLocation(dst) == common.ELocation.Unknown() {
+				return common.EFromTo.Unknown(), fmt.Errorf("the source/destination combination could not be inferred, since a URL that's addressed by IP may be for Azure Stack, " +
+					"the storage emulator, or a web server. Please give --from-to, e.g. BlobBlob, or HttpBlob for a web server")
+			}
 			return common.EFromTo.Unknown(), fmt.Errorf("the inferred source/destination combination could not be identified, or is currently not supported")
 		}
 		return inferredFromTo, nil
@@ -52,7 +56,7 @@ func validateFromTo(src, dst string, userSpecifiedFromTo string) (common.FromTo,
 }
 
 const fromToHelpText = "Valid values are two-word phases of the form BlobLocal, LocalBlob etc.  Use the word 'Blob' for Blob Storage, " +
-	"'Local' for the local file system, 'File' for Azure Files, and 'BlobFS' for ADLS Gen2. " +
+	"'Local' for the local file system, 'File' for Azure Files, 'BlobFS' for ADLS Gen2, 'S3' for Amazon S3, 'GCP' for Google Cloud Storage, and 'Http' for a plain HTTP(S) web server. " +
 	"If you need a combination that is not supported yet, please log an issue on the AzCopy GitHub issues list."
 
 func inferFromTo(src, dst string) common.FromTo {
@@ -80,6 +84,8 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.BlobLocal()
 	case srcLocation == common.ELocation.Local() && dstLocation == common.ELocation.File():
 		return common.EFromTo.LocalFile()
+	case srcLocation == common.ELocation.Local() && dstLocation == common.ELocation.Local():
+		return common.EFromTo.LocalLocal()
 	case srcLocation == common.ELocation.File() && dstLocation == common.ELocation.Local():
 		return common.EFromTo.FileLocal()
 	case srcLocation == common.ELocation.Pipe() && dstLocation == common.ELocation.Blob():
@@ -90,6 +96,12 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.LocalBlobFS()
 	case srcLocation == common.ELocation.BlobFS() && dstLocation == common.ELocation.Local():
 		return common.EFromTo.BlobFSLocal()
+	case srcLocation == common.ELocation.BlobFS() && dstLocation == common.ELocation.Blob():
+		return common.EFromTo.BlobFSBlob()
+	case srcLocation == common.ELocation.BlobFS() && dstLocation == common.ELocation.BlobFS():
+		return common.EFromTo.BlobFSBlobFS()
+	case srcLocation == common.ELocation.Blob() && dstLocation == common.ELocation.BlobFS():
+		return common.EFromTo.BlobBlobFS()
 	case srcLocation == common.ELocation.Blob() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.BlobBlob()
 	case srcLocation == common.ELocation.File() && dstLocation == common.ELocation.Blob():
@@ -100,6 +112,16 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.FileFile()
 	case srcLocation == common.ELocation.S3() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.S3Blob()
+	case srcLocation == common.ELocation.S3() && dstLocation == common.ELocation.Local():
+		return common.EFromTo.S3Local()
+	case srcLocation == common.ELocation.Local() && dstLocation == common.ELocation.S3():
+		return common.EFromTo.LocalS3()
+	case srcLocation == common.ELocation.Blob() && dstLocation == common.ELocation.S3():
+		return common.EFromTo.BlobS3()
+	case srcLocation == common.ELocation.GCP() && dstLocation == common.ELocation.Blob():
+		return common.EFromTo.GCPBlob()
+	case srcLocation == common.ELocation.Http() && dstLocation == common.ELocation.Blob():
+		return common.EFromTo.HttpBlob()
 	case srcLocation == common.ELocation.Benchmark() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.BenchmarkBlob()
 	case srcLocation == common.ELocation.Benchmark() && dstLocation == common.ELocation.File():
@@ -129,6 +151,14 @@ func inferArgumentLocation(arg string) common.Location {
 		u, err := url.Parse(arg)
 		// NOTE: sometimes, a local path can also be parsed as a url. To avoid thinking it's a URL, check Scheme, Host, and Path
 		if err == nil && u.Scheme != "" && u.Host != "" {
+			// Check for S3 first, since a custom S3-compatible endpoint may well be addressed by IP
+			if common.IsS3URL(*u) {
+				return common.ELocation.S3()
+			}
+			if common.IsGCPURL(*u) {
+				return common.ELocation.GCP()
+			}
+
 			// Is the argument a URL to blob storage?
 			switch host := strings.ToLower(u.Host); true {
 			// Azure Stack does not have the core.windows.net
@@ -140,13 +170,15 @@ func inferArgumentLocation(arg string) common.Location {
 				return common.ELocation.BlobFS()
 			case strings.Contains(host, benchmarkSourceHost):
 				return common.ELocation.Benchmark()
-				// enable targeting an emulator/stack
+				// enable targeting an emulator/stack, which can't be told apart from a web server addressed by IP,
+				// so all of them need --from-to
 			case IPv4Regex.MatchString(host):
 				return common.ELocation.Unknown()
 			}
 
-			if common.IsS3URL(*u) {
-				return common.ELocation.S3()
+			// Anything else is taken to be a plain web server
+			if strings.EqualFold(u.Scheme, "http") || strings.EqualFold(u.Scheme, "https") {
+				return common.ELocation.Http()
 			}
 		}
 	}
@@ -55,6 +55,10 @@ type storedObject struct {
 	md5              []byte
 	blobType         azblob.BlobType // will be "None" when unknown or not applicable
 
+	// the MD5 given by the ETag of an S3 object, which (unlike md5) is only used for comparisons, and never copied to a destination,
+	// since a listing can't tell whether the object is encrypted in a way that makes its ETag something else
+	etagMd5 []byte
+
 	// all of these will be empty when unknown or not applicable.
 	contentDisposition string
 	cacheControl       string
@@ -81,6 +85,8 @@ type storedObject struct {
 	// metadata, included in S2S transfers
 	Metadata      common.Metadata
 	blobVersionID string
+	// blob index tags, only included by the blob traversers when asked for them
+	blobTags common.BlobTags
 }
 
 const (
@@ -166,6 +172,7 @@ func (s *storedObject) ToNewCopyTransfer(
 		Metadata:           s.Metadata,
 		BlobType:           s.blobType,
 		BlobVersionID:      s.blobVersionID,
+		BlobTags:           s.blobTags,
 		// set this below, conditionally: BlobTier
 	}
 
@@ -189,7 +196,9 @@ func stripCompressionExtension(dest string, contentEncoding string) string {
 	ext := strings.ToLower(filepath.Ext(dest))
 	stripGzip := ct == common.ECompressionType.GZip() && (ext == ".gz" || ext == ".gzip")
 	stripZlib := ct == common.ECompressionType.ZLib() && ext == ".zz" // "standard" extension for zlib-wrapped files, according to pigz doc and Stack Overflow
-	if stripGzip || stripZlib {
+	stripZstd := ct == common.ECompressionType.Zstd() && ext == ".zst"
+	stripBrotli := ct == common.ECompressionType.Brotli() && ext == ".br"
+	if stripGzip || stripZlib || stripZstd || stripBrotli {
 		return strings.TrimSuffix(dest, filepath.Ext(dest))
 	}
 	return dest
@@ -257,6 +266,25 @@ type resourceTraverser interface {
 	// Thus, we only check the directory syntax on blob destinations. On sources, we check both syntax and remote, if syntax isn't a directory.
 }
 
+// sortedResourceTraverser is implemented by the traversers that can give their objects in ascending (byte-wise) order of relative path.
+// Sync uses them to compare the source and destination as two streams, rather than indexing one of them in memory.
+type sortedResourceTraverser interface {
+	resourceTraverser
+	// canTraverseSorted says whether traverseSorted can be used, given how the traverser was configured
+	canTraverseSorted() bool
+	// traverseSorted is like traverse, but promises the order. It may be slower, since it can't list in parallel.
+	traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error
+}
+
+// asSortedTraverser returns the traverser as a sortedResourceTraverser, if it can give its objects in order
+func asSortedTraverser(traverser resourceTraverser) (sortedResourceTraverser, bool) {
+	sorted, ok := traverser.(sortedResourceTraverser)
+	if !ok || !sorted.canTraverseSorted() {
+		return nil, false
+	}
+	return sorted, true
+}
+
 type accountTraverser interface {
 	resourceTraverser
 	listContainers() ([]string, error)
@@ -294,7 +322,7 @@ type enumerationCounterFunc func(entityType common.EntityType)
 // followSymlinks is only required for local resources (defaults to false)
 // errorOnDirWOutRecursive is used by copy.
 func initResourceTraverser(resource common.ResourceString, location common.Location, ctx *context.Context, credential *common.CredentialInfo,
-	followSymlinks *bool, listOfFilesChannel chan string, recursive, getProperties, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string) (resourceTraverser, error) {
+	followSymlinks *bool, listOfFilesChannel chan string, recursive, getProperties, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string, getBlobTags bool, cpkOptions common.CpkOptions) (resourceTraverser, error) {
 	var output resourceTraverser
 	var p *pipeline.Pipeline
 
@@ -331,7 +359,7 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}
 		}
 
-		output = newListTraverser(resource, location, credential, ctx, recursive, toFollow, getProperties, listOfFilesChannel, includeDirectoryStubs, incrementEnumerationCounter)
+		output = newListTraverser(resource, location, credential, ctx, recursive, toFollow, getProperties, listOfFilesChannel, includeDirectoryStubs, getBlobTags, cpkOptions, incrementEnumerationCounter)
 		return output, nil
 	}
 
@@ -358,7 +386,7 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}()
 
 			baseResource := resource.CloneWithValue(cleanLocalPath(basePath))
-			output = newListTraverser(baseResource, location, nil, nil, recursive, toFollow, getProperties, globChan, includeDirectoryStubs, incrementEnumerationCounter)
+			output = newListTraverser(baseResource, location, nil, nil, recursive, toFollow, getProperties, globChan, includeDirectoryStubs, getBlobTags, cpkOptions, incrementEnumerationCounter)
 		} else {
 			output = newLocalTraverser(resource.ValueLocal(), recursive, toFollow, incrementEnumerationCounter)
 		}
@@ -389,11 +417,11 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 				return nil, errors.New(accountTraversalInherentlyRecursiveError)
 			}
 
-			output = newBlobAccountTraverser(resourceURL, *p, *ctx, includeDirectoryStubs, incrementEnumerationCounter)
+			output = newBlobAccountTraverser(resourceURL, *p, *ctx, includeDirectoryStubs, getBlobTags, cpkOptions, incrementEnumerationCounter)
 		} else if listOfVersionIds != nil {
-			output = newBlobVersionsTraverser(resourceURL, *p, *ctx, recursive, includeDirectoryStubs, incrementEnumerationCounter, listOfVersionIds)
+			output = newBlobVersionsTraverser(resourceURL, *p, *ctx, recursive, includeDirectoryStubs, getBlobTags, cpkOptions, incrementEnumerationCounter, listOfVersionIds)
 		} else {
-			output = newBlobTraverser(resourceURL, *p, *ctx, recursive, includeDirectoryStubs, incrementEnumerationCounter)
+			output = newBlobTraverser(resourceURL, *p, *ctx, recursive, includeDirectoryStubs, getBlobTags, cpkOptions, incrementEnumerationCounter)
 		}
 	case common.ELocation.File():
 		resourceURL, err := resource.FullURL()
@@ -481,6 +509,53 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 				return nil, err
 			}
 		}
+	case common.ELocation.GCP():
+		resourceURL, err := resource.FullURL()
+		if err != nil {
+			return nil, err
+		}
+
+		recommendHttpsIfNecessary(*resourceURL)
+
+		gcpURLParts, err := common.NewGCPURLParts(*resourceURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if ctx == nil {
+			return nil, errors.New("a valid context must be supplied to create a GCP traverser")
+		}
+
+		if gcpURLParts.BucketName == "" || strings.Contains(gcpURLParts.BucketName, "*") {
+			if !recursive {
+				return nil, errors.New(accountTraversalInherentlyRecursiveError)
+			}
+
+			output, err = newGCPServiceTraverser(resourceURL, *ctx, getProperties, incrementEnumerationCounter)
+
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			output, err = newGCPTraverser(resourceURL, *ctx, recursive, getProperties, incrementEnumerationCounter)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	case common.ELocation.Http():
+		resourceURL, err := resource.FullURL()
+		if err != nil {
+			return nil, err
+		}
+
+		recommendHttpsIfNecessary(*resourceURL)
+
+		if ctx == nil || p == nil {
+			return nil, errors.New("a valid context must be supplied to create an HTTP traverser")
+		}
+
+		output = newHttpTraverser(resourceURL, *p, *ctx, incrementEnumerationCounter)
 	default:
 		return nil, errors.New("could not choose a traverser from currently available traversers")
 	}
@@ -557,6 +632,22 @@ type syncEnumerator struct {
 
 	// a finalizer that is always called if the enumeration finishes properly
 	finalize func() error
+
+	// when both sides can be listed in order, they are walked side by side instead, and compared by the mergeComparator
+	sortedSource      sortedResourceTraverser
+	sortedDestination sortedResourceTraverser
+	mergeComparator   syncMergeProcessor
+}
+
+func newSyncMergeEnumerator(source, destination sortedResourceTraverser, filters []objectFilter,
+	comparator syncMergeProcessor, finalize func() error) *syncEnumerator {
+	return &syncEnumerator{
+		sortedSource:      source,
+		sortedDestination: destination,
+		filters:           filters,
+		mergeComparator:   comparator,
+		finalize:          finalize,
+	}
 }
 
 func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, indexer *objectIndexer,
@@ -572,6 +663,13 @@ func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, i
 }
 
 func (e *syncEnumerator) enumerate() (err error) {
+	if e.mergeComparator != nil {
+		return e.enumerateMerged()
+	}
+
+	// get rid of any spill files, however the enumeration ends
+	defer e.objectIndexer.close()
+
 	// enumerate the primary resource and build lookup map
 	err = e.primaryTraverser.traverse(noPreProccessor, e.objectIndexer.store, e.filters)
 	if err != nil {
@@ -596,6 +694,97 @@ func (e *syncEnumerator) enumerate() (err error) {
 	return
 }
 
+// enumerateMerged walks the source and the destination at the same time, like a merge join.
+// Since both come in ascending order of relative path, whichever side is behind holds the object that the other side lacks.
+func (e *syncEnumerator) enumerateMerged() (err error) {
+	// stop the traversals if we return early
+	done := make(chan struct{})
+	defer close(done)
+
+	source := newSortedObjectStream("source", e.sortedSource, e.filters, done)
+	destination := newSortedObjectStream("destination", e.sortedDestination, e.filters, done)
+
+	if err = source.advance(); err != nil {
+		return
+	}
+	if err = destination.advance(); err != nil {
+		return
+	}
+
+	for source.ok || destination.ok {
+		switch {
+		case !destination.ok || (source.ok && source.current.relativePath < destination.current.relativePath):
+			if err = e.mergeComparator.processSourceOnly(source.current); err == nil {
+				err = source.advance()
+			}
+		case !source.ok || destination.current.relativePath < source.current.relativePath:
+			if err = e.mergeComparator.processDestinationOnly(destination.current); err == nil {
+				err = destination.advance()
+			}
+		default:
+			if err = e.mergeComparator.processPair(source.current, destination.current); err == nil {
+				if err = source.advance(); err == nil {
+					err = destination.advance()
+				}
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	// execute the finalize func which may perform useful clean up steps
+	return e.finalize()
+}
+
+// sortedObjectStream runs a sorted traversal in the background, and hands over its objects one at a time
+type sortedObjectStream struct {
+	side    string
+	objects chan storedObject
+	errs    chan error
+
+	current storedObject
+	ok      bool // false once the traversal is over
+}
+
+var errSortedStreamStopped = errors.New("sorted traversal stopped")
+
+func newSortedObjectStream(side string, traverser sortedResourceTraverser, filters []objectFilter, done <-chan struct{}) *sortedObjectStream {
+	s := &sortedObjectStream{side: side, objects: make(chan storedObject, 1000), errs: make(chan error, 1)}
+
+	go func() {
+		// the error is sent before the channel is closed, so that the end of the objects can't be mistaken for success
+		defer close(s.objects)
+		s.errs <- traverser.traverseSorted(noPreProccessor, func(object storedObject) error {
+			select {
+			case s.objects <- object:
+				return nil
+			case <-done:
+				return errSortedStreamStopped
+			}
+		}, filters)
+	}()
+
+	return s
+}
+
+// advance moves on to the next object, failing if the traversal failed, or if it broke its promise of ascending order
+func (s *sortedObjectStream) advance() error {
+	previous, hadPrevious := s.current, s.ok
+
+	s.current, s.ok = <-s.objects
+	if !s.ok {
+		return <-s.errs
+	}
+
+	// going wrong here would make us delete or overwrite the wrong things, so refuse to continue
+	if hadPrevious && s.current.relativePath < previous.relativePath {
+		return fmt.Errorf("the %s was not listed in order (%s came after %s), so it cannot be compared as a stream", s.side, s.current.relativePath, previous.relativePath)
+	}
+	return nil
+}
+
 type copyEnumerator struct {
 	traverser resourceTraverser
 
@@ -58,6 +58,29 @@ func (f *excludeBlobTypeFilter) doesPass(object storedObject) bool {
 	return false
 }
 
+// includeBlobTagsFilter only passes the blobs that have all of the given blob index tags
+type includeBlobTagsFilter struct {
+	tags common.BlobTags
+}
+
+func (f *includeBlobTagsFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *includeBlobTagsFilter) appliesOnlyToFiles() bool {
+	return true // there aren't any (real) folders in Blob Storage
+}
+
+func (f *includeBlobTagsFilter) doesPass(object storedObject) bool {
+	for k, v := range f.tags {
+		if value, ok := object.blobTags[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
 type excludeFilter struct {
 	pattern     string
 	targetsPath bool
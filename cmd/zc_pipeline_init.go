@@ -15,14 +15,15 @@ func initPipeline(ctx context.Context, location common.Location, credential comm
 		common.ELocation.Benchmark():
 		// Gracefully return
 		return nil, nil
-	case common.ELocation.Blob():
+	case common.ELocation.Blob(),
+		common.ELocation.Http(): // plain web servers are read anonymously, and the blob pipeline does that just fine
 		p, err = createBlobPipeline(ctx, credential)
 	case common.ELocation.File():
 		p, err = createFilePipeline(ctx, credential)
 	case common.ELocation.BlobFS():
 		p, err = createBlobFSPipeline(ctx, credential)
-	case common.ELocation.S3():
-		// Gracefully return because pipelines aren't used for S3
+	case common.ELocation.S3(), common.ELocation.GCP():
+		// Gracefully return because pipelines aren't used for S3 or GCP
 		return nil, nil
 	default:
 		err = fmt.Errorf("can't produce new pipeline for location %s", location)
@@ -43,6 +43,13 @@ type copyTransferProcessor struct {
 
 	preserveAccessTier     bool
 	folderPropertiesOption common.FolderPropertyOption
+
+	// when several processors feed parts into the same job (e.g. the two directions of a two-way sync),
+	// they must share one sequence of part numbers. Nil means this processor numbers its own parts.
+	sharedNextPartNum *common.PartNumber
+
+	// in a dry run, the transfers are reported here instead of being sent to the transfer engine
+	dryrunReporter *dryrunReporter
 }
 
 func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, numOfTransfersPerPart int,
@@ -61,11 +68,16 @@ func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, n
 }
 
 func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject storedObject) (err error) {
+	return s.scheduleCopyTransferAs(storedObject, storedObject.relativePath)
+}
+
+// scheduleCopyTransferAs transfers the object to the given path (relative to the destination root), rather than to its own relative path
+func (s *copyTransferProcessor) scheduleCopyTransferAs(storedObject storedObject, destinationRelativePath string) (err error) {
 
 	// Escape paths on destinations where the characters are invalid
 	// And re-encode them where the characters are valid.
 	srcRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, true)
-	dstRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, false)
+	dstRelativePath := pathEncodeRules(destinationRelativePath, s.copyJobTemplate.FromTo, false)
 
 	copyTransfer, shouldSendToSte := storedObject.ToNewCopyTransfer(
 		false, // sync has no --decompress option
@@ -79,6 +91,13 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject storedObject)
 		return nil // skip this one
 	}
 
+	if s.dryrunReporter != nil {
+		s.dryrunReporter.reportTransfer(storedObject,
+			common.GenerateFullPath(s.copyJobTemplate.SourceRoot.Value, copyTransfer.Source),
+			common.GenerateFullPath(s.copyJobTemplate.DestinationRoot.Value, copyTransfer.Destination))
+		return nil
+	}
+
 	if len(s.copyJobTemplate.Transfers) == s.numOfTransfersPerPart {
 		resp := s.sendPartToSte()
 
@@ -99,10 +118,32 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject storedObject)
 	return nil
 }
 
+// dispatchPendingPart sends the transfers scheduled so far to the transfer engine, without ending the job,
+// for when more transfers may come later, but those scheduled so far shouldn't wait for them
+func (s *copyTransferProcessor) dispatchPendingPart() error {
+	if s.dryrunReporter != nil || len(s.copyJobTemplate.Transfers) == 0 {
+		return nil
+	}
+
+	resp := s.sendPartToSte()
+	if resp.ErrorMsg != "" {
+		return errors.New(string(resp.ErrorMsg))
+	}
+
+	// reset the transfers buffer
+	s.copyJobTemplate.Transfers = []common.CopyTransfer{}
+	s.copyJobTemplate.PartNum++
+	return nil
+}
+
 var NothingScheduledError = errors.New("no transfers were scheduled because no files matched the specified criteria")
 var FinalPartCreatedMessage = "Final job part has been created"
 
 func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err error) {
+	if s.dryrunReporter != nil {
+		return false, nil // there's no job to start
+	}
+
 	var resp common.CopyJobPartOrderResponse
 	s.copyJobTemplate.IsFinalPart = true
 	resp = s.sendPartToSte()
@@ -129,6 +170,10 @@ func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err
 // only test the response on the final dispatch to help diagnose root cause of test failures from 0 transfers
 func (s *copyTransferProcessor) sendPartToSte() common.CopyJobPartOrderResponse {
 	var resp common.CopyJobPartOrderResponse
+	if s.sharedNextPartNum != nil {
+		s.copyJobTemplate.PartNum = *s.sharedNextPartNum
+		*s.sharedNextPartNum++
+	}
 	Rpc(common.ERpcCmd.CopyJobPartOrder(), s.copyJobTemplate, &resp)
 
 	// if the current part order sent to ste is 0, then alert the progress reporting routine
@@ -44,6 +44,12 @@ type blobTraverser struct {
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
 
+	// whether to get the blob index tags of each blob, which the listing otherwise leaves out
+	getBlobTags bool
+
+	// the customer-provided key, if the blobs are encrypted with one, since their properties can't be read without it
+	cpkOptions common.CpkOptions
+
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
 }
@@ -77,7 +83,7 @@ func (t *blobTraverser) getPropertiesIfSingleBlob() (props *azblob.BlobGetProper
 
 	// perform the check
 	blobURL := azblob.NewBlobURL(blobUrlParts.URL(), t.p)
-	props, err = blobURL.GetProperties(t.ctx, azblob.BlobAccessConditions{})
+	props, err = blobURL.GetProperties(t.ctx, azblob.BlobAccessConditions{}, t.cpkOptions.ToClientProvidedKeyOptions())
 
 	// if there was no problem getting the properties, it means that we are looking at a single blob
 	if err == nil {
@@ -92,6 +98,20 @@ func (t *blobTraverser) getPropertiesIfSingleBlob() (props *azblob.BlobGetProper
 }
 
 func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	return t.enumerate(preprocessor, processor, filters, false)
+}
+
+func (t *blobTraverser) canTraverseSorted() bool {
+	return true
+}
+
+// traverseSorted lists the blobs one page at a time, rather than crawling the virtual directories in parallel,
+// because the service returns each listing in ascending order of blob name
+func (t *blobTraverser) traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	return t.enumerate(preprocessor, processor, filters, true)
+}
+
+func (t *blobTraverser) enumerate(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter, sorted bool) (err error) {
 	blobUrlParts := azblob.NewBlobURLParts(*t.rawURL)
 	util := copyHandlerUtil{}
 
@@ -100,10 +120,13 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 
 	if stgErr, ok := propErr.(azblob.StorageError); ok {
 		// Don't error out unless it's a CPK error just yet
-		// If it's a CPK error, we know it's a single blob and that we can't get the properties on it anyway.
+		// If it's a CPK error, we know it's a single blob and that we can't get the properties on it without the right key.
 		if stgErr.ServiceCode() == common.CPK_ERROR_SERVICE_CODE {
-			return errors.New("this blob uses customer provided encryption keys (CPK). At the moment, AzCopy does not support CPK-encrypted blobs. " +
-				"If you wish to make use of this blob, we recommend using one of the Azure Storage SDKs")
+			if t.cpkOptions.Key == nil {
+				return fmt.Errorf("this blob uses a customer-provided encryption key (CPK). To use it, give --cpk-by-value and set %s to the key",
+					common.EEnvironmentVariable.CpkEncryptionKey().Name)
+			}
+			return errors.New("this blob uses a different customer-provided encryption key (CPK) from the one given")
 		}
 	}
 
@@ -131,6 +154,15 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 			blobUrlParts.ContainerName,
 		)
 
+		if t.getBlobTags && blobProperties.TagCount() > 0 {
+			blobURL := azblob.NewBlobURL(blobUrlParts.URL(), t.p)
+			blobTags, err := blobURL.GetTags(t.ctx, nil, nil, nil, nil, nil)
+			if err != nil {
+				return fmt.Errorf("cannot get the tags of the blob due to reason %s", err)
+			}
+			storedObject.blobTags = common.FromAzBlobTagsToCommonBlobTags(blobTags)
+		}
+
 		if t.incrementEnumerationCounter != nil {
 			t.incrementEnumerationCounter(common.EEntityType.File())
 		}
@@ -162,13 +194,40 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	// as a performance optimization, get an extra prefix to do pre-filtering. It's typically the start portion of a blob name.
 	extraSearchPrefix := filterSet(filters).GetEnumerationPreFilter(t.recursive)
 
+	// skip the blobs that represent hdi folders, unless we've been asked for directory stubs
+	shouldSkip := func(blobInfo azblob.BlobItemInternal) bool {
+		return util.doesBlobRepresentAFolder(blobInfo.Metadata) && !(t.includeDirectoryStubs && t.recursive)
+	}
+	createStoredObject := func(blobInfo azblob.BlobItemInternal) storedObject {
+		relativePath := strings.TrimPrefix(blobInfo.Name, searchPrefix)
+		adapter := blobPropertiesAdapter{blobInfo.Properties}
+		storedObject := newStoredObject(
+			preprocessor,
+			getObjectNameOnly(blobInfo.Name),
+			relativePath,
+			common.EEntityType.File(),
+			blobInfo.Properties.LastModified,
+			*blobInfo.Properties.ContentLength,
+			adapter,
+			adapter, // adapter satisfies both interfaces
+			common.FromAzBlobMetadataToCommonMetadata(blobInfo.Metadata),
+			blobUrlParts.ContainerName,
+		)
+		storedObject.blobTags = common.FromAzBlobTagsToCommonBlobTags(blobInfo.BlobTags)
+		return storedObject
+	}
+
+	if sorted {
+		return t.listSorted(containerURL, searchPrefix+extraSearchPrefix, shouldSkip, createStoredObject, processor, filters)
+	}
+
 	// Define how to enumerate its contents
 	// This func must be thread safe/goroutine safe
 	enumerateOneDir := func(dir parallel.Directory, enqueueDir func(parallel.Directory), enqueueOutput func(parallel.DirectoryEntry, error)) error {
 		currentDirPath := dir.(string)
 		for marker := (azblob.Marker{}); marker.NotDone(); {
 			lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, marker, "/", azblob.ListBlobsSegmentOptions{Prefix: currentDirPath,
-				Details: azblob.BlobListingDetails{Metadata: true}})
+				Details: azblob.BlobListingDetails{Metadata: true, Tags: t.getBlobTags}})
 			if err != nil {
 				return fmt.Errorf("cannot list files due to reason %s", err)
 			}
@@ -183,25 +242,11 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 			// process the blobs returned in this result segment
 			for _, blobInfo := range lResp.Segment.BlobItems {
 				// if the blob represents a hdi folder, then skip it
-				if util.doesBlobRepresentAFolder(blobInfo.Metadata) && !(t.includeDirectoryStubs && t.recursive) {
+				if shouldSkip(blobInfo) {
 					continue
 				}
 
-				relativePath := strings.TrimPrefix(blobInfo.Name, searchPrefix)
-				adapter := blobPropertiesAdapter{blobInfo.Properties}
-				storedObject := newStoredObject(
-					preprocessor,
-					getObjectNameOnly(blobInfo.Name),
-					relativePath,
-					common.EEntityType.File(),
-					blobInfo.Properties.LastModified,
-					*blobInfo.Properties.ContentLength,
-					adapter,
-					adapter, // adapter satisfies both interfaces
-					common.FromAzBlobMetadataToCommonMetadata(blobInfo.Metadata),
-					blobUrlParts.ContainerName,
-				)
-				enqueueOutput(storedObject, nil)
+				enqueueOutput(createStoredObject(blobInfo), nil)
 			}
 
 			marker = lResp.NextMarker
@@ -238,9 +283,51 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	return
 }
 
-func newBlobTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs bool,
-	incrementEnumerationCounter enumerationCounterFunc) (t *blobTraverser) {
+// listSorted processes the blobs under the prefix in the order that the service lists them in.
+// A flat listing covers the whole tree when recursive, and otherwise a hierarchical one gives just the blobs directly under the prefix.
+func (t *blobTraverser) listSorted(containerURL azblob.ContainerURL, prefix string, shouldSkip func(azblob.BlobItemInternal) bool,
+	createStoredObject func(azblob.BlobItemInternal) storedObject, processor objectProcessor, filters []objectFilter) error {
+	options := azblob.ListBlobsSegmentOptions{Prefix: prefix, Details: azblob.BlobListingDetails{Metadata: true, Tags: t.getBlobTags}}
+
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		var blobItems []azblob.BlobItemInternal
+		if t.recursive {
+			lResp, err := containerURL.ListBlobsFlatSegment(t.ctx, marker, options)
+			if err != nil {
+				return fmt.Errorf("cannot list files due to reason %s", err)
+			}
+			blobItems, marker = lResp.Segment.BlobItems, lResp.NextMarker
+		} else {
+			lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, marker, "/", options)
+			if err != nil {
+				return fmt.Errorf("cannot list files due to reason %s", err)
+			}
+			blobItems, marker = lResp.Segment.BlobItems, lResp.NextMarker
+		}
+
+		for _, blobInfo := range blobItems {
+			if shouldSkip(blobInfo) {
+				continue
+			}
+
+			if t.incrementEnumerationCounter != nil {
+				t.incrementEnumerationCounter(common.EEntityType.File())
+			}
+
+			processErr := processIfPassedFilters(filters, createStoredObject(blobInfo), processor)
+			_, processErr = getProcessingError(processErr)
+			if processErr != nil {
+				return processErr
+			}
+		}
+	}
+
+	return nil
+}
+
+func newBlobTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs, getBlobTags bool,
+	cpkOptions common.CpkOptions, incrementEnumerationCounter enumerationCounterFunc) (t *blobTraverser) {
 	t = &blobTraverser{rawURL: rawURL, p: p, ctx: ctx, recursive: recursive, includeDirectoryStubs: includeDirectoryStubs,
-		incrementEnumerationCounter: incrementEnumerationCounter}
+		getBlobTags: getBlobTags, cpkOptions: cpkOptions, incrementEnumerationCounter: incrementEnumerationCounter}
 	return
 }
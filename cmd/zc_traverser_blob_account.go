@@ -26,6 +26,7 @@ import (
 	"net/url"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 )
 
@@ -37,6 +38,8 @@ type blobAccountTraverser struct {
 	containerPattern      string
 	cachedContainers      []string
 	includeDirectoryStubs bool
+	getBlobTags           bool
+	cpkOptions            common.CpkOptions
 
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
@@ -95,7 +98,7 @@ func (t *blobAccountTraverser) traverse(preprocessor objectMorpher, processor ob
 
 	for _, v := range cList {
 		containerURL := t.accountURL.NewContainerURL(v).URL()
-		containerTraverser := newBlobTraverser(&containerURL, t.p, t.ctx, true, t.includeDirectoryStubs, t.incrementEnumerationCounter)
+		containerTraverser := newBlobTraverser(&containerURL, t.p, t.ctx, true, t.includeDirectoryStubs, t.getBlobTags, t.cpkOptions, t.incrementEnumerationCounter)
 
 		preprocessorForThisChild := preprocessor.FollowedBy(newContainerDecorator(v))
 
@@ -110,7 +113,7 @@ func (t *blobAccountTraverser) traverse(preprocessor objectMorpher, processor ob
 	return nil
 }
 
-func newBlobAccountTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc) (t *blobAccountTraverser) {
+func newBlobAccountTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, includeDirectoryStubs, getBlobTags bool, cpkOptions common.CpkOptions, incrementEnumerationCounter enumerationCounterFunc) (t *blobAccountTraverser) {
 	bURLParts := azblob.NewBlobURLParts(*rawURL)
 	cPattern := bURLParts.ContainerName
 
@@ -120,7 +123,7 @@ func newBlobAccountTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.C
 	}
 
 	t = &blobAccountTraverser{p: p, ctx: ctx, incrementEnumerationCounter: incrementEnumerationCounter,
-		accountURL: azblob.NewServiceURL(bURLParts.URL(), p), containerPattern: cPattern, includeDirectoryStubs: includeDirectoryStubs}
+		accountURL: azblob.NewServiceURL(bURLParts.URL(), p), containerPattern: cPattern, includeDirectoryStubs: includeDirectoryStubs, getBlobTags: getBlobTags, cpkOptions: cpkOptions}
 
 	return
 }
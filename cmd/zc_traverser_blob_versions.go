@@ -35,6 +35,8 @@ type blobVersionsTraverser struct {
 	p                           pipeline.Pipeline
 	ctx                         context.Context
 	includeDirectoryStubs       bool
+	getBlobTags                 bool
+	cpkOptions                  common.CpkOptions
 	incrementEnumerationCounter enumerationCounterFunc
 	listOfVersionIds            chan string
 }
@@ -60,7 +62,7 @@ func (t *blobVersionsTraverser) getBlobProperties(versionID string) (props *azbl
 	}
 
 	blobURL := azblob.NewBlobURL(blobURLParts.URL(), t.p)
-	props, err = blobURL.GetProperties(t.ctx, azblob.BlobAccessConditions{})
+	props, err = blobURL.GetProperties(t.ctx, azblob.BlobAccessConditions{}, t.cpkOptions.ToClientProvidedKeyOptions())
 	return props, err
 }
 
@@ -93,6 +95,15 @@ func (t *blobVersionsTraverser) traverse(preprocessor objectMorpher, processor o
 		)
 		storedObject.blobVersionID = versionID
 
+		if t.getBlobTags && blobProperties.TagCount() > 0 {
+			blobURL := azblob.NewBlobURL(blobURLParts.URL(), t.p)
+			blobTags, err := blobURL.GetTags(t.ctx, nil, nil, nil, &versionID, nil)
+			if err != nil {
+				return err
+			}
+			storedObject.blobTags = common.FromAzBlobTagsToCommonBlobTags(blobTags)
+		}
+
 		if t.incrementEnumerationCounter != nil {
 			t.incrementEnumerationCounter(common.EEntityType.File())
 		}
@@ -105,13 +116,15 @@ func (t *blobVersionsTraverser) traverse(preprocessor objectMorpher, processor o
 	return nil
 }
 
-func newBlobVersionsTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs bool,
-	incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string) (t *blobVersionsTraverser) {
+func newBlobVersionsTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs, getBlobTags bool,
+	cpkOptions common.CpkOptions, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string) (t *blobVersionsTraverser) {
 	return &blobVersionsTraverser{
 		rawURL:                      rawURL,
 		p:                           p,
 		ctx:                         ctx,
 		includeDirectoryStubs:       includeDirectoryStubs,
+		getBlobTags:                 getBlobTags,
+		cpkOptions:                  cpkOptions,
 		incrementEnumerationCounter: incrementEnumerationCounter,
 		listOfVersionIds:            listOfVersionIds,
 	}
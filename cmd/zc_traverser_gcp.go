@@ -0,0 +1,189 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Enumerates a Google Cloud Storage bucket, or a virtual directory or object within it.
+// GCS is reached through its S3-compatible XML API, so this closely follows s3Traverser.
+type gcpTraverser struct {
+	rawURL        *url.URL // No pipeline needed for GCP
+	ctx           context.Context
+	recursive     bool
+	getProperties bool
+
+	gcpURLParts common.GCPURLParts
+	gcpClient   *minio.Client
+
+	// A generic function to notify that a new stored object has been enumerated
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func (t *gcpTraverser) isDirectory(isSource bool) bool {
+	// Do a basic syntax check
+	isDirDirect := !t.gcpURLParts.IsObjectSyntactically() && (t.gcpURLParts.IsDirectorySyntactically() || t.gcpURLParts.IsBucketSyntactically())
+
+	// Like S3, GCS can have directories and objects sharing names.
+	if !isSource {
+		return isDirDirect
+	}
+
+	_, err := t.gcpClient.StatObject(t.gcpURLParts.BucketName, t.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+
+	if err != nil {
+		return true
+	}
+
+	return false
+}
+
+func (t *gcpTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	// Check if resource is a single object.
+	if t.gcpURLParts.IsObjectSyntactically() && !t.gcpURLParts.IsDirectorySyntactically() && !t.gcpURLParts.IsBucketSyntactically() {
+		objectPath := strings.Split(t.gcpURLParts.ObjectKey, "/")
+		objectName := objectPath[len(objectPath)-1]
+
+		oi, err := t.gcpClient.StatObject(t.gcpURLParts.BucketName, t.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+
+		// If we actually got object properties, process them.
+		// Otherwise, treat it as a directory.
+		if err == nil {
+			oie := common.GCPObjectInfoExtension{ObjectInfo: oi}
+			storedObject := newStoredObject(
+				preprocessor,
+				objectName,
+				"",
+				common.EEntityType.File(),
+				oi.LastModified,
+				oi.Size,
+				&oie,
+				noBlobProps,
+				oie.NewCommonMetadata(),
+				t.gcpURLParts.BucketName)
+
+			err = processIfPassedFilters(
+				filters,
+				storedObject,
+				processor)
+			_, err = getProcessingError(err)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	// Append a trailing slash if it is missing.
+	if !strings.HasSuffix(t.gcpURLParts.ObjectKey, "/") && t.gcpURLParts.ObjectKey != "" {
+		t.gcpURLParts.ObjectKey += "/"
+	}
+
+	searchPrefix := t.gcpURLParts.ObjectKey
+
+	// It's a bucket or virtual directory.
+	// The XML API of GCS supports the original (V1) listing, so use that rather than ListObjectsV2.
+	for objectInfo := range t.gcpClient.ListObjects(t.gcpURLParts.BucketName, searchPrefix, t.recursive, t.ctx.Done()) {
+		if objectInfo.Err != nil {
+			return fmt.Errorf("cannot list objects, %v", objectInfo.Err)
+		}
+
+		objectPath := strings.Split(objectInfo.Key, "/")
+		objectName := objectPath[len(objectPath)-1]
+
+		relativePath := strings.TrimPrefix(objectInfo.Key, searchPrefix)
+
+		if relativePath == "" || strings.HasSuffix(relativePath, "/") {
+			// Directory placeholders (and, when not recursive, common prefixes) end with /. Skip them.
+			continue
+		}
+
+		// default to empty props, but retrieve real ones if required
+		oie := common.GCPObjectInfoExtension{ObjectInfo: minio.ObjectInfo{}}
+		if t.getProperties {
+			oi, err := t.gcpClient.StatObject(t.gcpURLParts.BucketName, objectInfo.Key, minio.StatObjectOptions{})
+			if err != nil {
+				return err
+			}
+			oie = common.GCPObjectInfoExtension{ObjectInfo: oi}
+		}
+		storedObject := newStoredObject(
+			preprocessor,
+			objectName,
+			relativePath,
+			common.EEntityType.File(),
+			objectInfo.LastModified,
+			objectInfo.Size,
+			&oie,
+			noBlobProps,
+			oie.NewCommonMetadata(),
+			t.gcpURLParts.BucketName)
+
+		err = processIfPassedFilters(filters,
+			storedObject,
+			processor)
+		_, err = getProcessingError(err)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// createGCPClient creates a client for the XML API of Google Cloud Storage, authenticated with the user's HMAC key or service account.
+func createGCPClient(ctx context.Context, gcpURLParts common.GCPURLParts) (*minio.Client, error) {
+	credType, err := common.GetGCPCredentialType()
+	if err != nil {
+		return nil, err
+	}
+
+	return common.CreateS3Client(
+		ctx,
+		common.CredentialInfo{
+			CredentialType:   credType,
+			S3CredentialInfo: gcpURLParts.S3CredentialInfo(),
+		},
+		common.CredentialOpOptions{
+			LogError: glcm.Error,
+		})
+}
+
+func newGCPTraverser(rawURL *url.URL, ctx context.Context, recursive, getProperties bool, incrementEnumerationCounter enumerationCounterFunc) (t *gcpTraverser, err error) {
+	t = &gcpTraverser{rawURL: rawURL, ctx: ctx, recursive: recursive, getProperties: getProperties, incrementEnumerationCounter: incrementEnumerationCounter}
+
+	// initialize GCP client and URL parts
+	t.gcpURLParts, err = common.NewGCPURLParts(*t.rawURL)
+	if err != nil {
+		return
+	}
+
+	t.gcpClient, err = createGCPClient(t.ctx, t.gcpURLParts)
+	return
+}
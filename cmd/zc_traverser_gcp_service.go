@@ -0,0 +1,131 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/minio/minio-go"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Enumerates all the Google Cloud Storage buckets visible to the HMAC key, or those matching a pattern,
+// by spawning a gcpTraverser for each of them.
+type gcpServiceTraverser struct {
+	ctx           context.Context
+	bucketPattern string
+	cachedBuckets []string
+	getProperties bool
+
+	gcpURL    common.GCPURLParts
+	gcpClient *minio.Client
+
+	// a generic function to notify that a new stored object has been enumerated
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func (t *gcpServiceTraverser) isDirectory(isSource bool) bool {
+	return true // Returns true as account traversal is inherently folder-oriented and recursive.
+}
+
+func (t *gcpServiceTraverser) listContainers() ([]string, error) {
+	if len(t.cachedBuckets) != 0 {
+		return t.cachedBuckets, nil
+	}
+
+	bucketInfo, err := t.gcpClient.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	bucketList := make([]string, 0)
+	for _, v := range bucketInfo {
+		// Match a pattern for the bucket name and the bucket name only
+		if t.bucketPattern != "" {
+			if ok, err := containerNameMatchesPattern(v.Name, t.bucketPattern); err != nil {
+				// Break if the pattern is invalid
+				return nil, err
+			} else if !ok {
+				// Ignore the bucket if it does not match the pattern
+				continue
+			}
+		}
+
+		bucketList = append(bucketList, v.Name)
+	}
+
+	t.cachedBuckets = bucketList
+	return bucketList, nil
+}
+
+func (t *gcpServiceTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	bucketList, err := t.listContainers()
+
+	if err != nil {
+		return err
+	}
+
+	for _, v := range bucketList {
+		// bucket traversers are handed path-style URLs, whatever the style of the URL we were given
+		urlResult := url.URL{Scheme: t.gcpURL.Scheme, Host: common.GCPEndpoint, Path: "/" + v}
+		bucketTraverser, err := newGCPTraverser(&urlResult, t.ctx, true, t.getProperties, t.incrementEnumerationCounter)
+
+		if err != nil {
+			return err
+		}
+
+		preprocessorForThisChild := preprocessor.FollowedBy(newContainerDecorator(v))
+
+		err = bucketTraverser.traverse(preprocessorForThisChild, processor, filters)
+
+		if err != nil {
+			WarnStdoutAndJobLog(fmt.Sprintf("failed to list objects in bucket %s: %s", v, err))
+			continue
+		}
+	}
+
+	return nil
+}
+
+func newGCPServiceTraverser(rawURL *url.URL, ctx context.Context, getProperties bool, incrementEnumerationCounter enumerationCounterFunc) (t *gcpServiceTraverser, err error) {
+	t = &gcpServiceTraverser{ctx: ctx, incrementEnumerationCounter: incrementEnumerationCounter, getProperties: getProperties}
+
+	var gcpURLParts common.GCPURLParts
+	gcpURLParts, err = common.NewGCPURLParts(*rawURL)
+
+	if err != nil {
+		return
+	} else if !gcpURLParts.IsServiceSyntactically() {
+		// Yoink the bucket name off and treat it as the pattern.
+		t.bucketPattern = gcpURLParts.BucketName
+
+		gcpURLParts.BucketName = ""
+	}
+
+	t.gcpURL = gcpURLParts
+
+	t.gcpClient, err = createGCPClient(t.ctx, gcpURLParts)
+
+	return
+}
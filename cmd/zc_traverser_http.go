@@ -0,0 +1,94 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Enumerates a single resource on a plain HTTP(S) web server.
+// Web servers offer no standard way to list their contents, so a URL ending in / (a "directory")
+// can only be copied by supplying the resources under it with --list-of-files.
+type httpTraverser struct {
+	rawURL *url.URL
+	p      pipeline.Pipeline
+	ctx    context.Context
+
+	// A generic function to notify that a new stored object has been enumerated
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func (t *httpTraverser) isDirectory(bool) bool {
+	return t.rawURL.Path == "" || strings.HasSuffix(t.rawURL.Path, "/")
+}
+
+func (t *httpTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	if t.isDirectory(true) {
+		return errors.New("cannot enumerate the contents of a web server directory. Supply the URLs to copy with --list-of-files instead")
+	}
+
+	props, err := common.GetHTTPResourceProperties(t.ctx, t.p, *t.rawURL)
+	if err != nil {
+		return err
+	}
+
+	size, err := props.ContentLength()
+	if err != nil {
+		return err
+	}
+
+	storedObject := newStoredObject(
+		preprocessor,
+		path.Base(t.rawURL.Path),
+		"",
+		common.EEntityType.File(),
+		props.LastModified(),
+		size,
+		props,
+		noBlobProps,
+		common.Metadata{}, // web servers have no notion of user-defined metadata
+		"")
+
+	if t.incrementEnumerationCounter != nil {
+		t.incrementEnumerationCounter(common.EEntityType.File())
+	}
+
+	err = processIfPassedFilters(filters, storedObject, processor)
+	_, err = getProcessingError(err)
+	return err
+}
+
+func newHttpTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, incrementEnumerationCounter enumerationCounterFunc) *httpTraverser {
+	return &httpTraverser{
+		rawURL:                      rawURL,
+		p:                           p,
+		ctx:                         ctx,
+		incrementEnumerationCounter: incrementEnumerationCounter,
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -36,6 +37,9 @@ type localTraverser struct {
 	fullPath       string
 	recursive      bool
 	followSymlinks bool
+	// when following symlinks, give what's in a symlinked folder under the same one of the paths it can be reached by every time,
+	// rather than under whichever the walk happens upon first
+	stableLinkedPaths bool
 
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
@@ -153,6 +157,14 @@ func (s symlinkTargetFileInfo) Name() string {
 // 1) Cleaner code
 // 2) Easier to test individually than to test the entire traverser.
 func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlinks bool) (err error) {
+	return walkWithSymlinks(fullPath, walkFunc, followSymlinks, false)
+}
+
+// walkWithSymlinks is WalkWithSymlinks, with the option of making the path that what's in a symlinked folder is given under
+// the same every time. Each folder is still only walked once, but rather than under whichever path to it the walk happens upon first,
+// it's under its own path, if that's in the tree, or else the symlink to it that's found first when the symlinks in each folder are
+// taken in order. That matters to sync, since it deletes whatever isn't found at the same path as before.
+func walkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlinks bool, stableLinkedPaths bool) (err error) {
 
 	// We want to re-queue symlinks up in their evaluated form because filepath.Walk doesn't evaluate them for us.
 	// So, what is the plan of attack?
@@ -163,6 +175,14 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 		relativeBase string // We also need the relative base path we found the symlink at.
 	}
 
+	// a symlink to a folder, which is followed unless the folder has been seen already
+	type linkedFolder struct {
+		relativePath string // where the symlink was found, relative to the root
+		targetPath   string // the full, symlink-resolved path of the folder
+		linkPath     string // the full path of the symlink
+		info         os.FileInfo
+	}
+
 	fullPath, err = filepath.Abs(fullPath)
 
 	if err != nil {
@@ -178,10 +198,36 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 		seenPaths = &realSeenPathsRecorder{make(map[string]struct{})} // have to use the RAM if we are dealing with symlinks, to prevent cycles
 	}
 
+	followLinkedFolder := func(linked linkedFolder) error {
+		if seenPaths.HasSeen(linked.targetPath) {
+			WarnStdoutAndJobLog(fmt.Sprintf("Ignored already linked directory pointed at %s (link at %s)", linked.targetPath, common.GenerateFullPath(fullPath, linked.relativePath)))
+			return nil
+		}
+
+		err := walkFunc(common.GenerateFullPath(fullPath, linked.relativePath), linked.info, nil)
+		// Since this doesn't directly manipulate the error, and only checks for a specific error, it's OK to use in a generic function.
+		skipped, err := getProcessingError(err)
+
+		if !skipped { // Don't go any deeper (or record it) if we skipped it.
+			seenPaths.Record(linked.targetPath)
+			seenPaths.Record(linked.linkPath) // Note we've seen the symlink as well. We shouldn't ever have issues if we _don't_ do this because we'll just catch it by symlink result
+			walkQueue = append(walkQueue, walkItem{
+				fullPath:     linked.targetPath,
+				relativeBase: linked.relativePath,
+			})
+		}
+		// enumerate the FOLDER now (since its presence in seenDirs will prevent its properties getting enumerated later)
+		return err
+	}
+
 	for len(walkQueue) > 0 {
 		queueItem := walkQueue[0]
 		walkQueue = walkQueue[1:]
 
+		// with stable linked paths, the symlinked folders found in this walk are only followed once it's over,
+		// by when everything in the folder itself has been seen, and then in order of path
+		var linkedFolders []linkedFolder
+
 		// walk contents of this queueItem in parallel
 		// (for simplicity of coding, we don't parallelize across multiple queueItems)
 		parallel.Walk(queueItem.fullPath, enumerationParallelism, enumerationParallelStatFiles, func(filePath string, fileInfo os.FileInfo, fileError error) error {
@@ -224,24 +270,12 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 				}
 
 				if rStat.IsDir() {
-					if !seenPaths.HasSeen(result) {
-						err := walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), symlinkTargetFileInfo{rStat, fileInfo.Name()}, fileError)
-						// Since this doesn't directly manipulate the error, and only checks for a specific error, it's OK to use in a generic function.
-						skipped, err := getProcessingError(err)
-
-						if !skipped { // Don't go any deeper (or record it) if we skipped it.
-							seenPaths.Record(result)
-							seenPaths.Record(slPath) // Note we've seen the symlink as well. We shouldn't ever have issues if we _don't_ do this because we'll just catch it by symlink result
-							walkQueue = append(walkQueue, walkItem{
-								fullPath:     result,
-								relativeBase: computedRelativePath,
-							})
-						}
-						// enumerate the FOLDER now (since its presence in seenDirs will prevent its properties getting enumerated later)
-						return err
-					} else {
-						WarnStdoutAndJobLog(fmt.Sprintf("Ignored already linked directory pointed at %s (link at %s)", result, common.GenerateFullPath(fullPath, computedRelativePath)))
+					linked := linkedFolder{relativePath: computedRelativePath, targetPath: result, linkPath: slPath, info: symlinkTargetFileInfo{rStat, fileInfo.Name()}}
+					if stableLinkedPaths {
+						linkedFolders = append(linkedFolders, linked)
+						return nil
 					}
+					return followLinkedFolder(linked)
 				} else {
 					WarnStdoutAndJobLog(fmt.Sprintf("Symlinks to individual files are not currently supported, so will ignore file at %s (link at %s)", result, common.GenerateFullPath(fullPath, computedRelativePath)))
 					// TODO: remove the above info call and enable the below, with suitable multi-OS testing
@@ -295,11 +329,84 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 				}
 			}
 		})
+
+		sort.Slice(linkedFolders, func(i, j int) bool { return linkedFolders[i].relativePath < linkedFolders[j].relativePath })
+		for _, linked := range linkedFolders {
+			if followLinkedFolder(linked) != nil {
+				break // as the walk itself does
+			}
+		}
 	}
 	return
 }
 
+// walkSorted is a sequential version of filepath.Walk, which visits the paths in ascending order of their
+// path relative to the root (with forward slashes as the separators), which is the order that Blob and S3 list in.
+// That's not quite the order that filepath.Walk uses, since a folder's contents must come after any sibling
+// whose name is the folder's name followed by a character that sorts before the separator, e.g. "a/b" comes after "a-b".
+func walkSorted(root string, walkFunc filepath.WalkFunc) error {
+	rootInfo, err := common.OSStat(root)
+	if err != nil {
+		return walkFunc(root, nil, err)
+	}
+
+	if _, err = getProcessingError(walkFunc(root, rootInfo, nil)); err != nil {
+		return err
+	}
+	return walkSortedDir(root, walkFunc)
+}
+
+func walkSortedDir(dirPath string, walkFunc filepath.WalkFunc) error {
+	entries, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return walkFunc(dirPath, nil, err)
+	}
+
+	// each folder is in the list twice: once for the folder itself, and once (with a trailing separator) for its contents
+	type sortedEntry struct {
+		key        string
+		info       os.FileInfo
+		isContents bool
+	}
+	sortedEntries := make([]sortedEntry, 0, len(entries))
+	for _, entry := range entries {
+		sortedEntries = append(sortedEntries, sortedEntry{key: entry.Name(), info: entry})
+		if entry.IsDir() {
+			sortedEntries = append(sortedEntries, sortedEntry{key: entry.Name() + common.AZCOPY_PATH_SEPARATOR_STRING, info: entry, isContents: true})
+		}
+	}
+	sort.Slice(sortedEntries, func(i, j int) bool { return sortedEntries[i].key < sortedEntries[j].key })
+
+	for _, entry := range sortedEntries {
+		entryPath := filepath.Join(dirPath, entry.info.Name())
+		if entry.isContents {
+			err = walkSortedDir(entryPath, walkFunc)
+		} else {
+			_, err = getProcessingError(walkFunc(entryPath, entry.info, nil))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	return t.enumerate(preprocessor, processor, filters, false)
+}
+
+// sorted traversal doesn't follow symlinks, since a symlinked folder's contents
+// would have to be merged into the right place in the order of its parent's contents
+func (t *localTraverser) canTraverseSorted() bool {
+	return !t.followSymlinks
+}
+
+// traverseSorted walks the tree one directory at a time, giving the objects in ascending order of relative path
+func (t *localTraverser) traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	return t.enumerate(preprocessor, processor, filters, true)
+}
+
+func (t *localTraverser) enumerate(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter, sorted bool) (err error) {
 	singleFileInfo, isSingleFile, err := t.getInfoIfSingleFile()
 
 	if err != nil {
@@ -372,7 +479,10 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 			}
 
 			// note: Walk includes root, so no need here to separately create storedObject for root (as we do for other folder-aware sources)
-			return WalkWithSymlinks(t.fullPath, processFile, t.followSymlinks)
+			if sorted {
+				return walkSorted(t.fullPath, processFile)
+			}
+			return walkWithSymlinks(t.fullPath, processFile, t.followSymlinks, t.stableLinkedPaths)
 		} else {
 			// if recursive is off, we only need to scan the files immediately under the fullPath
 			// We don't transfer any directory properties here, not even the root. (Because the root's
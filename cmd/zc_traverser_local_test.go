@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	chk "gopkg.in/check.v1"
 )
@@ -50,3 +52,31 @@ func (s *localTraverserTestSuite) TestCleanLocalPathForWindows(c *chk.C) {
 		c.Assert(cleanLocalPath(orig), chk.Equals, expected)
 	}
 }
+
+func (s *localTraverserTestSuite) TestTraverseSorted(c *chk.C) {
+	root, err := ioutil.TempDir("", "sortedtraversal")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(root)
+
+	// "a-b" and "a.txt" sort between the folder "a" and its contents, since '-' and '.' come before '/'
+	for _, file := range []string{"b", "a-b", "a.txt", "a/x", "a/y/z"} {
+		fullPath := filepath.Join(root, filepath.FromSlash(file))
+		c.Assert(os.MkdirAll(filepath.Dir(fullPath), os.ModePerm), chk.IsNil)
+		c.Assert(ioutil.WriteFile(fullPath, []byte(file), 0644), chk.IsNil)
+	}
+
+	traverser := newLocalTraverser(root, true, false, nil)
+	c.Assert(traverser.canTraverseSorted(), chk.Equals, true)
+
+	processor := dummyProcessor{}
+	c.Assert(traverser.traverseSorted(noPreProccessor, processor.process, nil), chk.IsNil)
+
+	relativePaths := make([]string, 0)
+	for _, object := range processor.record {
+		relativePaths = append(relativePaths, object.relativePath)
+	}
+	c.Assert(relativePaths, chk.DeepEquals, []string{"", "a", "a-b", "a.txt", "a/x", "a/y", "a/y/z", "b"})
+
+	// following symlinks could put a folder's contents out of order
+	c.Assert(newLocalTraverser(root, true, true, nil).canTraverseSorted(), chk.Equals, false)
+}
@@ -88,6 +88,7 @@ func (t *s3Traverser) traverse(preprocessor objectMorpher, processor objectProce
 				noBlobProps,
 				oie.NewCommonMetadata(),
 				t.s3URLParts.BucketName)
+			storedObject.etagMd5 = oie.ETagMD5()
 
 			err = processIfPassedFilters(
 				filters,
@@ -136,6 +137,7 @@ func (t *s3Traverser) traverse(preprocessor objectMorpher, processor objectProce
 
 		// default to empty props, but retrieve real ones if required
 		oie := common.ObjectInfoExtension{ObjectInfo: minio.ObjectInfo{}}
+		listed := common.ObjectInfoExtension{ObjectInfo: objectInfo} // for the ETag, which the listing has too
 		if t.getProperties {
 			oi, err := t.s3Client.StatObject(t.s3URLParts.BucketName, objectInfo.Key, minio.StatObjectOptions{})
 			if err != nil {
@@ -154,6 +156,11 @@ func (t *s3Traverser) traverse(preprocessor objectMorpher, processor objectProce
 			noBlobProps,
 			oie.NewCommonMetadata(),
 			t.s3URLParts.BucketName)
+		if t.getProperties {
+			storedObject.etagMd5 = oie.ETagMD5()
+		} else {
+			storedObject.etagMd5 = listed.ETagMD5()
+		}
 
 		err = processIfPassedFilters(filters,
 			storedObject,
@@ -166,6 +173,15 @@ func (t *s3Traverser) traverse(preprocessor objectMorpher, processor objectProce
 	return
 }
 
+func (t *s3Traverser) canTraverseSorted() bool {
+	return true
+}
+
+// S3 always lists objects in ascending order of key, and traverse lists sequentially, so there's nothing extra to do
+func (t *s3Traverser) traverseSorted(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	return t.traverse(preprocessor, processor, filters)
+}
+
 func newS3Traverser(rawURL *url.URL, ctx context.Context, recursive, getProperties bool, incrementEnumerationCounter enumerationCounterFunc) (t *s3Traverser, err error) {
 	t = &s3Traverser{rawURL: rawURL, ctx: ctx, recursive: recursive, getProperties: getProperties, incrementEnumerationCounter: incrementEnumerationCounter}
 
@@ -184,11 +200,8 @@ func newS3Traverser(rawURL *url.URL, ctx context.Context, recursive, getProperti
 	t.s3Client, err = common.CreateS3Client(
 		t.ctx,
 		common.CredentialInfo{
-			CredentialType: common.ECredentialType.S3AccessKey(),
-			S3CredentialInfo: common.S3CredentialInfo{
-				Endpoint: t.s3URLParts.Endpoint,
-				Region:   t.s3URLParts.Region,
-			},
+			CredentialType:   common.ECredentialType.S3AccessKey(),
+			S3CredentialInfo: t.s3URLParts.S3CredentialInfo(),
 		},
 		common.CredentialOpOptions{
 			LogError: glcm.Error,
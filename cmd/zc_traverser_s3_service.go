@@ -141,13 +141,17 @@ func newS3ServiceTraverser(rawURL *url.URL, ctx context.Context, getProperties b
 
 	t.s3URL = s3URLPartsExtension{s3URLParts}
 
+	// Buckets are listed without pinning a region, unless the user told us which region their custom endpoint is in
+	s3CredInfo := s3URLParts.S3CredentialInfo()
+	if !s3URLParts.IsCustomEndpoint() {
+		s3CredInfo.Region = ""
+	}
+
 	t.s3Client, err = common.CreateS3Client(
 		t.ctx,
 		common.CredentialInfo{
-			CredentialType: common.ECredentialType.S3AccessKey(),
-			S3CredentialInfo: common.S3CredentialInfo{
-				Endpoint: t.s3URL.Endpoint,
-			},
+			CredentialType:   common.ECredentialType.S3AccessKey(),
+			S3CredentialInfo: s3CredInfo,
 		},
 		common.CredentialOpOptions{
 			LogError: glcm.Error,
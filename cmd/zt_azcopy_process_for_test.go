@@ -0,0 +1,65 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	chk "gopkg.in/check.v1"
+)
+
+// The transfer engine keeps process-wide state, and AzCopy exits when its job is done, so tests that need the data
+// to really be moved run AzCopy in a child process. The child is this test binary, told to act as AzCopy.
+
+// azcopyProcessFolderEnvVar holds the folder for the child's logs and plan files, and tells it to act as AzCopy
+const azcopyProcessFolderEnvVar = "AZCOPY_TEST_PROCESS_FOLDER"
+
+// TestActAsAzCopy isn't a test on its own. In the child process, it runs the command line given after "--" as AzCopy would.
+func TestActAsAzCopy(t *testing.T) {
+	folder := os.Getenv(azcopyProcessFolderEnvVar)
+	if folder == "" {
+		return
+	}
+
+	planFolder := filepath.Join(folder, "plans")
+	if err := os.MkdirAll(planFolder, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs(flag.Args())
+	Execute(folder, folder, planFolder, 1024) // exits the process
+}
+
+// runAzCopyProcess runs AzCopy with the given arguments in a child process, and returns what it printed
+func runAzCopyProcess(c *chk.C, args ...string) (string, error) {
+	folder, err := ioutil.TempDir("", "azcopyprocess")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(folder)
+
+	process := exec.Command(os.Args[0], append([]string{"-test.run=^TestActAsAzCopy$", "--"}, args...)...)
+	process.Env = append(os.Environ(), azcopyProcessFolderEnvVar+"="+folder)
+	output, err := process.CombinedOutput()
+	return string(output), err
+}
@@ -0,0 +1,67 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToBlobS3(c *chk.C) {
+	c.Assert(inferFromTo("https://myaccount.blob.core.windows.net/container/blob", "https://s3.amazonaws.com/bucket/object"),
+		chk.Equals, common.EFromTo.BlobS3())
+}
+
+func (s *cmdIntegrationSuite) TestS2SCopyFromBlobContainerToS3Bucket(c *chk.C) {
+	skipIfS3Disabled(c)
+	s3Client, err := createS3ClientWithMinio(createS3ResOptions{})
+	if err != nil {
+		c.Skip("S3 client credentials not supplied")
+	}
+	bsu := getBSU()
+
+	// set up the source container with numerous blobs
+	srcContainerURL, srcContainerName := createNewContainer(c, bsu)
+	defer deleteContainer(c, srcContainerURL)
+	blobList := scenarioHelper{}.generateCommonRemoteScenarioForBlob(c, srcContainerURL, "")
+	c.Assert(len(blobList), chk.Not(chk.Equals), 0)
+
+	// set up an empty bucket
+	bucketName := generateBucketName()
+	createNewBucketWithName(c, s3Client, bucketName, createS3ResOptions{})
+	defer deleteBucket(c, s3Client, bucketName, true)
+
+	// set up interceptor
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	// construct the raw input to simulate user input
+	rawSrcContainerURLWithSAS := scenarioHelper{}.getRawContainerURLWithSAS(c, srcContainerName)
+	rawDstS3BucketURL := scenarioHelper{}.getRawS3BucketURL(c, "", bucketName) // Use default region
+	raw := getDefaultRawCopyInput(rawSrcContainerURLWithSAS.String(), rawDstS3BucketURL.String())
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		validateS2STransfersAreScheduled(c, "/", "/", blobList, mockedRPC)
+	})
+}
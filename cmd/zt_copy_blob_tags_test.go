@@ -0,0 +1,73 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type copyBlobTagsSuite struct{}
+
+var _ = chk.Suite(&copyBlobTagsSuite{})
+
+func (s *copyBlobTagsSuite) TestCookBlobTags(c *chk.C) {
+	raw := getDefaultCopyRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.blobTags = "project=apollo&stage=final"
+
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.blobTags, chk.DeepEquals, common.BlobTags{"project": "apollo", "stage": "final"})
+
+	// tags can only be set on blobs
+	raw = getDefaultCopyRawInput("/src", "https://account.file.core.windows.net/share")
+	raw.blobTags = "project=apollo"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+
+	// and must be valid ones
+	raw = getDefaultCopyRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.blobTags = "project=apollo;11"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *copyBlobTagsSuite) TestCookIncludeBlobTags(c *chk.C) {
+	raw := getDefaultCopyRawInput("https://account.blob.core.windows.net/container", "/dst")
+	raw.recursive = true
+	raw.includeBlobTags = "project=apollo"
+
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.includeBlobTags, chk.DeepEquals, common.BlobTags{"project": "apollo"})
+
+	filters := cooked.initModularFilters()
+	c.Assert(filters, chk.HasLen, 1)
+	c.Assert(filters[0], chk.FitsTypeOf, &includeBlobTagsFilter{})
+
+	// only blobs have tags to filter on
+	raw = getDefaultCopyRawInput("https://account.file.core.windows.net/share", "/dst")
+	raw.recursive = true
+	raw.includeBlobTags = "project=apollo"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,47 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToBlobFSS2S(c *chk.C) {
+	c.Assert(inferFromTo("https://src.dfs.core.windows.net/fs/dir?sv=x", "https://dst.blob.core.windows.net/container"),
+		chk.Equals, common.EFromTo.BlobFSBlob())
+	c.Assert(inferFromTo("https://src.dfs.core.windows.net/fs/dir?sv=x", "https://dst.dfs.core.windows.net/fs"),
+		chk.Equals, common.EFromTo.BlobFSBlobFS())
+}
+
+func (s *cmdIntegrationSuite) TestBlobFSS2SFolderPropertyOption(c *chk.C) {
+	// ADLS Gen2 is folder-aware at both ends, so directories are carried over
+	fpo, _ := newFolderPropertyOption(common.EFromTo.BlobFSBlobFS(), true, false, nil, false, false)
+	c.Assert(fpo, chk.Equals, common.EFolderPropertiesOption.AllFolders())
+
+	fpo, _ = newFolderPropertyOption(common.EFromTo.BlobFSBlobFS(), true, true, nil, false, false)
+	c.Assert(fpo, chk.Equals, common.EFolderPropertiesOption.AllFoldersExceptRoot())
+
+	// but Blob isn't, so they are not
+	fpo, _ = newFolderPropertyOption(common.EFromTo.BlobFSBlob(), true, false, nil, false, false)
+	c.Assert(fpo, chk.Equals, common.EFolderPropertiesOption.NoFolders())
+}
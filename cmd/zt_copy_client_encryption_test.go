@@ -0,0 +1,76 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type copyClientEncryptionSuite struct{}
+
+var _ = chk.Suite(&copyClientEncryptionSuite{})
+
+func (s *copyClientEncryptionSuite) TestValidateClientSideEncryption(c *chk.C) {
+	dir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dir)
+
+	keyBytes := make([]byte, common.ClientEncryptionKeySize)
+	keyBytes[0] = 1
+	key, err := common.NewClientEncryptionKey(keyBytes)
+	c.Assert(err, chk.IsNil)
+	keyFile := filepath.Join(dir, "key")
+	c.Assert(ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(keyBytes)), 0600), chk.IsNil)
+
+	// uploads to block blobs are encrypted with the key from the file
+	hash, err := validateClientSideEncryption(true, keyFile, common.EFromTo.LocalBlob(), common.EBlobType.Detect())
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash, chk.Equals, key.Hash())
+
+	// but not other kinds of transfer, or blob
+	_, err = validateClientSideEncryption(true, keyFile, common.EFromTo.LocalFile(), common.EBlobType.Detect())
+	c.Assert(err, chk.NotNil)
+	_, err = validateClientSideEncryption(true, keyFile, common.EFromTo.LocalBlob(), common.EBlobType.PageBlob())
+	c.Assert(err, chk.NotNil)
+
+	// there must be a key to encrypt with
+	_, err = validateClientSideEncryption(true, filepath.Join(dir, "missing"), common.EFromTo.LocalBlob(), common.EBlobType.Detect())
+	c.Assert(err, chk.NotNil)
+
+	// downloads may be given a key, in case they're encrypted, but don't need one
+	hash, err = validateClientSideEncryption(false, keyFile, common.EFromTo.BlobLocal(), common.EBlobType.Detect())
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash, chk.Equals, key.Hash())
+	os.Unsetenv(common.EEnvironmentVariable.ClientEncryptionKey().Name)
+	hash, err = validateClientSideEncryption(false, "", common.EFromTo.BlobLocal(), common.EBlobType.Detect())
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash.IsZero(), chk.Equals, true)
+
+	// the key file means nothing for other transfers
+	_, err = validateClientSideEncryption(false, keyFile, common.EFromTo.BlobBlob(), common.EBlobType.Detect())
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type copyCompressSuite struct{}
+
+var _ = chk.Suite(&copyCompressSuite{})
+
+func (s *copyCompressSuite) TestCookCompress(c *chk.C) {
+	raw := getDefaultCopyRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.recursive = true
+	raw.compress = "zstd"
+	raw.compressionExtension = true
+
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.compression, chk.Equals, common.ECompressionType.Zstd())
+	c.Assert(cooked.compressionExtension, chk.Equals, true)
+	c.Assert(cooked.contentEncoding, chk.Equals, "zstd")
+
+	// the content-encoding may be given, so long as it agrees
+	raw.compress = "gzip"
+	raw.contentEncoding = "GZIP"
+	cooked, err = raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.contentEncoding, chk.Equals, "gzip")
+}
+
+func (s *copyCompressSuite) TestCookCompressRejectsUnsupportedCases(c *chk.C) {
+	cases := []func(raw *rawCopyCmdArgs){
+		func(raw *rawCopyCmdArgs) { raw.compress = "zlib" },           // can't be concatenated
+		func(raw *rawCopyCmdArgs) { raw.compress = "brotli" },         // unknown
+		func(raw *rawCopyCmdArgs) { raw.blobType = "PageBlob" },       // not a block blob
+		func(raw *rawCopyCmdArgs) { raw.contentEncoding = "br" },      // disagrees
+		func(raw *rawCopyCmdArgs) { raw.clientSideEncryption = true }, // encrypted chunks must be the same size
+		func(raw *rawCopyCmdArgs) { raw.compress = ""; raw.compressionExtension = true },
+		func(raw *rawCopyCmdArgs) { raw.src, raw.dst = raw.dst, raw.src }, // not an upload
+		func(raw *rawCopyCmdArgs) { raw.blockSizeMB = 4000 },              // no room to grow if it doesn't compress
+	}
+
+	for i, setUp := range cases {
+		raw := getDefaultCopyRawInput("/src", "https://account.blob.core.windows.net/container")
+		raw.recursive = true
+		raw.compress = "gzip"
+		setUp(&raw)
+
+		_, err := raw.cook()
+		c.Assert(err, chk.NotNil, chk.Commentf("case %d", i))
+	}
+}
+
+func (s *copyCompressSuite) TestStripCompressionExtension(c *chk.C) {
+	// what --compression-extension adds, --decompress takes away
+	for _, tp := range []common.CompressionType{common.ECompressionType.GZip(), common.ECompressionType.Zstd()} {
+		c.Assert(stripCompressionExtension("dir/file.txt"+tp.Extension(), tp.ContentEncoding()), chk.Equals, "dir/file.txt")
+	}
+	c.Assert(stripCompressionExtension("dir/file.txt.br", "br"), chk.Equals, "dir/file.txt")
+	c.Assert(stripCompressionExtension("dir/file.txt.zst", "gzip"), chk.Equals, "dir/file.txt.zst")
+	c.Assert(stripCompressionExtension("dir/file.txt.br", "zstd"), chk.Equals, "dir/file.txt.br")
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type copyCpkSuite struct{}
+
+var _ = chk.Suite(&copyCpkSuite{})
+
+func (s *copyCpkSuite) TestValidateCpkOptions(c *chk.C) {
+	name := common.EEnvironmentVariable.CpkEncryptionKey().Name
+	defer os.Unsetenv(name)
+	os.Unsetenv(name)
+
+	// nothing given is fine for any transfer
+	options, err := validateCpkOptions(false, "", "", common.EFromTo.LocalFile())
+	c.Assert(err, chk.IsNil)
+	c.Assert(options.Key, chk.IsNil)
+	c.Assert(options.EncryptionScope, chk.Equals, "")
+
+	// an encryption scope is for writing blobs, and cpk-by-name is another name for it
+	options, err = validateCpkOptions(false, "", "scope1", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(options.EncryptionScope, chk.Equals, "scope1")
+	options, err = validateCpkOptions(false, "scope1", "", common.EFromTo.BlobBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(options.EncryptionScope, chk.Equals, "scope1")
+	_, err = validateCpkOptions(false, "scope1", "scope2", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+	_, err = validateCpkOptions(false, "", "scope1", common.EFromTo.BlobLocal())
+	c.Assert(err, chk.NotNil)
+	_, err = validateCpkOptions(false, "", "scope1", common.EFromTo.LocalFile())
+	c.Assert(err, chk.NotNil)
+
+	// cpk-by-value needs the key in the environment
+	_, err = validateCpkOptions(true, "", "", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+
+	keyBytes := make([]byte, common.CpkKeySize)
+	keyBytes[0] = 1
+	os.Setenv(name, base64.StdEncoding.EncodeToString(keyBytes))
+	for _, fromTo := range []common.FromTo{common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal(), common.EFromTo.BlobBlob(), common.EFromTo.BlobBlobFS()} {
+		options, err = validateCpkOptions(true, "", "", fromTo)
+		c.Assert(err, chk.IsNil)
+		c.Assert(options.Key, chk.NotNil)
+	}
+
+	// but not where the service would have to read the blobs itself, or where there are no blobs
+	_, err = validateCpkOptions(true, "", "", common.EFromTo.BlobFile())
+	c.Assert(err, chk.NotNil)
+	_, err = validateCpkOptions(true, "", "", common.EFromTo.LocalFile())
+	c.Assert(err, chk.NotNil)
+
+	// and a key and a scope can't be used together
+	_, err = validateCpkOptions(true, "", "scope1", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *copyCpkSuite) TestSyncCpkOptions(c *chk.C) {
+	name := common.EEnvironmentVariable.CpkEncryptionKey().Name
+	defer os.Unsetenv(name)
+	keyBytes := make([]byte, common.CpkKeySize)
+	keyBytes[0] = 1
+	os.Setenv(name, base64.StdEncoding.EncodeToString(keyBytes))
+
+	localDir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(localDir)
+	blobURL := "https://account.blob.core.windows.net/container/dir?sv=2019-12-12&sig=x"
+	otherBlobURL := "https://other.blob.core.windows.net/container/dir?sv=2019-12-12&sig=x"
+
+	// the key is given to the traverser of the blob side, or the destination's if both sides are blobs, and its hash to the job
+	for _, t := range []struct {
+		src, dst                 string
+		sourceHasKey, destHasKey bool
+	}{
+		{localDir, blobURL, false, true},
+		{blobURL, localDir, true, false},
+		{blobURL, otherBlobURL, false, true},
+	} {
+		raw := getDefaultSyncRawInput(t.src, t.dst)
+		raw.cpkByValue = true
+		cooked, err := raw.cook()
+		c.Assert(err, chk.IsNil)
+		c.Assert(cooked.cpkOptionsFor(true).Key != nil, chk.Equals, t.sourceHasKey)
+		c.Assert(cooked.cpkOptionsFor(false).Key != nil, chk.Equals, t.destHasKey)
+
+		processor := newSyncTransferProcessorForDirection(&cooked, cooked.fromTo, cooked.source, cooked.destination, 1, common.EFolderPropertiesOption.NoFolders())
+		c.Assert(processor.copyJobTemplate.CpkKeyHash, chk.Equals, cooked.cpkOptions.Key.Hash())
+	}
+
+	// an encryption scope is given to the jobs that write blobs, but not to the return leg of a two-way sync, which reads them
+	os.Unsetenv(name)
+	raw := getDefaultSyncRawInput(localDir, blobURL)
+	raw.encryptionScope = "scope1"
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	forward := newSyncTransferProcessorForDirection(&cooked, cooked.fromTo, cooked.source, cooked.destination, 1, common.EFolderPropertiesOption.NoFolders())
+	c.Assert(forward.copyJobTemplate.EncryptionScope, chk.Equals, "scope1")
+	backward := newSyncTransferProcessorForDirection(&cooked, cooked.fromTo.Reverse(), cooked.destination, cooked.source, 1, common.EFolderPropertiesOption.NoFolders())
+	c.Assert(backward.copyJobTemplate.EncryptionScope, chk.Equals, "")
+
+	// and, as with copy, a scope can only be given when the destination is Blob storage
+	raw = getDefaultSyncRawInput(blobURL, localDir)
+	raw.encryptionScope = "scope1"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,129 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToGCPBlob(c *chk.C) {
+	c.Assert(inferFromTo("https://storage.googleapis.com/bucket/object", "https://myaccount.blob.core.windows.net/container"),
+		chk.Equals, common.EFromTo.GCPBlob())
+	c.Assert(inferFromTo("https://bucket.storage.googleapis.com/object", "https://myaccount.blob.core.windows.net/container"),
+		chk.Equals, common.EFromTo.GCPBlob())
+	c.Assert(inferArgumentLocation("https://storage.googleapis.com/bucket"), chk.Equals, common.ELocation.GCP())
+}
+
+func (s *cmdIntegrationSuite) TestGCPTraverserWithServiceAccount(c *chk.C) {
+	// GCS is faked by a fake S3, which only accepts the token that the fake token endpoint hands out
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.FormValue("grant_type"), chk.Equals, "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"faketoken","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	fakeGCS := newFakeS3Server()
+	defer fakeGCS.Close()
+	fakeGCS.authorization = "Bearer faketoken"
+	bucketName := "fakebucket"
+	fakeGCS.putObjects(bucketName, map[string]string{
+		"top.txt":          "top",
+		"dir/a.txt":        "aa",
+		"dir/sub/b.txt":    "bbb",
+		"other/ignore.txt": "c",
+	})
+
+	dir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dir)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, chk.IsNil)
+	keyJSON, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "azcopy@test-project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+		"token_uri":    tokenServer.URL,
+	})
+	c.Assert(err, chk.IsNil)
+	keyPath := filepath.Join(dir, "key.json")
+	c.Assert(ioutil.WriteFile(keyPath, keyJSON, 0600), chk.IsNil)
+	defer setEnvForTest(c, map[string]string{
+		common.EEnvironmentVariable.GoogleHMACAccessID().Name:   "",
+		common.EEnvironmentVariable.GoogleHMACSecret().Name:     "",
+		common.EEnvironmentVariable.GoogleAppCredentials().Name: keyPath,
+	})()
+
+	credType, err := common.GetGCPCredentialType()
+	c.Assert(err, chk.IsNil)
+	c.Assert(credType, chk.Equals, common.ECredentialType.GCPServiceAccount())
+
+	// the traverser is given a client for the fake, since the real one always talks to storage.googleapis.com
+	newTraverser := func(rawURL string, recursive bool) *gcpTraverser {
+		u, err := url.Parse(rawURL)
+		c.Assert(err, chk.IsNil)
+		gcpURLParts, err := common.NewGCPURLParts(*u)
+		c.Assert(err, chk.IsNil)
+		credInfo := gcpURLParts.S3CredentialInfo()
+		credInfo.Endpoint = fakeGCS.host()
+		credInfo.Insecure = true
+		client, err := common.CreateS3Client(context.Background(), common.CredentialInfo{CredentialType: credType, S3CredentialInfo: credInfo}, common.CredentialOpOptions{})
+		c.Assert(err, chk.IsNil)
+
+		return &gcpTraverser{rawURL: u, ctx: context.Background(), recursive: recursive, gcpURLParts: gcpURLParts, gcpClient: client,
+			incrementEnumerationCounter: func(common.EntityType) {}}
+	}
+	traverseAll := func(t *gcpTraverser) map[string]int64 {
+		found := make(map[string]int64)
+		err := t.traverse(noPreProccessor, func(object storedObject) error {
+			found[object.relativePath] = object.size
+			return nil
+		}, nil)
+		c.Assert(err, chk.IsNil)
+		return found
+	}
+
+	c.Assert(traverseAll(newTraverser("https://storage.googleapis.com/"+bucketName+"/dir/", true)), chk.DeepEquals,
+		map[string]int64{"a.txt": 2, "sub/b.txt": 3})
+	c.Assert(traverseAll(newTraverser("https://storage.googleapis.com/"+bucketName, false)), chk.DeepEquals,
+		map[string]int64{"top.txt": 3})
+	c.Assert(traverseAll(newTraverser("https://storage.googleapis.com/"+bucketName+"/dir/sub/b.txt", false)), chk.DeepEquals,
+		map[string]int64{"": 3})
+
+	// without the token, the fake refuses to list
+	fakeGCS.authorization = "Bearer othertoken"
+	err = newTraverser("https://storage.googleapis.com/"+bucketName, true).traverse(noPreProccessor, func(storedObject) error { return nil }, nil)
+	c.Assert(err, chk.NotNil)
+}
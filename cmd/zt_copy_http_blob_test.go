@@ -0,0 +1,179 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToHttpBlob(c *chk.C) {
+	c.Assert(inferArgumentLocation("https://data.contoso.com/datasets/file.csv"), chk.Equals, common.ELocation.Http())
+	c.Assert(inferArgumentLocation("http://data.contoso.com:8080/datasets/"), chk.Equals, common.ELocation.Http())
+	c.Assert(inferFromTo("https://data.contoso.com/datasets/file.csv", "https://myaccount.blob.core.windows.net/container"),
+		chk.Equals, common.EFromTo.HttpBlob())
+
+	// storage URLs must still be recognized as such
+	c.Assert(inferArgumentLocation("https://myaccount.blob.core.windows.net/container"), chk.Equals, common.ELocation.Blob())
+	c.Assert(inferArgumentLocation("https://s3.amazonaws.com/bucket"), chk.Equals, common.ELocation.S3())
+
+	// URLs that are addressed by IP may be for Azure Stack or the storage emulator, on any port, as much as for a web server,
+	// so they all need --from-to, and the error says so
+	for _, ipURL := range []string{"http://127.0.0.1:10000/devstoreaccount1/container", "https://10.1.2.3/myaccount/container", "https://192.168.1.20:8443/datasets/file.csv"} {
+		c.Assert(inferArgumentLocation(ipURL), chk.Equals, common.ELocation.Unknown())
+		_, err := validateFromTo(ipURL, "https://myaccount.blob.core.windows.net/container", "")
+		c.Assert(err, chk.ErrorMatches, ".*--from-to.*HttpBlob.*")
+	}
+	fromTo, err := validateFromTo("https://192.168.1.20:8443/datasets/file.csv", "https://myaccount.blob.core.windows.net/container", "HttpBlob")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.HttpBlob())
+}
+
+func (s *cmdIntegrationSuite) TestHttpLocationLevel(c *chk.C) {
+	level, err := determineLocationLevel("https://data.contoso.com/datasets/file.csv", common.ELocation.Http(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(level, chk.Equals, ELocationLevel.Object())
+
+	level, err = determineLocationLevel("https://data.contoso.com/datasets/", common.ELocation.Http(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(level, chk.Equals, ELocationLevel.Container())
+}
+
+func (s *cmdIntegrationSuite) TestRelativePathFromHttpListEntry(c *chk.C) {
+	source := common.ResourceString{Value: "https://data.contoso.com/datasets"}
+
+	testCases := map[string]string{
+		"2020/january.csv": "2020/january.csv", // already relative
+		"https://data.contoso.com/datasets/2020/january.csv":     "2020/january.csv",
+		"https://DATA.contoso.com/datasets/2020/feb%20ruary.csv": "2020/feb ruary.csv",
+	}
+
+	for entry, expected := range testCases {
+		relativePath, err := relativePathFromHttpListEntry(source, entry)
+		c.Assert(err, chk.IsNil)
+		c.Assert(relativePath, chk.Equals, expected)
+	}
+
+	for _, entry := range []string{
+		"https://data.contoso.com/other/2020/january.csv",      // not under the source
+		"https://mirror.contoso.com/datasets/2020/january.csv", // different host
+		"http://data.contoso.com/datasets/2020/january.csv",    // different scheme
+		"https://data.contoso.com/datasets/2020/january.csv?token=abc",
+	} {
+		_, err := relativePathFromHttpListEntry(source, entry)
+		c.Assert(err, chk.NotNil)
+	}
+}
+
+func (s *cmdIntegrationSuite) TestHttpTraverserSingleFile(c *chk.C) {
+	content := []byte("some dataset content")
+	contentMD5 := md5.Sum(content)
+	lmt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, chk.Equals, http.MethodHead)
+		if r.URL.Path != "/datasets/file.csv" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(contentMD5[:]))
+		w.Header().Set("Last-Modified", lmt.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	rawSource := server.URL + "/datasets/file.csv"
+	source, err := SplitResourceString(rawSource, common.ELocation.Http())
+	c.Assert(err, chk.IsNil)
+	credInfo := common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()}
+
+	traverser, err := initResourceTraverser(source, common.ELocation.Http(), &ctx, &credInfo, nil, nil, false, false, false, func(common.EntityType) {}, nil, false, common.CpkOptions{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(traverser.isDirectory(true), chk.Equals, false)
+
+	var objects []storedObject
+	err = traverser.traverse(noPreProccessor, func(object storedObject) error {
+		objects = append(objects, object)
+		return nil
+	}, nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(objects), chk.Equals, 1)
+	c.Assert(objects[0].name, chk.Equals, "file.csv")
+	c.Assert(objects[0].relativePath, chk.Equals, "")
+	c.Assert(objects[0].size, chk.Equals, int64(len(content)))
+	c.Assert(objects[0].contentType, chk.Equals, "text/csv")
+	c.Assert(objects[0].lastModifiedTime.Equal(lmt), chk.Equals, true)
+	c.Assert(objects[0].md5, chk.DeepEquals, contentMD5[:])
+
+	// a missing file is an error
+	source, _ = SplitResourceString(server.URL+"/datasets/missing.csv", common.ELocation.Http())
+	traverser, err = initResourceTraverser(source, common.ELocation.Http(), &ctx, &credInfo, nil, nil, false, false, false, func(common.EntityType) {}, nil, false, common.CpkOptions{})
+	c.Assert(err, chk.IsNil)
+	err = traverser.traverse(noPreProccessor, func(storedObject) error { return nil }, nil)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *cmdIntegrationSuite) TestHttpTraverserWithListOfFiles(c *chk.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	source, err := SplitResourceString(server.URL+"/datasets/", common.ELocation.Http())
+	c.Assert(err, chk.IsNil)
+	credInfo := common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()}
+
+	// without a list, a directory can't be enumerated
+	traverser, err := initResourceTraverser(source, common.ELocation.Http(), &ctx, &credInfo, nil, nil, true, false, false, func(common.EntityType) {}, nil, false, common.CpkOptions{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(traverser.isDirectory(true), chk.Equals, true)
+	err = traverser.traverse(noPreProccessor, func(storedObject) error { return nil }, nil)
+	c.Assert(err, chk.NotNil)
+
+	listChan := make(chan string, 2)
+	listChan <- "2020/january.csv"
+	listChan <- "2020/february.csv"
+	close(listChan)
+
+	traverser, err = initResourceTraverser(source, common.ELocation.Http(), &ctx, &credInfo, nil, listChan, true, false, false, func(common.EntityType) {}, nil, false, common.CpkOptions{})
+	c.Assert(err, chk.IsNil)
+
+	var relativePaths []string
+	err = traverser.traverse(noPreProccessor, func(object storedObject) error {
+		relativePaths = append(relativePaths, object.relativePath)
+		c.Assert(object.size, chk.Equals, int64(10))
+		return nil
+	}, nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(relativePaths, chk.DeepEquals, []string{"2020/january.csv", "2020/february.csv"})
+}
@@ -0,0 +1,182 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToLocalLocal(c *chk.C) {
+	srcDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(srcDirPath)
+	dstDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirPath)
+
+	c.Assert(inferFromTo(srcDirPath, dstDirPath), chk.Equals, common.EFromTo.LocalLocal())
+}
+
+func (s *cmdIntegrationSuite) TestCopyLocalDirectoryToLocal(c *chk.C) {
+	// set up the source with numerous files
+	srcDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(srcDirPath)
+	fileList := scenarioHelper{}.generateCommonRemoteScenarioForLocal(c, srcDirPath, "")
+
+	dstDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirPath)
+
+	// set up interceptor
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	// construct the raw input to simulate user input
+	raw := getDefaultCopyRawInput(srcDirPath, dstDirPath)
+	raw.recursive = true
+
+	// both ends are folder-aware, so the folders are transferred too, including the root (whose relative path is empty)
+	expectedTransfers := scenarioHelper{}.addPrefix(scenarioHelper{}.addFoldersToList(fileList, false), common.AZCOPY_PATH_SEPARATOR_STRING)
+	expectedTransfers = append(expectedTransfers, "")
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+
+		validateCopyTransfersAreScheduled(c, false, false, "",
+			common.AZCOPY_PATH_SEPARATOR_STRING+filepath.Base(srcDirPath), expectedTransfers, mockedRPC)
+	})
+
+	// with a trailing wildcard, the top directory is not recreated at the destination
+	mockedRPC.reset()
+	raw = getDefaultCopyRawInput(filepath.Join(srcDirPath, "*"), dstDirPath)
+	raw.recursive = true
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+
+		validateCopyTransfersAreScheduled(c, false, false, common.AZCOPY_PATH_SEPARATOR_STRING,
+			common.AZCOPY_PATH_SEPARATOR_STRING, scenarioHelper{}.addFoldersToList(fileList, false), mockedRPC)
+	})
+}
+
+func (s *cmdIntegrationSuite) TestCopyLocalDirectoryToLocalWithFilter(c *chk.C) {
+	srcDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(srcDirPath)
+	scenarioHelper{}.generateCommonRemoteScenarioForLocal(c, srcDirPath, "")
+	filesToInclude := []string{"important.pdf", "includeSub/amazing.pdf", "includeSub/wow/amazing.pdf"}
+	scenarioHelper{}.generateLocalFilesFromList(c, srcDirPath, filesToInclude)
+
+	dstDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirPath)
+
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	raw := getDefaultCopyRawInput(srcDirPath, dstDirPath)
+	raw.recursive = true
+	raw.include = "*.pdf"
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+
+		// the filter applies only to files, so no folders are transferred
+		validateCopyTransfersAreScheduled(c, false, false, common.AZCOPY_PATH_SEPARATOR_STRING,
+			common.AZCOPY_PATH_SEPARATOR_STRING+filepath.Base(srcDirPath)+common.AZCOPY_PATH_SEPARATOR_STRING, filesToInclude, mockedRPC)
+	})
+}
+
+func (s *cmdIntegrationSuite) TestCopyLocalToLocalRejectsBlobOnlyFlags(c *chk.C) {
+	srcDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(srcDirPath)
+	dstDirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirPath)
+
+	raw := getDefaultCopyRawInput(srcDirPath, dstDirPath)
+	raw.recursive = true
+	raw.blockBlobTier = common.EBlockBlobTier.Cool().String()
+
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *cmdIntegrationSuite) TestCopyLocalToLocalRejectsCopyOntoSource(c *chk.C) {
+	dirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirPath)
+	scenarioHelper{}.generateLocalFilesFromList(c, dirPath, []string{"a", "sub/b"})
+	filePath := filepath.Join(dirPath, "a")
+	c.Assert(os.Link(filePath, filepath.Join(dirPath, "hardlink")), chk.IsNil)
+
+	for _, t := range []struct {
+		src, dst  string
+		recursive bool
+	}{
+		{filePath, filePath, false},                              // the same file
+		{filePath, filepath.Join(dirPath, ".", "a"), false},      // by another path
+		{filePath, filepath.Join(dirPath, "hardlink"), false},    // by another name
+		{filePath, dirPath, false},                               // into the folder that it's in
+		{dirPath, filepath.Dir(dirPath), true},                   // likewise for a folder
+		{filepath.Join(dirPath, "*"), dirPath, false},            // the contents of a folder, into the folder
+		{filepath.Join(dirPath, "sub"), dirPath + "/sub/", true}, // a folder onto itself
+	} {
+		raw := getDefaultCopyRawInput(t.src, t.dst)
+		raw.recursive = t.recursive
+		_, err := raw.cook()
+		c.Assert(err, chk.NotNil, chk.Commentf("%s to %s", t.src, t.dst))
+	}
+
+	// the source is still there
+	_, err := os.Stat(filePath)
+	c.Assert(err, chk.IsNil)
+
+	// but a file can be copied to another one beside it
+	raw := getDefaultCopyRawInput(filePath, filepath.Join(dirPath, "copy"))
+	_, err = raw.cook()
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *cmdIntegrationSuite) TestCopyLocalToLocalRejectsCopyIntoSource(c *chk.C) {
+	dirPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirPath)
+	scenarioHelper{}.generateLocalFilesFromList(c, dirPath, []string{"a", "sub/b"})
+
+	for _, t := range []struct{ src, dst string }{
+		{dirPath, filepath.Join(dirPath, "sub")},                     // an existing folder inside the source
+		{dirPath, filepath.Join(dirPath, "new", "deeper")},           // or one that the copy would create
+		{filepath.Join(dirPath, "*"), filepath.Join(dirPath, "sub")}, // and with a wildcard
+	} {
+		raw := getDefaultCopyRawInput(t.src, t.dst)
+		raw.recursive = true
+		_, err := raw.cook()
+		c.Assert(err, chk.NotNil, chk.Commentf("%s to %s", t.src, t.dst))
+	}
+
+	// a folder that's beside the source, and merely has a name that starts the same way, is fine
+	sibling := dirPath + "-copy"
+	defer os.RemoveAll(sibling)
+	raw := getDefaultCopyRawInput(dirPath, sibling)
+	raw.recursive = true
+	_, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+}
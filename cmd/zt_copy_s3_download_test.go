@@ -0,0 +1,222 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestInferFromToS3Local(c *chk.C) {
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	c.Assert(inferFromTo("https://s3.amazonaws.com/bucket/object", dstDirName), chk.Equals, common.EFromTo.S3Local())
+	c.Assert(inferFromTo("https://s3-us-west-2.amazonaws.com/bucket", filepath.Join(dstDirName, "file")), chk.Equals, common.EFromTo.S3Local())
+}
+
+func (s *cmdIntegrationSuite) TestDownloadS3BucketToLocal(c *chk.C) {
+	skipIfS3Disabled(c)
+	s3Client, err := createS3ClientWithMinio(createS3ResOptions{})
+	if err != nil {
+		c.Skip("S3 client credentials not supplied")
+	}
+
+	// set up the source bucket with the common scenario
+	bucketName := generateBucketName()
+	createNewBucketWithName(c, s3Client, bucketName, createS3ResOptions{})
+	defer deleteBucket(c, s3Client, bucketName, true)
+
+	objectList := scenarioHelper{}.generateCommonRemoteScenarioForS3(c, s3Client, bucketName, "", false)
+	c.Assert(len(objectList), chk.Not(chk.Equals), 0)
+
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	// set up interceptor
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	// construct the raw input to simulate user input
+	rawSrcS3BucketURL := scenarioHelper{}.getRawS3BucketURL(c, "", bucketName) // Use default region
+	raw := getDefaultCopyRawInput(rawSrcS3BucketURL.String(), dstDirName)
+	raw.recursive = true
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+
+		// every object should be downloaded into a directory named after the bucket
+		validateDownloadTransfersAreScheduled(c, common.AZCOPY_PATH_SEPARATOR_STRING,
+			common.AZCOPY_PATH_SEPARATOR_STRING+bucketName+common.AZCOPY_PATH_SEPARATOR_STRING, objectList, mockedRPC)
+	})
+}
+
+func (s *cmdIntegrationSuite) TestDownloadS3ObjectWithDecompress(c *chk.C) {
+	skipIfS3Disabled(c)
+	s3Client, err := createS3ClientWithMinio(createS3ResOptions{})
+	if err != nil {
+		c.Skip("S3 client credentials not supplied")
+	}
+
+	bucketName := generateBucketName()
+	createNewBucketWithName(c, s3Client, bucketName, createS3ResOptions{})
+	defer deleteBucket(c, s3Client, bucketName, true)
+
+	objectName := "compressed.txt.gz"
+	scenarioHelper{}.generateObjects(c, s3Client, bucketName, []string{objectName})
+
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	// set up interceptor
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	rawSrcS3ObjectURL := scenarioHelper{}.getRawS3ObjectURL(c, "", bucketName, objectName)
+	raw := getDefaultCopyRawInput(rawSrcS3ObjectURL.String(), dstDirName)
+	raw.autoDecompress = true
+
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo, chk.Equals, common.EFromTo.S3Local())
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(len(mockedRPC.transfers), chk.Equals, 1)
+	})
+}
+
+// The tests below run against an in-process fake of the S3 API, so they need no credentials.
+
+// tinyBlockSizeMB is 16 bytes, so that all but the smallest objects are downloaded in several ranges
+const tinyBlockSizeMB = "0.0000152587890625"
+
+func (s *cmdIntegrationSuite) TestDownloadS3BucketToLocalWithFakeS3(c *chk.C) {
+	fakeS3 := newFakeS3Server()
+	defer fakeS3.Close()
+	defer fakeS3.setEnv(c)()
+
+	bucketName := "fakebucket"
+	objects := map[string]string{
+		"file1.txt":            "one",
+		"dir/file2.txt":        "two",
+		"dir/sub/file3.txt":    "three",
+		"dir/with space.txt":   "four, which is long enough to be downloaded in a few ranges",
+		"other/deeper/x/y.bin": "five, which is longer still, so that it takes more ranges than four does",
+	}
+	fakeS3.putObjects(bucketName, objects)
+	expectedList := make([]string, 0, len(objects))
+	for key := range objects {
+		expectedList = append(expectedList, key)
+	}
+
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	raw := getDefaultCopyRawInput(fakeS3.URL+"/"+bucketName, dstDirName)
+	raw.recursive = true
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(mockedRPC.lastRequest.(*common.CopyJobPartOrderRequest).FromTo, chk.Equals, common.EFromTo.S3Local())
+
+		validateDownloadTransfersAreScheduled(c, common.AZCOPY_PATH_SEPARATOR_STRING,
+			common.AZCOPY_PATH_SEPARATOR_STRING+bucketName+common.AZCOPY_PATH_SEPARATOR_STRING, expectedList, mockedRPC)
+		for _, transfer := range mockedRPC.transfers {
+			key, _ := url.PathUnescape(strings.TrimPrefix(transfer.Source, "/"))
+			c.Assert(transfer.SourceSize, chk.Equals, int64(len(objects[key])))
+		}
+	})
+
+	// without recursion, only the objects at the top of the bucket are downloaded
+	mockedRPC.reset()
+	raw = getDefaultCopyRawInput(fakeS3.URL+"/"+bucketName+"/*", dstDirName)
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		validateDownloadTransfersAreScheduled(c, common.AZCOPY_PATH_SEPARATOR_STRING,
+			common.AZCOPY_PATH_SEPARATOR_STRING, []string{"file1.txt"}, mockedRPC)
+	})
+
+	// the transfer engine downloads every object, a range at a time, into a folder named after the bucket
+	output, err := runAzCopyProcess(c, "copy", fakeS3.URL+"/"+bucketName, dstDirName, "--recursive", "--block-size-mb="+tinyBlockSizeMB)
+	c.Assert(err, chk.IsNil, chk.Commentf(output))
+	for key, content := range objects {
+		downloaded, err := ioutil.ReadFile(filepath.Join(dstDirName, bucketName, filepath.FromSlash(key)))
+		c.Assert(err, chk.IsNil)
+		c.Assert(string(downloaded), chk.Equals, content)
+	}
+}
+
+func (s *cmdIntegrationSuite) TestDownloadS3ObjectWithFakeS3(c *chk.C) {
+	fakeS3 := newFakeS3Server()
+	defer fakeS3.Close()
+	defer fakeS3.setEnv(c)()
+
+	bucketName := "fakebucket"
+	content := "the quick brown fox jumps over the lazy dog"
+	fakeS3.putObjects(bucketName, map[string]string{"dir/fox.txt": content})
+
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	rawSrcURL := fakeS3.URL + "/" + bucketName + "/dir/fox.txt"
+	raw := getDefaultCopyRawInput(rawSrcURL, dstDirName)
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(len(mockedRPC.transfers), chk.Equals, 1)
+		c.Assert(mockedRPC.transfers[0].SourceSize, chk.Equals, int64(len(content)))
+		c.Assert(mockedRPC.transfers[0].Destination, chk.Equals, common.AZCOPY_PATH_SEPARATOR_STRING+"fox.txt")
+	})
+
+	// the transfer engine downloads the object a range at a time
+	output, err := runAzCopyProcess(c, "copy", rawSrcURL, dstDirName, "--block-size-mb="+tinyBlockSizeMB)
+	c.Assert(err, chk.IsNil, chk.Commentf(output))
+	downloaded, err := ioutil.ReadFile(filepath.Join(dstDirName, "fox.txt"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(downloaded), chk.Equals, content)
+
+	// an object that changes part way through its download fails, rather than leaving a mix of the old and the new
+	fakeS3.putObjects(bucketName, map[string]string{"dir/changing.txt": content})
+	fakeS3.changeAfterFirstRead(bucketName, "dir/changing.txt")
+	output, err = runAzCopyProcess(c, "copy", fakeS3.URL+"/"+bucketName+"/dir/changing.txt", dstDirName, "--block-size-mb="+tinyBlockSizeMB)
+	c.Assert(err, chk.NotNil, chk.Commentf(output))
+	c.Assert(output, chk.Matches, "(?s).*Number of Transfers Failed: 1.*")
+	_, err = os.Stat(filepath.Join(dstDirName, "changing.txt"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
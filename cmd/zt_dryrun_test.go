@@ -0,0 +1,146 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type dryrunSuite struct{}
+
+var _ = chk.Suite(&dryrunSuite{})
+
+// drains whatever has been written to the channel so far
+func collectDryrunOutput(log chan string) []string {
+	output := make([]string, 0)
+	for {
+		select {
+		case line := <-log:
+			output = append(output, line)
+		default:
+			return output
+		}
+	}
+}
+
+func (s *dryrunSuite) TestSyncDryrunReportsInsteadOfTransferring(c *chk.C) {
+	mockedLcm := &mockedLifecycleManager{dryrunLog: make(chan string, 50), exitLog: make(chan string, 1), progressLog: make(chan string, 50)}
+	previousLcm := glcm
+	glcm = mockedLcm
+	defer func() { glcm = previousLcm }()
+
+	raw := getDefaultSyncRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.deleteDestination = "true"
+	raw.dryrun = true
+	cca, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cca.dryrunReporter, chk.NotNil)
+
+	fpo := common.EFolderPropertiesOption.NoFolders()
+	transferScheduler := newSyncTransferProcessor(&cca, NumOfFilesPerDispatchJobPart, fpo)
+	destinationCleaner, err := cca.newDestinationCleaner(fpo)
+	c.Assert(err, chk.IsNil)
+
+	now := time.Now()
+	source := &dummySortedTraverser{objects: []storedObject{
+		{relativePath: "a", lastModifiedTime: now, size: 1},                  // only at source
+		{relativePath: "b", lastModifiedTime: now.Add(time.Hour), size: 10},  // newer at source
+		{relativePath: "c", lastModifiedTime: now.Add(-time.Hour), size: 20}, // older at source
+	}}
+	destination := &dummySortedTraverser{objects: []storedObject{
+		{relativePath: "b", lastModifiedTime: now},
+		{relativePath: "c", lastModifiedTime: now},
+		{relativePath: "d", lastModifiedTime: now}, // only at destination
+	}}
+
+	comparator := newSyncMergeComparator(transferScheduler.scheduleCopyTransfer, destinationCleaner, cca.dryrunReporter.noteOverwrite(lmtChangeDetector{}))
+	enumerator := newSyncMergeEnumerator(source, destination, nil, comparator, func() error {
+		jobInitiated, err := transferScheduler.dispatchFinalPart()
+		c.Assert(err, chk.IsNil)
+		c.Assert(jobInitiated, chk.Equals, false)
+		quitIfInSync(jobInitiated, false, &cca)
+		return nil
+	})
+	c.Assert(enumerator.enumerate(), chk.IsNil)
+
+	// nothing was queued for the transfer engine
+	c.Assert(transferScheduler.copyJobTemplate.Transfers, chk.HasLen, 0)
+
+	c.Assert(collectDryrunOutput(mockedLcm.dryrunLog), chk.DeepEquals, []string{
+		"DRYRUN: copy /src/a to https://account.blob.core.windows.net/container/a",
+		"DRYRUN: overwrite https://account.blob.core.windows.net/container/b with /src/b",
+		"DRYRUN: delete https://account.blob.core.windows.net/container/d",
+	})
+	c.Assert(cca.dryrunReporter.copies, chk.Equals, uint64(1))
+	c.Assert(cca.dryrunReporter.overwrites, chk.Equals, uint64(1))
+	c.Assert(cca.dryrunReporter.deletions, chk.Equals, uint64(1))
+	c.Assert(cca.dryrunReporter.totalBytes, chk.Equals, uint64(11))
+	c.Assert(<-mockedLcm.exitLog, chk.Matches, "(?s)Dry run complete.*Overwrites: 1.*Deletions: 1.*")
+}
+
+func (s *dryrunSuite) TestSyncDryrunLeavesDeletionsOutUnlessAsked(c *chk.C) {
+	mockedLcm := &mockedLifecycleManager{dryrunLog: make(chan string, 50)}
+	previousLcm := glcm
+	glcm = mockedLcm
+	defer func() { glcm = previousLcm }()
+
+	raw := getDefaultSyncRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.deleteDestination = "false"
+	raw.dryrun = true
+	cca, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+
+	destinationCleaner, err := cca.newDestinationCleaner(common.EFolderPropertiesOption.NoFolders())
+	c.Assert(err, chk.IsNil)
+	c.Assert(destinationCleaner(storedObject{relativePath: "d"}), chk.IsNil)
+	c.Assert(collectDryrunOutput(mockedLcm.dryrunLog), chk.HasLen, 0)
+	c.Assert(cca.dryrunReporter.deletions, chk.Equals, uint64(0))
+}
+
+func (s *dryrunSuite) TestDryrunCannotBeUsedWithTwoWaySync(c *chk.C) {
+	raw := getDefaultSyncRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.dryrun = true
+	raw.bidirectional = true
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *dryrunSuite) TestRemoveDryrunReportsVersions(c *chk.C) {
+	mockedLcm := &mockedLifecycleManager{dryrunLog: make(chan string, 50)}
+	previousLcm := glcm
+	glcm = mockedLcm
+	defer func() { glcm = previousLcm }()
+
+	reporter := newDryrunReporter()
+	reportDeletion := reporter.deletionProcessor(common.ResourceString{Value: "https://account.blob.core.windows.net/container/dir"})
+	c.Assert(reportDeletion(storedObject{relativePath: "blob"}), chk.IsNil)
+	c.Assert(reportDeletion(storedObject{relativePath: "", blobVersionID: "2020-01-01T00:00:00.0000000Z"}), chk.IsNil)
+
+	c.Assert(collectDryrunOutput(mockedLcm.dryrunLog), chk.DeepEquals, []string{
+		"DRYRUN: delete https://account.blob.core.windows.net/container/dir/blob",
+		"DRYRUN: delete https://account.blob.core.windows.net/container/dir?versionid=2020-01-01T00:00:00.0000000Z",
+	})
+	c.Assert(reporter.deletions, chk.Equals, uint64(2))
+}
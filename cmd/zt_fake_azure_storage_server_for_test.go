@@ -0,0 +1,440 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// fakeAzureStorageAccount is the account name that the fake's URLs carry in their path, as with any IP-addressed endpoint
+const fakeAzureStorageAccount = "fakeaccount"
+
+// fakeAzureStorageServer is an in-process stand-in for the parts of the Blob, Azure Files or ADLS Gen2 (DFS) API
+// that the traversers and deleters of sync use: listing, getting the properties of files and folders, and deleting files.
+// It serves one of the three, depending on its location. Folders only exist by virtue of the files in them.
+// For blobs, it also copies within the server, always finishing the copy at once, even when asked for an asynchronous one.
+// Authorization isn't checked, so the URLs can carry any SAS.
+type fakeAzureStorageServer struct {
+	*httptest.Server
+	location common.Location
+
+	lock       sync.Mutex
+	containers map[string]map[string]fakeAzureStorageFile
+	deleted    []string
+	copies     []fakeAzureStorageCopy
+}
+
+type fakeAzureStorageCopy struct {
+	source, destination string // as container/name
+	synchronous         bool
+}
+
+type fakeAzureStorageFile struct {
+	size         int64
+	lastModified time.Time
+	blobType     azblob.BlobType
+}
+
+func newFakeAzureStorageServer(location common.Location) *fakeAzureStorageServer {
+	f := &fakeAzureStorageServer{
+		location:   location,
+		containers: make(map[string]map[string]fakeAzureStorageFile),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// resourceURL is the URL of the path (i.e. container, share or file system, followed by the path within it), with a dummy SAS
+func (f *fakeAzureStorageServer) resourceURL(path string) string {
+	return f.URL + "/" + fakeAzureStorageAccount + "/" + path + "?sv=2019-12-12&sig=fakeSignature"
+}
+
+func (f *fakeAzureStorageServer) putFile(container, name string, size int64, lastModified time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.containers[container] == nil {
+		f.containers[container] = make(map[string]fakeAzureStorageFile)
+	}
+	f.containers[container][name] = fakeAzureStorageFile{size: size, lastModified: lastModified, blobType: azblob.BlobBlockBlob}
+}
+
+// putPageBlob puts a blob that isn't a block blob
+func (f *fakeAzureStorageServer) putPageBlob(container, name string, size int64, lastModified time.Time) {
+	f.putFile(container, name, size, lastModified)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file := f.containers[container][name]
+	file.blobType = azblob.BlobPageBlob
+	f.containers[container][name] = file
+}
+
+// deletedFiles lists the files that have been deleted, as container/name
+func (f *fakeAzureStorageServer) deletedFiles() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return append([]string(nil), f.deleted...)
+}
+
+// copiesMade lists the blobs that have been copied, in the order they were
+func (f *fakeAzureStorageServer) copiesMade() []fakeAzureStorageCopy {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return append([]fakeAzureStorageCopy(nil), f.copies...)
+}
+
+func (f *fakeAzureStorageServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// only IP-style requests are expected, so the path starts with the account name
+	segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(segments) < 2 || segments[0] != fakeAzureStorageAccount {
+		f.writeError(w, http.StatusBadRequest, "InvalidUri")
+		return
+	}
+	containerName, name := segments[1], ""
+	if len(segments) == 3 {
+		name = strings.TrimSuffix(segments[2], "/")
+	}
+
+	container, ok := f.containers[containerName]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, map[common.Location]string{
+			common.ELocation.Blob():   "ContainerNotFound",
+			common.ELocation.File():   "ShareNotFound",
+			common.ELocation.BlobFS(): "FilesystemNotFound",
+		}[f.location])
+		return
+	}
+
+	query := r.URL.Query()
+	switch f.location {
+	case common.ELocation.Blob():
+		switch {
+		case r.Method == http.MethodGet && query.Get("comp") == "list":
+			f.listBlobs(w, containerName, container, query.Get("prefix"), query.Get("delimiter"))
+		case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+			f.copyBlob(w, r, containerName, name)
+		case r.Method == http.MethodHead:
+			f.writeFileProperties(w, container, name, "BlobNotFound")
+		case r.Method == http.MethodDelete:
+			f.deleteFile(w, containerName, container, name, "BlobNotFound")
+		default:
+			f.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+		}
+	case common.ELocation.File():
+		switch {
+		case r.Method == http.MethodGet && query.Get("restype") == "directory" && query.Get("comp") == "list":
+			f.listDirectory(w, containerName, container, name)
+		case r.Method == http.MethodGet && query.Get("restype") == "directory":
+			if !f.isFolder(container, name) {
+				f.writeError(w, http.StatusNotFound, "ResourceNotFound")
+				return
+			}
+			w.Header().Set("Last-Modified", time.Time{}.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead:
+			w.Header().Set("x-ms-type", "File")
+			f.writeFileProperties(w, container, name, "ResourceNotFound")
+		case r.Method == http.MethodDelete:
+			f.deleteFile(w, containerName, container, name, "ResourceNotFound")
+		default:
+			f.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+		}
+	case common.ELocation.BlobFS():
+		switch {
+		case r.Method == http.MethodGet && query.Get("resource") == "filesystem":
+			f.listPaths(w, container, query.Get("directory"), query.Get("recursive") == "true")
+		case r.Method == http.MethodHead && f.isFolder(container, name):
+			w.Header().Set("x-ms-resource-type", "directory")
+			w.Header().Set("Last-Modified", time.Time{}.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead:
+			w.Header().Set("x-ms-resource-type", "file")
+			f.writeFileProperties(w, container, name, "PathNotFound")
+		case r.Method == http.MethodDelete:
+			f.deleteFile(w, containerName, container, name, "PathNotFound")
+		default:
+			f.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+		}
+	}
+}
+
+// isFolder says whether there are any files inside the folder, or whether it's the root
+func (f *fakeAzureStorageServer) isFolder(container map[string]fakeAzureStorageFile, name string) bool {
+	if name == "" {
+		return true
+	}
+	for fileName := range container {
+		if strings.HasPrefix(fileName, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeAzureStorageServer) writeFileProperties(w http.ResponseWriter, container map[string]fakeAzureStorageFile, name, notFoundCode string) {
+	file, ok := container[name]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, notFoundCode)
+		return
+	}
+
+	w.Header().Set("Last-Modified", file.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.FormatInt(file.size, 10))
+	w.Header().Set("x-ms-blob-type", string(file.blobType))
+	w.Header().Set("x-ms-copy-status", "success")
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyBlob copies the blob at the x-ms-copy-source URL, which must be on this server, to the container and name
+func (f *fakeAzureStorageServer) copyBlob(w http.ResponseWriter, r *http.Request, containerName, name string) {
+	sourceURL, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+	if err != nil {
+		f.writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+		return
+	}
+	segments := strings.SplitN(strings.TrimPrefix(sourceURL.Path, "/"), "/", 3)
+	if len(segments) != 3 || segments[0] != fakeAzureStorageAccount {
+		f.writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+		return
+	}
+	source, ok := f.containers[segments[1]][segments[2]]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "CannotVerifyCopySource")
+		return
+	}
+
+	f.containers[containerName][name] = source
+	f.copies = append(f.copies, fakeAzureStorageCopy{
+		source:      segments[1] + "/" + segments[2],
+		destination: containerName + "/" + name,
+		synchronous: r.Header.Get("x-ms-requires-sync") == "true",
+	})
+	w.Header().Set("x-ms-copy-id", strconv.Itoa(len(f.copies)))
+	w.Header().Set("x-ms-copy-status", "success")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fakeAzureStorageServer) deleteFile(w http.ResponseWriter, containerName string, container map[string]fakeAzureStorageFile, name, notFoundCode string) {
+	if _, ok := container[name]; !ok {
+		f.writeError(w, http.StatusNotFound, notFoundCode)
+		return
+	}
+
+	delete(container, name)
+	f.deleted = append(f.deleted, containerName+"/"+name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sortedNames lists the files under the prefix in order
+func (f *fakeAzureStorageServer) sortedNames(container map[string]fakeAzureStorageFile, prefix string) []string {
+	names := make([]string, 0, len(container))
+	for name := range container {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type fakeBlobProperties struct {
+	LastModified  string `xml:"Last-Modified"`
+	Etag          string
+	ContentLength int64 `xml:"Content-Length"`
+	BlobType      string
+}
+
+type fakeBlob struct {
+	Name       string
+	Properties fakeBlobProperties
+}
+
+type fakeBlobPrefix struct {
+	Name string
+}
+
+type fakeBlobListResult struct {
+	XMLName       xml.Name `xml:"EnumerationResults"`
+	ContainerName string   `xml:"ContainerName,attr"`
+	Prefix        string
+	Delimiter     string
+	Blobs         []fakeBlob       `xml:"Blobs>Blob"`
+	BlobPrefixes  []fakeBlobPrefix `xml:"Blobs>BlobPrefix"`
+	NextMarker    string
+}
+
+// listBlobs answers both flat and hierarchical listings in one page
+func (f *fakeAzureStorageServer) listBlobs(w http.ResponseWriter, containerName string, container map[string]fakeAzureStorageFile, prefix, delimiter string) {
+	result := fakeBlobListResult{ContainerName: containerName, Prefix: prefix, Delimiter: delimiter}
+
+	seenPrefixes := make(map[string]bool)
+	for _, name := range f.sortedNames(container, prefix) {
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i != -1 {
+				blobPrefix := name[:len(prefix)+i+len(delimiter)]
+				if !seenPrefixes[blobPrefix] {
+					seenPrefixes[blobPrefix] = true
+					result.BlobPrefixes = append(result.BlobPrefixes, fakeBlobPrefix{Name: blobPrefix})
+				}
+				continue
+			}
+		}
+		result.Blobs = append(result.Blobs, fakeBlob{Name: name, Properties: fakeBlobProperties{
+			LastModified:  container[name].lastModified.Format(http.TimeFormat),
+			Etag:          "0x" + strconv.FormatInt(container[name].lastModified.UnixNano(), 16),
+			ContentLength: container[name].size,
+			BlobType:      string(container[name].blobType),
+		}})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+type fakeFileProperties struct {
+	ContentLength int64 `xml:"Content-Length"`
+}
+
+type fakeFileEntry struct {
+	Name       string
+	Properties fakeFileProperties
+}
+
+type fakeDirectoryEntry struct {
+	Name string
+}
+
+type fakeFileListResult struct {
+	XMLName       xml.Name             `xml:"EnumerationResults"`
+	ShareName     string               `xml:"ShareName,attr"`
+	DirectoryPath string               `xml:"DirectoryPath,attr"`
+	Files         []fakeFileEntry      `xml:"Entries>File"`
+	Directories   []fakeDirectoryEntry `xml:"Entries>Directory"`
+	NextMarker    string
+}
+
+// listDirectory lists the files and folders directly inside the directory, in one page
+func (f *fakeAzureStorageServer) listDirectory(w http.ResponseWriter, shareName string, share map[string]fakeAzureStorageFile, directory string) {
+	if !f.isFolder(share, directory) {
+		f.writeError(w, http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	prefix := ""
+	if directory != "" {
+		prefix = directory + "/"
+	}
+	result := fakeFileListResult{ShareName: shareName, DirectoryPath: directory}
+
+	seenDirectories := make(map[string]bool)
+	for _, name := range f.sortedNames(share, prefix) {
+		childName := name[len(prefix):]
+		if i := strings.Index(childName, "/"); i != -1 {
+			if !seenDirectories[childName[:i]] {
+				seenDirectories[childName[:i]] = true
+				result.Directories = append(result.Directories, fakeDirectoryEntry{Name: childName[:i]})
+			}
+			continue
+		}
+		result.Files = append(result.Files, fakeFileEntry{Name: childName, Properties: fakeFileProperties{ContentLength: share[name].size}})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+type fakePath struct {
+	Name          string `json:"name"`
+	IsDirectory   string `json:"isDirectory,omitempty"`
+	LastModified  string `json:"lastModified"`
+	ContentLength string `json:"contentLength,omitempty"`
+}
+
+// listPaths lists what's inside the directory, in one page, including the folders
+func (f *fakeAzureStorageServer) listPaths(w http.ResponseWriter, fileSystem map[string]fakeAzureStorageFile, directory string, recursive bool) {
+	if !f.isFolder(fileSystem, directory) {
+		f.writeError(w, http.StatusNotFound, "PathNotFound")
+		return
+	}
+
+	prefix := ""
+	if directory != "" {
+		prefix = directory + "/"
+	}
+	paths := make([]fakePath, 0)
+
+	seenDirectories := make(map[string]bool)
+	for _, name := range f.sortedNames(fileSystem, prefix) {
+		// the folders above the file, that are inside the directory, are listed before it
+		parts := strings.Split(name[len(prefix):], "/")
+		for depth := 1; depth < len(parts) && (recursive || depth == 1); depth++ {
+			folder := prefix + strings.Join(parts[:depth], "/")
+			if !seenDirectories[folder] {
+				seenDirectories[folder] = true
+				paths = append(paths, fakePath{Name: folder, IsDirectory: "true", LastModified: time.Time{}.Format(http.TimeFormat)})
+			}
+		}
+		if !recursive && len(parts) > 1 {
+			continue
+		}
+		paths = append(paths, fakePath{
+			Name:          name,
+			LastModified:  fileSystem[name].lastModified.Format(http.TimeFormat),
+			ContentLength: strconv.FormatInt(fileSystem[name].size, 10),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string][]fakePath{"paths": paths})
+}
+
+func (f *fakeAzureStorageServer) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	if f.location == common.ELocation.BlobFS() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `{"error":{"code":"%s","message":"%s"}}`, code, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
@@ -0,0 +1,277 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+// fakeS3Server is an in-process stand-in for the parts of the S3 XML API that AzCopy reads from:
+// listing (both versions), getting the properties of objects, and downloading them, optionally by range and
+// only if unmodified since a given time. Since GCS speaks the same API, it stands in for that too. Signatures aren't checked,
+// but when authorization is set, requests must carry that Authorization header, as with OAuth tokens.
+type fakeS3Server struct {
+	*httptest.Server
+	lastModified  time.Time
+	authorization string
+
+	lock    sync.Mutex
+	buckets map[string]map[string][]byte
+
+	// changingObjects are changed, as if by someone else, as soon as a first range of them has been read.
+	// Their new last modified times are in changedAt.
+	changingObjects map[string]bool
+	changedAt       map[string]time.Time
+}
+
+func newFakeS3Server() *fakeS3Server {
+	f := &fakeS3Server{
+		buckets:         make(map[string]map[string][]byte),
+		lastModified:    time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		changingObjects: make(map[string]bool),
+		changedAt:       make(map[string]time.Time),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// host is the address of the server, suitable for AZCOPY_S3_ENDPOINT
+func (f *fakeS3Server) host() string {
+	u, _ := url.Parse(f.URL)
+	return u.Host
+}
+
+func (f *fakeS3Server) putObjects(bucket string, objects map[string]string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = make(map[string][]byte)
+	}
+	for key, content := range objects {
+		f.buckets[bucket][key] = []byte(content)
+	}
+}
+
+// changeAfterFirstRead makes the object change at the source as soon as a first range of it has been downloaded
+func (f *fakeS3Server) changeAfterFirstRead(bucket, key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.changingObjects[bucket+"/"+key] = true
+}
+
+type fakeS3Object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+	StorageClass string
+}
+
+type fakeS3Prefix struct {
+	Prefix string
+}
+
+type fakeS3ListResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Name           string
+	Prefix         string
+	Delimiter      string
+	MaxKeys        int
+	KeyCount       int
+	IsTruncated    bool
+	Contents       []fakeS3Object
+	CommonPrefixes []fakeS3Prefix
+}
+
+func (f *fakeS3Server) handle(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.authorization != "" && r.Header.Get("Authorization") != f.authorization {
+		f.writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	// only path-style requests are expected
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucketName, key := path, ""
+	if i := strings.Index(path, "/"); i != -1 {
+		bucketName, key = path[:i], path[i+1:]
+	}
+
+	bucket, ok := f.buckets[bucketName]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if key == "" {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case query.Get("location") != "" || strings.Contains(r.URL.RawQuery, "location"):
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint></LocationConstraint>`)
+		default:
+			f.list(w, bucketName, bucket, query.Get("prefix"), query.Get("delimiter"))
+		}
+		return
+	}
+
+	content, ok := bucket[key]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	lastModified, changed := f.changedAt[path]
+	if !changed {
+		lastModified = f.lastModified
+	}
+	if ifUnmodifiedSince, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && lastModified.After(ifUnmodifiedSince) {
+		f.writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	w.Header().Set("ETag", `"`+strconv.Itoa(len(content))+`"`)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	switch r.Method {
+	case http.MethodHead:
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		start, end := 0, len(content)-1
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			_, _ = fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end)
+			if end >= len(content) {
+				end = len(content) - 1
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+		}
+		_, _ = w.Write(content[start : end+1])
+
+		if f.changingObjects[path] && !changed {
+			f.changedAt[path] = lastModified.Add(time.Hour)
+		}
+	default:
+		f.writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list answers both versions of the listing in one page, which suits both since the fields they share are the same
+func (f *fakeS3Server) list(w http.ResponseWriter, bucketName string, bucket map[string][]byte, prefix, delimiter string) {
+	result := fakeS3ListResult{Name: bucketName, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+
+	keys := make([]string, 0, len(bucket))
+	for key := range bucket {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	seenPrefixes := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i != -1 {
+				commonPrefix := key[:len(prefix)+i+len(delimiter)]
+				if !seenPrefixes[commonPrefix] {
+					seenPrefixes[commonPrefix] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, fakeS3Prefix{Prefix: commonPrefix})
+				}
+				continue
+			}
+		}
+		result.Contents = append(result.Contents, fakeS3Object{
+			Key:          key,
+			LastModified: f.lastModified.Format(time.RFC3339),
+			ETag:         `"` + strconv.Itoa(len(bucket[key])) + `"`,
+			Size:         len(bucket[key]),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3Server) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// setEnv points S3 URLs on the fake server at it, with dummy credentials, and returns a func to undo that
+func (f *fakeS3Server) setEnv(c *chk.C) func() {
+	vars := map[string]string{
+		"AZCOPY_S3_ENDPOINT":    f.host(),
+		"AZCOPY_S3_REGION":      "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "fakeAccessKey",
+		"AWS_SECRET_ACCESS_KEY": "fakeSecretKey",
+	}
+	return setEnvForTest(c, vars)
+}
+
+// setEnvForTest sets the environment variables, and returns a func that restores their old values
+func setEnvForTest(c *chk.C, vars map[string]string) func() {
+	old := make(map[string]*string, len(vars))
+	for name, value := range vars {
+		if oldValue, ok := os.LookupEnv(name); ok {
+			old[name] = &oldValue
+		} else {
+			old[name] = nil
+		}
+		c.Assert(os.Setenv(name, value), chk.IsNil)
+	}
+
+	return func() {
+		for name, oldValue := range old {
+			if oldValue == nil {
+				_ = os.Unsetenv(name)
+			} else {
+				_ = os.Setenv(name, *oldValue)
+			}
+		}
+	}
+}
@@ -26,6 +26,8 @@ import (
 	chk "gopkg.in/check.v1"
 	"strings"
 	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 type genericFilterSuite struct{}
@@ -78,6 +80,18 @@ func (s *genericFilterSuite) TestExcludeFilter(c *chk.C) {
 	}
 }
 
+func (s *genericFilterSuite) TestIncludeBlobTagsFilter(c *chk.C) {
+	filter := &includeBlobTagsFilter{tags: common.BlobTags{"project": "apollo", "stage": "final"}}
+
+	// a blob must have all of the tags, though it may have others too
+	c.Assert(filter.doesPass(storedObject{blobTags: common.BlobTags{"project": "apollo", "stage": "final"}}), chk.Equals, true)
+	c.Assert(filter.doesPass(storedObject{blobTags: common.BlobTags{"project": "apollo", "stage": "final", "owner": "x"}}), chk.Equals, true)
+
+	c.Assert(filter.doesPass(storedObject{blobTags: common.BlobTags{"project": "apollo"}}), chk.Equals, false)
+	c.Assert(filter.doesPass(storedObject{blobTags: common.BlobTags{"project": "apollo", "stage": "draft"}}), chk.Equals, false)
+	c.Assert(filter.doesPass(storedObject{}), chk.Equals, false)
+}
+
 func (s *genericFilterSuite) TestDateParsingForIncludeAfter(c *chk.C) {
 	examples := []struct {
 		input                 string // ISO 8601
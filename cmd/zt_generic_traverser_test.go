@@ -23,8 +23,10 @@ package cmd
 import (
 	"context"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -171,6 +173,57 @@ func (s *genericTraverserSuite) TestS3GetProperties(c *chk.C) {
 }
 
 // Test follow symlink functionality
+// The merge comparator relies on S3 listing keys in the same (byte-wise) order as local folders are walked in order
+func (s *genericTraverserSuite) TestS3TraverseSortedMatchesLocalOrder(c *chk.C) {
+	fakeS3 := newFakeS3Server()
+	defer fakeS3.Close()
+	defer fakeS3.setEnv(c)()
+
+	// '-' and '.' come before '/', upper case before lower case, and non-ASCII characters after all of them
+	keys := []string{"b", "a-b", "a.txt", "a/x", "a/y/z", "a/\u00fc", "\u00e9.txt", "Z", "z/\u65e5\u672c", "a b/c"}
+	objects := make(map[string]string)
+	for _, key := range keys {
+		objects[key] = key
+	}
+	fakeS3.putObjects("fakebucket", objects)
+
+	bucketURL, err := url.Parse(fakeS3.URL + "/fakebucket")
+	c.Assert(err, chk.IsNil)
+	s3Traverser, err := newS3Traverser(bucketURL, context.TODO(), true, false, func(common.EntityType) {})
+	c.Assert(err, chk.IsNil)
+	c.Assert(s3Traverser.canTraverseSorted(), chk.Equals, true)
+
+	s3Processor := dummyProcessor{}
+	c.Assert(s3Traverser.traverseSorted(noPreProccessor, s3Processor.process, nil), chk.IsNil)
+	s3Paths := make([]string, 0)
+	for _, object := range s3Processor.record {
+		s3Paths = append(s3Paths, object.relativePath)
+	}
+
+	// the same tree, walked locally
+	root, err := ioutil.TempDir("", "sortedtraversal")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(root)
+	for _, key := range keys {
+		fullPath := filepath.Join(root, filepath.FromSlash(key))
+		c.Assert(os.MkdirAll(filepath.Dir(fullPath), os.ModePerm), chk.IsNil)
+		c.Assert(ioutil.WriteFile(fullPath, []byte(key), 0644), chk.IsNil)
+	}
+	localProcessor := dummyProcessor{}
+	c.Assert(newLocalTraverser(root, true, false, nil).traverseSorted(noPreProccessor, localProcessor.process, nil), chk.IsNil)
+	localPaths := make([]string, 0)
+	for _, object := range localProcessor.record {
+		if object.entityType == common.EEntityType.File() {
+			localPaths = append(localPaths, object.relativePath)
+		}
+	}
+
+	expectedPaths := append([]string{}, keys...)
+	sort.Strings(expectedPaths)
+	c.Assert(s3Paths, chk.DeepEquals, expectedPaths)
+	c.Assert(localPaths, chk.DeepEquals, expectedPaths)
+}
+
 func (s *genericTraverserSuite) TestWalkWithSymlinks_ToFolder(c *chk.C) {
 	fileNames := []string{"March 20th is international happiness day.txt", "wonderwall but it goes on and on and on.mp3", "bonzi buddy.exe"}
 	tmpDir := scenarioHelper{}.generateLocalDirectory(c)
@@ -421,7 +474,7 @@ func (s *genericTraverserSuite) TestTraverserWithSingleObject(c *chk.C) {
 		ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
 		p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
 		rawBlobURLWithSAS := scenarioHelper{}.getRawBlobURLWithSAS(c, containerName, blobList[0])
-		blobTraverser := newBlobTraverser(&rawBlobURLWithSAS, p, ctx, false, false, func(common.EntityType) {})
+		blobTraverser := newBlobTraverser(&rawBlobURLWithSAS, p, ctx, false, false, false, common.CpkOptions{}, func(common.EntityType) {})
 
 		// invoke the blob traversal with a dummy processor
 		blobDummyProcessor := dummyProcessor{}
@@ -554,7 +607,7 @@ func (s *genericTraverserSuite) TestTraverserContainerAndLocalDirectory(c *chk.C
 		ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
 		p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
 		rawContainerURLWithSAS := scenarioHelper{}.getRawContainerURLWithSAS(c, containerName)
-		blobTraverser := newBlobTraverser(&rawContainerURLWithSAS, p, ctx, isRecursiveOn, false, func(common.EntityType) {})
+		blobTraverser := newBlobTraverser(&rawContainerURLWithSAS, p, ctx, isRecursiveOn, false, false, common.CpkOptions{}, func(common.EntityType) {})
 
 		// invoke the local traversal with a dummy processor
 		blobDummyProcessor := dummyProcessor{}
@@ -694,7 +747,7 @@ func (s *genericTraverserSuite) TestTraverserWithVirtualAndLocalDirectory(c *chk
 		ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
 		p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
 		rawVirDirURLWithSAS := scenarioHelper{}.getRawBlobURLWithSAS(c, containerName, virDirName)
-		blobTraverser := newBlobTraverser(&rawVirDirURLWithSAS, p, ctx, isRecursiveOn, false, func(common.EntityType) {})
+		blobTraverser := newBlobTraverser(&rawVirDirURLWithSAS, p, ctx, isRecursiveOn, false, false, common.CpkOptions{}, func(common.EntityType) {})
 
 		// invoke the local traversal with a dummy processor
 		blobDummyProcessor := dummyProcessor{}
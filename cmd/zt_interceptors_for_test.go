@@ -78,6 +78,7 @@ type mockedLifecycleManager struct {
 	errorLog    chan string
 	progressLog chan string
 	exitLog     chan string
+	dryrunLog   chan string
 }
 
 func (m *mockedLifecycleManager) Progress(o common.OutputBuilder) {
@@ -93,6 +94,12 @@ func (m *mockedLifecycleManager) Info(msg string) {
 	default:
 	}
 }
+func (m *mockedLifecycleManager) Dryrun(o common.OutputBuilder) {
+	select {
+	case m.dryrunLog <- o(common.EOutputFormat.Text()):
+	default:
+	}
+}
 func (*mockedLifecycleManager) Prompt(message string, details common.PromptDetails) common.ResponseOption {
 	return common.EResponseOption.Default()
 }
@@ -0,0 +1,226 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncBackupSuite struct{}
+
+var _ = chk.Suite(&syncBackupSuite{})
+
+func (s *syncBackupSuite) TestCookRemoteBackupDestination(c *chk.C) {
+	containerRoot := common.ResourceString{Value: "https://account.blob.core.windows.net/container"}
+	folder := common.ResourceString{Value: "https://account.blob.core.windows.net/container/data"}
+
+	// at the root of the container, the backups are always inside the destination
+	backupDestination, excludedPath, err := cookBackupDestination("/backups/", containerRoot, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(backupDestination, chk.Equals, "backups")
+	c.Assert(excludedPath, chk.Equals, "backups")
+
+	backupDestination, excludedPath, err = cookBackupDestination("data/backups", folder, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(backupDestination, chk.Equals, "data/backups")
+	c.Assert(excludedPath, chk.Equals, "backups")
+
+	// a sibling that merely starts with the same name is outside
+	_, excludedPath, err = cookBackupDestination("data-backups", folder, common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(excludedPath, chk.Equals, "")
+
+	for _, invalid := range []string{"data", "https://account.blob.core.windows.net/other", "../backups", "a//b", "/"} {
+		_, _, err = cookBackupDestination(invalid, folder, common.ELocation.Blob())
+		c.Assert(err, chk.NotNil, chk.Commentf(invalid))
+	}
+}
+
+func (s *syncBackupSuite) TestCookLocalBackupDestination(c *chk.C) {
+	destinationPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(destinationPath)
+	destination := common.ResourceString{Value: destinationPath}
+
+	backupDestination, excludedPath, err := cookBackupDestination(filepath.Join(destinationPath, ".backups", "sync"), destination, common.ELocation.Local())
+	c.Assert(err, chk.IsNil)
+	c.Assert(backupDestination, chk.Equals, filepath.Join(destinationPath, ".backups", "sync"))
+	c.Assert(excludedPath, chk.Equals, ".backups/sync")
+
+	_, excludedPath, err = cookBackupDestination(destinationPath+"-backups", destination, common.ELocation.Local())
+	c.Assert(err, chk.IsNil)
+	c.Assert(excludedPath, chk.Equals, "")
+
+	_, _, err = cookBackupDestination(destinationPath, destination, common.ELocation.Local())
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncBackupSuite) TestBackupDestinationCannotBeUsedWithTwoWaySync(c *chk.C) {
+	raw := getDefaultSyncRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.bidirectional = true
+	raw.backupDestination = "backups"
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncBackupSuite) TestLocalBackupMovesFiles(c *chk.C) {
+	mockedLcm := &mockedLifecycleManager{infoLog: make(chan string, 50)}
+	previousLcm := glcm
+	glcm = mockedLcm
+	defer func() { glcm = previousLcm }()
+
+	destinationPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(destinationPath)
+	backupPath := filepath.Join(destinationPath, ".backups", syncBackupFolderName(time.Now()))
+	scenarioHelper{}.generateLocalFilesFromList(c, destinationPath, []string{"extra.txt", "sub/changed.txt"})
+
+	backup := &localSyncBackup{rootPath: destinationPath, backupPath: backupPath}
+	c.Assert(backup.remove(storedObject{relativePath: "extra.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(backup.preserve(storedObject{relativePath: "sub/changed.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+	// something that has already gone has nothing to keep
+	c.Assert(backup.remove(storedObject{relativePath: "missing.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+	// and folders are left alone
+	c.Assert(backup.remove(storedObject{relativePath: "sub", entityType: common.EEntityType.Folder()}), chk.IsNil)
+
+	for _, relativePath := range []string{"extra.txt", "sub/changed.txt"} {
+		_, err := os.Stat(filepath.Join(destinationPath, relativePath))
+		c.Assert(os.IsNotExist(err), chk.Equals, true)
+		_, err = os.Stat(filepath.Join(backupPath, relativePath))
+		c.Assert(err, chk.IsNil)
+	}
+	_, err := os.Stat(filepath.Join(destinationPath, "sub"))
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *syncBackupSuite) TestLocalBackupOfSingleFile(c *chk.C) {
+	destinationPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(destinationPath)
+	filePath := filepath.Join(destinationPath, "file.txt")
+	c.Assert(ioutil.WriteFile(filePath, []byte("old"), common.DEFAULT_FILE_PERM), chk.IsNil)
+
+	// when syncing a single file, the root is the file, and its relative path is empty
+	backup := &localSyncBackup{rootPath: filePath, backupPath: filepath.Join(destinationPath, "backups")}
+	c.Assert(backup.preserve(storedObject{name: "file.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+
+	data, err := ioutil.ReadFile(filepath.Join(destinationPath, "backups", "file.txt"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "old")
+}
+
+func (s *syncBackupSuite) TestBeforeOverwriteRunsOnlyForOverwrites(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	dummyBackup := dummyProcessor{}
+	now := time.Now()
+
+	comparator := newSyncMergeComparator(dummyCopyScheduler.process, dummyCleaner.process, lmtChangeDetector{})
+	comparator.beforeOverwrite = dummyBackup.process
+
+	c.Assert(comparator.processSourceOnly(storedObject{relativePath: "new", lastModifiedTime: now}), chk.IsNil)
+	c.Assert(comparator.processPair(storedObject{relativePath: "changed", lastModifiedTime: now}, storedObject{relativePath: "changed", lastModifiedTime: now.Add(-time.Hour), size: 5}), chk.IsNil)
+	c.Assert(comparator.processPair(storedObject{relativePath: "same", lastModifiedTime: now}, storedObject{relativePath: "same", lastModifiedTime: now}), chk.IsNil)
+
+	// the destination's version is what's kept
+	c.Assert(len(dummyBackup.record), chk.Equals, 1)
+	c.Assert(dummyBackup.record[0].relativePath, chk.Equals, "changed")
+	c.Assert(dummyBackup.record[0].size, chk.Equals, int64(5))
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 2)
+}
+
+func (s *syncBackupSuite) TestFailedBackupStopsOverwrite(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	now := time.Now()
+
+	comparator := newSyncMergeComparator(dummyCopyScheduler.process, dummyCleaner.process, lmtChangeDetector{})
+	comparator.beforeOverwrite = func(storedObject) error { return errors.New("cannot back up") }
+
+	err := comparator.processPair(storedObject{relativePath: "changed", lastModifiedTime: now}, storedObject{relativePath: "changed", lastModifiedTime: now.Add(-time.Hour)})
+	c.Assert(err, chk.NotNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 0)
+}
+
+func (s *syncBackupSuite) TestBackupsAreLeftOutOfTheDestination(c *chk.C) {
+	destination := newBackupExcludingTraverser(&dummySortedTraverser{objects: []storedObject{
+		{relativePath: "a"},
+		{relativePath: "backups"},
+		{relativePath: "backups-old.txt"},
+		{relativePath: "backups/20201017T093000Z/a"},
+		{relativePath: "z"},
+	}}, "backups")
+
+	sorted, ok := asSortedTraverser(destination)
+	c.Assert(ok, chk.Equals, true)
+
+	dummyComparator := dummyProcessor{}
+	c.Assert(sorted.traverseSorted(nil, dummyComparator.process, nil), chk.IsNil)
+
+	relativePaths := make([]string, 0)
+	for _, object := range dummyComparator.record {
+		relativePaths = append(relativePaths, object.relativePath)
+	}
+	c.Assert(relativePaths, chk.DeepEquals, []string{"a", "backups", "backups-old.txt", "z"})
+}
+
+func (s *syncBackupSuite) TestRemoteBlobBackupCopiesSynchronouslyWhenItCan(c *chk.C) {
+	fakeBlob := newFakeAzureStorageServer(common.ELocation.Blob())
+	defer fakeBlob.Close()
+	lastModified := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	fakeBlob.putFile("ctr", "dir/small.txt", 10, lastModified)
+	fakeBlob.putFile("ctr", "dir/big.bin", syncBackupMaxSyncCopyBytes+1, lastModified)
+	fakeBlob.putPageBlob("ctr", "dir/disk.vhd", 512, lastModified)
+
+	ctx := context.Background()
+	p, err := initPipeline(ctx, common.ELocation.Blob(), common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()})
+	c.Assert(err, chk.IsNil)
+	rootURL, err := url.Parse(fakeBlob.resourceURL("ctr/dir"))
+	c.Assert(err, chk.IsNil)
+	backup := &remoteSyncBackup{rootURL: *rootURL, backupPath: "backups/20210102T030405Z", p: p, ctx: ctx, targetLocation: common.ELocation.Blob()}
+
+	c.Assert(backup.preserve(storedObject{relativePath: "small.txt", size: 10, blobType: azblob.BlobBlockBlob, entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(backup.preserve(storedObject{relativePath: "big.bin", size: syncBackupMaxSyncCopyBytes + 1, blobType: azblob.BlobBlockBlob, entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(backup.remove(storedObject{relativePath: "disk.vhd", size: 512, blobType: azblob.BlobPageBlob, entityType: common.EEntityType.File()}), chk.IsNil)
+
+	// only the small block blob can be copied synchronously
+	c.Assert(fakeBlob.copiesMade(), chk.DeepEquals, []fakeAzureStorageCopy{
+		{source: "ctr/dir/small.txt", destination: "ctr/backups/20210102T030405Z/small.txt", synchronous: true},
+		{source: "ctr/dir/big.bin", destination: "ctr/backups/20210102T030405Z/big.bin", synchronous: false},
+		{source: "ctr/dir/disk.vhd", destination: "ctr/backups/20210102T030405Z/disk.vhd", synchronous: false},
+	})
+	c.Assert(fakeBlob.deletedFiles(), chk.DeepEquals, []string{"ctr/dir/disk.vhd"})
+
+	// without a SAS, the source of a synchronous copy can't be read, so the copy must be asynchronous
+	rootURL.RawQuery = ""
+	backup.rootURL = *rootURL
+	c.Assert(backup.preserve(storedObject{relativePath: "small.txt", size: 10, blobType: azblob.BlobBlockBlob, entityType: common.EEntityType.File()}), chk.IsNil)
+	copies := fakeBlob.copiesMade()
+	c.Assert(copies[len(copies)-1].synchronous, chk.Equals, false)
+}
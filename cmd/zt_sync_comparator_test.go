@@ -21,8 +21,15 @@
 package cmd
 
 import (
-	chk "gopkg.in/check.v1"
+	"crypto/md5"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 type syncComparatorSuite struct{}
@@ -36,7 +43,7 @@ func (s *syncComparatorSuite) TestSyncSourceComparator(c *chk.C) {
 
 	// set up the indexer as well as the source comparator
 	indexer := newObjectIndexer()
-	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process)
+	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, lmtChangeDetector{})
 
 	// create a sample destination object
 	sampleDestinationObject := storedObject{name: "test", relativePath: "/usr/test", lastModifiedTime: time.Now(), md5: destMD5}
@@ -89,7 +96,7 @@ func (s *syncComparatorSuite) TestSyncDestinationComparator(c *chk.C) {
 
 	// set up the indexer as well as the destination comparator
 	indexer := newObjectIndexer()
-	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process)
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, lmtChangeDetector{})
 
 	// create a sample source object
 	sampleSourceObject := storedObject{name: "test", relativePath: "/usr/test", lastModifiedTime: time.Now(), md5: srcMD5}
@@ -137,3 +144,170 @@ func (s *syncComparatorSuite) TestSyncDestinationComparator(c *chk.C) {
 	c.Assert(dummyCopyScheduler.record[0].md5, chk.DeepEquals, srcMD5)
 	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
 }
+
+func (s *syncComparatorSuite) TestSizeOnlyChangeDetector(c *chk.C) {
+	detector := sizeOnlyChangeDetector{}
+	older := storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(-time.Hour)}
+
+	// the last modified times are ignored, only the sizes matter
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now()}, older), chk.Equals, false)
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 11, lastModifiedTime: time.Now().Add(-2 * time.Hour)}, older), chk.Equals, true)
+}
+
+func (s *syncComparatorSuite) TestMd5ChangeDetector(c *chk.C) {
+	detector := md5ChangeDetector{sourceMd5: remoteMd5Getter, destinationMd5: remoteMd5Getter}
+	hashA := []byte{'a'}
+	hashB := []byte{'b'}
+	destinationObject := storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now(), md5: hashA}
+
+	// same content, even though the source is more recent
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(time.Hour), md5: hashA}, destinationObject), chk.Equals, false)
+
+	// different content, even though the source is older
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(-time.Hour), md5: hashB}, destinationObject), chk.Equals, true)
+
+	// different sizes are enough to tell
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 11, md5: hashA}, destinationObject), chk.Equals, true)
+
+	// an unknown hash can't be shown to be the same
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10}, destinationObject), chk.Equals, true)
+
+	// folders have nothing to compare
+	c.Assert(detector.hasChanged(storedObject{relativePath: "dir", entityType: common.EEntityType.Folder()}, storedObject{relativePath: "dir", entityType: common.EEntityType.Folder()}), chk.Equals, false)
+}
+
+func (s *syncComparatorSuite) TestS3ChangeDetector(c *chk.C) {
+	detector := s3ChangeDetector{}
+	hashA := []byte{'a'}
+	hashB := []byte{'b'}
+	destinationObject := storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now(), md5: hashA}
+
+	// the ETag stands in for a missing Content-MD5, and the hashes win over the last modified times
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(time.Hour), etagMd5: hashA}, destinationObject), chk.Equals, false)
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(-time.Hour), etagMd5: hashB}, destinationObject), chk.Equals, true)
+
+	// the Content-MD5 is preferred to the ETag
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, md5: hashA, etagMd5: hashB}, destinationObject), chk.Equals, false)
+
+	// different sizes are enough to tell
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 11, etagMd5: hashA}, destinationObject), chk.Equals, true)
+
+	// without a hash (e.g. a multipart upload), the last modified times decide
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(-time.Hour)}, destinationObject), chk.Equals, false)
+	c.Assert(detector.hasChanged(storedObject{relativePath: "test", size: 10, lastModifiedTime: time.Now().Add(time.Hour)}, destinationObject), chk.Equals, true)
+}
+
+func (s *syncComparatorSuite) TestLocalMd5Cache(c *chk.C) {
+	rootPath, err := ioutil.TempDir("", "syncmd5root")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(rootPath)
+	cacheFolder, err := ioutil.TempDir("", "syncmd5cache")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(cacheFolder)
+
+	content := []byte("some content")
+	c.Assert(ioutil.WriteFile(filepath.Join(rootPath, "file"), content, 0644), chk.IsNil)
+	expectedHash := md5.Sum(content)
+	object := storedObject{relativePath: "file", size: int64(len(content)), lastModifiedTime: time.Now()}
+
+	// the hash is computed from the file
+	cache := newLocalMd5Cache(rootPath, cacheFolder)
+	hash, err := cache.getMd5(object)
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash, chk.DeepEquals, expectedHash[:])
+	c.Assert(cache.save(), chk.IsNil)
+
+	// change the file without changing its size or last modified time, so that we can tell if it gets read again
+	c.Assert(ioutil.WriteFile(filepath.Join(rootPath, "file"), []byte("other content"), 0644), chk.IsNil)
+
+	// a new run reuses the saved hash
+	cache = newLocalMd5Cache(rootPath, cacheFolder)
+	hash, err = cache.getMd5(object)
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash, chk.DeepEquals, expectedHash[:])
+
+	// but not once the file looks different
+	object.size = int64(len("other content"))
+	expectedHash = md5.Sum([]byte("other content"))
+	hash, err = cache.getMd5(object)
+	c.Assert(err, chk.IsNil)
+	c.Assert(hash, chk.DeepEquals, expectedHash[:])
+}
+
+// gives its objects in the order it was given them, so that tests can make it keep (or break) the promise of sorted output
+type dummySortedTraverser struct {
+	objects []storedObject
+}
+
+func (t *dummySortedTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	return t.traverseSorted(preprocessor, processor, filters)
+}
+
+func (t *dummySortedTraverser) isDirectory(bool) bool {
+	return true
+}
+
+func (t *dummySortedTraverser) canTraverseSorted() bool {
+	return true
+}
+
+func (t *dummySortedTraverser) traverseSorted(_ objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	for _, object := range t.objects {
+		if err := processIfPassedFilters(filters, object, processor); err != nil && err != ignoredError {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syncComparatorSuite) TestSyncMergeEnumerator(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	now := time.Now()
+
+	source := &dummySortedTraverser{objects: []storedObject{
+		{relativePath: "a", lastModifiedTime: now},                           // only at source
+		{relativePath: "b", lastModifiedTime: now.Add(time.Hour)},            // newer at source
+		{relativePath: "c", lastModifiedTime: now.Add(-time.Hour)},           // older at source
+		{relativePath: "e/f", lastModifiedTime: now},                         // only at source, after an extra destination object
+		{relativePath: "g", lastModifiedTime: now, name: "source-only-tail"}, // only at source, at the end
+	}}
+	destination := &dummySortedTraverser{objects: []storedObject{
+		{relativePath: "b", lastModifiedTime: now},
+		{relativePath: "c", lastModifiedTime: now},
+		{relativePath: "d", lastModifiedTime: now}, // only at destination
+	}}
+
+	finalized := false
+	comparator := newSyncMergeComparator(dummyCopyScheduler.process, dummyCleaner.process, lmtChangeDetector{})
+	enumerator := newSyncMergeEnumerator(source, destination, nil, comparator, func() error {
+		finalized = true
+		return nil
+	})
+	c.Assert(enumerator.enumerate(), chk.IsNil)
+	c.Assert(finalized, chk.Equals, true)
+
+	transferred := make([]string, 0)
+	for _, object := range dummyCopyScheduler.record {
+		transferred = append(transferred, object.relativePath)
+	}
+	c.Assert(transferred, chk.DeepEquals, []string{"a", "b", "e/f", "g"})
+	c.Assert(len(dummyCleaner.record), chk.Equals, 1)
+	c.Assert(dummyCleaner.record[0].relativePath, chk.Equals, "d")
+}
+
+func (s *syncComparatorSuite) TestSyncMergeEnumeratorRejectsUnsortedInput(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+
+	// "b" comes after "c", by which time it's too late to tell whether "b" exists at the destination
+	source := &dummySortedTraverser{objects: []storedObject{{relativePath: "a"}, {relativePath: "c"}, {relativePath: "b"}}}
+	destination := &dummySortedTraverser{objects: []storedObject{{relativePath: "c"}, {relativePath: "d"}}}
+
+	comparator := newSyncMergeComparator(dummyCopyScheduler.process, dummyCleaner.process, lmtChangeDetector{})
+	enumerator := newSyncMergeEnumerator(source, destination, nil, comparator, func() error { return nil })
+	c.Assert(enumerator.enumerate(), chk.NotNil)
+
+	// nothing more is done once the problem is found, so "d" isn't deleted
+	c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+}
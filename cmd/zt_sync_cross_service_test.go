@@ -0,0 +1,157 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func (s *cmdIntegrationSuite) TestSyncCrossServiceFromTos(c *chk.C) {
+	c.Assert(inferFromTo("https://src.blob.core.windows.net/container?sv=x", "https://dst.dfs.core.windows.net/fs"),
+		chk.Equals, common.EFromTo.BlobBlobFS())
+
+	for _, pair := range []struct {
+		src, dst string
+		fromTo   common.FromTo
+	}{
+		{"https://src.file.core.windows.net/share/dir?sv=x", "https://dst.blob.core.windows.net/container/dir?sv=y", common.EFromTo.FileBlob()},
+		{"https://src.blob.core.windows.net/container/dir?sv=x", "https://dst.file.core.windows.net/share/dir?sv=y", common.EFromTo.BlobFile()},
+		{"https://src.blob.core.windows.net/container/dir?sv=x", "https://dst.dfs.core.windows.net/fs/dir", common.EFromTo.BlobBlobFS()},
+		{"https://src.dfs.core.windows.net/fs/dir?sv=x", "https://dst.blob.core.windows.net/container/dir", common.EFromTo.BlobFSBlob()},
+		{"https://src.dfs.core.windows.net/fs/dir?sv=x", "https://dst.dfs.core.windows.net/fs/dir", common.EFromTo.BlobFSBlobFS()},
+	} {
+		raw := getDefaultSyncRawInput(pair.src, pair.dst)
+		cooked, err := raw.cook()
+		c.Assert(err, chk.IsNil)
+		c.Assert(cooked.fromTo, chk.Equals, pair.fromTo)
+
+		// the SAS of each side stays with that side
+		c.Assert(cooked.source.SAS, chk.Equals, "sv=x")
+		c.Assert(cooked.destination.Value, chk.Not(chk.Matches), ".*sv=.*")
+	}
+
+	// S3 has no SAS, and is authorized from the environment instead
+	raw := getDefaultSyncRawInput("https://bucket.s3.amazonaws.com/dir", "https://dst.blob.core.windows.net/container/dir?sv=y")
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo, chk.Equals, common.EFromTo.S3Blob())
+	c.Assert(cooked.source.Value, chk.Equals, "https://bucket.s3.amazonaws.com/dir")
+	c.Assert(cooked.destination.SAS, chk.Equals, "sv=y")
+}
+
+// fakeServiceHosts are hosts of each service, for URLs that cook into the right FromTo
+var fakeServiceHosts = map[common.Location]string{
+	common.ELocation.Blob():   "https://fakeaccount.blob.core.windows.net/",
+	common.ELocation.File():   "https://fakeaccount.file.core.windows.net/",
+	common.ELocation.BlobFS(): "https://fakeaccount.dfs.core.windows.net/",
+}
+
+// runSyncBetweenFakeServices syncs the path at the source to the one at the destination. Since the fakes' IP-addressed URLs
+// don't say which service they're for, the sync is cooked as though it were between the real services, and then pointed at the fakes.
+func runSyncBetweenFakeServices(c *chk.C, source, destination *fakeAzureStorageServer, srcPath, dstPath string, verifier func(err error)) {
+	raw := getDefaultSyncRawInput(fakeServiceHosts[source.location]+srcPath+"?sv=x&sig=y", fakeServiceHosts[destination.location]+dstPath+"?sv=x&sig=y")
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo.From(), chk.Equals, source.location)
+	c.Assert(cooked.fromTo.To(), chk.Equals, destination.location)
+
+	cooked.source, err = SplitResourceString(source.resourceURL(srcPath), source.location)
+	c.Assert(err, chk.IsNil)
+	cooked.destination, err = SplitResourceString(destination.resourceURL(dstPath), destination.location)
+	c.Assert(err, chk.IsNil)
+
+	// the enumeration ends when process() returns
+	verifier(cooked.process())
+}
+
+// syncBetweenFakeServices syncs a folder from one service to another, and checks that the source is listed and compared with the destination,
+// and that only what's newer or missing at the destination is transferred, while what's only at the destination is deleted
+func syncBetweenFakeServices(c *chk.C, fromTo common.FromTo) {
+	source := newFakeAzureStorageServer(fromTo.From())
+	defer source.Close()
+	destination := newFakeAzureStorageServer(fromTo.To())
+	defer destination.Close()
+
+	lastModified := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	// newer at the source, so it's transferred
+	source.putFile("src", "dir/updated.txt", 10, lastModified.Add(time.Hour))
+	destination.putFile("dst", "dir/updated.txt", 5, lastModified)
+	// older at the source, so it's left as it is
+	source.putFile("src", "dir/stale.txt", 10, lastModified.Add(-time.Hour))
+	destination.putFile("dst", "dir/stale.txt", 5, lastModified)
+	// only at the source, so it's transferred
+	source.putFile("src", "dir/sub/new.txt", 10, lastModified)
+	source.putFile("src", "dir/sub/deeper/new.txt", 10, lastModified)
+	// only at the destination, so it's deleted
+	destination.putFile("dst", "dir/sub/extra.txt", 5, lastModified)
+	// outside of the folders being synced, so neither is touched
+	source.putFile("src", "elsewhere.txt", 10, lastModified)
+	destination.putFile("dst", "other/extra.txt", 5, lastModified)
+
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	runSyncBetweenFakeServices(c, source, destination, "src/dir", "dst/dir", func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(mockedRPC.lastRequest.(*common.CopyJobPartOrderRequest).FromTo, chk.Equals, fromTo)
+
+		// folders aren't transferred between these services, since at least one side of each pair doesn't have them
+		validateS2SSyncTransfersAreScheduled(c, "/", "/", []string{"updated.txt", "sub/new.txt", "sub/deeper/new.txt"}, mockedRPC)
+		for _, transfer := range mockedRPC.transfers {
+			c.Assert(transfer.SourceSize, chk.Equals, int64(10))
+		}
+
+		c.Assert(destination.deletedFiles(), chk.DeepEquals, []string{"dst/dir/sub/extra.txt"})
+		c.Assert(source.deletedFiles(), chk.HasLen, 0)
+	})
+
+	// once the destination has caught up, there's nothing left to do
+	mockedRPC.reset()
+	for _, name := range []string{"dir/updated.txt", "dir/sub/new.txt", "dir/sub/deeper/new.txt"} {
+		destination.putFile("dst", name, 10, lastModified.Add(2*time.Hour))
+	}
+	runSyncBetweenFakeServices(c, source, destination, "src/dir", "dst/dir", func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(mockedRPC.transfers, chk.HasLen, 0)
+		c.Assert(destination.deletedFiles(), chk.HasLen, 1)
+	})
+}
+
+func (s *cmdIntegrationSuite) TestSyncFileToBlobWithFakeServices(c *chk.C) {
+	syncBetweenFakeServices(c, common.EFromTo.FileBlob())
+}
+
+func (s *cmdIntegrationSuite) TestSyncBlobToFileWithFakeServices(c *chk.C) {
+	syncBetweenFakeServices(c, common.EFromTo.BlobFile())
+}
+
+func (s *cmdIntegrationSuite) TestSyncBlobToBlobFSWithFakeServices(c *chk.C) {
+	syncBetweenFakeServices(c, common.EFromTo.BlobBlobFS())
+}
+
+func (s *cmdIntegrationSuite) TestSyncBlobFSToBlobWithFakeServices(c *chk.C) {
+	syncBetweenFakeServices(c, common.EFromTo.BlobFSBlob())
+}
@@ -0,0 +1,164 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncIndexerSuite struct{}
+
+var _ = chk.Suite(&syncIndexerSuite{})
+
+func (s *syncIndexerSuite) TestSpillingIndexer(c *chk.C) {
+	spillFolder, err := ioutil.TempDir("", "syncindex")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(spillFolder)
+	spillFile := filepath.Join(spillFolder, "test.syncindex")
+
+	// leave room for just a couple of objects in memory, so that the rest must be spilled
+	sample := storedObject{name: "file0", relativePath: "dir/file0", md5: []byte{0}, etagMd5: []byte{0, 1},
+		Metadata: common.Metadata{"key": "file0"}, blobTags: common.BlobTags{"tag": "file0"}}
+	indexer := newSpillingObjectIndexer(2*sample.approximateMemorySize()+10, spillFile)
+
+	lmt := time.Now().UTC().Truncate(time.Second)
+	expectedNames := make([]string, 0)
+	for n := 0; n < 10; n++ {
+		name := "file" + strconv.Itoa(n)
+		expectedNames = append(expectedNames, name)
+		err = indexer.store(storedObject{name: name, relativePath: "dir/" + name, lastModifiedTime: lmt, size: int64(n),
+			md5: []byte{byte(n)}, etagMd5: []byte{byte(n), 1}, blobTags: common.BlobTags{"tag": name}, entityType: common.EEntityType.File(), Metadata: common.Metadata{"key": name}})
+		c.Assert(err, chk.IsNil)
+	}
+	c.Assert(len(indexer.indexMap), chk.Equals, 2)
+	c.Assert(indexer.spill, chk.NotNil)
+	_, err = os.Stat(spillFile)
+	c.Assert(err, chk.IsNil)
+
+	// objects can be found whether they are in memory or on disk, with all their properties intact
+	object, present, err := indexer.lookup("dir/file7")
+	c.Assert(err, chk.IsNil)
+	c.Assert(present, chk.Equals, true)
+	c.Assert(object.name, chk.Equals, "file7")
+	c.Assert(object.size, chk.Equals, int64(7))
+	c.Assert(object.lastModifiedTime.Equal(lmt), chk.Equals, true)
+	c.Assert(object.md5, chk.DeepEquals, []byte{7})
+	c.Assert(object.Metadata, chk.DeepEquals, common.Metadata{"key": "file7"})
+	c.Assert(object.etagMd5, chk.DeepEquals, []byte{7, 1})
+	c.Assert(object.blobTags, chk.DeepEquals, common.BlobTags{"tag": "file7"})
+
+	_, present, err = indexer.lookup("dir/missing")
+	c.Assert(err, chk.IsNil)
+	c.Assert(present, chk.Equals, false)
+
+	// storing the same path again replaces the old entry
+	err = indexer.store(storedObject{name: "file8", relativePath: "dir/file8", size: 88})
+	c.Assert(err, chk.IsNil)
+	object, _, err = indexer.lookup("dir/file8")
+	c.Assert(err, chk.IsNil)
+	c.Assert(object.size, chk.Equals, int64(88))
+
+	// removed objects are no longer found, either in memory or on disk
+	c.Assert(indexer.remove("dir/file0"), chk.IsNil)
+	c.Assert(indexer.remove("dir/file5"), chk.IsNil)
+	_, present, err = indexer.lookup("dir/file5")
+	c.Assert(err, chk.IsNil)
+	c.Assert(present, chk.Equals, false)
+
+	// and traversal sees everything that's left, exactly once
+	processor := dummyProcessor{}
+	c.Assert(indexer.traverse(processor.process, nil), chk.IsNil)
+	traversedNames := make([]string, 0)
+	for _, o := range processor.record {
+		traversedNames = append(traversedNames, o.name)
+	}
+	sort.Strings(traversedNames)
+	c.Assert(traversedNames, chk.DeepEquals, []string{"file1", "file2", "file3", "file4", "file6", "file7", "file8", "file9"})
+
+	// closing the index gets rid of the spill files, and closing it again is harmless
+	c.Assert(indexer.close(), chk.IsNil)
+	_, err = os.Stat(spillFile)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	_, err = os.Stat(spillFile + ".heads")
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	c.Assert(indexer.close(), chk.IsNil)
+}
+
+func (s *syncIndexerSuite) TestSpilledIndexGrows(c *chk.C) {
+	spillFolder, err := ioutil.TempDir("", "syncindex")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(spillFolder)
+
+	// start with a small table, so that it doesn't take many objects to make it grow
+	const bucketBits = 6
+	spill, err := newSpilledIndexWithBuckets(filepath.Join(spillFolder, "test.syncindex"), bucketBits)
+	c.Assert(err, chk.IsNil)
+	defer spill.close()
+
+	// enough objects that the table has to double (twice), with some removed before and after each doubling
+	count := spilledMaxLoad<<bucketBits*3 + 1
+	for n := 0; n < count; n++ {
+		c.Assert(spill.store(storedObject{name: strconv.Itoa(n), relativePath: "dir/" + strconv.Itoa(n), size: int64(n)}), chk.IsNil)
+		if n%100 == 99 {
+			c.Assert(spill.remove("dir/"+strconv.Itoa(n-50)), chk.IsNil)
+		}
+	}
+	c.Assert(spill.bucketBits, chk.Equals, uint(bucketBits+2))
+	expectedCount := count - count/100
+	c.Assert(spill.count, chk.Equals, int64(expectedCount))
+
+	for n := 0; n < count; n++ {
+		object, present, err := spill.lookup("dir/" + strconv.Itoa(n))
+		c.Assert(err, chk.IsNil)
+		c.Assert(present, chk.Equals, n%100 != 49 || n+50 >= count, chk.Commentf("object %d", n))
+		if present {
+			c.Assert(object.size, chk.Equals, int64(n))
+		}
+	}
+
+	seen := make(map[string]bool)
+	c.Assert(spill.traverse(func(object storedObject) error {
+		c.Assert(seen[object.name], chk.Equals, false)
+		seen[object.name] = true
+		return nil
+	}), chk.IsNil)
+	c.Assert(len(seen), chk.Equals, expectedCount)
+}
+
+func (s *syncIndexerSuite) TestIndexerWithoutLimitNeverSpills(c *chk.C) {
+	indexer := newObjectIndexer()
+	for n := 0; n < 100; n++ {
+		c.Assert(indexer.store(storedObject{name: strconv.Itoa(n), relativePath: strconv.Itoa(n)}), chk.IsNil)
+	}
+
+	c.Assert(len(indexer.indexMap), chk.Equals, 100)
+	c.Assert(indexer.spill, chk.IsNil)
+	c.Assert(indexer.close(), chk.IsNil)
+}
@@ -0,0 +1,152 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncLocalFileSuite struct{}
+
+var _ = chk.Suite(&syncLocalFileSuite{})
+
+const localFileTestShareURL = "https://dst.file.core.windows.net/share/dir?sv=x"
+
+func (s *syncLocalFileSuite) TestSyncLocalFileFromTos(c *chk.C) {
+	dirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirName)
+
+	// upload
+	raw := getDefaultSyncRawInput(dirName, localFileTestShareURL)
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo, chk.Equals, common.EFromTo.LocalFile())
+	c.Assert(cooked.source.ValueLocal(), chk.Equals, cleanLocalPath(dirName))
+	c.Assert(cooked.destination.SAS, chk.Equals, "sv=x")
+
+	// download
+	raw = getDefaultSyncRawInput(localFileTestShareURL, dirName)
+	cooked, err = raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo, chk.Equals, common.EFromTo.FileLocal())
+	c.Assert(cooked.source.SAS, chk.Equals, "sv=x")
+	c.Assert(cooked.destination.ValueLocal(), chk.Equals, cleanLocalPath(dirName))
+}
+
+func (s *syncLocalFileSuite) TestSyncForceIfReadOnly(c *chk.C) {
+	dirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirName)
+
+	// the share can always be forced
+	raw := getDefaultSyncRawInput(dirName, localFileTestShareURL)
+	raw.forceIfReadOnly = true
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.forceIfReadOnly, chk.Equals, true)
+
+	// but only Windows has read-only files to force on the local side
+	raw = getDefaultSyncRawInput(localFileTestShareURL, dirName)
+	raw.forceIfReadOnly = true
+	_, err = raw.cook()
+	if runtime.GOOS == "windows" {
+		c.Assert(err, chk.IsNil)
+	} else {
+		c.Assert(err, chk.NotNil)
+	}
+
+	// and Blob storage has no read-only attribute
+	raw = getDefaultSyncRawInput(dirName, "https://dst.blob.core.windows.net/container/dir?sv=x")
+	raw.forceIfReadOnly = true
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncLocalFileSuite) TestSyncPreserveOwnerOnlyOnDownload(c *chk.C) {
+	dirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirName)
+
+	raw := getDefaultSyncRawInput(localFileTestShareURL, dirName)
+	raw.preserveOwner = false
+	_, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+
+	raw = getDefaultSyncRawInput(dirName, localFileTestShareURL)
+	raw.preserveOwner = false
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncLocalFileSuite) TestSyncPreserveSMBInfo(c *chk.C) {
+	dirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirName)
+
+	for _, pair := range []struct{ src, dst string }{
+		{dirName, localFileTestShareURL},
+		{localFileTestShareURL, dirName},
+	} {
+		raw := getDefaultSyncRawInput(pair.src, pair.dst)
+		raw.preserveSMBInfo = true
+		cooked, err := raw.cook()
+
+		// SMB properties can only be read and written locally on Windows
+		if runtime.GOOS == "windows" {
+			c.Assert(err, chk.IsNil)
+			c.Assert(cooked.preserveSMBInfo, chk.Equals, true)
+		} else {
+			c.Assert(err, chk.NotNil)
+		}
+	}
+
+	// Blob storage isn't SMB-aware
+	raw := getDefaultSyncRawInput(dirName, "https://dst.blob.core.windows.net/container/dir?sv=x")
+	raw.preserveSMBInfo = true
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncLocalFileSuite) TestLocalDeleterForcesReadOnlyFiles(c *chk.C) {
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+	dstFileName := "readOnlyFile.txt"
+	scenarioHelper{}.generateLocalFilesFromList(c, dstDirName, []string{dstFileName})
+
+	// on Windows, this sets the read-only attribute
+	c.Assert(os.Chmod(filepath.Join(dstDirName, dstFileName), 0444), chk.IsNil)
+
+	cca := &cookedSyncCmdArgs{
+		destination:       newLocalRes(dstDirName),
+		deleteDestination: common.EDeleteDestination.True(),
+		forceIfReadOnly:   true,
+	}
+	deleter := newSyncLocalDeleteProcessor(cca)
+
+	err := deleter.removeImmediately(storedObject{relativePath: dstFileName, entityType: common.EEntityType.File()})
+	c.Assert(err, chk.IsNil)
+
+	_, err = os.Stat(filepath.Join(dstDirName, dstFileName))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
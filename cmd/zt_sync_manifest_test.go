@@ -0,0 +1,176 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncManifestSuite struct{}
+
+var _ = chk.Suite(&syncManifestSuite{})
+
+func readTestManifest(c *chk.C, filePath string) []syncManifestEntry {
+	entries := make([]syncManifestEntry, 0)
+	c.Assert(readSyncManifestEntries(filePath, func(entry syncManifestEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}), chk.IsNil)
+	return entries
+}
+
+func (s *syncManifestSuite) TestSyncManifestCommit(c *chk.C) {
+	manifestFolder, err := ioutil.TempDir("", "syncmanifest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(manifestFolder)
+	manifestPath := filepath.Join(manifestFolder, "manifest.jsonl")
+	lastFullCheck := time.Now().UTC().Truncate(time.Second)
+
+	for _, transfersSucceeded := range []bool{false, true} {
+		writer, err := newSyncManifestWriter(manifestPath, syncManifestHeader{LastFullCheck: lastFullCheck})
+		c.Assert(err, chk.IsNil)
+		c.Assert(writer.record(syncManifestEntry{RelativePath: "a", Size: 1}), chk.IsNil)
+		c.Assert(writer.record(syncManifestEntry{RelativePath: "b", Size: 2, Pending: true}), chk.IsNil)
+		c.Assert(writer.record(syncManifestEntry{RelativePath: "c", Size: 3}), chk.IsNil)
+		c.Assert(writer.close(), chk.IsNil)
+		c.Assert(writer.commit(transfersSucceeded, nil), chk.IsNil)
+
+		header, exists, err := readSyncManifestHeader(manifestPath)
+		c.Assert(err, chk.IsNil)
+		c.Assert(exists, chk.Equals, true)
+		c.Assert(header.LastFullCheck.Equal(lastFullCheck), chk.Equals, true)
+
+		// the file that was being transferred is only kept if the transfers succeeded
+		if transfersSucceeded {
+			c.Assert(readTestManifest(c, manifestPath), chk.DeepEquals, []syncManifestEntry{
+				{RelativePath: "a", Size: 1}, {RelativePath: "b", Size: 2}, {RelativePath: "c", Size: 3}})
+		} else {
+			c.Assert(readTestManifest(c, manifestPath), chk.DeepEquals, []syncManifestEntry{
+				{RelativePath: "a", Size: 1}, {RelativePath: "c", Size: 3}})
+		}
+		_, err = os.Stat(writer.tempPath)
+		c.Assert(os.IsNotExist(err), chk.Equals, true)
+	}
+}
+
+func (s *syncManifestSuite) TestSyncManifestCommitDropsOnlyFailedTransfers(c *chk.C) {
+	manifestFolder, err := ioutil.TempDir("", "syncmanifest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(manifestFolder)
+	manifestPath := filepath.Join(manifestFolder, "manifest.jsonl")
+
+	writer, err := newSyncManifestWriter(manifestPath, syncManifestHeader{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(writer.record(syncManifestEntry{RelativePath: "a", Size: 1}), chk.IsNil)
+	c.Assert(writer.record(syncManifestEntry{RelativePath: "b", Size: 2, Pending: true}), chk.IsNil)
+	c.Assert(writer.record(syncManifestEntry{RelativePath: "c", Size: 3, Pending: true}), chk.IsNil)
+	c.Assert(writer.record(syncManifestEntry{RelativePath: "d/e", Size: 4, Pending: true}), chk.IsNil)
+	c.Assert(writer.close(), chk.IsNil)
+	c.Assert(writer.commit(false, map[string]bool{"c": true}), chk.IsNil)
+
+	// only the file whose transfer failed will be transferred again next time
+	c.Assert(readTestManifest(c, manifestPath), chk.DeepEquals, []syncManifestEntry{
+		{RelativePath: "a", Size: 1}, {RelativePath: "b", Size: 2}, {RelativePath: "d/e", Size: 4}})
+}
+
+func (s *syncManifestSuite) TestSyncManifestDiscard(c *chk.C) {
+	manifestFolder, err := ioutil.TempDir("", "syncmanifest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(manifestFolder)
+	manifestPath := filepath.Join(manifestFolder, "manifest.jsonl")
+
+	// a sync that fails part way through, before the new manifest is closed, leaves nothing behind
+	writer, err := newSyncManifestWriter(manifestPath, syncManifestHeader{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(writer.record(syncManifestEntry{RelativePath: "a", Size: 1, Pending: true}), chk.IsNil)
+	c.Assert(writer.discard(), chk.IsNil)
+
+	files, err := ioutil.ReadDir(manifestFolder)
+	c.Assert(err, chk.IsNil)
+	c.Assert(files, chk.HasLen, 0)
+}
+
+func (s *syncManifestSuite) TestTransferRelativePath(c *chk.C) {
+	c.Assert(transferRelativePath("/src/dir/file", "/src", common.ELocation.Local()), chk.Equals, "dir/file")
+	c.Assert(transferRelativePath("/src/dir/file", "/src/", common.ELocation.Local()), chk.Equals, "dir/file")
+	c.Assert(transferRelativePath("https://account.blob.core.windows.net/container/dir/a%20b?extra=1",
+		"https://account.blob.core.windows.net/container", common.ELocation.Blob()), chk.Equals, "dir/a b")
+}
+
+func (s *syncManifestSuite) TestIncrementalSyncAgainstManifest(c *chk.C) {
+	manifestFolder, err := ioutil.TempDir("", "syncmanifest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(manifestFolder)
+	manifestPath := filepath.Join(manifestFolder, "manifest.jsonl")
+
+	lmt := time.Now().UTC()
+	previous, err := newSyncManifestWriter(manifestPath, syncManifestHeader{LastFullCheck: lmt})
+	c.Assert(err, chk.IsNil)
+	for _, name := range []string{"a", "b", "d", "e"} {
+		c.Assert(previous.record(syncManifestEntry{RelativePath: name, Size: 1, LMT: lmt}), chk.IsNil)
+	}
+	c.Assert(previous.close(), chk.IsNil)
+	c.Assert(previous.commit(true, nil), chk.IsNil)
+
+	file := func(name string, lmt time.Time) storedObject {
+		return storedObject{name: name, relativePath: name, entityType: common.EEntityType.File(), size: 1, lastModifiedTime: lmt}
+	}
+	source := &dummySortedTraverser{objects: []storedObject{
+		file("a", lmt),                // unchanged
+		file("b", lmt.Add(time.Hour)), // changed
+		file("c", lmt),                // new
+		file("e", lmt),                // unchanged, after a deleted one
+	}}
+	destinationsScanned := 0
+	destination := newSyncManifestTraverser(manifestPath, func(common.EntityType) { destinationsScanned++ })
+
+	writer, err := newSyncManifestWriter(manifestPath, syncManifestHeader{LastFullCheck: lmt})
+	c.Assert(err, chk.IsNil)
+	dummyCopyScheduler, dummyCleaner := dummyProcessor{}, dummyProcessor{}
+	recorder := &syncManifestRecorder{writer: writer}
+	recorder.syncMergeProcessor = newSyncMergeComparator(recorder.recordTransfers(dummyCopyScheduler.process), dummyCleaner.process, lmtChangeDetector{})
+
+	enumerator := newSyncMergeEnumerator(source, destination, nil, recorder, writer.close)
+	c.Assert(enumerator.enumerate(), chk.IsNil)
+	c.Assert(destinationsScanned, chk.Equals, 4)
+
+	transferred := make([]string, 0)
+	for _, object := range dummyCopyScheduler.record {
+		transferred = append(transferred, object.relativePath)
+	}
+	c.Assert(transferred, chk.DeepEquals, []string{"b", "c"})
+	c.Assert(len(dummyCleaner.record), chk.Equals, 1)
+	c.Assert(dummyCleaner.record[0].relativePath, chk.Equals, "d")
+
+	c.Assert(writer.commit(true, nil), chk.IsNil)
+	recorded := make([]string, 0)
+	for _, entry := range readTestManifest(c, manifestPath) {
+		recorded = append(recorded, entry.RelativePath)
+	}
+	c.Assert(recorded, chk.DeepEquals, []string{"a", "b", "c", "e"})
+}
@@ -0,0 +1,206 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncSymlinksSuite struct{}
+
+var _ = chk.Suite(&syncSymlinksSuite{})
+
+// listFollowingSymlinks lists the files under the root the way that sync does, sorted so that they can be compared
+func listFollowingSymlinks(c *chk.C, root string) []storedObject {
+	traverser := newLocalTraverser(root, true, true, nil)
+	traverser.stableLinkedPaths = true
+
+	processor := dummyProcessor{}
+	c.Assert(traverser.traverse(noPreProccessor, processor.process, nil), chk.IsNil)
+
+	files := make([]storedObject, 0)
+	for _, object := range processor.record {
+		if object.entityType == common.EEntityType.File() {
+			files = append(files, object)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].relativePath < files[j].relativePath })
+	return files
+}
+
+func relativePathsOf(objects []storedObject) []string {
+	paths := make([]string, 0, len(objects))
+	for _, object := range objects {
+		paths = append(paths, object.relativePath)
+	}
+	return paths
+}
+
+func (s *syncSymlinksSuite) TestLinkedPathsAreStable(c *chk.C) {
+	root := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(root)
+	target := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(target)
+
+	c.Assert(os.MkdirAll(filepath.Join(target, "child"), os.ModePerm), chk.IsNil)
+	scenarioHelper{}.generateLocalFilesFromList(c, target, []string{"file1.txt", "child/file2.txt"})
+	trySymlink(target, filepath.Join(root, "toroot"), c)
+	trySymlink(filepath.Join(target, "child"), filepath.Join(root, "tochild"), c)
+
+	// child's contents are only taken once, and, unlike copy, which takes them under whichever symlink it happens upon first,
+	// always under the symlink that comes first in order of path
+	for i := 0; i < 5; i++ {
+		c.Assert(relativePathsOf(listFollowingSymlinks(c, root)), chk.DeepEquals,
+			[]string{"tochild/file2.txt", "toroot/file1.txt"})
+	}
+}
+
+func (s *syncSymlinksSuite) TestRealPathIsPreferredToSymlinks(c *chk.C) {
+	root := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(root)
+
+	c.Assert(os.MkdirAll(filepath.Join(root, "sub"), os.ModePerm), chk.IsNil)
+	scenarioHelper{}.generateLocalFilesFromList(c, root, []string{"sub/file1.txt"})
+	trySymlink(filepath.Join(root, "sub"), filepath.Join(root, "a-link"), c) // which comes before sub in order of path
+
+	c.Assert(relativePathsOf(listFollowingSymlinks(c, root)), chk.DeepEquals, []string{"sub/file1.txt"})
+}
+
+func (s *syncSymlinksSuite) TestManySymlinksToOneFolderAreWalkedOnce(c *chk.C) {
+	root := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(root)
+	store := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(store)
+
+	// every level only links to the next, twice, so following every path would take the file 2^depth times
+	const depth = 12
+	level := filepath.Join(store, "level0")
+	c.Assert(os.MkdirAll(level, os.ModePerm), chk.IsNil)
+	trySymlink(level, filepath.Join(root, "linked"), c)
+	for i := 1; i <= depth; i++ {
+		next := filepath.Join(store, fmt.Sprintf("level%d", i))
+		c.Assert(os.MkdirAll(next, os.ModePerm), chk.IsNil)
+		trySymlink(next, filepath.Join(level, "left"), c)
+		trySymlink(next, filepath.Join(level, "right"), c)
+		level = next
+	}
+	scenarioHelper{}.generateLocalFilesFromList(c, level, []string{"file.txt"})
+
+	c.Assert(relativePathsOf(listFollowingSymlinks(c, root)), chk.DeepEquals,
+		[]string{"linked/" + strings.Repeat("left/", depth) + "file.txt"})
+}
+
+func (s *syncSymlinksSuite) TestLoopsAreBroken(c *chk.C) {
+	root := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(root)
+
+	c.Assert(os.MkdirAll(filepath.Join(root, "sub", "deeper"), os.ModePerm), chk.IsNil)
+	scenarioHelper{}.generateLocalFilesFromList(c, root, []string{"file1.txt", "sub/deeper/file2.txt"})
+	trySymlink(root, filepath.Join(root, "sub", "deeper", "toroot"), c)
+	trySymlink(filepath.Join(root, "sub"), filepath.Join(root, "tosub"), c)
+
+	// neither toroot, which leads back round, nor tosub, which leads somewhere already seen, is followed
+	c.Assert(relativePathsOf(listFollowingSymlinks(c, root)), chk.DeepEquals,
+		[]string{"file1.txt", "sub/deeper/file2.txt"})
+}
+
+func (s *syncSymlinksSuite) TestDeletionsWhenSymlinkTargetMoves(c *chk.C) {
+	root := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(root)
+	parent := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(parent)
+
+	target := filepath.Join(parent, "target")
+	c.Assert(os.MkdirAll(target, os.ModePerm), chk.IsNil)
+	scenarioHelper{}.generateLocalFilesFromList(c, root, []string{"file1.txt"})
+	scenarioHelper{}.generateLocalFilesFromList(c, target, []string{"file2.txt"})
+	link := filepath.Join(root, "linked")
+	trySymlink(target, link, c)
+
+	// what the last sync left at the destination
+	synced := listFollowingSymlinks(c, root)
+	c.Assert(relativePathsOf(synced), chk.DeepEquals, []string{"file1.txt", "linked/file2.txt"})
+
+	// compares the source, as it is now, against what was synced last time
+	extraAtDestination := func() []string {
+		indexer := newObjectIndexer()
+		for _, object := range listFollowingSymlinks(c, root) {
+			c.Assert(indexer.store(object), chk.IsNil)
+		}
+
+		copyScheduler, cleaner := dummyProcessor{}, dummyProcessor{}
+		comparator := newSyncDestinationComparator(indexer, copyScheduler.process, cleaner.process, lmtChangeDetector{})
+		for _, object := range synced {
+			c.Assert(comparator.processIfNecessary(object), chk.IsNil)
+		}
+		return relativePathsOf(cleaner.record)
+	}
+	c.Assert(extraAtDestination(), chk.DeepEquals, []string{})
+
+	// once the target has moved away, what was synced through the symlink is no longer at the source
+	moved := filepath.Join(parent, "moved")
+	c.Assert(os.Rename(target, moved), chk.IsNil)
+	c.Assert(extraAtDestination(), chk.DeepEquals, []string{"linked/file2.txt"})
+
+	// and when the symlink follows it, it's back
+	c.Assert(os.Remove(link), chk.IsNil)
+	trySymlink(moved, link, c)
+	c.Assert(extraAtDestination(), chk.DeepEquals, []string{})
+}
+
+func (s *syncSymlinksSuite) TestCookFollowSymlinks(c *chk.C) {
+	dirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dirName)
+	containerURL := "https://dst.blob.core.windows.net/container/dir?sv=x"
+
+	raw := getDefaultSyncRawInput(dirName, containerURL)
+	raw.followSymlinks = true
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.followSymlinks, chk.Equals, true)
+
+	// symlinks are only followed at a local source
+	raw = getDefaultSyncRawInput(containerURL, dirName)
+	raw.followSymlinks = true
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+
+	// and not by the kinds of sync that need the source listed in order, or that write to it
+	raw = getDefaultSyncRawInput(dirName, containerURL)
+	raw.followSymlinks = true
+	raw.incremental = true
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+
+	raw = getDefaultSyncRawInput(dirName, containerURL)
+	raw.followSymlinks = true
+	raw.bidirectional = true
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
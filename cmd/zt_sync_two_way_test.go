@@ -0,0 +1,300 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncTwoWaySuite struct{}
+
+var _ = chk.Suite(&syncTwoWaySuite{})
+
+// records the transfers that a two-way comparator schedules, as "object -> destination path"
+type dummyTwoWayTransfers struct {
+	record []string
+}
+
+func (d *dummyTwoWayTransfers) schedule(object storedObject, destinationRelativePath string) error {
+	d.record = append(d.record, object.relativePath+" -> "+destinationRelativePath)
+	return nil
+}
+
+func newTestTwoWayComparator(state *twoWaySyncState, policy common.SyncConflictPolicy, localRoot string,
+	forward, backward *dummyTwoWayTransfers, sourceCleaner, destinationCleaner *dummyProcessor, conflicts *int) *twoWaySyncComparator {
+	return &twoWaySyncComparator{
+		state:                  state,
+		policy:                 policy,
+		sourceIsLocal:          true,
+		localRoot:              localRoot,
+		localMd5:               remoteMd5Getter, // the test objects carry their own hashes
+		copyForward:            forward.schedule,
+		copyBackward:           backward.schedule,
+		sourceCleaner:          sourceCleaner.process,
+		destinationCleaner:     destinationCleaner.process,
+		propagateDeletions:     true,
+		conflictTime:           time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		incrementConflictCount: func() { *conflicts++ },
+	}
+}
+
+func (s *syncTwoWaySuite) TestTwoWayReconcile(c *chk.C) {
+	stateFolder, err := ioutil.TempDir("", "syncstate")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(stateFolder)
+
+	state, err := loadTwoWaySyncState(filepath.Join(stateFolder, "state.json"))
+	c.Assert(err, chk.IsNil)
+
+	t0 := time.Now().UTC().Truncate(time.Second)
+	t1 := t0.Add(time.Minute)
+	t2 := t0.Add(2 * time.Minute)
+	file := func(name string, size int64, lmt time.Time, md5 byte) storedObject {
+		return storedObject{name: name, relativePath: name, entityType: common.EEntityType.File(), size: size, lastModifiedTime: lmt, md5: []byte{md5}}
+	}
+	for _, name := range []string{"unchanged", "sourceChanged", "destinationChanged", "deletedFromDestination", "deletedFromSource", "changedThenDeleted", "bothChanged"} {
+		state.previous[name] = twoWaySyncEntry{Size: 1, SourceLMT: t0, DestinationLMT: t0}
+	}
+
+	forward, backward := &dummyTwoWayTransfers{}, &dummyTwoWayTransfers{}
+	sourceCleaner, destinationCleaner := &dummyProcessor{}, &dummyProcessor{}
+	conflicts := 0
+	comparator := newTestTwoWayComparator(state, common.ESyncConflictPolicy.NewerWins(), stateFolder, forward, backward, sourceCleaner, destinationCleaner, &conflicts)
+
+	c.Assert(comparator.processPair(file("unchanged", 1, t0, 0), file("unchanged", 1, t0, 0)), chk.IsNil)
+	c.Assert(comparator.processPair(file("sourceChanged", 1, t1, 0), file("sourceChanged", 1, t0, 0)), chk.IsNil)
+	c.Assert(comparator.processPair(file("destinationChanged", 1, t0, 0), file("destinationChanged", 2, t0, 0)), chk.IsNil)
+	c.Assert(comparator.processSourceOnly(file("deletedFromDestination", 1, t0, 0)), chk.IsNil)
+	c.Assert(comparator.processDestinationOnly(file("deletedFromSource", 1, t0, 0)), chk.IsNil)
+	c.Assert(comparator.processSourceOnly(file("changedThenDeleted", 3, t1, 0)), chk.IsNil)
+	c.Assert(comparator.processSourceOnly(file("new", 1, t1, 0)), chk.IsNil)
+	c.Assert(comparator.processPair(file("newAtBothEnds", 1, t1, 7), file("newAtBothEnds", 1, t2, 7)), chk.IsNil)
+	c.Assert(comparator.processPair(file("bothChanged", 1, t1, 1), file("bothChanged", 1, t2, 2)), chk.IsNil)
+
+	c.Assert(forward.record, chk.DeepEquals, []string{"sourceChanged -> sourceChanged", "changedThenDeleted -> changedThenDeleted", "new -> new"})
+	c.Assert(backward.record, chk.DeepEquals, []string{"destinationChanged -> destinationChanged", "bothChanged -> bothChanged"})
+	c.Assert(len(sourceCleaner.record), chk.Equals, 1)
+	c.Assert(sourceCleaner.record[0].relativePath, chk.Equals, "deletedFromDestination")
+	c.Assert(len(destinationCleaner.record), chk.Equals, 1)
+	c.Assert(destinationCleaner.record[0].relativePath, chk.Equals, "deletedFromSource")
+	c.Assert(conflicts, chk.Equals, 1)
+
+	// paths that were in step are recorded straight away, and transferred ones only once the transfers succeed
+	c.Assert(state.commit(true, nil), chk.IsNil)
+	saved, err := loadTwoWaySyncState(state.filePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(saved.previous), chk.Equals, 7)
+	_, stillRecorded := saved.previous["deletedFromDestination"]
+	c.Assert(stillRecorded, chk.Equals, false)
+	c.Assert(saved.previous["newAtBothEnds"].DestinationLMT.Equal(t2), chk.Equals, true)
+	c.Assert(saved.previous["new"].DestinationLMT.IsZero(), chk.Equals, true)     // it was uploaded, so its remote time isn't known yet
+	c.Assert(saved.previous["bothChanged"].SourceLMT.Equal(t2), chk.Equals, true) // it was downloaded, which keeps the remote time
+}
+
+func (s *syncTwoWaySuite) TestTwoWayNoticesEditsOfUploadsWithoutMD5(c *chk.C) {
+	stateFolder, err := ioutil.TempDir("", "syncstate")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(stateFolder)
+
+	state, err := loadTwoWaySyncState(filepath.Join(stateFolder, "state.json"))
+	c.Assert(err, chk.IsNil)
+
+	// both were uploaded by the last sync, so their remote times weren't known
+	t0 := time.Now().UTC().Truncate(time.Second)
+	t1 := t0.Add(time.Minute)
+	for _, name := range []string{"untouched", "editedElsewhere"} {
+		state.previous[name] = twoWaySyncEntry{Size: 1, MD5: []byte{5}, SourceLMT: t0}
+	}
+
+	forward, backward := &dummyTwoWayTransfers{}, &dummyTwoWayTransfers{}
+	conflicts := 0
+	comparator := newTestTwoWayComparator(state, common.ESyncConflictPolicy.NewerWins(), stateFolder, forward, backward, &dummyProcessor{}, &dummyProcessor{}, &conflicts)
+	local := func(name string) storedObject {
+		return storedObject{name: name, relativePath: name, entityType: common.EEntityType.File(), size: 1, lastModifiedTime: t0, md5: []byte{5}}
+	}
+
+	// the untouched blob still has the MD5 it was uploaded with, so it's in step, and its time is now known
+	c.Assert(comparator.processPair(local("untouched"), storedObject{name: "untouched", relativePath: "untouched", entityType: common.EEntityType.File(),
+		size: 1, lastModifiedTime: t1, md5: []byte{5}}), chk.IsNil)
+	// whereas the other has been rewritten, with the same size but no MD5, so it can't be taken to be unchanged
+	c.Assert(comparator.processPair(local("editedElsewhere"), storedObject{name: "editedElsewhere", relativePath: "editedElsewhere", entityType: common.EEntityType.File(),
+		size: 1, lastModifiedTime: t1}), chk.IsNil)
+
+	// which is settled by the conflict policy: here the newer version, i.e. the edit, wins
+	c.Assert(forward.record, chk.HasLen, 0)
+	c.Assert(backward.record, chk.DeepEquals, []string{"editedElsewhere -> editedElsewhere"})
+	c.Assert(conflicts, chk.Equals, 1)
+
+	c.Assert(state.commit(true, nil), chk.IsNil)
+	saved, err := loadTwoWaySyncState(state.filePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(saved.previous["untouched"].DestinationLMT.Equal(t1), chk.Equals, true)
+	c.Assert(saved.previous["editedElsewhere"].SourceLMT.Equal(t1), chk.Equals, true) // it was downloaded, which keeps the remote time
+}
+
+func (s *syncTwoWaySuite) TestTwoWayStateKeepsPreviousEntriesIfTransfersFail(c *chk.C) {
+	stateFolder, err := ioutil.TempDir("", "syncstate")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(stateFolder)
+
+	t0 := time.Now().UTC().Truncate(time.Second)
+	state, err := loadTwoWaySyncState(filepath.Join(stateFolder, "state.json"))
+	c.Assert(err, chk.IsNil)
+	state.previous["a"] = twoWaySyncEntry{Size: 1, SourceLMT: t0, DestinationLMT: t0}
+	state.previous["unseen"] = twoWaySyncEntry{Size: 2, SourceLMT: t0, DestinationLMT: t0}
+
+	state.recordPending("a", twoWaySyncEntry{Size: 5, SourceLMT: t0.Add(time.Hour)})
+	state.recordPending("b", twoWaySyncEntry{Size: 6})
+	c.Assert(state.commit(false, nil), chk.IsNil)
+
+	saved, err := loadTwoWaySyncState(state.filePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(saved.previous), chk.Equals, 2)
+	c.Assert(saved.previous["a"].Size, chk.Equals, int64(1))
+	c.Assert(saved.previous["unseen"].Size, chk.Equals, int64(2))
+}
+
+func (s *syncTwoWaySuite) TestTwoWayKeepBoth(c *chk.C) {
+	localRoot, err := ioutil.TempDir("", "synclocal")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(localRoot)
+	c.Assert(os.Mkdir(filepath.Join(localRoot, "dir"), 0755), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(localRoot, "dir", "report.txt"), []byte("local"), 0644), chk.IsNil)
+
+	state, err := loadTwoWaySyncState(filepath.Join(localRoot, "state.json"))
+	c.Assert(err, chk.IsNil)
+	forward, backward := &dummyTwoWayTransfers{}, &dummyTwoWayTransfers{}
+	conflicts := 0
+	comparator := newTestTwoWayComparator(state, common.ESyncConflictPolicy.KeepBoth(), localRoot, forward, backward, &dummyProcessor{}, &dummyProcessor{}, &conflicts)
+
+	lmt := time.Now().UTC()
+	local := storedObject{name: "report.txt", relativePath: "dir/report.txt", entityType: common.EEntityType.File(), size: 5, lastModifiedTime: lmt, md5: []byte{1}}
+	remote := storedObject{name: "report.txt", relativePath: "dir/report.txt", entityType: common.EEntityType.File(), size: 6, lastModifiedTime: lmt, md5: []byte{2}}
+	c.Assert(comparator.processPair(local, remote), chk.IsNil)
+
+	// the local version has been moved out of the way, and goes up under its new name, while the remote one comes down
+	renamed := "dir/report.conflict-20200102T030405.txt"
+	_, err = os.Stat(filepath.Join(localRoot, "dir", "report.txt"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	_, err = os.Stat(filepath.Join(localRoot, filepath.FromSlash(renamed)))
+	c.Assert(err, chk.IsNil)
+	c.Assert(forward.record, chk.DeepEquals, []string{renamed + " -> " + renamed})
+	c.Assert(backward.record, chk.DeepEquals, []string{"dir/report.txt -> dir/report.txt"})
+	c.Assert(conflicts, chk.Equals, 1)
+}
+
+func (s *syncTwoWaySuite) TestTwoWayTransfersGoInOneJobPerDirection(c *chk.C) {
+	type partOrder struct {
+		fromTo    common.FromTo
+		partNum   common.PartNumber
+		isFinal   bool
+		transfers int
+	}
+	orders := make(map[common.JobID][]partOrder)
+	Rpc = func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		c.Assert(cmd, chk.Equals, common.ERpcCmd.CopyJobPartOrder())
+		order := request.(*common.CopyJobPartOrderRequest)
+		orders[order.JobID] = append(orders[order.JobID], partOrder{order.FromTo, order.PartNum, order.IsFinalPart, len(order.Transfers)})
+
+		// like the transfer engine, refuse to start a job with nothing in it
+		if order.PartNum == 0 && len(order.Transfers) == 0 {
+			*(response.(*common.CopyJobPartOrderResponse)) = common.CopyJobPartOrderResponse{ErrorMsg: common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr()}
+		} else {
+			*(response.(*common.CopyJobPartOrderResponse)) = common.CopyJobPartOrderResponse{JobStarted: true}
+		}
+	}
+
+	newScheduler := func() (*twoWayTransferScheduler, common.JobID, common.JobID) {
+		local := common.ResourceString{Value: "/local"}
+		remote := common.ResourceString{Value: "https://account.blob.core.windows.net/container"}
+		forwardTemplate := &common.CopyJobPartOrderRequest{JobID: common.NewJobID(), FromTo: common.EFromTo.LocalBlob(), SourceRoot: local, DestinationRoot: remote}
+		backwardTemplate := &common.CopyJobPartOrderRequest{JobID: common.NewJobID(), FromTo: common.EFromTo.BlobLocal(), SourceRoot: remote, DestinationRoot: local}
+		forward := newCopyTransferProcessor(forwardTemplate, 2, local, remote, nil, nil, false)
+		backward := newCopyTransferProcessor(backwardTemplate, 2, remote, local, nil, nil, false)
+		return newTwoWayTransferScheduler(forward, backward), forwardTemplate.JobID, backwardTemplate.JobID
+	}
+
+	// the uploads and downloads each get a job of their own, whose parts all go the same way, and which each end with a final part
+	scheduler, uploadJobID, downloadJobID := newScheduler()
+	for _, name := range []string{"up1", "up2", "up3"} {
+		c.Assert(scheduler.forward.scheduleCopyTransfer(storedObject{name: name, relativePath: name}), chk.IsNil)
+	}
+	c.Assert(scheduler.backward.scheduleCopyTransfer(storedObject{name: "down1", relativePath: "down1"}), chk.IsNil)
+	jobInitiated, err := scheduler.dispatchFinalPart()
+	c.Assert(err, chk.IsNil)
+	c.Assert(jobInitiated, chk.Equals, true)
+
+	c.Assert(orders, chk.HasLen, 2)
+	c.Assert(orders[uploadJobID], chk.DeepEquals, []partOrder{
+		{common.EFromTo.LocalBlob(), 0, false, 2},
+		{common.EFromTo.LocalBlob(), 1, true, 1},
+	})
+	c.Assert(orders[downloadJobID], chk.DeepEquals, []partOrder{
+		{common.EFromTo.BlobLocal(), 0, true, 1},
+	})
+
+	// with transfers in only one direction, only that direction's job is started, since the other's (empty) final part is refused
+	orders = make(map[common.JobID][]partOrder)
+	scheduler, uploadJobID, downloadJobID = newScheduler()
+	c.Assert(scheduler.backward.scheduleCopyTransfer(storedObject{name: "down1", relativePath: "down1"}), chk.IsNil)
+	jobInitiated, err = scheduler.dispatchFinalPart()
+	c.Assert(err, chk.IsNil)
+	c.Assert(jobInitiated, chk.Equals, true)
+	c.Assert(orders[uploadJobID], chk.DeepEquals, []partOrder{{common.EFromTo.LocalBlob(), 0, true, 0}})
+	c.Assert(orders[downloadJobID], chk.DeepEquals, []partOrder{{common.EFromTo.BlobLocal(), 0, true, 1}})
+
+	// and with none at all, there's nothing to do
+	scheduler, _, _ = newScheduler()
+	_, err = scheduler.dispatchFinalPart()
+	c.Assert(err, chk.Equals, NothingScheduledError)
+}
+
+func (s *syncTwoWaySuite) TestCombineJobSummaries(c *chk.C) {
+	upload := common.ListJobSummaryResponse{JobStatus: common.EJobStatus.Completed(), TotalTransfers: 3, TransfersCompleted: 3,
+		TotalBytesExpected: 300, TotalBytesTransferred: 300}
+	download := common.ListJobSummaryResponse{JobStatus: common.EJobStatus.InProgress(), TotalTransfers: 2, TransfersCompleted: 1,
+		TotalBytesExpected: 100, TotalBytesTransferred: 0}
+
+	// the pair isn't done until both jobs are
+	combined := combineJobSummaries(upload, download)
+	c.Assert(combined.JobStatus, chk.Equals, common.EJobStatus.InProgress())
+	c.Assert(combined.TotalTransfers, chk.Equals, uint32(5))
+	c.Assert(combined.TransfersCompleted, chk.Equals, uint32(4))
+	c.Assert(combined.PercentComplete, chk.Equals, float32(75))
+
+	// and a failure in either makes the pair's status reflect it
+	download.JobStatus = common.EJobStatus.Failed()
+	download.TransfersFailed = 1
+	download.TotalBytesTransferred = 50
+	combined = combineJobSummaries(upload, download)
+	c.Assert(combined.JobStatus, chk.Equals, common.EJobStatus.CompletedWithErrors())
+	c.Assert(combined.TransfersFailed, chk.Equals, uint32(1))
+
+	download.JobStatus = common.EJobStatus.Cancelled()
+	c.Assert(combineJobSummaries(upload, download).JobStatus, chk.Equals, common.EJobStatus.Cancelled())
+}
@@ -0,0 +1,80 @@
+// +build linux
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// waits for the watcher to report a change that matches
+func waitForLocalChange(c *chk.C, watcher localChangeWatcher, matches func(localChange) bool) localChange {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case change := <-watcher.changes():
+			c.Assert(change.err, chk.IsNil)
+			if matches(change) {
+				return change
+			}
+		case <-timeout:
+			c.Fatal("the expected change was not reported")
+		}
+	}
+}
+
+func (s *syncWatchSuite) TestInotifyWatcher(c *chk.C) {
+	rootPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(rootPath)
+	c.Assert(os.MkdirAll(filepath.Join(rootPath, "existing"), os.ModePerm), chk.IsNil)
+
+	watcher, err := newLocalChangeWatcher(rootPath, true)
+	c.Assert(err, chk.IsNil)
+	defer watcher.close()
+
+	// a new file is reported as having appeared
+	c.Assert(ioutil.WriteFile(filepath.Join(rootPath, "existing", "a.txt"), []byte("a"), common.DEFAULT_FILE_PERM), chk.IsNil)
+	change := waitForLocalChange(c, watcher, func(change localChange) bool { return change.relativePath == "existing/a.txt" })
+	c.Assert(change.appeared, chk.Equals, true)
+
+	// new directories are watched too
+	c.Assert(os.MkdirAll(filepath.Join(rootPath, "new"), os.ModePerm), chk.IsNil)
+	waitForLocalChange(c, watcher, func(change localChange) bool { return change.relativePath == "new" && change.appeared })
+	c.Assert(ioutil.WriteFile(filepath.Join(rootPath, "new", "b.txt"), []byte("b"), common.DEFAULT_FILE_PERM), chk.IsNil)
+	waitForLocalChange(c, watcher, func(change localChange) bool { return change.relativePath == "new/b.txt" })
+
+	// a deleted file is reported, but not as having appeared
+	c.Assert(os.Remove(filepath.Join(rootPath, "existing", "a.txt")), chk.IsNil)
+	change = waitForLocalChange(c, watcher, func(change localChange) bool { return change.relativePath == "existing/a.txt" && !change.appeared })
+
+	// what went with a directory that's moved away can't be known, so the whole source must be compared again
+	c.Assert(os.Rename(filepath.Join(rootPath, "new"), filepath.Join(rootPath, "..", filepath.Base(rootPath)+"-moved")), chk.IsNil)
+	defer os.RemoveAll(filepath.Join(rootPath, "..", filepath.Base(rootPath)+"-moved"))
+	waitForLocalChange(c, watcher, func(change localChange) bool { return change.rescan })
+}
@@ -0,0 +1,290 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncWatchSuite struct{}
+
+var _ = chk.Suite(&syncWatchSuite{})
+
+func (s *syncWatchSuite) TestCollectChangesWaitsForQuiet(c *chk.C) {
+	changes := make(chan localChange, 10)
+	changes <- localChange{relativePath: "a", appeared: true}
+	changes <- localChange{relativePath: "b"}
+	changes <- localChange{relativePath: "a"} // the first change to a path is what tells whether it appeared
+
+	start := time.Now()
+	batch, stopped, err := collectChanges(changes, 50*time.Millisecond, make(chan struct{}))
+	c.Assert(err, chk.IsNil)
+	c.Assert(stopped, chk.Equals, false)
+	c.Assert(time.Since(start) >= 50*time.Millisecond, chk.Equals, true)
+	c.Assert(batch.rescan, chk.Equals, false)
+	c.Assert(batch.paths, chk.DeepEquals, map[string]bool{"a": true, "b": false})
+}
+
+func (s *syncWatchSuite) TestCollectChangesDoesNotWaitForeverOnBusySource(c *chk.C) {
+	changes := make(chan localChange)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case changes <- localChange{relativePath: "busy"}:
+				time.Sleep(5 * time.Millisecond)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	batch, _, err := collectChanges(changes, 20*time.Millisecond, make(chan struct{}))
+	c.Assert(err, chk.IsNil)
+	c.Assert(time.Since(start) < time.Second, chk.Equals, true)
+	c.Assert(batch.paths["busy"], chk.Equals, false)
+}
+
+func (s *syncWatchSuite) TestCollectChangesStopsAndFails(c *chk.C) {
+	stop := make(chan struct{})
+	close(stop)
+	batch, stopped, err := collectChanges(make(chan localChange), time.Hour, stop)
+	c.Assert(err, chk.IsNil)
+	c.Assert(stopped, chk.Equals, true)
+	c.Assert(batch.isEmpty(), chk.Equals, true)
+
+	changes := make(chan localChange, 1)
+	changes <- localChange{err: errors.New("cannot read")}
+	_, _, err = collectChanges(changes, time.Hour, make(chan struct{}))
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *syncWatchSuite) TestScheduleBatch(c *chk.C) {
+	rootPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(rootPath)
+	scenarioHelper{}.generateLocalFilesFromList(c, rootPath, []string{"written.txt", "ignored.log", "new/a.txt", "new/sub/b.txt"})
+
+	dummyTransferScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	dummyBackup := dummyProcessor{}
+	scheduler := &syncWatchScheduler{
+		rootPath:           rootPath,
+		recursive:          true,
+		filters:            buildExcludeFilters([]string{"*.log"}, false),
+		scheduleTransfer:   dummyTransferScheduler.process,
+		beforeOverwrite:    dummyBackup.process,
+		destinationCleaner: dummyCleaner.process,
+	}
+
+	err := scheduler.schedule(syncWatchBatch{paths: map[string]bool{
+		"written.txt":   false,
+		"ignored.log":   false,
+		"new":           true,
+		"deleted.txt":   false, // was there before the batch, so it goes from the destination too
+		"temporary.tmp": true,  // came and went within the batch
+	}})
+	c.Assert(err, chk.IsNil)
+
+	transferred := make([]string, 0)
+	for _, object := range dummyTransferScheduler.record {
+		transferred = append(transferred, object.relativePath)
+	}
+	sort.Strings(transferred)
+	c.Assert(transferred, chk.DeepEquals, []string{"new", "new/a.txt", "new/sub", "new/sub/b.txt", "written.txt"})
+	c.Assert(dummyTransferScheduler.countFilesOnly(), chk.Equals, 3)
+
+	// anything that might overwrite a file at the destination is given to beforeOverwrite first
+	c.Assert(len(dummyBackup.record), chk.Equals, 3)
+
+	c.Assert(len(dummyCleaner.record), chk.Equals, 1)
+	c.Assert(dummyCleaner.record[0].relativePath, chk.Equals, "deleted.txt")
+	c.Assert(dummyCleaner.record[0].entityType, chk.Equals, common.EEntityType.File())
+}
+
+func (s *syncWatchSuite) TestScheduleBatchWithoutRecursion(c *chk.C) {
+	rootPath := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(rootPath)
+	c.Assert(os.MkdirAll(filepath.Join(rootPath, "new"), os.ModePerm), chk.IsNil)
+
+	dummyTransferScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	scheduler := &syncWatchScheduler{rootPath: rootPath, scheduleTransfer: dummyTransferScheduler.process, destinationCleaner: dummyCleaner.process}
+
+	c.Assert(scheduler.schedule(syncWatchBatch{paths: map[string]bool{"new": true}}), chk.IsNil)
+	c.Assert(len(dummyTransferScheduler.record), chk.Equals, 0)
+}
+
+func (s *syncWatchSuite) TestWatchValidation(c *chk.C) {
+	raw := getDefaultSyncRawInput("https://account.blob.core.windows.net/container?sv=x", "/dst")
+	raw.watch = true
+	_, err := raw.cook()
+	c.Assert(err, chk.NotNil) // the source must be local
+
+	raw = getDefaultSyncRawInput("/src", "https://account.blob.core.windows.net/container")
+	raw.watchDebounce = "5s"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil) // only with --watch
+
+	raw.watch = true
+	raw.deleteDestination = "prompt"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+
+	raw.deleteDestination = "true"
+	raw.watchDebounce = "-1s"
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+
+	raw.watchDebounce = "5s"
+	cca, err := raw.cook()
+	if err != nil {
+		// only Linux can watch
+		c.Assert(err.Error(), chk.Equals, "watch is only supported on Linux")
+		return
+	}
+	c.Assert(cca.watch, chk.NotNil)
+	c.Assert(cca.watch.debounce, chk.Equals, 5*time.Second)
+
+	// the first request only stops the watch
+	c.Assert(cca.watch.requestStop(), chk.Equals, true)
+	c.Assert(cca.watch.requestStop(), chk.Equals, false)
+}
+
+func (s *syncWatchSuite) TestWatchMovesToNewJobAfterEnoughPartsOrTime(c *chk.C) {
+	w := newSyncWatch(time.Second)
+	w.maxJobParts = 10
+	now := time.Now()
+	w.jobStartTime = now
+
+	// nothing has been ordered yet, so there's no job to end
+	c.Assert(w.shouldMoveToNewJob(now.Add(2*w.maxJobAge)), chk.Equals, false)
+
+	w.nextPartNum = 9
+	c.Assert(w.shouldMoveToNewJob(now), chk.Equals, false)
+	w.nextPartNum = 10
+	c.Assert(w.shouldMoveToNewJob(now), chk.Equals, true)
+
+	w.nextPartNum = 1
+	c.Assert(w.shouldMoveToNewJob(now.Add(w.maxJobAge-time.Minute)), chk.Equals, false)
+	c.Assert(w.shouldMoveToNewJob(now.Add(w.maxJobAge)), chk.Equals, true)
+}
+
+func (s *syncWatchSuite) TestWatchMoveToNewJob(c *chk.C) {
+	mockedRPC := interceptor{}
+	mockedRPC.init()
+	// the processor reuses its template from one part to the next, so each part is copied as it's ordered
+	var parts []common.CopyJobPartOrderRequest
+	Rpc = func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		if cmd == common.ERpcCmd.CopyJobPartOrder() {
+			parts = append(parts, *request.(*common.CopyJobPartOrderRequest))
+		}
+		mockedRPC.intercept(cmd, request, response)
+	}
+
+	cca := &cookedSyncCmdArgs{jobID: common.NewJobID(), watch: newSyncWatch(time.Second)}
+	firstJobID := cca.jobID
+	transferScheduler := newSyncTransferProcessor(cca, 10, common.EFolderPropertiesOption.NoFolders())
+	cca.orderIntoWatchJobs(transferScheduler)
+	scheduleAndDispatch := func(name string) {
+		object := newStoredObject(noPreProccessor, name, name, common.EEntityType.File(), time.Now(), 1, noContentProps, noBlobProps, noMetdata, "")
+		c.Assert(transferScheduler.scheduleCopyTransfer(object), chk.IsNil)
+		c.Assert(transferScheduler.dispatchPendingPart(), chk.IsNil)
+	}
+
+	scheduleAndDispatch("a.txt")
+	scheduleAndDispatch("b.txt")
+	c.Assert(cca.orderedJobIDs(), chk.DeepEquals, []common.JobID{firstJobID})
+
+	// the old job is ended with a final part, numbered after its others
+	c.Assert(cca.moveToNewJob(transferScheduler), chk.IsNil)
+	finalPart := parts[len(parts)-1]
+	c.Assert(finalPart.JobID, chk.Equals, firstJobID)
+	c.Assert(finalPart.PartNum, chk.Equals, common.PartNumber(2))
+	c.Assert(finalPart.IsFinalPart, chk.Equals, true)
+	c.Assert(cca.jobID, chk.Not(chk.Equals), firstJobID)
+
+	// and the next batch is the first part of the new one
+	scheduleAndDispatch("c.txt")
+	part := parts[len(parts)-1]
+	c.Assert(part.JobID, chk.Equals, cca.jobID)
+	c.Assert(part.PartNum, chk.Equals, common.PartNumber(0))
+	c.Assert(part.IsFinalPart, chk.Equals, false)
+	c.Assert(cca.orderedJobIDs(), chk.DeepEquals, []common.JobID{firstJobID, cca.jobID})
+	c.Assert(cca.watch.jobsOrdered(), chk.Equals, 2)
+}
+
+func (s *syncWatchSuite) TestWatchCleansUpFinishedJobs(c *chk.C) {
+	w := newSyncWatch(time.Second)
+	first, second, current := common.NewJobID(), common.NewJobID(), common.NewJobID()
+	for _, jobID := range []common.JobID{first, second, current} {
+		w.addJob(jobID)
+	}
+
+	summaries := map[common.JobID]common.ListJobSummaryResponse{
+		first:   {JobID: first, JobStatus: common.EJobStatus.Completed(), TotalTransfers: 3, TransfersCompleted: 3, BytesOverWire: 100},
+		second:  {JobID: second, JobStatus: common.EJobStatus.InProgress(), TotalTransfers: 2, TransfersCompleted: 1, BytesOverWire: 100},
+		current: {JobID: current, JobStatus: common.EJobStatus.InProgress(), TotalTransfers: 1, BytesOverWire: 100},
+	}
+	getSummary := func(jobID common.JobID) common.ListJobSummaryResponse {
+		summary, ok := summaries[jobID]
+		c.Assert(ok, chk.Equals, true) // a job that's been cleaned up can't be summarized
+		return summary
+	}
+	var cleanedUp []common.JobID
+	cleanUp := func(jobID common.JobID) {
+		cleanedUp = append(cleanedUp, jobID)
+		delete(summaries, jobID)
+	}
+
+	// only the job that's done is cleaned up, but what it did still counts
+	w.cleanUpFinishedJobs(current, getSummary, cleanUp)
+	c.Assert(cleanedUp, chk.DeepEquals, []common.JobID{first})
+	c.Assert(w.orderedJobIDs(), chk.DeepEquals, []common.JobID{second, current})
+	summary, done, ok := w.summarizeJobs(getSummary)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(done, chk.Equals, false)
+	c.Assert(summary.TotalTransfers, chk.Equals, uint32(6))
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint32(4))
+	c.Assert(summary.BytesOverWire, chk.Equals, uint64(200))
+
+	// the current job isn't cleaned up, even once it's done
+	summaries[second] = common.ListJobSummaryResponse{JobID: second, JobStatus: common.EJobStatus.Completed(), TotalTransfers: 2, TransfersCompleted: 2}
+	summaries[current] = common.ListJobSummaryResponse{JobID: current, JobStatus: common.EJobStatus.Completed(), TotalTransfers: 1, TransfersCompleted: 1}
+	w.cleanUpFinishedJobs(current, getSummary, cleanUp)
+	c.Assert(cleanedUp, chk.DeepEquals, []common.JobID{first, second})
+	c.Assert(w.orderedJobIDs(), chk.DeepEquals, []common.JobID{current})
+	summary, done, ok = w.summarizeJobs(getSummary)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(done, chk.Equals, true)
+	c.Assert(summary.TotalTransfers, chk.Equals, uint32(6))
+	c.Assert(summary.TransfersCompleted, chk.Equals, uint32(6))
+	c.Assert(w.jobsOrdered(), chk.Equals, 3)
+}
@@ -0,0 +1,347 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ClientEncryptionMetadataKey names the blob metadata that holds the envelope of a client-side encrypted blob
+const ClientEncryptionMetadataKey = "azcopyencryptiondata"
+
+const (
+	ClientEncryptionKeySize   = 32 // in bytes. Both the key-encryption key and the content keys are AES-256 keys
+	clientEncryptionAlgorithm = "AES_256_GCM"
+	clientEncryptionNonceSize = 12
+	ClientEncryptionTagSize   = 16 // each encrypted chunk is this much longer than its plaintext
+)
+
+// ClientEncryptionKeyHash identifies a key-encryption key without giving it away. It's what job plans keep, rather than the key.
+type ClientEncryptionKeyHash [16]byte
+
+func (h ClientEncryptionKeyHash) IsZero() bool {
+	return h == ClientEncryptionKeyHash{}
+}
+
+func (h ClientEncryptionKeyHash) String() string {
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// ClientEncryptionKey is the key-encryption key, held by the user, that wraps the content key of each blob
+type ClientEncryptionKey struct {
+	aead cipher.AEAD
+	hash ClientEncryptionKeyHash
+}
+
+func NewClientEncryptionKey(key []byte) (*ClientEncryptionKey, error) {
+	if len(key) != ClientEncryptionKeySize {
+		return nil, fmt.Errorf("the client-side encryption key must be %d bytes (256 bits) long, but it is %d bytes long", ClientEncryptionKeySize, len(key))
+	}
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &ClientEncryptionKey{aead: aead}
+	sum := sha256.Sum256(key)
+	copy(k.hash[:], sum[:])
+	return k, nil
+}
+
+// LoadClientEncryptionKey reads the key from the key file or, if there's no key file, from the environment.
+// The key file may hold the key as it is, or base64-encoded. If there's no key either way, the key is nil.
+func LoadClientEncryptionKey(keyFile string) (*ClientEncryptionKey, error) {
+	var encoded string
+	if keyFile != "" {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the client-side encryption key file: %w", err)
+		}
+		if len(content) == ClientEncryptionKeySize {
+			return NewClientEncryptionKey(content)
+		}
+		encoded = strings.TrimSpace(string(content))
+	} else {
+		encoded = strings.TrimSpace(GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.ClientEncryptionKey()))
+		if encoded == "" {
+			return nil, nil
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("the client-side encryption key must be either 32 bytes, or those bytes base64-encoded")
+	}
+	return NewClientEncryptionKey(key)
+}
+
+func (k *ClientEncryptionKey) Hash() ClientEncryptionKeyHash {
+	return k.hash
+}
+
+// wrap encrypts a content key, with a random nonce, which goes in front
+func (k *ClientEncryptionKey) wrap(contentKey []byte) (string, error) {
+	nonce := make([]byte, clientEncryptionNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(k.aead.Seal(nonce, nonce, contentKey, []byte(clientEncryptionAlgorithm))), nil
+}
+
+func (k *ClientEncryptionKey) unwrap(envelope *ClientEncryptionEnvelope) ([]byte, error) {
+	if envelope.KeyHash != k.hash.String() {
+		return nil, errors.New("the blob was encrypted with a different client-side encryption key")
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(envelope.WrappedContentKey)
+	if err != nil || len(wrapped) < clientEncryptionNonceSize {
+		return nil, errors.New("the blob's wrapped content key is malformed")
+	}
+	contentKey, err := k.aead.Open(nil, wrapped[:clientEncryptionNonceSize], wrapped[clientEncryptionNonceSize:], []byte(clientEncryptionAlgorithm))
+	if err != nil {
+		return nil, errors.New("cannot unwrap the blob's content key: " + err.Error())
+	}
+	return contentKey, nil
+}
+
+// ClientEncryptionEnvelope describes how a blob was encrypted. It's kept, as JSON, in the blob's metadata.
+// The plaintext is encrypted in chunks of ChunkSize bytes (the last may be shorter), each of which
+// is followed by its authentication tag, so that the blob can be uploaded, and decrypted, a chunk at a time.
+type ClientEncryptionEnvelope struct {
+	Algorithm     string
+	ChunkSize     int64
+	PlaintextSize int64
+	// the blob's own content key, encrypted with the key-encryption key
+	WrappedContentKey string
+	KeyHash           string
+	// the MD5 of the plaintext, base64-encoded. (The Content-MD5 of the blob itself would be the MD5 of the ciphertext.)
+	PlaintextMD5 string `json:",omitempty"`
+}
+
+// ClientEncryptedSize is how large the plaintext will be, once it's encrypted in chunks of the given size
+func ClientEncryptedSize(plaintextSize, chunkSize int64) int64 {
+	numChunks := (plaintextSize + chunkSize - 1) / chunkSize
+	return plaintextSize + numChunks*ClientEncryptionTagSize
+}
+
+func (e *ClientEncryptionEnvelope) EncryptedSize() int64 {
+	return ClientEncryptedSize(e.PlaintextSize, e.ChunkSize)
+}
+
+func (e *ClientEncryptionEnvelope) numChunks() int64 {
+	return (e.PlaintextSize + e.ChunkSize - 1) / e.ChunkSize
+}
+
+// GetPlaintextMD5 returns the MD5 of the plaintext, if it was recorded when the blob was uploaded
+func (e *ClientEncryptionEnvelope) GetPlaintextMD5() []byte {
+	md5, err := base64.StdEncoding.DecodeString(e.PlaintextMD5)
+	if err != nil {
+		return nil
+	}
+	return md5
+}
+
+// ParseClientEncryptionEnvelope returns the envelope in the blob's metadata, or nil if the blob isn't client-side encrypted
+func ParseClientEncryptionEnvelope(metadata Metadata) (*ClientEncryptionEnvelope, error) {
+	for key, value := range metadata {
+		if !strings.EqualFold(key, ClientEncryptionMetadataKey) {
+			continue
+		}
+
+		envelope := &ClientEncryptionEnvelope{}
+		if err := json.Unmarshal([]byte(value), envelope); err != nil {
+			return nil, fmt.Errorf("the blob's client-side encryption metadata is malformed: %w", err)
+		}
+		if envelope.Algorithm != clientEncryptionAlgorithm {
+			return nil, fmt.Errorf("the blob was encrypted with %s, which isn't supported", envelope.Algorithm)
+		}
+		if envelope.ChunkSize <= 0 || envelope.PlaintextSize < 0 {
+			return nil, errors.New("the blob's client-side encryption metadata is malformed")
+		}
+		return envelope, nil
+	}
+	return nil, nil
+}
+
+// ChunkEncryptor encrypts the chunks of a single blob, under a content key of the blob's own
+type ChunkEncryptor struct {
+	aead     cipher.AEAD
+	envelope ClientEncryptionEnvelope
+}
+
+func (k *ClientEncryptionKey) NewChunkEncryptor(chunkSize, plaintextSize int64) (*ChunkEncryptor, error) {
+	contentKey := make([]byte, ClientEncryptionKeySize)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, err
+	}
+	aead, err := newAESGCM(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := k.wrap(contentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChunkEncryptor{
+		aead: aead,
+		envelope: ClientEncryptionEnvelope{
+			Algorithm:         clientEncryptionAlgorithm,
+			ChunkSize:         chunkSize,
+			PlaintextSize:     plaintextSize,
+			WrappedContentKey: wrapped,
+			KeyHash:           k.hash.String(),
+		},
+	}, nil
+}
+
+// Seal appends the encrypted chunk to dst. As with cipher.AEAD, the plaintext may be encrypted in place by passing plaintext[:0] as dst.
+func (e *ChunkEncryptor) Seal(dst, plaintext []byte, chunkIndex int64) []byte {
+	return e.aead.Seal(dst, chunkNonce(chunkIndex), plaintext, chunkAdditionalData(chunkIndex, chunkIndex == e.envelope.numChunks()-1))
+}
+
+// Envelope returns the metadata value that describes the encryption, for the blob to be stored with
+func (e *ChunkEncryptor) Envelope(plaintextMD5 []byte) (string, error) {
+	envelope := e.envelope
+	if len(plaintextMD5) > 0 {
+		envelope.PlaintextMD5 = base64.StdEncoding.EncodeToString(plaintextMD5)
+	}
+	value, err := json.Marshal(envelope)
+	return string(value), err
+}
+
+// DecryptingWriter decrypts the ciphertext that is written to it, chunk by chunk, and writes the plaintext on to a final destination.
+// Since the chunks are authenticated, any tampering with the blob, including reordering or truncating its chunks, makes it fail.
+type DecryptingWriter struct {
+	destination io.WriteCloser
+	aead        cipher.AEAD
+	envelope    *ClientEncryptionEnvelope
+	buffer      []byte
+	chunkIndex  int64
+	md5Hasher   hash.Hash
+}
+
+// NewDecryptingWriter returns a DecryptingWriter that writes to the destination. Like the decompressing writer, it closes the destination when closed.
+func (k *ClientEncryptionKey) NewDecryptingWriter(destination io.WriteCloser, envelope *ClientEncryptionEnvelope) (*DecryptingWriter, error) {
+	contentKey, err := k.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAESGCM(contentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptingWriter{
+		destination: destination,
+		aead:        aead,
+		envelope:    envelope,
+		buffer:      make([]byte, 0, envelope.ChunkSize+ClientEncryptionTagSize),
+		md5Hasher:   md5.New(),
+	}, nil
+}
+
+// encryptedLengthOfCurrentChunk is the length of the chunk that's being buffered. It's only the last chunk that may be shorter.
+func (d *DecryptingWriter) encryptedLengthOfCurrentChunk() int64 {
+	fullLength := d.envelope.ChunkSize + ClientEncryptionTagSize
+	remaining := d.envelope.EncryptedSize() - d.chunkIndex*fullLength
+	return Iffint64(remaining < fullLength, remaining, fullLength)
+}
+
+func (d *DecryptingWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if d.chunkIndex >= d.envelope.numChunks() {
+			return n, errors.New("the blob is longer than its client-side encryption metadata says it should be")
+		}
+
+		toBuffer := Iffint64(int64(len(p)) < d.encryptedLengthOfCurrentChunk()-int64(len(d.buffer)), int64(len(p)), d.encryptedLengthOfCurrentChunk()-int64(len(d.buffer)))
+		d.buffer = append(d.buffer, p[:toBuffer]...)
+		p = p[toBuffer:]
+		n += int(toBuffer)
+
+		if int64(len(d.buffer)) == d.encryptedLengthOfCurrentChunk() {
+			isLast := d.chunkIndex == d.envelope.numChunks()-1
+			plaintext, err := d.aead.Open(d.buffer[:0], chunkNonce(d.chunkIndex), d.buffer, chunkAdditionalData(d.chunkIndex, isLast))
+			if err != nil {
+				return n, fmt.Errorf("cannot decrypt chunk %d of the blob: %w", d.chunkIndex, err)
+			}
+			d.md5Hasher.Write(plaintext)
+			if _, err = d.destination.Write(plaintext); err != nil {
+				return n, err
+			}
+
+			d.buffer = d.buffer[:0]
+			d.chunkIndex++
+		}
+	}
+	return n, nil
+}
+
+func (d *DecryptingWriter) Close() error {
+	closeErr := d.destination.Close()
+	if d.chunkIndex != d.envelope.numChunks() {
+		return errors.New("the blob is shorter than its client-side encryption metadata says it should be")
+	}
+	return closeErr
+}
+
+// PlaintextMD5 is the MD5 of the plaintext that has been written to the destination
+func (d *DecryptingWriter) PlaintextMD5() []byte {
+	return d.md5Hasher.Sum(nil)
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the chunk's index. That's safe, since each content key only encrypts one blob.
+func chunkNonce(chunkIndex int64) []byte {
+	nonce := make([]byte, clientEncryptionNonceSize)
+	binary.BigEndian.PutUint64(nonce[clientEncryptionNonceSize-8:], uint64(chunkIndex))
+	return nonce
+}
+
+// chunkAdditionalData authenticates whether the chunk is the last, so that dropping chunks from the end can't go unnoticed
+func chunkAdditionalData(chunkIndex int64, isLast bool) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint64(data, uint64(chunkIndex))
+	if isLast {
+		data[8] = 1
+	}
+	return data
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxCompressedSize is the most that the given number of bytes can come to once compressed, by any of the compressions that can be
+// used while uploading. Data that doesn't compress (e.g. data that's compressed already) grows slightly, by the framing of the format:
+// zstd adds at most a 256th, and gzip much less, along with a header and trailer of a few bytes.
+func MaxCompressedSize(size int64) int64 {
+	return size + size/256 + 1024
+}
+
+// NewCompressingWriter returns a WriteCloser which compresses the data that is written to it, before passing the
+// compressed data on to the destination. Close flushes the last of the compressed data, but (unlike the decompressing writer)
+// does not close the destination, since the destination is usually an in-memory buffer.
+// Several compressed streams of the same type may be concatenated, and will decompress to the concatenation of their data.
+// That's true of gzip members and zstd frames, and is what lets us compress each chunk of a file independently.
+func NewCompressingWriter(destination io.Writer, ct CompressionType) (io.WriteCloser, error) {
+	switch ct {
+	case ECompressionType.GZip():
+		return gzip.NewWriter(destination), nil
+	case ECompressionType.ZLib():
+		return zlib.NewWriter(destination), nil
+	case ECompressionType.Zstd():
+		return zstd.NewWriter(destination, zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, errors.New("unexpected compression type")
+	}
+}
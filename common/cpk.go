@@ -0,0 +1,112 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// CpkKeySize is the size, in bytes, of a customer-provided key. The service only takes AES-256 keys.
+const CpkKeySize = 32
+
+// MaxEncryptionScopeLength is the longest name that the service allows an encryption scope
+const MaxEncryptionScopeLength = 63
+
+// CpkKeyHash is the SHA-256 hash of a customer-provided key. It's what job plans keep, rather than the key.
+// (The service is sent it along with the key, to check that the key arrived intact.)
+type CpkKeyHash [sha256.Size]byte
+
+func (h CpkKeyHash) IsZero() bool {
+	return h == CpkKeyHash{}
+}
+
+func (h CpkKeyHash) String() string {
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// CpkKey is a customer-provided key, which the service encrypts and decrypts blobs with, but never stores
+type CpkKey struct {
+	encoded string // base64-encoded, which is how the service takes it
+	hash    CpkKeyHash
+}
+
+// LoadCpkKey reads the base64-encoded key from the environment. If there's no key there, the key is nil.
+func LoadCpkKey() (*CpkKey, error) {
+	encoded := strings.TrimSpace(GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.CpkEncryptionKey()))
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("the customer-provided key in %s must be base64-encoded", EEnvironmentVariable.CpkEncryptionKey().Name)
+	}
+	if len(key) != CpkKeySize {
+		return nil, fmt.Errorf("the customer-provided key must be %d bytes (256 bits) long, but it is %d bytes long", CpkKeySize, len(key))
+	}
+
+	return &CpkKey{encoded: base64.StdEncoding.EncodeToString(key), hash: sha256.Sum256(key)}, nil
+}
+
+func (k *CpkKey) Hash() CpkKeyHash {
+	return k.hash
+}
+
+// CpkOptions say how the service encrypts the blobs that a job writes: either with a customer-provided key,
+// which must then be given to read them too, or with the key of one of the account's encryption scopes.
+// When neither is given, the service uses the account's default encryption.
+type CpkOptions struct {
+	Key             *CpkKey
+	EncryptionScope string
+}
+
+func (o CpkOptions) Validate() error {
+	if o.Key != nil && o.EncryptionScope != "" {
+		return errors.New("a customer-provided key and an encryption scope can't be used together")
+	}
+	if len(o.EncryptionScope) > MaxEncryptionScopeLength {
+		return fmt.Errorf("the encryption scope name %q is longer than %d characters", o.EncryptionScope, MaxEncryptionScopeLength)
+	}
+	return nil
+}
+
+// ToClientProvidedKeyOptions gives the options as azblob sends them. They're empty when neither a key nor a scope is used,
+// so that no encryption headers are sent. (azblob only sends the scope on requests that write.)
+func (o CpkOptions) ToClientProvidedKeyOptions() azblob.ClientProvidedKeyOptions {
+	result := azblob.ClientProvidedKeyOptions{}
+	if o.Key != nil {
+		hash := o.Key.hash.String()
+		result.EncryptionKey = &o.Key.encoded
+		result.EncryptionKeySha256 = &hash
+		result.EncryptionAlgorithm = azblob.EncryptionAlgorithmAES256
+	}
+	if o.EncryptionScope != "" {
+		scope := o.EncryptionScope
+		result.EncryptionScope = &scope
+	}
+	return result
+}
@@ -195,6 +195,7 @@ func CreateBlobFSCredential(ctx context.Context, credInfo CredentialInfo, option
 }
 
 // CreateS3Credential creates AWS S3 credential according to credential info.
+// It also creates the HMAC credential for Google Cloud Storage, which is accessed through its S3-compatible API.
 func CreateS3Credential(ctx context.Context, credInfo CredentialInfo, options CredentialOpOptions) (*credentials.Credentials, error) {
 	glcm := GetLifecycleMgr()
 	switch credInfo.CredentialType {
@@ -209,6 +210,16 @@ func CreateS3Credential(ctx context.Context, credInfo CredentialInfo, options Cr
 
 		// create and return s3 credential
 		return credentials.NewStaticV4(accessKeyID, secretAccessKey, sessionToken), nil // S3 uses V4 signature
+	case ECredentialType.GCPHMACKey():
+		accessID := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACAccessID())
+		secret := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACSecret())
+
+		if accessID == "" || secret == "" {
+			return nil, errors.New("GOOGLE_CLOUD_HMAC_ACCESS_ID and GOOGLE_CLOUD_HMAC_SECRET environment variables must be set before creating the GCP HMAC key credential")
+		}
+
+		// GCS accepts V4 signatures made with HMAC keys on its S3-interoperable XML API
+		return credentials.NewStaticV4(accessID, secret, ""), nil
 	default:
 		options.panicError(fmt.Errorf("invalid state, credential type %v is not supported", credInfo.CredentialType))
 	}
@@ -240,13 +251,23 @@ func refreshBlobFSToken(ctx context.Context, tokenInfo OAuthTokenInfo, tokenCred
 // S3 credential related factory methods
 // ==============================================================================================
 func CreateS3Client(ctx context.Context, credInfo CredentialInfo, option CredentialOpOptions) (*minio.Client, error) {
-	// Currently only support access key
+	// GCP service accounts use OAuth tokens, rather than signing requests
+	if credInfo.CredentialType == ECredentialType.GCPServiceAccount() {
+		return createGCPServiceAccountClient(credInfo)
+	}
+
+	// Otherwise, only support access key (or, for GCP, HMAC key)
 	credential, err := CreateS3Credential(ctx, credInfo, option)
 	if err != nil {
 		return nil, err
 	}
 
-	return minio.NewWithCredentials(credInfo.S3CredentialInfo.Endpoint, credential, true, credInfo.S3CredentialInfo.Region)
+	return minio.NewWithOptions(credInfo.S3CredentialInfo.Endpoint, &minio.Options{
+		Creds:        credential,
+		Secure:       !credInfo.S3CredentialInfo.Insecure,
+		Region:       credInfo.S3CredentialInfo.Region,
+		BucketLookup: credInfo.S3CredentialInfo.BucketLookup,
+	})
 }
 
 type S3ClientFactory struct {
@@ -21,11 +21,16 @@
 package common
 
 import (
+	"bytes"
 	"compress/gzip"
 	"compress/zlib"
 	"errors"
 	"io"
+	"io/ioutil"
 	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 type decompressingWriter struct {
@@ -39,7 +44,7 @@ var decompressingWriterBufferPool = NewMultiSizeSlicePool(decompressingWriterCop
 // NewDecompressingWriter returns a WriteCloser which decompresses the data
 // that is written to it, before passing the decompressed data on to a final destination.
 // This decompressor is intended to work with compressed data wrapped in either the ZLib headers or the slightly larger
-// Gzip headers. Both of those formats compress a single file (often a .tar archive in the case of Gzip).
+// Gzip headers, or in Zstandard or Brotli streams. All of those formats compress a single file (often a .tar archive in the case of Gzip).
 // So there is no need to to expand the decompressed info out into multiple files (as we would have to do,
 // if we were to support "zip" compression). See https://stackoverflow.com/a/20765054
 func NewDecompressingWriter(destination io.WriteCloser, ct CompressionType) io.WriteCloser {
@@ -62,11 +67,79 @@ func (d decompressingWriter) decompressorFactory(tp CompressionType, preader *io
 		return zlib.NewReader(preader)
 	case ECompressionType.GZip():
 		return gzip.NewReader(preader)
+	case ECompressionType.Zstd():
+		dec, err := zstd.NewReader(preader, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case ECompressionType.Brotli():
+		return newBrotliDecompressor(preader), nil
 	default:
 		return nil, errors.New("unexpected compression type")
 	}
 }
 
+// brotliDecompressor reports truncated brotli streams, which brotli.Reader does not reliably do by itself: if its
+// source reaches EOF between two of its reads, it returns a clean io.EOF whether or not the stream was complete.
+// So when that happens, we offer it one more byte. A decoder that has finished the stream always rejects that
+// as excessive input, and anything else means the stream was cut short.
+type brotliDecompressor struct {
+	src *brotliSource
+	dec *brotli.Reader
+}
+
+// brotliExcessiveInput is the error that brotli.Reader gives for input after the end of the stream. The package doesn't export it,
+// so it's taken from a reader that's offered a byte after a complete stream, and errors are compared with it, rather than with its text.
+// Were the package ever to stop giving it, this would be nil, and every brotli stream would be taken to be truncated, rather than none.
+var brotliExcessiveInput = func() error {
+	stream := &bytes.Buffer{}
+	if err := brotli.NewWriter(stream).Close(); err != nil {
+		return nil
+	}
+	stream.WriteByte(0)
+	_, err := ioutil.ReadAll(brotli.NewReader(stream))
+	return err
+}()
+
+func newBrotliDecompressor(src io.Reader) io.ReadCloser {
+	s := &brotliSource{src: src}
+	return &brotliDecompressor{src: s, dec: brotli.NewReader(s)}
+}
+
+func (b *brotliDecompressor) Read(p []byte) (int, error) {
+	n, err := b.dec.Read(p)
+	if err == io.EOF {
+		b.src.probing = true
+		if _, probeErr := b.dec.Read(make([]byte, 1)); brotliExcessiveInput == nil || probeErr != brotliExcessiveInput {
+			return n, io.ErrUnexpectedEOF
+		}
+	}
+	return n, err
+}
+
+func (b *brotliDecompressor) Close() error {
+	return nil
+}
+
+type brotliSource struct {
+	src     io.Reader
+	probing bool
+	probed  bool
+}
+
+func (s *brotliSource) Read(p []byte) (int, error) {
+	if !s.probing {
+		return s.src.Read(p)
+	}
+	if s.probed || len(p) == 0 {
+		return 0, io.EOF
+	}
+	s.probed = true
+	p[0] = 0
+	return 1, nil
+}
+
 func (d decompressingWriter) worker(tp CompressionType, preader *io.PipeReader, destination io.WriteCloser, workerError chan error) {
 
 	var err error
@@ -43,8 +43,16 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.LogLocation(),
 	EEnvironmentVariable.JobPlanLocation(),
 	EEnvironmentVariable.BufferGB(),
+	EEnvironmentVariable.SyncIndexGB(),
 	EEnvironmentVariable.AWSAccessKeyID(),
 	EEnvironmentVariable.AWSSecretAccessKey(),
+	EEnvironmentVariable.S3Endpoint(),
+	EEnvironmentVariable.S3Region(),
+	EEnvironmentVariable.GoogleHMACAccessID(),
+	EEnvironmentVariable.GoogleHMACSecret(),
+	EEnvironmentVariable.GoogleAppCredentials(),
+	EEnvironmentVariable.ClientEncryptionKey(),
+	EEnvironmentVariable.CpkEncryptionKey(),
 	EEnvironmentVariable.ShowPerfStates(),
 	EEnvironmentVariable.PacePageBlobs(),
 	EEnvironmentVariable.DefaultServiceApiVersion(),
@@ -156,6 +164,13 @@ func (EnvironmentVariable) BufferGB() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) SyncIndexGB() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SYNC_INDEX_GB",
+		Description: "Max number of GB that the sync command should use to hold its index of files in memory, before keeping the rest on disk in the job plan folder. May include decimal point, e.g. 0.5. The default is 2.",
+	}
+}
+
 func (EnvironmentVariable) AccountName() EnvironmentVariable {
 	return EnvironmentVariable{Name: "ACCOUNT_NAME"}
 }
@@ -204,6 +219,58 @@ func (EnvironmentVariable) AWSSecretAccessKey() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) ClientEncryptionKey() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CLIENT_ENCRYPTION_KEY",
+		Description: "The base64-encoded 256-bit key used for client-side encryption, when no key file is given with --client-encryption-key-file.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) CpkEncryptionKey() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CPK_ENCRYPTION_KEY",
+		Description: "The base64-encoded 256-bit customer-provided key that Blob Storage encrypts and decrypts blobs with, when --cpk-by-value is given.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) S3Endpoint() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_S3_ENDPOINT",
+		Description: "The host name (and optional port) of a custom S3-compatible service, such as MinIO, Ceph or Wasabi. URLs on this host, in path-style or virtual-hosted-style, are treated as S3 URLs.",
+	}
+}
+
+func (EnvironmentVariable) S3Region() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_S3_REGION",
+		Description: "The region to use when talking to the custom S3 endpoint set in AZCOPY_S3_ENDPOINT. If unset, the region of each bucket is looked up.",
+	}
+}
+
+func (EnvironmentVariable) GoogleHMACAccessID() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "GOOGLE_CLOUD_HMAC_ACCESS_ID",
+		Description: "The HMAC key access ID for Google Cloud Storage source used in service to service copy.",
+	}
+}
+
+func (EnvironmentVariable) GoogleHMACSecret() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "GOOGLE_CLOUD_HMAC_SECRET",
+		Description: "The HMAC key secret for Google Cloud Storage source used in service to service copy.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) GoogleAppCredentials() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "GOOGLE_APPLICATION_CREDENTIALS",
+		Description: "The path of the JSON key file of the Google Cloud service account to read Google Cloud Storage sources with, when no HMAC key is given.",
+	}
+}
+
 // AwsSessionToken is temporaily internally reserved, and not exposed to users.
 func (EnvironmentVariable) AwsSessionToken() EnvironmentVariable {
 	return EnvironmentVariable{Name: "AWS_SESSION_TOKEN"}
@@ -34,8 +34,8 @@ type ExclusiveStringMap struct {
 
 func NewExclusiveStringMap(fromTo FromTo, goos string) *ExclusiveStringMap {
 
-	caseInsenstiveDownload := fromTo.IsDownload() &&
-		(strings.EqualFold(goos, "windows") || strings.EqualFold(goos, "darwin")) // download to case insensitive OS
+	caseInsenstiveDownload := (fromTo.IsDownload() || fromTo == EFromTo.LocalLocal()) &&
+		(strings.EqualFold(goos, "windows") || strings.EqualFold(goos, "darwin")) // download (or local copy) to case insensitive OS
 	caseSensitiveToRemote := fromTo.To() == ELocation.File() // upload to Windows-like cloud file system
 	insensitive := caseInsenstiveDownload || caseSensitiveToRemote
 	sensitive := !insensitive
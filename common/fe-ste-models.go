@@ -22,10 +22,13 @@ package common
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"math"
+	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -54,8 +57,8 @@ const (
 	//  we decided that the best option was to leave it as is, and only relax it if user feedback so requires.
 	DEFAULT_FILE_PERM = 0644
 
-	// Since we haven't updated the Go SDKs to handle CPK just yet, we need to detect CPK related errors
-	// and inform the user that we don't support CPK yet.
+	// The service's answer when a blob encrypted with a customer-provided key is read without the key, or with the wrong one,
+	// so that users can be told to give the key.
 	CPK_ERROR_SERVICE_CODE = "BlobUsesCustomerSpecifiedEncryption"
 )
 
@@ -415,6 +418,8 @@ func (Location) File() Location      { return Location(4) }
 func (Location) BlobFS() Location    { return Location(5) }
 func (Location) S3() Location        { return Location(6) }
 func (Location) Benchmark() Location { return Location(7) }
+func (Location) GCP() Location       { return Location(8) }
+func (Location) Http() Location      { return Location(9) } // any plain HTTP(S) web server, read-only
 
 func (l Location) String() string {
 	return enum.StringInt(l, reflect.TypeOf(l))
@@ -428,7 +433,7 @@ func (Location) AllStandardLocations() []Location {
 		ELocation.File(),
 		ELocation.BlobFS(),
 		ELocation.S3(),
-		// TODO: ELocation.GCP
+		ELocation.GCP(),
 	}
 }
 
@@ -441,7 +446,7 @@ func fromToValue(from Location, to Location) FromTo {
 
 func (l Location) IsRemote() bool {
 	switch l {
-	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3():
+	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.GCP(), ELocation.Http():
 		return true
 	case ELocation.Local(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
 		return false
@@ -464,7 +469,7 @@ func (l Location) IsFolderAware() bool {
 	switch l {
 	case ELocation.BlobFS(), ELocation.File(), ELocation.Local():
 		return true
-	case ELocation.Blob(), ELocation.S3(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
+	case ELocation.Blob(), ELocation.S3(), ELocation.GCP(), ELocation.Http(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
 		return false
 	default:
 		panic("unexpected location, please specify if it is folder-aware")
@@ -483,6 +488,9 @@ type FromTo uint16
 func (FromTo) Unknown() FromTo   { return FromTo(0) }
 func (FromTo) LocalBlob() FromTo { return FromTo(fromToValue(ELocation.Local(), ELocation.Blob())) }
 func (FromTo) LocalFile() FromTo { return FromTo(fromToValue(ELocation.Local(), ELocation.File())) }
+func (FromTo) LocalLocal() FromTo {
+	return FromTo(fromToValue(ELocation.Local(), ELocation.Local()))
+}
 func (FromTo) BlobLocal() FromTo { return FromTo(fromToValue(ELocation.Blob(), ELocation.Local())) }
 func (FromTo) FileLocal() FromTo { return FromTo(fromToValue(ELocation.File(), ELocation.Local())) }
 func (FromTo) BlobPipe() FromTo  { return FromTo(fromToValue(ELocation.Blob(), ELocation.Pipe())) }
@@ -501,6 +509,16 @@ func (FromTo) FileBlob() FromTo    { return FromTo(fromToValue(ELocation.File(),
 func (FromTo) BlobFile() FromTo    { return FromTo(fromToValue(ELocation.Blob(), ELocation.File())) }
 func (FromTo) FileFile() FromTo    { return FromTo(fromToValue(ELocation.File(), ELocation.File())) }
 func (FromTo) S3Blob() FromTo      { return FromTo(fromToValue(ELocation.S3(), ELocation.Blob())) }
+func (FromTo) S3Local() FromTo     { return FromTo(fromToValue(ELocation.S3(), ELocation.Local())) }
+func (FromTo) LocalS3() FromTo     { return FromTo(fromToValue(ELocation.Local(), ELocation.S3())) }
+func (FromTo) BlobS3() FromTo      { return FromTo(fromToValue(ELocation.Blob(), ELocation.S3())) }
+func (FromTo) GCPBlob() FromTo     { return FromTo(fromToValue(ELocation.GCP(), ELocation.Blob())) }
+func (FromTo) HttpBlob() FromTo    { return FromTo(fromToValue(ELocation.Http(), ELocation.Blob())) }
+func (FromTo) BlobFSBlob() FromTo  { return FromTo(fromToValue(ELocation.BlobFS(), ELocation.Blob())) }
+func (FromTo) BlobFSBlobFS() FromTo {
+	return FromTo(fromToValue(ELocation.BlobFS(), ELocation.BlobFS()))
+}
+func (FromTo) BlobBlobFS() FromTo { return FromTo(fromToValue(ELocation.Blob(), ELocation.BlobFS())) }
 
 // todo: to we really want these?  Starts to look like a bit of a combinatorial explosion
 func (FromTo) BenchmarkBlob() FromTo {
@@ -545,6 +563,11 @@ func (ft *FromTo) From() Location {
 	return Location((((1 << 16) - 1) & *ft) >> 8)
 }
 
+// Reverse returns the FromTo for going the other way, e.g. BlobLocal for LocalBlob
+func (ft *FromTo) Reverse() FromTo {
+	return fromToValue(ft.To(), ft.From())
+}
+
 func (ft *FromTo) IsDownload() bool {
 	return ft.From().IsRemote() && ft.To().IsLocal()
 }
@@ -770,11 +793,13 @@ var ECredentialType = CredentialType(0)
 // CredentialType defines the different types of credentials
 type CredentialType uint8
 
-func (CredentialType) Unknown() CredentialType     { return CredentialType(0) }
-func (CredentialType) OAuthToken() CredentialType  { return CredentialType(1) } // For Azure, OAuth
-func (CredentialType) Anonymous() CredentialType   { return CredentialType(2) } // For Azure, SAS or public.
-func (CredentialType) SharedKey() CredentialType   { return CredentialType(3) } // For Azure, SharedKey
-func (CredentialType) S3AccessKey() CredentialType { return CredentialType(4) } // For S3, AccessKeyID and SecretAccessKey
+func (CredentialType) Unknown() CredentialType           { return CredentialType(0) }
+func (CredentialType) OAuthToken() CredentialType        { return CredentialType(1) } // For Azure, OAuth
+func (CredentialType) Anonymous() CredentialType         { return CredentialType(2) } // For Azure, SAS or public.
+func (CredentialType) SharedKey() CredentialType         { return CredentialType(3) } // For Azure, SharedKey
+func (CredentialType) S3AccessKey() CredentialType       { return CredentialType(4) } // For S3, AccessKeyID and SecretAccessKey
+func (CredentialType) GCPHMACKey() CredentialType        { return CredentialType(5) } // For GCP, HMAC access ID and secret
+func (CredentialType) GCPServiceAccount() CredentialType { return CredentialType(6) } // For GCP, a service account's JSON key, used to get OAuth tokens
 
 func (ct CredentialType) String() string {
 	return enum.StringInt(ct, reflect.TypeOf(ct))
@@ -838,6 +863,60 @@ func (hvo *HashValidationOption) UnmarshalJSON(b []byte) error {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var ECompareHashType = CompareHashType(0)
+
+// CompareHashType is the content hash that sync uses to decide whether a file has changed.
+type CompareHashType uint8
+
+// None means sync doesn't look at content hashes at all, but compares last modified times instead
+func (CompareHashType) None() CompareHashType { return CompareHashType(0) }
+
+// MD5 means sync compares sizes and MD5 hashes, computing the hashes of local files as necessary
+func (CompareHashType) MD5() CompareHashType { return CompareHashType(1) }
+
+func (ht CompareHashType) String() string {
+	return enum.StringInt(ht, reflect.TypeOf(ht))
+}
+
+func (ht *CompareHashType) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(ht), s, true, true)
+	if err == nil {
+		*ht = val.(CompareHashType)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var ESyncConflictPolicy = SyncConflictPolicy(0)
+
+// SyncConflictPolicy is how a two-way sync resolves a file that has changed at both ends since the last sync.
+type SyncConflictPolicy uint8
+
+// NewerWins means the version with the later last modified time replaces the other
+func (SyncConflictPolicy) NewerWins() SyncConflictPolicy { return SyncConflictPolicy(0) }
+
+// SourceWins means the source's version replaces the destination's
+func (SyncConflictPolicy) SourceWins() SyncConflictPolicy { return SyncConflictPolicy(1) }
+
+// KeepBoth means the local version is renamed, so that both versions end up at both ends
+func (SyncConflictPolicy) KeepBoth() SyncConflictPolicy { return SyncConflictPolicy(2) }
+
+func (cp SyncConflictPolicy) String() string {
+	return enum.StringInt(cp, reflect.TypeOf(cp))
+}
+
+// Parse accepts the names with or without hyphens, e.g. both newer-wins and NewerWins
+func (cp *SyncConflictPolicy) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(cp), strings.ReplaceAll(s, "-", ""), true, true)
+	if err == nil {
+		*cp = val.(SyncConflictPolicy)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 var EInvalidMetadataHandleOption = InvalidMetadataHandleOption(0)
 
 var DefaultInvalidMetadataHandleOption = EInvalidMetadataHandleOption.ExcludeIfInvalid()
@@ -917,6 +996,7 @@ type CopyTransfer struct {
 	BlobType      azblob.BlobType
 	BlobTier      azblob.AccessTierType
 	BlobVersionID string
+	BlobTags      BlobTags
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -944,6 +1024,30 @@ func FromAzFileMetadataToCommonMetadata(m azfile.Metadata) Metadata {
 	return Metadata(m)
 }
 
+// FromBlobFSPropertiesToCommonMetadata converts the x-ms-properties header of an ADLS Gen2 path to common metadata.
+// The header is a comma-separated list of name=value pairs, in which each value is base64-encoded.
+func FromBlobFSPropertiesToCommonMetadata(xMsProperties string) (Metadata, error) {
+	result := Metadata{}
+	if xMsProperties == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(xMsProperties, ",") {
+		pieces := strings.SplitN(pair, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return nil, fmt.Errorf("invalid property %q in x-ms-properties", pair)
+		}
+
+		value, err := base64.StdEncoding.DecodeString(pieces[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for property %q in x-ms-properties: %w", pieces[0], err)
+		}
+		result[pieces[0]] = string(value)
+	}
+
+	return result, nil
+}
+
 // Marshal marshals metadata to string.
 func (m Metadata) Marshal() (string, error) {
 	b, err := json.Marshal(m)
@@ -1077,6 +1181,119 @@ func (m Metadata) ConcatenatedKeys() string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// BlobTags are the index tags of a blob, by which blobs can be found, and lifecycle management rules can select them.
+type BlobTags map[string]string
+
+const (
+	MaxBlobTagCount       = 10
+	MaxBlobTagKeyLength   = 128
+	MaxBlobTagValueLength = 256
+)
+
+// ToAzBlobTagsMap converts tags to azblob's tags. It's nil when there are no tags, so that none are sent.
+func (t BlobTags) ToAzBlobTagsMap() azblob.BlobTagsMap {
+	if len(t) == 0 {
+		return nil
+	}
+	return azblob.BlobTagsMap(t)
+}
+
+// FromAzBlobTagsToCommonBlobTags converts the tags that the service returns for a blob to common tags.
+func FromAzBlobTagsToCommonBlobTags(tags *azblob.BlobTags) BlobTags {
+	if tags == nil || len(tags.BlobTagSet) == 0 {
+		return nil
+	}
+
+	result := BlobTags{}
+	for _, tag := range tags.BlobTagSet {
+		result[tag.Key] = tag.Value
+	}
+	return result
+}
+
+// ToString gives the tags in the form of the x-ms-tags header (and of the --blob-tags flag): URL-encoded key=value pairs, separated by '&'.
+// The keys are sorted, so that the same tags always give the same string.
+func (t BlobTags) ToString() string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = url.QueryEscape(k) + "=" + url.QueryEscape(t[k])
+	}
+	return strings.Join(pairs, "&")
+}
+
+// ToCommonBlobTags parses tags from the form that ToString gives, and checks that the service would accept them.
+func ToCommonBlobTags(s string) (BlobTags, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	result := BlobTags{}
+	for _, pair := range strings.Split(s, "&") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid blob tag %q: expected key=value", pair)
+		}
+		key, err := url.QueryUnescape(kv[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob tag key %q: %w", kv[0], err)
+		}
+		value, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob tag value %q: %w", kv[1], err)
+		}
+		if _, exists := result[key]; exists {
+			return nil, fmt.Errorf("blob tag %q is given more than once", key)
+		}
+		result[key] = value
+	}
+
+	return result, result.Validate()
+}
+
+// Validate checks the tags against the service's rules: at most 10 tags, with keys of 1 to 128 characters and values of up to 256,
+// made of letters, digits, spaces and the characters + - . / : = _
+func (t BlobTags) Validate() error {
+	if len(t) > MaxBlobTagCount {
+		return fmt.Errorf("a blob can have at most %d tags, but %d were given", MaxBlobTagCount, len(t))
+	}
+
+	for k, v := range t {
+		if len(k) == 0 || len(k) > MaxBlobTagKeyLength {
+			return fmt.Errorf("blob tag key %q must be between 1 and %d characters long", k, MaxBlobTagKeyLength)
+		}
+		if len(v) > MaxBlobTagValueLength {
+			return fmt.Errorf("the value of blob tag %q must be at most %d characters long", k, MaxBlobTagValueLength)
+		}
+		if !isValidBlobTagString(k) || !isValidBlobTagString(v) {
+			return fmt.Errorf("blob tag %q=%q contains characters that aren't allowed in tags. "+
+				"Only letters, digits, spaces and the characters + - . / : = _ are", k, v)
+		}
+	}
+
+	return nil
+}
+
+func isValidBlobTagString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte(" +-./:=_", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 // Common resource's HTTP headers stands for properties used in AzCopy.
 type ResourceHTTPHeaders struct {
 	ContentType        string
@@ -1237,12 +1454,66 @@ type CompressionType uint8
 func (CompressionType) None() CompressionType        { return CompressionType(0) }
 func (CompressionType) ZLib() CompressionType        { return CompressionType(1) }
 func (CompressionType) GZip() CompressionType        { return CompressionType(2) }
+func (CompressionType) Zstd() CompressionType        { return CompressionType(3) }
+func (CompressionType) Brotli() CompressionType      { return CompressionType(4) }
 func (CompressionType) Unsupported() CompressionType { return CompressionType(255) }
 
 func (ct CompressionType) String() string {
 	return enum.StringInt(ct, reflect.TypeOf(ct))
 }
 
+func (ct *CompressionType) Parse(s string) error {
+	// allow empty to mean "None"
+	if s == "" {
+		*ct = ECompressionType.None()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(ct), s, true, true)
+	if err == nil {
+		*ct = val.(CompressionType)
+	}
+	return err
+}
+
+// ContentEncoding is the content-encoding that says that content is compressed in this way. It's the inverse of GetCompressionType.
+func (ct CompressionType) ContentEncoding() string {
+	switch ct {
+	case ECompressionType.GZip():
+		return "gzip"
+	case ECompressionType.ZLib():
+		return "deflate"
+	case ECompressionType.Zstd():
+		return "zstd"
+	case ECompressionType.Brotli():
+		return "br"
+	default:
+		return ""
+	}
+}
+
+// Extension is the usual file extension for content that is compressed in this way
+func (ct CompressionType) Extension() string {
+	switch ct {
+	case ECompressionType.GZip():
+		return ".gz"
+	case ECompressionType.ZLib():
+		return ".zz"
+	case ECompressionType.Zstd():
+		return ".zst"
+	case ECompressionType.Brotli():
+		return ".br"
+	default:
+		return ""
+	}
+}
+
+// CanConcatenate says whether streams compressed this way can be simply concatenated, as described at NewCompressingWriter.
+// Zlib streams can't (the decompressor stops at the end of the first).
+func (ct CompressionType) CanConcatenate() bool {
+	return ct == ECompressionType.GZip() || ct == ECompressionType.Zstd()
+}
+
 func GetCompressionType(contentEncoding string) (CompressionType, error) {
 	switch strings.ToLower(contentEncoding) {
 	case "":
@@ -1251,6 +1522,10 @@ func GetCompressionType(contentEncoding string) (CompressionType, error) {
 		return ECompressionType.GZip(), nil
 	case "deflate":
 		return ECompressionType.ZLib(), nil
+	case "zstd":
+		return ECompressionType.Zstd(), nil
+	case "br":
+		return ECompressionType.Brotli(), nil
 	default:
 		return ECompressionType.Unsupported(), fmt.Errorf("encoding type '%s' is not recognised as a supported encoding type for auto-decompression", contentEncoding)
 	}
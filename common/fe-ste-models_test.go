@@ -21,6 +21,9 @@
 package common_test
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/Azure/azure-storage-azcopy/common"
 	chk "gopkg.in/check.v1"
 )
@@ -173,3 +176,60 @@ func (s *feSteModelsTestSuite) TestMetadataResolveInvalidKeyNegative(c *chk.C) {
 	_, err = mNegative3.ResolveInvalidKey()
 	c.Assert(err, chk.NotNil)
 }
+
+func (s *feSteModelsTestSuite) TestMetadataFromBlobFSProperties(c *chk.C) {
+	// "hello" and "wor,=ld" in base64; the encoding is what allows values to contain commas and equals signs
+	m, err := common.FromBlobFSPropertiesToCommonMetadata("key1=aGVsbG8=,key2=d29yLD1sZA==")
+	c.Assert(err, chk.IsNil)
+	validateMapEqual(c, m, map[string]string{"key1": "hello", "key2": "wor,=ld"})
+
+	m, err = common.FromBlobFSPropertiesToCommonMetadata("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(m, chk.HasLen, 0)
+
+	_, err = common.FromBlobFSPropertiesToCommonMetadata("key1")
+	c.Assert(err, chk.NotNil)
+
+	_, err = common.FromBlobFSPropertiesToCommonMetadata("key1=not base64")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *feSteModelsTestSuite) TestBlobTagsRoundTrip(c *chk.C) {
+	tags, err := common.ToCommonBlobTags("stage=final&project=apollo+11&path=a%2Fb")
+	c.Assert(err, chk.IsNil)
+	validateMapEqual(c, tags, map[string]string{"project": "apollo 11", "stage": "final", "path": "a/b"})
+
+	// the keys are sorted, so the same tags always give the same string
+	c.Assert(tags.ToString(), chk.Equals, "path=a%2Fb&project=apollo+11&stage=final")
+
+	again, err := common.ToCommonBlobTags(tags.ToString())
+	c.Assert(err, chk.IsNil)
+	validateMapEqual(c, again, tags)
+
+	// no tags means nothing to send
+	tags, err = common.ToCommonBlobTags("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.HasLen, 0)
+	c.Assert(tags.ToAzBlobTagsMap(), chk.IsNil)
+}
+
+func (s *feSteModelsTestSuite) TestBlobTagsNegative(c *chk.C) {
+	tooMany := make([]string, common.MaxBlobTagCount+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("k%d=v", i)
+	}
+
+	for _, invalid := range []string{
+		"novalue",
+		"=empty-key",
+		"a=1&a=2",
+		"semi=colon;",
+		"percent=%zz",
+		strings.Join(tooMany, "&"),
+		strings.Repeat("k", common.MaxBlobTagKeyLength+1) + "=v",
+		"k=" + strings.Repeat("v", common.MaxBlobTagValueLength+1),
+	} {
+		_, err := common.ToCommonBlobTags(invalid)
+		c.Assert(err, chk.NotNil, chk.Commentf("%q", invalid))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	minio "github.com/minio/minio-go"
+)
+
+// GCPObjectInfoExtension surfaces the properties of a Google Cloud Storage object, as returned by its XML API.
+type GCPObjectInfoExtension struct {
+	ObjectInfo minio.ObjectInfo
+}
+
+const gcpMetadataPrefix = "x-goog-meta-"
+
+const gcpMetadataPrefixLen = len(gcpMetadataPrefix)
+
+// gcpHashHeader carries the hashes of an object, e.g. "crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ==".
+const gcpHashHeader = "x-goog-hash"
+
+func (oie *GCPObjectInfoExtension) ContentType() string {
+	return oie.ObjectInfo.ContentType
+}
+
+// CacheControl returns the value for header Cache-Control.
+func (oie *GCPObjectInfoExtension) CacheControl() string {
+	return oie.ObjectInfo.Metadata.Get("Cache-Control")
+}
+
+// ContentDisposition returns the value for header Content-Disposition.
+func (oie *GCPObjectInfoExtension) ContentDisposition() string {
+	return oie.ObjectInfo.Metadata.Get("Content-Disposition")
+}
+
+// ContentEncoding returns the value for header Content-Encoding.
+func (oie *GCPObjectInfoExtension) ContentEncoding() string {
+	return oie.ObjectInfo.Metadata.Get("Content-Encoding")
+}
+
+// ContentLanguage returns the value for header Content-Language.
+func (oie *GCPObjectInfoExtension) ContentLanguage() string {
+	return oie.ObjectInfo.Metadata.Get("Content-Language")
+}
+
+// ContentMD5 returns the MD5 hash from header x-goog-hash.
+// Composite objects only have a CRC32C hash, in which case nil is returned.
+func (oie *GCPObjectInfoExtension) ContentMD5() []byte {
+	for _, header := range oie.ObjectInfo.Metadata[http.CanonicalHeaderKey(gcpHashHeader)] {
+		for _, hash := range strings.Split(header, ",") {
+			hash = strings.TrimSpace(hash)
+			if !strings.HasPrefix(hash, "md5=") {
+				continue
+			}
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "md5="))
+			if err != nil {
+				return nil
+			}
+			return b
+		}
+	}
+	return nil
+}
+
+// NewCommonMetadata returns user-defined key/value pairs.
+func (oie *GCPObjectInfoExtension) NewCommonMetadata() Metadata {
+	md := Metadata{}
+	for k, v := range oie.ObjectInfo.Metadata {
+		if len(k) > gcpMetadataPrefixLen {
+			if prefix := k[0:gcpMetadataPrefixLen]; strings.EqualFold(prefix, gcpMetadataPrefix) {
+				md[k[gcpMetadataPrefixLen:]] = v[0]
+			}
+		}
+	}
+	return md
+}
@@ -0,0 +1,348 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	minio "github.com/minio/minio-go"
+	"github.com/minio/minio-go/pkg/credentials"
+)
+
+// gcpReadOnlyScope is all that AzCopy needs, since GCS is only ever a source
+const gcpReadOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+const gcpDefaultTokenURI = "https://oauth2.googleapis.com/token"
+
+// refresh OAuth tokens this long before they expire, so that requests in flight don't fail
+const gcpTokenRefreshMargin = 5 * time.Minute
+
+// GetGCPCredentialType says which of the Google Cloud credentials in the environment to use:
+// the HMAC key if there is one, otherwise the service account key in GOOGLE_APPLICATION_CREDENTIALS.
+func GetGCPCredentialType() (CredentialType, error) {
+	glcm := GetLifecycleMgr()
+	accessID := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACAccessID())
+	secret := glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleHMACSecret())
+	if accessID != "" && secret != "" {
+		return ECredentialType.GCPHMACKey(), nil
+	}
+	if glcm.GetEnvironmentVariable(EEnvironmentVariable.GoogleAppCredentials()) != "" {
+		return ECredentialType.GCPServiceAccount(), nil
+	}
+	return ECredentialType.Unknown(), errors.New("GOOGLE_CLOUD_HMAC_ACCESS_ID and GOOGLE_CLOUD_HMAC_SECRET, or GOOGLE_APPLICATION_CREDENTIALS, " +
+		"environment variables must be set before accessing Google Cloud Storage")
+}
+
+// GCPServiceAccountKey is the JSON key of a Google Cloud service account, as downloaded from the Google Cloud console.
+// It's used to get OAuth tokens for the XML API, and to sign URLs that Blob Storage can read objects through.
+type GCPServiceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+
+	tokenLock   sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+var gcpServiceAccountKeys = struct {
+	sync.Mutex
+	byPath map[string]*GCPServiceAccountKey
+}{byPath: make(map[string]*GCPServiceAccountKey)}
+
+// GetGCPServiceAccountKey loads the key named by GOOGLE_APPLICATION_CREDENTIALS.
+// Keys are only loaded once, so that all the clients of a job share their OAuth tokens.
+func GetGCPServiceAccountKey() (*GCPServiceAccountKey, error) {
+	path := GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.GoogleAppCredentials())
+	if path == "" {
+		return nil, errors.New("GOOGLE_APPLICATION_CREDENTIALS environment variable must be set before using a GCP service account")
+	}
+
+	gcpServiceAccountKeys.Lock()
+	defer gcpServiceAccountKeys.Unlock()
+	if key, ok := gcpServiceAccountKeys.byPath[path]; ok {
+		return key, nil
+	}
+
+	key, err := LoadGCPServiceAccountKey(path)
+	if err != nil {
+		return nil, err
+	}
+	gcpServiceAccountKeys.byPath[path] = key
+	return key, nil
+}
+
+// LoadGCPServiceAccountKey reads a service account's JSON key file
+func LoadGCPServiceAccountKey(path string) (*GCPServiceAccountKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the GCP service account key file: %w", err)
+	}
+
+	key := &GCPServiceAccountKey{}
+	if err = json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("cannot parse the GCP service account key file %s: %w", path, err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("the GCP credentials in %s are of type %q, but only service account keys are supported", path, key.Type)
+	}
+	if key.ClientEmail == "" {
+		return nil, fmt.Errorf("the GCP service account key file %s has no client_email", path)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = gcpDefaultTokenURI
+	}
+	if key.key, err = parseRSAPrivateKey(key.PrivateKey); err != nil {
+		return nil, fmt.Errorf("cannot parse the private key in the GCP service account key file %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// parseRSAPrivateKey parses a PEM-encoded RSA key, which is PKCS #8 in the key files that Google Cloud gives out, though PKCS #1 is accepted too
+func parseRSAPrivateKey(keyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("the key is not PEM-encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if key, pkcs1Err := x509.ParsePKCS1PrivateKey(block.Bytes); pkcs1Err == nil {
+			return key, nil
+		}
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the key is not an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT makes a JSON Web Token of the claims, signed with the key by RS256
+func (k *GCPServiceAccountKey) signJWT(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Token returns an OAuth access token for reading GCS, getting a new one when the last is about to expire
+func (k *GCPServiceAccountKey) Token(client *http.Client) (string, error) {
+	k.tokenLock.Lock()
+	defer k.tokenLock.Unlock()
+
+	if k.token != "" && time.Now().Add(gcpTokenRefreshMargin).Before(k.tokenExpiry) {
+		return k.token, nil
+	}
+
+	// exchange a JWT, signed with the key, for the token (https://developers.google.com/identity/protocols/oauth2/service-account#authorizingrequests)
+	now := time.Now()
+	signedAssertion, err := k.signJWT(map[string]interface{}{
+		"iss":   k.ClientEmail,
+		"scope": gcpReadOnlyScope,
+		"aud":   k.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.PostForm(k.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signedAssertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot get an OAuth token for the GCP service account %s: %w", k.ClientEmail, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot get an OAuth token for the GCP service account %s: %s %s", k.ClientEmail, resp.Status, string(body))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("the OAuth token response for the GCP service account %s could not be understood", k.ClientEmail)
+	}
+
+	k.token = result.AccessToken
+	k.tokenExpiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return k.token, nil
+}
+
+// PresignGetObject makes a V4 signed URL that anyone can read the object through until it expires,
+// signed with the service account's private key (https://cloud.google.com/storage/docs/access-control/signing-urls-manually)
+func (k *GCPServiceAccountKey) PresignGetObject(bucketName, objectKey string, expires time.Duration) (*url.URL, error) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + gcpRegion + "/storage/goog4_request"
+
+	query := url.Values{}
+	query.Set("X-Goog-Algorithm", "GOOG4-RSA-SHA256")
+	query.Set("X-Goog-Credential", k.ClientEmail+"/"+scope)
+	query.Set("X-Goog-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Goog-Expires", fmt.Sprintf("%d", int64(expires.Seconds())))
+	query.Set("X-Goog-SignedHeaders", "host")
+
+	u := &url.URL{
+		Scheme: "https",
+		Host:   GCPEndpoint,
+		Path:   "/" + bucketName + "/" + objectKey,
+	}
+	canonicalURI := gcpEncodePath(u.Path)
+	canonicalQuery := gcpCanonicalQuery(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalURI,
+		canonicalQuery,
+		"host:" + GCPEndpoint + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"GOOG4-RSA-SHA256", query.Get("X-Goog-Date"), scope, hex.EncodeToString(requestHash[:])}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawPath = canonicalURI
+	u.RawQuery = canonicalQuery + "&X-Goog-Signature=" + hex.EncodeToString(signature)
+	return u, nil
+}
+
+// gcpEncodePath percent-encodes everything in the path but the unreserved characters and the separators, as signing expects
+func gcpEncodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = gcpEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func gcpCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, gcpEncode(key)+"="+gcpEncode(query.Get(key)))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func gcpEncode(s string) string {
+	// QueryEscape encodes spaces as +, and leaves ~ alone; signing wants %20 and ~
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// gcpOAuthTransport adds the service account's OAuth token to every request
+type gcpOAuthTransport struct {
+	key  *GCPServiceAccountKey
+	base http.RoundTripper
+}
+
+func (t *gcpOAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.key.Token(&http.Client{Transport: t.base})
+	if err != nil {
+		return nil, err
+	}
+
+	// requests mustn't be changed by RoundTrip, so add the header to a copy
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+token)
+	if req.URL.Path == "" || req.URL.Path == "/" {
+		// listing buckets is the one request that the token doesn't say enough for
+		authorized.Header.Set("x-goog-project-id", t.key.ProjectID)
+	}
+	return t.base.RoundTrip(authorized)
+}
+
+// createGCPServiceAccountClient creates a client for the XML API of GCS that authenticates with OAuth tokens,
+// rather than signing requests like S3, since that's what service accounts get.
+func createGCPServiceAccountClient(credInfo CredentialInfo) (*minio.Client, error) {
+	key, err := GetGCPServiceAccountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.NewWithOptions(credInfo.S3CredentialInfo.Endpoint, &minio.Options{
+		Creds:        credentials.NewStatic("", "", "", credentials.SignatureAnonymous),
+		Secure:       !credInfo.S3CredentialInfo.Insecure,
+		Region:       credInfo.S3CredentialInfo.Region,
+		BucketLookup: credInfo.S3CredentialInfo.BucketLookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	setS3ClientTransport(client, &gcpOAuthTransport{key: key, base: minio.DefaultTransport})
+	return client, nil
+}
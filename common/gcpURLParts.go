@@ -0,0 +1,146 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go"
+)
+
+// GCPURLParts represents the components that make up a Google Cloud Storage Service/Bucket/Object URL.
+// You parse an existing URL into its parts by calling NewGCPURLParts().
+// Both path-style and virtual-hosted-style URLs of the XML API are supported, as well as the authenticated
+// browser URLs shown in the Google Cloud console:
+// a. https://storage.googleapis.com/bucket/object
+// b. https://bucket.storage.googleapis.com/object
+// c. https://storage.cloud.google.com/bucket/object
+type GCPURLParts struct {
+	Scheme         string // Ex: "https://"
+	Host           string // Ex: "storage.googleapis.com", "bucket.storage.googleapis.com"
+	BucketName     string // Ex: "MyBucket"
+	ObjectKey      string // Ex: "hello.txt", "foo/bar"
+	UnparsedParams string
+
+	isPathStyle bool
+}
+
+// GCPEndpoint is the host of the S3-compatible XML API of Google Cloud Storage, through which all requests are made.
+const GCPEndpoint = "storage.googleapis.com"
+
+// gcpRegion is used for signing requests. GCS ignores the region of the signature, but "auto" is what it documents.
+const gcpRegion = "auto"
+
+const gcpConsoleHost = "storage.cloud.google.com"
+const invalidGCPURLErrorMessage = "Invalid GCP URL. AzCopy supports path-style or virtual-hosted-style URLs of Google Cloud Storage, E.g: https://storage.googleapis.com/bucket or https://bucket.storage.googleapis.com"
+
+// IsGCPURL verifies if a given URL points to Google Cloud Storage.
+func IsGCPURL(u url.URL) bool {
+	host := strings.ToLower(u.Host)
+	return host == GCPEndpoint || host == gcpConsoleHost || strings.HasSuffix(host, "."+GCPEndpoint)
+}
+
+// NewGCPURLParts parses a URL initializing GCPURLParts' fields. This method overwrites all fields in the GCPURLParts object.
+func NewGCPURLParts(u url.URL) (GCPURLParts, error) {
+	if !IsGCPURL(u) {
+		return GCPURLParts{}, errors.New(invalidGCPURLErrorMessage)
+	}
+
+	host := strings.ToLower(u.Host)
+	path := u.Path
+	// Remove the initial '/' if exists
+	if path != "" && path[0] == '/' {
+		path = path[1:]
+	}
+
+	up := GCPURLParts{
+		Scheme:         u.Scheme,
+		Host:           host,
+		UnparsedParams: u.RawQuery,
+	}
+
+	if host == GCPEndpoint || host == gcpConsoleHost {
+		up.isPathStyle = true
+		up.BucketName, up.ObjectKey = splitS3BucketAndObject(path)
+	} else {
+		up.BucketName = strings.TrimSuffix(host, "."+GCPEndpoint)
+		up.ObjectKey = path
+	}
+
+	return up, nil
+}
+
+// URL returns a URL object whose fields are initialized from the GCPURLParts fields.
+func (p *GCPURLParts) URL() url.URL {
+	path := ""
+
+	// Concatenate bucket & object names (if they exist)
+	if p.BucketName != "" {
+		if p.isPathStyle {
+			path += "/" + p.BucketName
+		}
+		if p.ObjectKey != "" {
+			path += "/" + p.ObjectKey
+		}
+	}
+
+	return url.URL{
+		Scheme:   p.Scheme,
+		Host:     p.Host,
+		Path:     path,
+		RawQuery: p.UnparsedParams,
+	}
+}
+
+func (p *GCPURLParts) String() string {
+	u := p.URL()
+	return u.String()
+}
+
+// S3CredentialInfo returns the endpoint details needed to create a client for the URL.
+// GCS is always reached through its XML API, with path-style addressing so that bucket names containing dots work over https.
+func (p *GCPURLParts) S3CredentialInfo() S3CredentialInfo {
+	return S3CredentialInfo{
+		Endpoint:     GCPEndpoint,
+		Region:       gcpRegion,
+		BucketLookup: minio.BucketLookupPath,
+	}
+}
+
+func (p *GCPURLParts) IsServiceSyntactically() bool {
+	return p.Host != "" && p.BucketName == ""
+}
+
+func (p *GCPURLParts) IsBucketSyntactically() bool {
+	return p.BucketName != "" && p.ObjectKey == ""
+}
+
+func (p *GCPURLParts) IsObjectSyntactically() bool {
+	return p.ObjectKey != ""
+}
+
+// IsDirectorySyntactically validates if the GCPURLParts is indicating a directory.
+// Note: like S3, directories in GCS are a virtual abstraction.
+func (p *GCPURLParts) IsDirectorySyntactically() bool {
+	return p.IsObjectSyntactically() && strings.HasSuffix(p.ObjectKey, "/")
+}
@@ -0,0 +1,98 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	minio "github.com/minio/minio-go"
+	chk "gopkg.in/check.v1"
+)
+
+// Hookup to the testing framework
+type gcpURLPartsTestSuite struct{}
+
+var _ = chk.Suite(&gcpURLPartsTestSuite{})
+
+func (s *gcpURLPartsTestSuite) TestGCPURLParse(c *chk.C) {
+	u, _ := url.Parse("https://storage.googleapis.com/bucket/keydir/keyname")
+	c.Assert(IsGCPURL(*u), chk.Equals, true)
+	p, err := NewGCPURLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.Host, chk.Equals, "storage.googleapis.com")
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keydir/keyname")
+	c.Assert(p.IsObjectSyntactically(), chk.Equals, true)
+	c.Assert(p.String(), chk.Equals, "https://storage.googleapis.com/bucket/keydir/keyname")
+
+	u, _ = url.Parse("https://bucket.storage.googleapis.com/keydir/")
+	p, err = NewGCPURLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keydir/")
+	c.Assert(p.IsDirectorySyntactically(), chk.Equals, true)
+	c.Assert(p.String(), chk.Equals, "https://bucket.storage.googleapis.com/keydir/")
+
+	u, _ = url.Parse("https://storage.cloud.google.com/my.bucket")
+	p, err = NewGCPURLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.BucketName, chk.Equals, "my.bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "")
+	c.Assert(p.IsBucketSyntactically(), chk.Equals, true)
+	c.Assert(p.S3CredentialInfo(), chk.DeepEquals, S3CredentialInfo{Endpoint: GCPEndpoint, Region: "auto", BucketLookup: minio.BucketLookupPath})
+
+	u, _ = url.Parse("https://storage.googleapis.com")
+	p, err = NewGCPURLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsServiceSyntactically(), chk.Equals, true)
+}
+
+func (s *gcpURLPartsTestSuite) TestGCPURLParseNegative(c *chk.C) {
+	for _, rawURL := range []string{"https://storage.googleapis.com.evil.com/bucket", "https://s3.amazonaws.com/bucket", "https://account.blob.core.windows.net/container"} {
+		u, _ := url.Parse(rawURL)
+		c.Assert(IsGCPURL(*u), chk.Equals, false)
+		_, err := NewGCPURLParts(*u)
+		c.Assert(err, chk.NotNil)
+		c.Assert(strings.Contains(err.Error(), invalidGCPURLErrorMessage), chk.Equals, true)
+	}
+}
+
+func (s *gcpURLPartsTestSuite) TestGCPObjectInfoExtension(c *chk.C) {
+	oie := GCPObjectInfoExtension{ObjectInfo: minio.ObjectInfo{
+		ContentType: "text/plain",
+		Metadata: http.Header{
+			"X-Goog-Hash":     []string{"crc32c=n03x6A==,md5=XrY7u+Ae7tCTyyK7j1rNww=="},
+			"X-Goog-Meta-Foo": []string{"bar"},
+			"Cache-Control":   []string{"no-cache"},
+		},
+	}}
+
+	c.Assert(oie.ContentType(), chk.Equals, "text/plain")
+	c.Assert(oie.CacheControl(), chk.Equals, "no-cache")
+	c.Assert(oie.ContentMD5(), chk.HasLen, 16)
+	c.Assert(oie.NewCommonMetadata(), chk.DeepEquals, Metadata{"Foo": "bar"})
+
+	// composite objects have no MD5
+	oie.ObjectInfo.Metadata.Set("X-Goog-Hash", "crc32c=n03x6A==")
+	c.Assert(oie.ContentMD5(), chk.IsNil)
+}
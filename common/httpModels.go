@@ -0,0 +1,127 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// HTTPResourceProperties surfaces the properties of a resource served by a plain HTTP(S) web server,
+// as returned by a HEAD request.
+type HTTPResourceProperties struct {
+	Header http.Header
+}
+
+// GetHTTPResourceProperties issues a HEAD request for the resource, through the given pipeline.
+// Any response other than 200 OK is treated as an error, since there's nothing we could copy.
+func GetHTTPResourceProperties(ctx context.Context, p pipeline.Pipeline, resourceURL url.URL) (HTTPResourceProperties, error) {
+	request, err := pipeline.NewRequest(http.MethodHead, resourceURL, nil)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+
+	response, err := p.Do(ctx, nil, request)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+	rawResponse := response.Response()
+	if rawResponse.Body != nil {
+		_, _ = io.Copy(ioutil.Discard, rawResponse.Body)
+		_ = rawResponse.Body.Close()
+	}
+
+	if rawResponse.StatusCode != http.StatusOK {
+		return HTTPResourceProperties{}, fmt.Errorf("HEAD %s returned %s", URLExtension{URL: resourceURL}.RedactSecretQueryParamForLogging(), rawResponse.Status)
+	}
+
+	return HTTPResourceProperties{Header: rawResponse.Header}, nil
+}
+
+// ContentLength returns the size of the resource. The size must be known up front for us to copy it,
+// so a missing or malformed Content-Length is an error.
+func (p HTTPResourceProperties) ContentLength() (int64, error) {
+	raw := p.Header.Get("Content-Length")
+	if raw == "" {
+		return 0, fmt.Errorf("the server did not return a Content-Length")
+	}
+	size, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("the server returned an invalid Content-Length %q", raw)
+	}
+	return size, nil
+}
+
+// LastModified returns the value for header Last-Modified, or the zero time if the server didn't supply one.
+func (p HTTPResourceProperties) LastModified() time.Time {
+	lmt, err := http.ParseTime(p.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return lmt
+}
+
+// ContentType returns the value for header Content-Type.
+func (p HTTPResourceProperties) ContentType() string {
+	return p.Header.Get("Content-Type")
+}
+
+// CacheControl returns the value for header Cache-Control.
+func (p HTTPResourceProperties) CacheControl() string {
+	return p.Header.Get("Cache-Control")
+}
+
+// ContentDisposition returns the value for header Content-Disposition.
+func (p HTTPResourceProperties) ContentDisposition() string {
+	return p.Header.Get("Content-Disposition")
+}
+
+// ContentEncoding returns the value for header Content-Encoding.
+func (p HTTPResourceProperties) ContentEncoding() string {
+	return p.Header.Get("Content-Encoding")
+}
+
+// ContentLanguage returns the value for header Content-Language.
+func (p HTTPResourceProperties) ContentLanguage() string {
+	return p.Header.Get("Content-Language")
+}
+
+// ContentMD5 returns the decoded value of header Content-MD5, or nil if the server didn't supply a valid one.
+func (p HTTPResourceProperties) ContentMD5() []byte {
+	raw := p.Header.Get("Content-MD5")
+	if raw == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil
+	}
+	return b
+}
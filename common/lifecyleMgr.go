@@ -48,6 +48,7 @@ type LifecycleMgr interface {
 	Progress(OutputBuilder)                                      // print on the same line over and over again, not allowed to float up
 	Exit(OutputBuilder, ExitCode)                                // indicates successful execution exit after printing, allow user to specify exit code
 	Info(string)                                                 // simple print, allowed to float up
+	Dryrun(OutputBuilder)                                        // print an operation that a dry run would have performed, allowed to float up
 	Error(string)                                                // indicates fatal error, exit after printing, exit code is always Failed (1)
 	Prompt(message string, details PromptDetails) ResponseOption // ask the user a question(after erasing the progress), then return the response
 	SurrenderControl()                                           // give up control, this should never return
@@ -230,6 +231,13 @@ func (lcm *lifecycleMgr) Info(msg string) {
 	}
 }
 
+func (lcm *lifecycleMgr) Dryrun(o OutputBuilder) {
+	lcm.msgQueue <- outputMessage{
+		msgContent: o(lcm.outputFormat),
+		msgType:    eOutputMessageType.Dryrun(),
+	}
+}
+
 func (lcm *lifecycleMgr) Prompt(message string, details PromptDetails) ResponseOption {
 	expectedInputChannel := make(chan string, 1)
 	lcm.msgQueue <- outputMessage{
@@ -390,7 +398,7 @@ func (lcm *lifecycleMgr) processTextOutput(msgToOutput outputMessage) {
 
 		lcm.progressCache = msgToOutput.msgContent
 
-	case eOutputMessageType.Init(), eOutputMessageType.Info():
+	case eOutputMessageType.Init(), eOutputMessageType.Info(), eOutputMessageType.Dryrun():
 		if lcm.progressCache != "" { // a progress status is already on the last line
 			// print the info from the beginning on current line
 			fmt.Print("\r")
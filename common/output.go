@@ -26,6 +26,7 @@ func (outputMessageType) EndOfJob() outputMessageType { return outputMessageType
 
 func (outputMessageType) Error() outputMessageType  { return outputMessageType(4) } // indicate fatal error, exit right after
 func (outputMessageType) Prompt() outputMessageType { return outputMessageType(5) } // ask the user a question after erasing the progress
+func (outputMessageType) Dryrun() outputMessageType { return outputMessageType(6) } // report what would have been done, allowed to float up
 
 func (o outputMessageType) String() string {
 	return enum.StringInt(o, reflect.TypeOf(o))
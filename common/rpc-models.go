@@ -8,6 +8,7 @@ import (
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/JeffreyRichter/enum/enum"
+	"github.com/minio/minio-go"
 )
 
 var ERpcCmd = RpcCmd("")
@@ -137,6 +138,12 @@ type CopyJobPartOrderRequest struct {
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
 	S2SInvalidMetadataHandleOption InvalidMetadataHandleOption
+	S2SPreserveBlobTags            bool                    // if true, S2S copies give each destination blob the index tags of its source
+	ClientSideEncrypt              bool                    // if true, uploads are encrypted before they're sent
+	ClientEncryptionKeyFile        string                  // the client-side encryption key comes from here, or the environment if it's empty
+	ClientEncryptionKeyHash        ClientEncryptionKeyHash // identifies the client-side encryption key, if there is one. The key itself is never sent to the STE.
+	CpkKeyHash                     CpkKeyHash              // identifies the customer-provided key that the service encrypts with, if there is one. Likewise, the key isn't sent.
+	EncryptionScope                string                  // the encryption scope that the service encrypts with, if there is one
 }
 
 // CredentialInfo contains essential credential info which need be transited between modules,
@@ -151,6 +158,10 @@ type CredentialInfo struct {
 type S3CredentialInfo struct {
 	Endpoint string
 	Region   string
+
+	// Only used for custom S3-compatible endpoints
+	Insecure     bool                   // talk to the endpoint over http rather than https
+	BucketLookup minio.BucketLookupType // path-style or virtual-hosted-style addressing
 }
 
 type CopyJobPartOrderErrorType string
@@ -184,9 +195,11 @@ type BlobTransferAttributes struct {
 	BlockBlobTier            BlockBlobTier         // Specifies the tier to set on the block blobs.
 	PageBlobTier             PageBlobTier          // Specifies the tier to set on the page blobs.
 	Metadata                 string                // User-defined Name-value pairs associated with the blob
+	BlobTagsString           string                // User-defined index tags for the blobs, in the form of BlobTags.ToString. These replace any tags of the source.
 	NoGuessMimeType          bool                  // represents user decision to interpret the content-encoding from source file
 	PreserveLastModifiedTime bool                  // when downloading, tell engine to set file's timestamp to timestamp of blob
 	PutMd5                   bool                  // when uploading, should we create and PUT Content-MD5 hashes
+	Compression              CompressionType       // when uploading, how (if at all) should we compress the files as we send them
 	MD5ValidationOption      HashValidationOption  // when downloading, how strictly should we validate MD5 hashes?
 	BlockSizeInBytes         int64                 // when uploading/downloading/copying, specify the size of each chunk
 	DeleteSnapshotsOption    DeleteSnapshotsOption // when deleting, specify what to do with the snapshots
@@ -268,6 +281,7 @@ type ListSyncJobSummaryResponse struct {
 	ListJobSummaryResponse
 	DeleteTotalTransfers     uint32 `json:",string"`
 	DeleteTransfersCompleted uint32 `json:",string"`
+	ConflictCount            uint32 `json:",string"`
 }
 
 type ListJobTransfersRequest struct {
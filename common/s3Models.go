@@ -21,8 +21,17 @@
 package common
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	minio "github.com/minio/minio-go"
 )
@@ -55,8 +64,12 @@ func (oie *ObjectInfoExtension) ContentLanguage() string {
 }
 
 // ContentMD5 returns the value for header Content-MD5.
+// S3 doesn't persist Content-MD5, so fall back to the user-defined metadata that AzCopy writes on upload.
 func (oie *ObjectInfoExtension) ContentMD5() []byte {
 	s := oie.ObjectInfo.Metadata.Get("Content-MD5")
+	if s == "" {
+		s = oie.ObjectInfo.Metadata.Get(s3MetadataPrefix + S3ContentMD5MetadataKey)
+	}
 	if s == "" {
 		return nil
 	}
@@ -67,19 +80,174 @@ func (oie *ObjectInfoExtension) ContentMD5() []byte {
 	return b
 }
 
+// ETagMD5 returns the MD5 of the object's content, as given by its ETag, or nil if the ETag isn't one.
+// It isn't for objects uploaded in several parts (whose ETags end in -<number of parts>), nor for objects encrypted with SSE-KMS or SSE-C.
+// The encryption is only known when the object's headers are, so from a listing, the result is only good for comparisons.
+func (oie *ObjectInfoExtension) ETagMD5() []byte {
+	etag := strings.Trim(oie.ObjectInfo.ETag, `"`)
+	if len(etag) != hex.EncodedLen(16) {
+		return nil
+	}
+	if strings.EqualFold(oie.ObjectInfo.Metadata.Get("X-Amz-Server-Side-Encryption"), "aws:kms") ||
+		oie.ObjectInfo.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+		return nil
+	}
+
+	b, err := hex.DecodeString(etag)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
 const s3MetadataPrefix = "x-amz-meta-"
 
 const s3MetadataPrefixLen = len(s3MetadataPrefix)
 
+// S3ContentMD5MetadataKey is the user-defined metadata key under which the MD5 hash of an uploaded object is stored.
+// S3 has no equivalent of Azure's Content-MD5 property, and the ETag of a multipart upload is not an MD5 of the content.
+const S3ContentMD5MetadataKey = "content-md5"
+
+// Limits on multipart uploads, as documented at https://docs.aws.amazon.com/AmazonS3/latest/dev/qfacts.html
+const (
+	S3MinPartSize      = 5 * 1024 * 1024
+	S3MaxPartSize      = 5 * 1024 * 1024 * 1024
+	S3MaxNumberOfParts = 10000
+)
+
 // NewMetadata returns user-defined key/value pairs.
 func (oie *ObjectInfoExtension) NewCommonMetadata() Metadata {
 	md := Metadata{}
 	for k, v := range oie.ObjectInfo.Metadata {
 		if len(k) > s3MetadataPrefixLen {
 			if prefix := k[0:s3MetadataPrefixLen]; strings.EqualFold(prefix, s3MetadataPrefix) {
+				if strings.EqualFold(k[s3MetadataPrefixLen:], S3ContentMD5MetadataKey) {
+					continue // surfaced through ContentMD5 instead
+				}
 				md[k[s3MetadataPrefixLen:]] = v[0]
 			}
 		}
 	}
 	return md
 }
+
+// ToS3PutObjectOptions converts ResourceHTTPHeaders and Metadata to the options used when creating an S3 object.
+func (h ResourceHTTPHeaders) ToS3PutObjectOptions(m Metadata) minio.PutObjectOptions {
+	userMetadata := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		userMetadata[k] = v
+	}
+	if len(h.ContentMD5) != 0 {
+		userMetadata[S3ContentMD5MetadataKey] = base64.StdEncoding.EncodeToString(h.ContentMD5)
+	}
+
+	return minio.PutObjectOptions{
+		UserMetadata:       userMetadata,
+		ContentType:        h.ContentType,
+		ContentEncoding:    h.ContentEncoding,
+		ContentDisposition: h.ContentDisposition,
+		ContentLanguage:    h.ContentLanguage,
+		CacheControl:       h.CacheControl,
+	}
+}
+
+// s3Tagging is the body of a response to GET Object tagging
+type s3Tagging struct {
+	TagSet []struct {
+		Key   string
+		Value string
+	} `xml:"TagSet>Tag"`
+}
+
+// ParseS3Tagging parses the tags of an S3 object, from the body of a response to GET Object tagging.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectTagging.html
+func ParseS3Tagging(body []byte) (BlobTags, error) {
+	var tagging s3Tagging
+	if err := xml.Unmarshal(body, &tagging); err != nil {
+		return nil, err
+	}
+	if len(tagging.TagSet) == 0 {
+		return nil, nil
+	}
+
+	tags := BlobTags{}
+	for _, tag := range tagging.TagSet {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// s3ClientTransports holds the transports of the S3 clients that don't use minio's default one. minio doesn't say
+// what a client's transport is, and the requests that it can't make for us must still go by it.
+var s3ClientTransports sync.Map
+
+// setS3ClientTransport makes client send its requests by transport, and records that it does
+func setS3ClientTransport(client *minio.Client, transport http.RoundTripper) {
+	client.SetCustomTransport(transport)
+	s3ClientTransports.Store(client, transport)
+}
+
+// s3ClientTransport gets the transport that client sends its requests by
+func s3ClientTransport(client *minio.Client) http.RoundTripper {
+	if transport, ok := s3ClientTransports.Load(client); ok {
+		return transport.(http.RoundTripper)
+	}
+	return minio.DefaultTransport
+}
+
+// GetS3ObjectTags gets the tags of an S3 object. The version of minio that we use can't get them,
+// so this requests the object's tagging subresource, with a pre-signed URL from the client.
+// The request goes by the client's transport, and is retried as minio retries its own.
+func GetS3ObjectTags(ctx context.Context, client *minio.Client, bucketName, objectKey string) (BlobTags, error) {
+	presignedURL, err := client.Presign(http.MethodGet, bucketName, objectKey, time.Hour, url.Values{"tagging": []string{""}})
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Transport: s3ClientTransport(client)}
+	for try := 0; ; try++ {
+		tags, retryable, err := getS3ObjectTagsOnce(ctx, httpClient, presignedURL.String())
+		if err == nil || !retryable || try >= minio.MaxRetry {
+			return tags, err
+		}
+
+		delay := minio.DefaultRetryUnit << uint(try)
+		if delay > minio.DefaultRetryCap {
+			delay = minio.DefaultRetryCap
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func getS3ObjectTagsOnce(ctx context.Context, httpClient *http.Client, presignedURL string) (tags BlobTags, retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, presignedURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		var s3Err minio.ErrorResponse
+		if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+			retryable = retryable || s3Err.Code == "SlowDown" || s3Err.Code == "RequestTimeout"
+			return nil, retryable, fmt.Errorf("getting the tags of the object returned %s: %s", resp.Status, s3Err.Message)
+		}
+		return nil, retryable, fmt.Errorf("getting the tags of the object returned %s", resp.Status)
+	}
+
+	tags, err = ParseS3Tagging(body)
+	return tags, false, err
+}
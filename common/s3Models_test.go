@@ -0,0 +1,118 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/minio/minio-go"
+	"github.com/minio/minio-go/pkg/credentials"
+	chk "gopkg.in/check.v1"
+)
+
+type s3ModelsTestSuite struct{}
+
+var _ = chk.Suite(&s3ModelsTestSuite{})
+
+func (s *s3ModelsTestSuite) TestETagMD5(c *chk.C) {
+	objectWith := func(etag string, header http.Header) *ObjectInfoExtension {
+		return &ObjectInfoExtension{ObjectInfo: minio.ObjectInfo{ETag: etag, Metadata: header}}
+	}
+
+	// a single-part upload's ETag is the hex of its MD5, in quotes or not
+	c.Assert(objectWith(`"d41d8cd98f00b204e9800998ecf8427e"`, nil).ETagMD5(), chk.DeepEquals,
+		[]byte{0xd4, 0x1d, 0x8c, 0xd9, 0x8f, 0x00, 0xb2, 0x04, 0xe9, 0x80, 0x09, 0x98, 0xec, 0xf8, 0x42, 0x7e})
+	c.Assert(objectWith("d41d8cd98f00b204e9800998ecf8427e", http.Header{}).ETagMD5(), chk.HasLen, 16)
+
+	// a multipart upload's isn't
+	c.Assert(objectWith(`"d41d8cd98f00b204e9800998ecf8427e-3"`, nil).ETagMD5(), chk.IsNil)
+	c.Assert(objectWith("not-hex-but-thirty-two-character", nil).ETagMD5(), chk.IsNil)
+
+	// nor is the ETag of an object encrypted with SSE-KMS or SSE-C
+	c.Assert(objectWith("d41d8cd98f00b204e9800998ecf8427e", http.Header{"X-Amz-Server-Side-Encryption": []string{"aws:kms"}}).ETagMD5(), chk.IsNil)
+	c.Assert(objectWith("d41d8cd98f00b204e9800998ecf8427e", http.Header{"X-Amz-Server-Side-Encryption-Customer-Algorithm": []string{"AES256"}}).ETagMD5(), chk.IsNil)
+	c.Assert(objectWith("d41d8cd98f00b204e9800998ecf8427e", http.Header{"X-Amz-Server-Side-Encryption": []string{"AES256"}}).ETagMD5(), chk.HasLen, 16)
+}
+
+func (s *s3ModelsTestSuite) TestParseS3Tagging(c *chk.C) {
+	tags, err := ParseS3Tagging([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Tagging xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<TagSet>
+		<Tag><Key>project</Key><Value>apollo</Value></Tag>
+		<Tag><Key>stage</Key><Value></Value></Tag>
+	</TagSet>
+</Tagging>`))
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.DeepEquals, BlobTags{"project": "apollo", "stage": ""})
+
+	tags, err = ParseS3Tagging([]byte(`<Tagging><TagSet></TagSet></Tagging>`))
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.HasLen, 0)
+
+	_, err = ParseS3Tagging([]byte(`not xml`))
+	c.Assert(err, chk.NotNil)
+}
+
+// countingTransport counts the requests that go by it
+type countingTransport struct {
+	count int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.count, 1)
+	return minio.DefaultTransport.RoundTrip(req)
+}
+
+func (s *s3ModelsTestSuite) TestGetS3ObjectTags(c *chk.C) {
+	// the first request is throttled, and the second gets the tags
+	requests := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, chk.Equals, "/bucket/object")
+		c.Check(r.URL.Query()["tagging"], chk.HasLen, 1)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<Tagging><TagSet><Tag><Key>project</Key><Value>apollo</Value></Tag></TagSet></Tagging>`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := minio.NewWithOptions(serverURL.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	c.Assert(err, chk.IsNil)
+	transport := &countingTransport{}
+	setS3ClientTransport(client, transport)
+
+	tags, err := GetS3ObjectTags(context.Background(), client, "bucket", "object")
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.DeepEquals, BlobTags{"project": "apollo"})
+	// both requests went by the client's transport
+	c.Assert(atomic.LoadInt32(&transport.count), chk.Equals, int32(2))
+}
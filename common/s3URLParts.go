@@ -25,6 +25,8 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+
+	"github.com/minio/minio-go"
 )
 
 // S3URLParts represents the components that make up AWS S3 Service/Bucket/Object URL.
@@ -40,6 +42,8 @@ import (
 // b. http://s3-aws-region.amazonaws.com/bucket (Region-specific endpoint)
 // Dual stack endpoint(IPv6&IPv4) is also supported (https://docs.aws.amazon.com/AmazonS3/latest/dev/dual-stack-endpoints.html#dual-stack-endpoints-description)
 // i.e. the endpoint in http://bucketname.s3.dualstack.aws-region.amazonaws.com or http://s3.dualstack.aws-region.amazonaws.com/bucketname
+// S3-compatible services (e.g. MinIO, Ceph or Wasabi) are supported by naming their endpoint in AZCOPY_S3_ENDPOINT,
+// in which case both http://endpoint/bucket and http://bucket.endpoint are accepted, e.g. http://minio.contoso.local:9000/bucket
 type S3URLParts struct {
 	Scheme         string // Ex: "https://", "s3://"
 	Host           string // Ex: "s3.amazonaws.com", "s3-eu-west-1.amazonaws.com", "bucket.s3-eu-west-1.amazonaws.com"
@@ -50,13 +54,14 @@ type S3URLParts struct {
 	Region         string // Ex: endpoint region, e.g. "eu-west-1"
 	UnparsedParams string
 
-	isPathStyle bool
-	isDualStack bool
-	// TODO: Other S3 compatible service which might be with IP endpoint style
+	isPathStyle      bool
+	isDualStack      bool
+	isCustomEndpoint bool
 }
 
 const s3HostPattern = "^(?P<bucketName>.+\\.)?s3[.-](?P<dualStackOrRegionOrAWSDomain>[a-z0-9-]+)\\.(?P<regionOrAWSDomainOrCom>[a-z0-9-]+)"
-const invalidS3URLErrorMessage = "Invalid S3 URL. AzCopy supports standard virtual-hosted-style or path-style URLs defined by AWS, E.g: https://bucket.s3.amazonaws.com or https://s3.amazonaws.com/bucket"
+const invalidS3URLErrorMessage = "Invalid S3 URL. AzCopy supports standard virtual-hosted-style or path-style URLs defined by AWS, E.g: https://bucket.s3.amazonaws.com or https://s3.amazonaws.com/bucket. " +
+	"To use an S3-compatible service, set its endpoint in AZCOPY_S3_ENDPOINT"
 const versionQueryParamKey = "versionId"
 const s3KeywordAmazonAWS = "amazonaws"
 const s3KeywordDualStack = "dualstack"
@@ -66,12 +71,42 @@ var s3HostRegex = regexp.MustCompile(s3HostPattern)
 
 // IsS3URL verfies if a given URL points to S3 URL supported by AzCopy-v10
 func IsS3URL(u url.URL) bool {
-	if _, isS3URL := findS3URLMatches(strings.ToLower(u.Host)); isS3URL {
+	host := strings.ToLower(u.Host)
+	if _, isS3URL := findS3URLMatches(host); isS3URL {
+		return true
+	}
+	if _, isCustomHost := findCustomS3EndpointMatch(host); isCustomHost {
 		return true
 	}
 	return false
 }
 
+// customS3Endpoint returns the S3-compatible endpoint configured by the user, if any, in lower case and without scheme.
+func customS3Endpoint() string {
+	endpoint := strings.ToLower(strings.TrimSpace(GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.S3Endpoint())))
+	if i := strings.Index(endpoint, "://"); i != -1 {
+		endpoint = endpoint[i+3:]
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+// findCustomS3EndpointMatch checks whether host is the custom S3 endpoint (path-style),
+// or a bucket sub-domain of it (virtual-hosted-style), and returns the bucket name in the latter case.
+func findCustomS3EndpointMatch(host string) (bucketName string, isCustomHost bool) {
+	endpoint := customS3Endpoint()
+	if endpoint == "" {
+		return "", false
+	}
+
+	if host == endpoint {
+		return "", true
+	}
+	if strings.HasSuffix(host, "."+endpoint) {
+		return strings.TrimSuffix(host, "."+endpoint), true
+	}
+	return "", false
+}
+
 func findS3URLMatches(host string) (matches []string, isS3Host bool) {
 	matchSlices := s3HostRegex.FindStringSubmatch(host) // If match the first element would be entire host, and then follows the sub match strings.
 	if matchSlices == nil || !strings.Contains(host, s3EssentialHostPart) {
@@ -85,11 +120,6 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	// S3's bucket name should be in lower case
 	host := strings.ToLower(u.Host)
 
-	matchSlices, isS3URL := findS3URLMatches(host)
-	if !isS3URL {
-		return S3URLParts{}, errors.New(invalidS3URLErrorMessage)
-	}
-
 	path := u.Path
 	// Remove the initial '/' if exists
 	if path != "" && path[0] == '/' {
@@ -101,6 +131,28 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 		Host:   host,
 	}
 
+	matchSlices, isS3URL := findS3URLMatches(host)
+	if !isS3URL {
+		bucketName, isCustomHost := findCustomS3EndpointMatch(host)
+		if !isCustomHost {
+			return S3URLParts{}, errors.New(invalidS3URLErrorMessage)
+		}
+
+		up.isCustomEndpoint = true
+		up.Endpoint = customS3Endpoint()
+		up.Region = GetLifecycleMgr().GetEnvironmentVariable(EEnvironmentVariable.S3Region())
+		if bucketName != "" {
+			up.BucketName = bucketName
+			up.ObjectKey = path
+		} else {
+			up.isPathStyle = true
+			up.BucketName, up.ObjectKey = splitS3BucketAndObject(path)
+		}
+		up.parseQuery(u)
+
+		return up, nil
+	}
+
 	// Check what's the path style, and parse accordingly.
 	if matchSlices[1] != "" { // Go's implementatoin is a bit strange, even if the first subexp fail to be matched, "" will be returned for that sub exp
 		// In this case, it would be in virtual-hosted-style URL, and has host prefix like bucket.s3[-.]
@@ -111,14 +163,7 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	} else {
 		// In this case, it would be in path-style URL. Host prefix like s3[-.], and path contains the bucket name and object id.
 		up.isPathStyle = true
-
-		if bucketEndIndex := strings.Index(path, "/"); bucketEndIndex != -1 {
-			up.BucketName = path[:bucketEndIndex]
-			up.ObjectKey = path[bucketEndIndex+1:]
-		} else {
-			up.BucketName = path
-		}
-
+		up.BucketName, up.ObjectKey = splitS3BucketAndObject(path)
 		up.Endpoint = host
 	}
 	// Check if dualstack is contained in host name
@@ -131,18 +176,30 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 		up.Region = matchSlices[2]
 	}
 
+	up.parseQuery(u)
+
+	return up, nil
+}
+
+// splitS3BucketAndObject splits a path-style URL path into the bucket name and the object key.
+func splitS3BucketAndObject(path string) (bucketName, objectKey string) {
+	if bucketEndIndex := strings.Index(path, "/"); bucketEndIndex != -1 {
+		return path[:bucketEndIndex], path[bucketEndIndex+1:]
+	}
+	return path, ""
+}
+
+func (p *S3URLParts) parseQuery(u url.URL) {
 	// Convert the query parameters to a case-sensitive map & trim whitespace
 	paramsMap := u.Query()
 
 	if versionStr, ok := caseInsensitiveValues(paramsMap).Get(versionQueryParamKey); ok {
-		up.Version = versionStr[0]
+		p.Version = versionStr[0]
 		// If we recognized the query parameter, remove it from the map
 		delete(paramsMap, versionQueryParamKey)
 	}
 
-	up.UnparsedParams = paramsMap.Encode()
-
-	return up, nil
+	p.UnparsedParams = paramsMap.Encode()
 }
 
 // URL returns a URL object whose fields are initialized from the S3URLParts fields.
@@ -181,6 +238,30 @@ func (p *S3URLParts) String() string {
 	return u.String()
 }
 
+// IsCustomEndpoint indicates whether the URL points to the S3-compatible endpoint set in AZCOPY_S3_ENDPOINT, rather than to AWS.
+func (p *S3URLParts) IsCustomEndpoint() bool {
+	return p.isCustomEndpoint
+}
+
+// S3CredentialInfo returns the endpoint details needed to create an S3 client for the URL.
+// Custom endpoints honour the scheme of the URL, and are addressed in the same style as the URL.
+func (p *S3URLParts) S3CredentialInfo() S3CredentialInfo {
+	info := S3CredentialInfo{
+		Endpoint: p.Endpoint,
+		Region:   p.Region,
+	}
+
+	if p.isCustomEndpoint {
+		info.Insecure = strings.EqualFold(p.Scheme, "http")
+		info.BucketLookup = minio.BucketLookupDNS
+		if p.isPathStyle {
+			info.BucketLookup = minio.BucketLookupPath
+		}
+	}
+
+	return info
+}
+
 func (p *S3URLParts) IsServiceSyntactically() bool {
 	if p.Host != "" && p.BucketName == "" {
 		return true
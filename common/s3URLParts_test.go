@@ -22,8 +22,10 @@ package common
 
 import (
 	"net/url"
+	"os"
 	"strings"
 
+	"github.com/minio/minio-go"
 	chk "gopkg.in/check.v1"
 )
 
@@ -205,3 +207,64 @@ func (s *s3URLPartsTestSuite) TestS3URLParseNegative(c *chk.C) {
 	c.Assert(err, chk.NotNil)
 	c.Assert(strings.Contains(err.Error(), invalidS3URLErrorMessage), chk.Equals, true)
 }
+
+func (s *s3URLPartsTestSuite) TestS3URLParseCustomEndpoint(c *chk.C) {
+	defer os.Unsetenv(EEnvironmentVariable.S3Endpoint().Name)
+	defer os.Unsetenv(EEnvironmentVariable.S3Region().Name)
+
+	u, _ := url.Parse("http://minio.contoso.local:9000/bucket/keydir/keyname")
+	c.Assert(IsS3URL(*u), chk.Equals, false)
+
+	os.Setenv(EEnvironmentVariable.S3Endpoint().Name, "https://MinIO.contoso.local:9000/")
+	os.Setenv(EEnvironmentVariable.S3Region().Name, "on-prem-1")
+	c.Assert(IsS3URL(*u), chk.Equals, true)
+
+	// path-style over http
+	p, err := NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsCustomEndpoint(), chk.Equals, true)
+	c.Assert(p.Host, chk.Equals, "minio.contoso.local:9000")
+	c.Assert(p.Endpoint, chk.Equals, "minio.contoso.local:9000")
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keydir/keyname")
+	c.Assert(p.Region, chk.Equals, "on-prem-1")
+	c.Assert(p.String(), chk.Equals, "http://minio.contoso.local:9000/bucket/keydir/keyname")
+	credInfo := p.S3CredentialInfo()
+	c.Assert(credInfo.Endpoint, chk.Equals, "minio.contoso.local:9000")
+	c.Assert(credInfo.Region, chk.Equals, "on-prem-1")
+	c.Assert(credInfo.Insecure, chk.Equals, true)
+	c.Assert(credInfo.BucketLookup, chk.Equals, minio.BucketLookupPath)
+
+	// virtual-hosted-style over https
+	u, _ = url.Parse("https://bucket.minio.contoso.local:9000/keyname?versionId=1")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsCustomEndpoint(), chk.Equals, true)
+	c.Assert(p.Endpoint, chk.Equals, "minio.contoso.local:9000")
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keyname")
+	c.Assert(p.Version, chk.Equals, "1")
+	c.Assert(p.String(), chk.Equals, "https://bucket.minio.contoso.local:9000/keyname?versionId=1")
+	credInfo = p.S3CredentialInfo()
+	c.Assert(credInfo.Insecure, chk.Equals, false)
+	c.Assert(credInfo.BucketLookup, chk.Equals, minio.BucketLookupDNS)
+
+	// service
+	u, _ = url.Parse("http://minio.contoso.local:9000")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsServiceSyntactically(), chk.Equals, true)
+
+	// AWS URLs are unaffected by the custom endpoint
+	u, _ = url.Parse("https://bucket.s3-aws-region.amazonaws.com/keyname")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsCustomEndpoint(), chk.Equals, false)
+	c.Assert(p.Region, chk.Equals, "aws-region")
+	c.Assert(p.S3CredentialInfo(), chk.DeepEquals, S3CredentialInfo{Endpoint: "s3-aws-region.amazonaws.com", Region: "aws-region"})
+
+	// other hosts are still rejected
+	u, _ = url.Parse("http://minio.contoso.local:9001/bucket")
+	_, err = NewS3URLParts(*u)
+	c.Assert(err, chk.NotNil)
+}
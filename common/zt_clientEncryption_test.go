@@ -0,0 +1,210 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	chk "gopkg.in/check.v1"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+type clientEncryptionSuite struct{}
+
+var _ = chk.Suite(&clientEncryptionSuite{})
+
+func newTestClientEncryptionKey(c *chk.C) *ClientEncryptionKey {
+	keyBytes := make([]byte, ClientEncryptionKeySize)
+	rand.Read(keyBytes)
+	key, err := NewClientEncryptionKey(keyBytes)
+	c.Assert(err, chk.IsNil)
+	return key
+}
+
+// encryptForTest encrypts the plaintext the way the uploader does: a chunk at a time, in place
+func encryptForTest(c *chk.C, key *ClientEncryptionKey, plaintext []byte, chunkSize int64) ([]byte, *ClientEncryptionEnvelope) {
+	encryptor, err := key.NewChunkEncryptor(chunkSize, int64(len(plaintext)))
+	c.Assert(err, chk.IsNil)
+
+	ciphertext := make([]byte, 0, ClientEncryptedSize(int64(len(plaintext)), chunkSize))
+	for index := int64(0); index*chunkSize < int64(len(plaintext)); index++ {
+		end := (index + 1) * chunkSize
+		if end > int64(len(plaintext)) {
+			end = int64(len(plaintext))
+		}
+		chunk := make([]byte, end-index*chunkSize, end-index*chunkSize+ClientEncryptionTagSize)
+		copy(chunk, plaintext[index*chunkSize:end])
+		ciphertext = append(ciphertext, encryptor.Seal(chunk[:0], chunk, index)...)
+	}
+
+	plaintextMD5 := md5.Sum(plaintext)
+	value, err := encryptor.Envelope(plaintextMD5[:])
+	c.Assert(err, chk.IsNil)
+	envelope, err := ParseClientEncryptionEnvelope(Metadata{"AzCopyEncryptionData": value})
+	c.Assert(err, chk.IsNil)
+	c.Assert(envelope, chk.NotNil)
+	c.Assert(envelope.EncryptedSize(), chk.Equals, int64(len(ciphertext)))
+	return ciphertext, envelope
+}
+
+func decryptForTest(key *ClientEncryptionKey, ciphertext []byte, envelope *ClientEncryptionEnvelope, writeBufferSize int) (*closeableBuffer, *DecryptingWriter, error) {
+	dest := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	w, err := key.NewDecryptingWriter(dest, envelope)
+	if err != nil {
+		return dest, nil, err
+	}
+
+	for len(ciphertext) > 0 {
+		size := writeBufferSize
+		if size > len(ciphertext) {
+			size = len(ciphertext)
+		}
+		if _, err = w.Write(ciphertext[:size]); err != nil {
+			return dest, w, err
+		}
+		ciphertext = ciphertext[size:]
+	}
+	return dest, w, w.Close()
+}
+
+func (s *clientEncryptionSuite) TestClientEncryption_RoundTrip(c *chk.C) {
+	key := newTestClientEncryptionKey(c)
+	cases := []struct {
+		plaintextSize int
+		chunkSize     int64
+	}{
+		{0, 1024},
+		{1, 1024},
+		{1024, 1024}, // exactly one chunk
+		{1025, 1024},
+		{10 * 1024, 1024}, // exact multiple of chunks
+		{rand.Intn(100 * 1024), int64(1 + rand.Intn(8*1024))},
+	}
+
+	for _, tc := range cases {
+		plaintext := make([]byte, tc.plaintextSize)
+		rand.Read(plaintext)
+		ciphertext, envelope := encryptForTest(c, key, plaintext, tc.chunkSize)
+		if tc.plaintextSize >= 16 { // shorter plaintexts could turn up in the ciphertext just by chance
+			c.Assert(bytes.Contains(ciphertext, plaintext), chk.Equals, false)
+		}
+
+		for _, writeBufferSize := range []int{1, 1 + rand.Intn(16*1024), len(ciphertext) + 1} {
+			dest, w, err := decryptForTest(key, ciphertext, envelope, writeBufferSize)
+			c.Assert(err, chk.IsNil)
+			c.Assert(dest.closeWasCalled(), chk.Equals, true)
+			c.Assert(bytes.Equal(dest.Bytes(), plaintext), chk.Equals, true)
+			c.Assert(w.PlaintextMD5(), chk.DeepEquals, envelope.GetPlaintextMD5())
+		}
+	}
+}
+
+func (s *clientEncryptionSuite) TestClientEncryption_TamperingIsDetected(c *chk.C) {
+	key := newTestClientEncryptionKey(c)
+	plaintext := make([]byte, 4000)
+	rand.Read(plaintext)
+	ciphertext, envelope := encryptForTest(c, key, plaintext, 1000)
+	chunkLength := 1000 + 16
+
+	// a flipped bit
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[1500] ^= 1
+	_, _, err := decryptForTest(key, tampered, envelope, 64)
+	c.Assert(err, chk.NotNil)
+
+	// swapped chunks
+	tampered = append([]byte(nil), ciphertext[chunkLength:2*chunkLength]...)
+	tampered = append(tampered, ciphertext[:chunkLength]...)
+	tampered = append(tampered, ciphertext[2*chunkLength:]...)
+	_, _, err = decryptForTest(key, tampered, envelope, 64)
+	c.Assert(err, chk.NotNil)
+
+	// a dropped chunk, with the metadata changed to match
+	shortened := *envelope
+	shortened.PlaintextSize = 3000
+	_, _, err = decryptForTest(key, ciphertext[:3*chunkLength], &shortened, 64)
+	c.Assert(err, chk.NotNil)
+
+	// truncation
+	_, _, err = decryptForTest(key, ciphertext[:len(ciphertext)-1], envelope, 64)
+	c.Assert(err, chk.NotNil)
+
+	// extra data
+	_, _, err = decryptForTest(key, append(append([]byte(nil), ciphertext...), 0), envelope, 64)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *clientEncryptionSuite) TestClientEncryption_WrongKey(c *chk.C) {
+	key := newTestClientEncryptionKey(c)
+	ciphertext, envelope := encryptForTest(c, key, []byte("some plaintext"), 1024)
+
+	_, _, err := decryptForTest(newTestClientEncryptionKey(c), ciphertext, envelope, 64)
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Equals, "the blob was encrypted with a different client-side encryption key")
+}
+
+func (s *clientEncryptionSuite) TestClientEncryption_NoEnvelope(c *chk.C) {
+	envelope, err := ParseClientEncryptionEnvelope(Metadata{"other": "value"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(envelope, chk.IsNil)
+
+	_, err = ParseClientEncryptionEnvelope(Metadata{ClientEncryptionMetadataKey: "not json"})
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *clientEncryptionSuite) TestLoadClientEncryptionKey(c *chk.C) {
+	dir, err := ioutil.TempDir("", "clientEncryption")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	keyBytes := make([]byte, ClientEncryptionKeySize)
+	rand.Read(keyBytes)
+	expected, err := NewClientEncryptionKey(keyBytes)
+	c.Assert(err, chk.IsNil)
+
+	// raw
+	rawFile := filepath.Join(dir, "raw.key")
+	c.Assert(ioutil.WriteFile(rawFile, keyBytes, 0600), chk.IsNil)
+	key, err := LoadClientEncryptionKey(rawFile)
+	c.Assert(err, chk.IsNil)
+	c.Assert(key.Hash(), chk.Equals, expected.Hash())
+
+	// base64, with the trailing new line that editors add
+	encodedFile := filepath.Join(dir, "encoded.key")
+	c.Assert(ioutil.WriteFile(encodedFile, []byte(base64.StdEncoding.EncodeToString(keyBytes)+"\n"), 0600), chk.IsNil)
+	key, err = LoadClientEncryptionKey(encodedFile)
+	c.Assert(err, chk.IsNil)
+	c.Assert(key.Hash(), chk.Equals, expected.Hash())
+
+	// too short
+	shortFile := filepath.Join(dir, "short.key")
+	c.Assert(ioutil.WriteFile(shortFile, []byte(base64.StdEncoding.EncodeToString(keyBytes[:16])), 0600), chk.IsNil)
+	_, err = LoadClientEncryptionKey(shortFile)
+	c.Assert(err, chk.NotNil)
+
+	// missing
+	_, err = LoadClientEncryptionKey(filepath.Join(dir, "missing.key"))
+	c.Assert(err, chk.NotNil)
+}
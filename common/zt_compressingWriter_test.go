@@ -0,0 +1,118 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	chk "gopkg.in/check.v1"
+	"io"
+	"math/rand"
+)
+
+type compressingWriterSuite struct{}
+
+var _ = chk.Suite(&compressingWriterSuite{})
+
+func (s *compressingWriterSuite) TestCompressingWriter_ConcatenatedChunksRoundTrip(c *chk.C) {
+	cases := []CompressionType{
+		ECompressionType.GZip(),
+		ECompressionType.Zstd(),
+	}
+
+	for _, tp := range cases {
+		// given:
+		// data that is compressed a chunk at a time, as it is when uploading, with the compressed chunks concatenated
+		originalData := (&decompressingWriterSuite{}).genCompressibleTestData(5*1024*1024 + rand.Intn(1024*1024))
+		chunkSize := 1024*1024 + rand.Intn(1024)
+		compressedData := &bytes.Buffer{}
+		for start := 0; start < len(originalData); start += chunkSize {
+			end := start + chunkSize
+			if end > len(originalData) {
+				end = len(originalData)
+			}
+			comp, err := NewCompressingWriter(compressedData, tp)
+			c.Assert(err, chk.IsNil)
+			_, err = comp.Write(originalData[start:end])
+			c.Assert(err, chk.IsNil)
+			c.Assert(comp.Close(), chk.IsNil)
+		}
+		c.Assert(compressedData.Len() < len(originalData), chk.Equals, true)
+
+		// when:
+		// we decompress it as we would when downloading
+		destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+		decWriter := NewDecompressingWriter(destFile, tp)
+		_, err := io.Copy(decWriter, compressedData)
+		c.Assert(err, chk.IsNil)
+		c.Assert(decWriter.Close(), chk.IsNil)
+
+		// then:
+		// we get all the original data back
+		c.Assert(destFile.Bytes(), chk.DeepEquals, originalData)
+	}
+}
+
+func (s *compressingWriterSuite) TestMaxCompressedSize(c *chk.C) {
+	for _, size := range []int{0, 1, 1000, 128 * 1024, 5*1024*1024 + 7} {
+		incompressible := make([]byte, size)
+		_, _ = rand.Read(incompressible)
+
+		for _, tp := range []CompressionType{ECompressionType.GZip(), ECompressionType.Zstd()} {
+			compressedData := &bytes.Buffer{}
+			comp, err := NewCompressingWriter(compressedData, tp)
+			c.Assert(err, chk.IsNil)
+			_, err = comp.Write(incompressible)
+			c.Assert(err, chk.IsNil)
+			c.Assert(comp.Close(), chk.IsNil)
+
+			c.Assert(int64(compressedData.Len()) <= MaxCompressedSize(int64(size)), chk.Equals, true, chk.Commentf("%s of %d bytes", tp, size))
+		}
+	}
+}
+
+func (s *compressingWriterSuite) TestCompressingWriter_EmptyData(c *chk.C) {
+	// an empty file must still be uploaded as a valid compressed stream, so that it can be decompressed
+	for _, tp := range []CompressionType{ECompressionType.GZip(), ECompressionType.Zstd()} {
+		compressedData := &bytes.Buffer{}
+		comp, err := NewCompressingWriter(compressedData, tp)
+		c.Assert(err, chk.IsNil)
+		c.Assert(comp.Close(), chk.IsNil)
+
+		destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+		decWriter := NewDecompressingWriter(destFile, tp)
+		_, err = io.Copy(decWriter, compressedData)
+		c.Assert(err, chk.IsNil)
+		c.Assert(decWriter.Close(), chk.IsNil)
+		c.Assert(destFile.Len(), chk.Equals, 0)
+	}
+}
+
+func (s *compressingWriterSuite) TestCompressionTypeParseAndContentEncoding(c *chk.C) {
+	for _, tp := range []CompressionType{ECompressionType.GZip(), ECompressionType.Zstd()} {
+		var parsed CompressionType
+		c.Assert(parsed.Parse(tp.ContentEncoding()), chk.IsNil)
+		c.Assert(parsed, chk.Equals, tp)
+
+		fromEncoding, err := GetCompressionType(tp.ContentEncoding())
+		c.Assert(err, chk.IsNil)
+		c.Assert(fromEncoding, chk.Equals, tp)
+	}
+}
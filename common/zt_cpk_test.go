@@ -0,0 +1,98 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type cpkSuite struct{}
+
+var _ = chk.Suite(&cpkSuite{})
+
+func setCpkKeyForTest(value string) func() {
+	name := EEnvironmentVariable.CpkEncryptionKey().Name
+	old, wasSet := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if wasSet {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func (s *cpkSuite) TestLoadCpkKey(c *chk.C) {
+	keyBytes := make([]byte, CpkKeySize)
+	keyBytes[0] = 7
+	encoded := base64.StdEncoding.EncodeToString(keyBytes)
+	defer setCpkKeyForTest(encoded)()
+
+	key, err := LoadCpkKey()
+	c.Assert(err, chk.IsNil)
+	c.Assert(key, chk.NotNil)
+	c.Assert(key.Hash(), chk.Equals, CpkKeyHash(sha256.Sum256(keyBytes)))
+
+	// the service is sent the key and its hash, both base64-encoded
+	options := CpkOptions{Key: key}.ToClientProvidedKeyOptions()
+	c.Assert(*options.EncryptionKey, chk.Equals, encoded)
+	c.Assert(*options.EncryptionKeySha256, chk.Equals, key.Hash().String())
+	c.Assert(options.EncryptionAlgorithm, chk.Equals, azblob.EncryptionAlgorithmAES256)
+	c.Assert(options.EncryptionScope, chk.IsNil)
+}
+
+func (s *cpkSuite) TestLoadCpkKeyNegative(c *chk.C) {
+	// no key is not an error, since the key is only needed with cpk-by-value
+	defer setCpkKeyForTest("")()
+	key, err := LoadCpkKey()
+	c.Assert(err, chk.IsNil)
+	c.Assert(key, chk.IsNil)
+
+	setCpkKeyForTest("not base64!")
+	_, err = LoadCpkKey()
+	c.Assert(err, chk.NotNil)
+
+	setCpkKeyForTest(base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	_, err = LoadCpkKey()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *cpkSuite) TestCpkOptions(c *chk.C) {
+	// with neither a key nor a scope, nothing is sent
+	options := CpkOptions{}.ToClientProvidedKeyOptions()
+	c.Assert(options, chk.DeepEquals, azblob.ClientProvidedKeyOptions{})
+
+	options = CpkOptions{EncryptionScope: "scope1"}.ToClientProvidedKeyOptions()
+	c.Assert(*options.EncryptionScope, chk.Equals, "scope1")
+	c.Assert(options.EncryptionKey, chk.IsNil)
+	c.Assert(options.EncryptionAlgorithm, chk.Equals, azblob.EncryptionAlgorithmNone)
+
+	c.Assert(CpkOptions{EncryptionScope: "scope1"}.Validate(), chk.IsNil)
+	c.Assert(CpkOptions{EncryptionScope: strings.Repeat("a", MaxEncryptionScopeLength+1)}.Validate(), chk.NotNil)
+	c.Assert(CpkOptions{Key: &CpkKey{}, EncryptionScope: "scope1"}.Validate(), chk.NotNil)
+}
@@ -24,6 +24,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	chk "gopkg.in/check.v1"
 	"io"
 	"math/rand"
@@ -65,6 +67,14 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_SuccessCases(c *chk.C
 		{"big zlib", ECompressionType.ZLib(), 10 * 1024 * 1024, rand.Intn(1024*1024) + 1},
 		{"sml zlib", ECompressionType.ZLib(), 1024, rand.Intn(1024*1024) + 1},
 		{"1bytzlib", ECompressionType.ZLib(), 1234, 1},
+
+		{"big zstd", ECompressionType.Zstd(), 10 * 1024 * 1024, rand.Intn(1024*1024) + 1},
+		{"sml zstd", ECompressionType.Zstd(), 1024, rand.Intn(1024*1024) + 1},
+		{"1bytzstd", ECompressionType.Zstd(), 1234, 1},
+
+		{"big brotli", ECompressionType.Brotli(), 10 * 1024 * 1024, rand.Intn(1024*1024) + 1},
+		{"sml brotli", ECompressionType.Brotli(), 1024, rand.Intn(1024*1024) + 1},
+		{"1bytbrotli", ECompressionType.Brotli(), 1234, 1},
 	}
 
 	for _, cs := range cases {
@@ -95,6 +105,8 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_EarlyClose(c *chk.C)
 	cases := []CompressionType{
 		ECompressionType.GZip(),
 		ECompressionType.ZLib(),
+		ECompressionType.Zstd(),
+		ECompressionType.Brotli(),
 	}
 	for _, tp := range cases {
 		// given:
@@ -114,7 +126,28 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_EarlyClose(c *chk.C)
 		// the amount processed was as expected, the dest file is closed, and an error was returned from close (because decompressor never sees the expected footer)
 		c.Assert(n, chk.Equals, sizeBeforeEarlyClose)
 		c.Assert(destFile.closeWasCalled(), chk.Equals, true)
-		c.Assert(err, chk.NotNil)
+		c.Assert(err, chk.NotNil, chk.Commentf("%s", tp))
+	}
+}
+
+func (d *decompressingWriterSuite) TestDecompressingWriter_BrotliTruncation(c *chk.C) {
+	// the error that tells a complete brotli stream from a truncated one must have been found, or every stream would be taken to be truncated
+	c.Assert(brotliExcessiveInput, chk.NotNil)
+
+	original, compressedData := d.getTestData(c, ECompressionType.Brotli(), 64*1024)
+	for _, size := range []int{len(compressedData), len(compressedData) - 1, len(compressedData) / 2} {
+		destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+		decWriter := NewDecompressingWriter(destFile, ECompressionType.Brotli())
+		_, err := decWriter.Write(compressedData[:size])
+		c.Assert(err, chk.IsNil)
+		err = decWriter.Close()
+
+		if size == len(compressedData) {
+			c.Assert(err, chk.IsNil)
+			c.Assert(destFile.Bytes(), chk.DeepEquals, original)
+		} else {
+			c.Assert(err, chk.NotNil, chk.Commentf("truncated to %d of %d bytes", size, len(compressedData)))
+		}
 	}
 }
 
@@ -123,9 +156,18 @@ func (d *decompressingWriterSuite) getTestData(c *chk.C, tp CompressionType, ori
 	originalData := d.genCompressibleTestData(originalSize)
 	// and from that we have original compressed data
 	compBuf := &bytes.Buffer{}
-	var comp io.WriteCloser = zlib.NewWriter(compBuf)
-	if tp == ECompressionType.GZip() {
+	var comp io.WriteCloser
+	switch tp {
+	case ECompressionType.GZip():
 		comp = gzip.NewWriter(compBuf)
+	case ECompressionType.Zstd():
+		var err error
+		comp, err = zstd.NewWriter(compBuf)
+		c.Assert(err, chk.IsNil)
+	case ECompressionType.Brotli():
+		comp = brotli.NewWriter(compBuf)
+	default:
+		comp = zlib.NewWriter(compBuf)
 	}
 	_, err := io.Copy(comp, bytes.NewReader(originalData))
 	// write into buf by way of comp
@@ -0,0 +1,241 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type gcpServiceAccountSuite struct{}
+
+var _ = chk.Suite(&gcpServiceAccountSuite{})
+
+// writeTestGCPServiceAccountKey writes a key file for a new service account, whose tokens come from tokenURI
+func writeTestGCPServiceAccountKey(c *chk.C, dir string, tokenURI string) (string, *rsa.PrivateKey) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, chk.IsNil)
+	// Google Cloud gives out PKCS #8 keys
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	c.Assert(err, chk.IsNil)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	keyJSON, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key1",
+		"private_key":    string(keyPEM),
+		"client_email":   "azcopy@test-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	})
+	c.Assert(err, chk.IsNil)
+
+	path := filepath.Join(dir, "key.json")
+	c.Assert(ioutil.WriteFile(path, keyJSON, 0600), chk.IsNil)
+	return path, privateKey
+}
+
+// parseTestJWT checks the signature of an RS256 JSON Web Token, and returns its header and claims
+func parseTestJWT(c *chk.C, token string, publicKey *rsa.PublicKey) (header, claims map[string]interface{}) {
+	parts := strings.Split(token, ".")
+	c.Assert(parts, chk.HasLen, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	c.Assert(err, chk.IsNil)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	c.Assert(rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature), chk.IsNil)
+
+	for i, decoded := range []*map[string]interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		c.Assert(err, chk.IsNil)
+		c.Assert(json.Unmarshal(data, decoded), chk.IsNil)
+	}
+	return header, claims
+}
+
+func (s *gcpServiceAccountSuite) TestGCPServiceAccountToken(c *chk.C) {
+	dir, err := ioutil.TempDir("", "gcpkey")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	var privateKey *rsa.PrivateKey
+	var requests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		c.Check(r.FormValue("grant_type"), chk.Equals, "urn:ietf:params:oauth:grant-type:jwt-bearer")
+
+		// the assertion must be signed with the service account's key, and ask for read-only access
+		header, claims := parseTestJWT(c, r.FormValue("assertion"), &privateKey.PublicKey)
+		c.Check(header["alg"], chk.Equals, "RS256")
+		c.Check(header["kid"], chk.Equals, "key1")
+		c.Check(claims["iss"], chk.Equals, "azcopy@test-project.iam.gserviceaccount.com")
+		c.Check(claims["scope"], chk.Equals, gcpReadOnlyScope)
+		c.Check(claims["aud"], chk.Equals, "http://"+r.Host+"/token")
+		c.Check(claims["exp"].(float64)-claims["iat"].(float64), chk.Equals, float64(3600))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token1","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer tokenServer.Close()
+
+	var path string
+	path, privateKey = writeTestGCPServiceAccountKey(c, dir, tokenServer.URL+"/token")
+	key, err := LoadGCPServiceAccountKey(path)
+	c.Assert(err, chk.IsNil)
+
+	token, err := key.Token(http.DefaultClient)
+	c.Assert(err, chk.IsNil)
+	c.Assert(token, chk.Equals, "token1")
+
+	// the token is reused until it's about to expire
+	token, err = key.Token(http.DefaultClient)
+	c.Assert(err, chk.IsNil)
+	c.Assert(token, chk.Equals, "token1")
+	c.Assert(atomic.LoadInt32(&requests), chk.Equals, int32(1))
+
+	key.tokenExpiry = time.Now().Add(time.Minute)
+	_, err = key.Token(http.DefaultClient)
+	c.Assert(err, chk.IsNil)
+	c.Assert(atomic.LoadInt32(&requests), chk.Equals, int32(2))
+}
+
+func (s *gcpServiceAccountSuite) TestGCPServiceAccountPresign(c *chk.C) {
+	dir, err := ioutil.TempDir("", "gcpkey")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path, privateKey := writeTestGCPServiceAccountKey(c, dir, "")
+	key, err := LoadGCPServiceAccountKey(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(key.TokenURI, chk.Equals, gcpDefaultTokenURI)
+
+	u, err := key.PresignGetObject("bucket", "dir/with space~.txt", time.Hour)
+	c.Assert(err, chk.IsNil)
+	c.Assert(u.Host, chk.Equals, GCPEndpoint)
+	c.Assert(u.EscapedPath(), chk.Equals, "/bucket/dir/with%20space~.txt")
+
+	query := u.Query()
+	c.Assert(query.Get("X-Goog-Algorithm"), chk.Equals, "GOOG4-RSA-SHA256")
+	c.Assert(query.Get("X-Goog-Expires"), chk.Equals, "3600")
+	c.Assert(query.Get("X-Goog-SignedHeaders"), chk.Equals, "host")
+	date := query.Get("X-Goog-Date")
+	scope := date[:8] + "/auto/storage/goog4_request"
+	c.Assert(query.Get("X-Goog-Credential"), chk.Equals, "azcopy@test-project.iam.gserviceaccount.com/"+scope)
+
+	// the signature covers the request as GCS will see it: everything but the signature itself
+	unsignedQuery := strings.Split(u.RawQuery, "&X-Goog-Signature=")[0]
+	canonicalRequest := "GET\n/bucket/dir/with%20space~.txt\n" + unsignedQuery + "\nhost:storage.googleapis.com\n\nhost\nUNSIGNED-PAYLOAD"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	digest := sha256.Sum256([]byte("GOOG4-RSA-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])))
+	signature, err := hex.DecodeString(query.Get("X-Goog-Signature"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature), chk.IsNil)
+}
+
+func (s *gcpServiceAccountSuite) TestGCPServiceAccountKeyNegative(c *chk.C) {
+	dir, err := ioutil.TempDir("", "gcpkey")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	_, err = LoadGCPServiceAccountKey(filepath.Join(dir, "missing.json"))
+	c.Assert(err, chk.NotNil)
+
+	// only service account keys can be used, not e.g. user credentials
+	path := filepath.Join(dir, "user.json")
+	c.Assert(ioutil.WriteFile(path, []byte(`{"type":"authorized_user","client_id":"id"}`), 0600), chk.IsNil)
+	_, err = LoadGCPServiceAccountKey(path)
+	c.Assert(err, chk.NotNil)
+
+	c.Assert(ioutil.WriteFile(path, []byte(`{"type":"service_account","client_email":"a@b","private_key":"not a key"}`), 0600), chk.IsNil)
+	_, err = LoadGCPServiceAccountKey(path)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *gcpServiceAccountSuite) TestParseRSAPrivateKey(c *chk.C) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, chk.IsNil)
+
+	// PKCS #1 keys are accepted as well as PKCS #8 ones
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	parsed, err := parseRSAPrivateKey(string(pkcs1))
+	c.Assert(err, chk.IsNil)
+	c.Assert(parsed.Equal(privateKey), chk.Equals, true)
+
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	c.Assert(err, chk.IsNil)
+	parsed, err = parseRSAPrivateKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes})))
+	c.Assert(err, chk.IsNil)
+	c.Assert(parsed.Equal(privateKey), chk.Equals, true)
+
+	// but not keys of other kinds
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, chk.IsNil)
+	ecBytes, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	c.Assert(err, chk.IsNil)
+	_, err = parseRSAPrivateKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecBytes})))
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *gcpServiceAccountSuite) TestGetGCPCredentialType(c *chk.C) {
+	names := []string{EEnvironmentVariable.GoogleHMACAccessID().Name, EEnvironmentVariable.GoogleHMACSecret().Name, EEnvironmentVariable.GoogleAppCredentials().Name}
+	for _, name := range names {
+		if old, ok := os.LookupEnv(name); ok {
+			defer os.Setenv(name, old)
+		} else {
+			defer os.Unsetenv(name)
+		}
+		os.Unsetenv(name)
+	}
+
+	_, err := GetGCPCredentialType()
+	c.Assert(err, chk.NotNil)
+
+	os.Setenv(EEnvironmentVariable.GoogleAppCredentials().Name, "key.json")
+	credType, err := GetGCPCredentialType()
+	c.Assert(err, chk.IsNil)
+	c.Assert(credType, chk.Equals, ECredentialType.GCPServiceAccount())
+
+	// an HMAC key wins, since that's what was supported first
+	os.Setenv(EEnvironmentVariable.GoogleHMACAccessID().Name, "id")
+	os.Setenv(EEnvironmentVariable.GoogleHMACSecret().Name, "secret")
+	credType, err = GetGCPCredentialType()
+	c.Assert(err, chk.IsNil)
+	c.Assert(credType, chk.Equals, ECredentialType.GCPHMACKey())
+}
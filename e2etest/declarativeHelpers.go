@@ -395,7 +395,7 @@ func (tft TestFromTo) getValues(op Operation) []common.FromTo {
 
 			// TODO: remove this temp block
 			// temp
-			if fromTo.From() == common.ELocation.S3() ||
+			if fromTo.From() == common.ELocation.S3() || fromTo.From() == common.ELocation.GCP() ||
 				fromTo.From() == common.ELocation.BlobFS() || fromTo.To() == common.ELocation.BlobFS() {
 				continue // until we implement the declarativeResourceManagers
 			}
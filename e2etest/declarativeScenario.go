@@ -138,6 +138,9 @@ func (s *scenario) assignSourceAndDest() {
 		case common.ELocation.S3():
 			s.a.Error("Not implementd yet for S3")
 			return &resourceDummy{}
+		case common.ELocation.GCP():
+			s.a.Error("Not implementd yet for GCP")
+			return &resourceDummy{}
 		case common.ELocation.Unknown():
 			return &resourceDummy{}
 		default:
@@ -332,7 +332,7 @@ func (scenarioHelper) generateBlobsFromList(c asserter, containerURL azblob.Cont
 			reader,
 			headers,
 			ad.toMetadata(),
-			azblob.BlobAccessConditions{})
+			azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil, azblob.ClientProvidedKeyOptions{})
 		c.AssertNoErr(err)
 		c.Assert(cResp.StatusCode(), equals(), 201)
 	}
@@ -386,7 +386,7 @@ func (s scenarioHelper) enumerateContainerBlobProperties(a asserter, containerUR
 
 func (s scenarioHelper) downloadBlobContent(a asserter, containerURL azblob.ContainerURL, resourceRelPath string) []byte {
 	blobURL := containerURL.NewBlobURL(resourceRelPath)
-	downloadResp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	downloadResp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
 	a.AssertNoErr(err)
 
 	retryReader := downloadResp.Body(azblob.RetryReaderOptions{})
@@ -422,6 +422,9 @@ func (scenarioHelper) generatePageBlobsFromList(c asserter, containerURL azblob.
 			},
 			azblob.Metadata{},
 			azblob.BlobAccessConditions{},
+			azblob.DefaultPremiumBlobAccessTier,
+			nil,
+			azblob.ClientProvidedKeyOptions{},
 		)
 		c.AssertNoErr(err)
 		c.Assert(cResp.StatusCode(), equals(), 201)
@@ -432,6 +435,7 @@ func (scenarioHelper) generatePageBlobsFromList(c asserter, containerURL azblob.
 			strings.NewReader(data),
 			azblob.PageBlobAccessConditions{},
 			nil,
+			azblob.ClientProvidedKeyOptions{},
 		)
 		c.AssertNoErr(err)
 		c.Assert(uResp.StatusCode(), equals(), 201)
@@ -451,6 +455,8 @@ func (scenarioHelper) generateAppendBlobsFromList(c asserter, containerURL azblo
 			},
 			azblob.Metadata{},
 			azblob.BlobAccessConditions{},
+			nil,
+			azblob.ClientProvidedKeyOptions{},
 		)
 		c.AssertNoErr(err)
 		c.Assert(cResp.StatusCode(), equals(), 201)
@@ -459,7 +465,7 @@ func (scenarioHelper) generateAppendBlobsFromList(c asserter, containerURL azblo
 		uResp, err := blob.AppendBlock(ctx,
 			strings.NewReader(data),
 			azblob.AppendBlobAccessConditions{},
-			nil)
+			nil, azblob.ClientProvidedKeyOptions{})
 		c.AssertNoErr(err)
 		c.Assert(uResp.StatusCode(), equals(), 201)
 	}
@@ -471,7 +477,7 @@ func (scenarioHelper) generateAppendBlobsFromList(c asserter, containerURL azblo
 func (scenarioHelper) generateBlockBlobWithAccessTier(c asserter, containerURL azblob.ContainerURL, blobName string, accessTier azblob.AccessTierType) {
 	blob := containerURL.NewBlockBlobURL(blobName)
 	cResp, err := blob.Upload(ctx, strings.NewReader(blockBlobDefaultData), azblob.BlobHTTPHeaders{},
-		nil, azblob.BlobAccessConditions{})
+		nil, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil, azblob.ClientProvidedKeyOptions{})
 	c.AssertNoErr(err)
 	c.Assert(cResp.StatusCode(), equals(), 201)
 
@@ -849,7 +855,7 @@ func (scenarioHelper) getRawShareURLWithSAS(c asserter, shareName string) url.UR
 }
 
 func (scenarioHelper) blobExists(blobURL azblob.BlobURL) bool {
-	_, err := blobURL.GetProperties(context.Background(), azblob.BlobAccessConditions{})
+	_, err := blobURL.GetProperties(context.Background(), azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
 	if err == nil {
 		return true
 	}
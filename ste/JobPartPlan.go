@@ -14,12 +14,14 @@ import (
 // dataSchemaVersion defines the data schema version of JobPart order files supported by
 // current version of azcopy
 // To be Incremented every time when we release azcopy with changed dataSchema
-const DataSchemaVersion common.Version = 15
+const DataSchemaVersion common.Version = 19
 
 const (
 	CustomHeaderMaxBytes = 256
 	MetadataMaxBytes     = 1000 // If > 65536, then jobPartPlanBlobData's MetadataLength field's type must change
 	BlobTierMaxBytes     = 10
+	// enough for the most tags the service allows, even if every character of them must be escaped
+	BlobTagsMaxBytes = common.MaxBlobTagCount * (3*(common.MaxBlobTagKeyLength+common.MaxBlobTagValueLength) + 2)
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -74,6 +76,22 @@ type JobPartPlanHeader struct {
 	DestLengthValidation bool
 	// S2SInvalidMetadataHandleOption represents how user wants to handle invalid metadata.
 	S2SInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	// S2SPreserveBlobTags represents whether S2S copies give each destination blob the index tags of its source
+	S2SPreserveBlobTags bool
+	// ClientSideEncrypt represents whether uploads are encrypted, client-side, before they're sent
+	ClientSideEncrypt bool
+	// ClientEncryptionKeyFile is where the client-side encryption key is read from. If it's empty, the key comes from the environment.
+	ClientEncryptionKeyFileLength uint16
+	ClientEncryptionKeyFile       [1000]byte
+	// ClientEncryptionKeyHash identifies the client-side encryption key, so that the job can't be resumed with a different one.
+	// The key itself is never persisted. It's zero if there's no key.
+	ClientEncryptionKeyHash common.ClientEncryptionKeyHash
+	// CpkKeyHash identifies the customer-provided key that Blob Storage encrypts and decrypts with, which, like the client-side one,
+	// is read again from the environment. It's zero if there's no key.
+	CpkKeyHash common.CpkKeyHash
+	// EncryptionScope names the encryption scope that Blob Storage encrypts with, if there is one
+	EncryptionScopeLength uint16
+	EncryptionScope       [common.MaxEncryptionScopeLength]byte
 
 	// Any fields below this comment are NOT constants; they may change over as the job part is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -162,7 +180,7 @@ func (jpph *JobPartPlanHeader) getString(offset int64, length int16) string {
 // TransferSrcPropertiesAndMetadata returns the SrcHTTPHeaders, properties and metadata for a transfer at given transferIndex in JobPartOrder
 // TODO: Refactor return type to an object
 func (jpph *JobPartPlanHeader) TransferSrcPropertiesAndMetadata(transferIndex uint32) (h common.ResourceHTTPHeaders, metadata common.Metadata, blobType azblob.BlobType, blobTier azblob.AccessTierType,
-	s2sGetPropertiesInBackend bool, DestLengthValidation bool, s2sSourceChangeValidation bool, s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption, entityType common.EntityType, blobVersionID string) {
+	s2sGetPropertiesInBackend bool, DestLengthValidation bool, s2sSourceChangeValidation bool, s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption, entityType common.EntityType, blobVersionID string, blobTags common.BlobTags) {
 	var err error
 	t := jpph.Transfer(transferIndex)
 
@@ -219,6 +237,11 @@ func (jpph *JobPartPlanHeader) TransferSrcPropertiesAndMetadata(transferIndex ui
 		blobVersionID = jpph.getString(offset, t.SrcBlobVersionIDLength)
 		offset += int64(t.SrcBlobVersionIDLength)
 	}
+	if t.SrcBlobTagsLength != 0 {
+		blobTags, err = common.ToCommonBlobTags(jpph.getString(offset, t.SrcBlobTagsLength))
+		common.PanicIfErr(err)
+		offset += int64(t.SrcBlobTagsLength)
+	}
 	return
 }
 
@@ -269,9 +292,16 @@ type JobPartPlanDstBlob struct {
 	// Controls uploading of MD5 hashes
 	PutMd5 bool
 
+	// Specifies how files are compressed as they're uploaded, if at all
+	Compression common.CompressionType
+
 	MetadataLength uint16
 	Metadata       [MetadataMaxBytes]byte
 
+	// Specifies the index tags of the blobs, in the form of common.BlobTags.ToString
+	BlobTagsLength uint16
+	BlobTags       [BlobTagsMaxBytes]byte
+
 	// Specifies the maximum size of block which determines the number of chunks and chunk size of a transfer
 	BlockSize int64
 }
@@ -325,6 +355,7 @@ type JobPartPlanTransfer struct {
 	SrcBlobTypeLength           int16
 	SrcBlobTierLength           int16
 	SrcBlobVersionIDLength      int16
+	SrcBlobTagsLength           int16
 
 	// Any fields below this comment are NOT constants; they may change over as the transfer is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -101,6 +101,15 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	if len(order.BlobAttributes.Metadata) > len(JobPartPlanDstBlob{}.Metadata) {
 		panic(fmt.Errorf("metadata string is too large: %q", order.BlobAttributes.Metadata))
 	}
+	if len(order.BlobAttributes.BlobTagsString) > len(JobPartPlanDstBlob{}.BlobTags) {
+		panic(fmt.Errorf("blob tags string is too large: %q", order.BlobAttributes.BlobTagsString))
+	}
+	if len(order.ClientEncryptionKeyFile) > len(JobPartPlanHeader{}.ClientEncryptionKeyFile) {
+		panic(fmt.Errorf("client-side encryption key file path is too long: %q", order.ClientEncryptionKeyFile))
+	}
+	if len(order.EncryptionScope) > len(JobPartPlanHeader{}.EncryptionScope) {
+		panic(fmt.Errorf("encryption scope is too long: %q", order.EncryptionScope))
+	}
 
 	// This nested function writes a structure value to an io.Writer & returns the number of bytes written
 	writeValue := func(writer io.Writer, v interface{}) int64 {
@@ -181,9 +190,11 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			ContentLanguageLength:    uint16(len(order.BlobAttributes.ContentLanguage)),
 			CacheControlLength:       uint16(len(order.BlobAttributes.CacheControl)),
 			PutMd5:                   order.BlobAttributes.PutMd5, // here because it relates to uploads (blob destination)
+			Compression:              order.BlobAttributes.Compression,
 			BlockBlobTier:            order.BlobAttributes.BlockBlobTier,
 			PageBlobTier:             order.BlobAttributes.PageBlobTier,
 			MetadataLength:           uint16(len(order.BlobAttributes.Metadata)),
+			BlobTagsLength:           uint16(len(order.BlobAttributes.BlobTagsString)),
 			BlockSize:                blockSize,
 		},
 		DstLocalData: JobPartPlanDstLocal{
@@ -196,7 +207,13 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		S2SGetPropertiesInBackend:      order.S2SGetPropertiesInBackend,
 		S2SSourceChangeValidation:      order.S2SSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: order.S2SInvalidMetadataHandleOption,
+		S2SPreserveBlobTags:            order.S2SPreserveBlobTags,
 		DestLengthValidation:           order.DestLengthValidation,
+		ClientSideEncrypt:              order.ClientSideEncrypt,
+		ClientEncryptionKeyFileLength:  uint16(len(order.ClientEncryptionKeyFile)),
+		ClientEncryptionKeyHash:        order.ClientEncryptionKeyHash,
+		CpkKeyHash:                     order.CpkKeyHash,
+		EncryptionScopeLength:          uint16(len(order.EncryptionScope)),
 		atomicJobStatus:                common.EJobStatus.InProgress(), // We default to InProgress
 		DeleteSnapshotsOption:          order.BlobAttributes.DeleteSnapshotsOption,
 	}
@@ -213,6 +230,9 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	copy(jpph.DstBlobData.ContentDisposition[:], order.BlobAttributes.ContentDisposition)
 	copy(jpph.DstBlobData.CacheControl[:], order.BlobAttributes.CacheControl)
 	copy(jpph.DstBlobData.Metadata[:], order.BlobAttributes.Metadata)
+	copy(jpph.DstBlobData.BlobTags[:], order.BlobAttributes.BlobTagsString)
+	copy(jpph.ClientEncryptionKeyFile[:], order.ClientEncryptionKeyFile)
+	copy(jpph.EncryptionScope[:], order.EncryptionScope)
 
 	eof += writeValue(file, &jpph)
 
@@ -269,6 +289,7 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			SrcBlobTypeLength:           int16(len(order.Transfers[t].BlobType)),
 			SrcBlobTierLength:           int16(len(order.Transfers[t].BlobTier)),
 			SrcBlobVersionIDLength:      int16(len(order.Transfers[t].BlobVersionID)),
+			SrcBlobTagsLength:           int16(len(order.Transfers[t].BlobTags.ToString())),
 
 			atomicTransferStatus: common.ETransferStatus.Started(), // Default
 			//ChunkNum:                getNumChunks(uint64(order.Transfers[t].SourceSize), uint64(data.BlockSize)),
@@ -281,7 +302,7 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		currentSrcStringOffset += int64(jppt.SrcLength + jppt.DstLength + jppt.SrcContentTypeLength +
 			jppt.SrcContentEncodingLength + jppt.SrcContentLanguageLength + jppt.SrcContentDispositionLength +
 			jppt.SrcCacheControlLength + jppt.SrcContentMD5Length + jppt.SrcMetadataLength +
-			jppt.SrcBlobTypeLength + jppt.SrcBlobTierLength + jppt.SrcBlobVersionIDLength)
+			jppt.SrcBlobTypeLength + jppt.SrcBlobTierLength + jppt.SrcBlobVersionIDLength + jppt.SrcBlobTagsLength)
 	}
 
 	// All the transfers were written; now write each transfer's src/dst strings
@@ -355,6 +376,11 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
+		if len(order.Transfers[t].BlobTags) != 0 {
+			bytesWritten, err = file.WriteString(order.Transfers[t].BlobTags.ToString())
+			common.PanicIfErr(err)
+			eof += int64(bytesWritten)
+		}
 	}
 	// the file is closed to due to defer above
 }
@@ -103,6 +103,10 @@ var JobsAdmin interface {
 	LogToJobLog(msg string, level pipeline.LogLevel)
 
 	//DeleteJob(jobID common.JobID)
+
+	// CleanUpJob forgets a job that's done, and deletes its plan files, so that a process that runs one job after another
+	// doesn't pile them up. The job's log is closed, but kept.
+	CleanUpJob(jobID common.JobID)
 	common.ILoggerCloser
 
 	CurrentMainPoolSize() int
@@ -686,38 +690,25 @@ func (ja *jobsAdmin) DeleteJob(jobID common.JobID) {
 	ja.jobIDToJobMgr.Delete(jobID)
 }
 
-// cleanUpJob api unmaps all the memory map JobPartFile and deletes the JobPartFile
-/*
-	* Load PartMap for given JobId
-    * Iterate through each part order of given Job and then shutdowns the JobInfo handler
-    * Iterate through each part order of given Job and then shutdowns the JobInfo handler
-	* Delete all the job part files stored on disk
-    * Closes the logger file opened for logging logs related to given job
-	* Removes the entry of given JobId from JobsInfo
-*/
-
-// TODO: take care fo this.
-/*func (ja *jobsAdmin) cleanUpJob(jobID common.JobID) {
+// CleanUpJob unmaps and deletes the plan files of a job that's done, closes its log, and forgets it
+func (ja *jobsAdmin) CleanUpJob(jobID common.JobID) {
 	jm, found := ja.JobMgr(jobID)
 	if !found {
-		ja.Panic(fmt.Errorf("no job found with JobID %v to clean up", jobID))
-	}
-	for p := PartNumber(0); true; p++ {
-		jpm, found := jm.JobPartMgr(p)
-		if !found { // TODO
-		}
-		// TODO: Fix jpm.planMMF.Unmap()	// unmapping the memory map JobPart file
-		err := jpm.filename.Delete()
-		if err != nil {
-			ja.Panic(fmt.Errorf("error removing the job part file %s. Failed with following error %s", jpm.filename, err))
-		}
-		//TODO: jobHandler.shutDownHandler(ji.logger)
+		return
 	}
-	ji.closeLogForJob()
-	// deletes the entry for given JobId from Map
 	ja.DeleteJob(jobID)
+
+	jm.(*jobMgr).jobPartMgrs.Iterate(false, func(partNum common.PartNumber, jpm IJobPartMgr) {
+		jpm.Close()
+		planFilePath := jpm.(*jobPartMgr).filename.GetJobPartPlanPath()
+		if err := os.Remove(planFilePath); err != nil && !os.IsNotExist(err) {
+			ja.Log(pipeline.LogWarning, fmt.Sprintf("failed to delete the plan file %s of job %s: %v", planFilePath, jobID, err))
+		}
+	})
+	jm.HttpClient().CloseIdleConnections()
+	jm.CloseLog()
 }
-*/
+
 func (ja *jobsAdmin) ShouldLog(level pipeline.LogLevel) bool  { return ja.logger.ShouldLog(level) }
 func (ja *jobsAdmin) Log(level pipeline.LogLevel, msg string) { ja.logger.Log(level, msg) }
 func (ja *jobsAdmin) Panic(err error)                         { ja.logger.Panic(err) }
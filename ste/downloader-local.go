@@ -0,0 +1,102 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// localDownloader "downloads" from one local file system to another (i.e. LocalLocal).
+// It reads chunks straight from the source file, and hands them to the usual ChunkedFileWriter,
+// so everything from there on (overwrite handling, MD5 of the file as written, resume etc.) is the same as for a download.
+type localDownloader struct {
+	jptm    IJobPartTransferMgr
+	sip     ILocalSourceInfoProvider
+	srcFile common.CloseableReaderAt
+
+	// openErr records any failure to open the source in the prologue,
+	// so that the chunk funcs can fail the transfer with a meaningful message.
+	openErr error
+}
+
+func newLocalDownloader() downloader {
+	return &localDownloader{}
+}
+
+func (ld *localDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	ld.jptm = jptm
+
+	sip, err := newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		ld.openErr = err
+		return
+	}
+	ld.sip = sip.(ILocalSourceInfoProvider)
+
+	// use the source info provider to open the file, so that we get the same (e.g. backup mode) semantics as for uploads
+	ld.srcFile, ld.openErr = ld.sip.OpenSourceFile()
+}
+
+func (ld *localDownloader) Epilogue() {
+	if ld.srcFile != nil {
+		_ = ld.srcFile.Close()
+	}
+
+	// As for uploads, check the source to see if it was changed during transfer. If it was, mark the transfer as failed.
+	if ld.jptm != nil && ld.sip != nil && ld.jptm.IsLive() {
+		lmt, err := ld.sip.GetFreshFileLastModifiedTime()
+		if err != nil {
+			ld.jptm.FailActiveDownload("Checking source last modified time", err)
+		} else if !lmt.Equal(ld.jptm.LastModifiedTime()) {
+			ld.jptm.FailActiveDownload("Checking source last modified time", errors.New("source modified during transfer"))
+		}
+	}
+}
+
+// Returns a chunk-func for local file copies
+func (ld *localDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if ld.openErr != nil {
+			jptm.FailActiveDownload("Opening source file", ld.openErr)
+			return
+		}
+
+		// Enqueue the section of the source file to be written out to disk.
+		// No pacer here, since that's for network bandwidth, and no retries, since there's no network read to retry.
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		body := io.NewSectionReader(ld.srcFile, id.OffsetInFile(), length)
+		err := destWriter.EnqueueChunk(jptm.Context(), id, length, body, false)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
+
+func (ld *localDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
+	// no-op (we don't currently preserve any properties of local folders)
+	return nil
+}
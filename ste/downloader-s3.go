@@ -0,0 +1,86 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	minio "github.com/minio/minio-go"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type s3Downloader struct {
+	s3Client  *minio.Client
+	s3URLPart common.S3URLParts
+
+	// clientErr records any failure to create the S3 client in the prologue,
+	// so that the chunk funcs can fail the transfer with a meaningful message.
+	clientErr error
+}
+
+func newS3Downloader() downloader {
+	return &s3Downloader{}
+}
+
+func (sd *s3Downloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	sd.s3Client, sd.s3URLPart, sd.clientErr = newS3ClientForURL(jptm, jptm.Info().Source)
+}
+
+func (sd *s3Downloader) Epilogue() {
+}
+
+// Returns a chunk-func for S3 object downloads
+func (sd *s3Downloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if sd.clientErr != nil {
+			jptm.FailActiveDownload("Creating S3 client", sd.clientErr)
+			return
+		}
+
+		// set the range, and protect against inconsistencies from changes-while-being-read
+		opts := minio.GetObjectOptions{}
+		if err := opts.SetRange(id.OffsetInFile(), id.OffsetInFile()+length-1); err != nil {
+			jptm.FailActiveDownload("Setting range", err)
+			return
+		}
+		if lmt := jptm.LastModifiedTime(); !lmt.IsZero() {
+			_ = opts.SetUnmodified(lmt)
+		}
+
+		// download object from start Index till startIndex + adjustedChunkSize
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		core := minio.Core{Client: sd.s3Client}
+		body, _, err := core.GetObject(sd.s3URLPart.BucketName, sd.s3URLPart.ObjectKey, opts)
+		if err != nil {
+			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
+			return
+		}
+		defer body.Close()
+
+		// Enqueue the response body to be written out to disk
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), true)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
@@ -293,7 +293,9 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		switch jpm.Plan().FromTo {
 		case common.EFromTo.LocalBlob(),
 			common.EFromTo.LocalFile(),
-			common.EFromTo.S3Blob():
+			common.EFromTo.S3Blob(),
+			common.EFromTo.GCPBlob(),
+			common.EFromTo.HttpBlob():
 			if len(req.DestinationSAS) == 0 {
 				errorMsg = "The destination-sas switch must be provided to resume the job"
 			}
@@ -305,7 +307,10 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 				errorMsg = "The source-sas switch must be provided to resume the job"
 			}
 		case common.EFromTo.BlobBlob(),
-			common.EFromTo.FileBlob():
+			common.EFromTo.FileBlob(),
+			common.EFromTo.BlobFSBlob(),
+			common.EFromTo.BlobFSBlobFS(),
+			common.EFromTo.BlobBlobFS():
 			if len(req.SourceSAS) == 0 ||
 				len(req.DestinationSAS) == 0 {
 				errorMsg = "Both the source-sas and destination-sas switches must be provided to resume the job"
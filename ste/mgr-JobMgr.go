@@ -405,7 +405,7 @@ func (jm *jobMgr) setDirection(fromTo common.FromTo) {
 	if fromTo.IsUpload() {
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Upload())
 	}
-	if fromTo.IsDownload() {
+	if fromTo.IsDownload() || fromTo == common.EFromTo.LocalLocal() { // a local copy writes to disk just as a download does
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Download())
 		JobsAdmin.RequestTuneSlowly()
 	}
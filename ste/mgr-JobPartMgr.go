@@ -2,6 +2,7 @@ package ste
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -30,11 +32,16 @@ type IJobPartMgr interface {
 	GetOverwriteOption() common.OverwriteOption
 	GetForceIfReadOnly() bool
 	AutoDecompress() bool
+	ShouldClientEncrypt() bool
+	ClientEncryptionKey() (*common.ClientEncryptionKey, error)
+	CpkOptions() (common.CpkOptions, error)
 	ScheduleChunks(chunkFunc chunkFunc)
 	RescheduleTransfer(jptm IJobPartTransferMgr)
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
 	ShouldPutMd5() bool
+	CompressOnUpload() common.CompressionType
+	BlobTags() common.BlobTags
 	SAS() (string, string)
 	//CancelJob()
 	Close()
@@ -251,12 +258,28 @@ type jobPartMgr struct {
 	// Additional data shared by all of this Job Part's transfers; initialized when this jobPartMgr is created
 	putMd5 bool
 
+	// how the files of uploads are compressed as they're sent, a chunk at a time; None unless --compress was given
+	compression common.CompressionType
+
 	metadata common.Metadata
 
+	// the index tags given for all the blobs of this job part, which replace those of their sources
+	blobTags common.BlobTags
+
 	blobTypeOverride common.BlobType // User specified blob type
 
 	preserveLastModifiedTime bool
 
+	// the client-side encryption key is loaded, once, by the first transfer that needs it
+	clientEncryptionKeyOnce sync.Once
+	clientEncryptionKey     *common.ClientEncryptionKey
+	clientEncryptionKeyErr  error
+
+	// likewise the customer-provided key, which goes into the CPK options
+	cpkOptionsOnce sync.Once
+	cpkOptions     common.CpkOptions
+	cpkOptionsErr  error
+
 	newJobXfer newJobXfer // Method used to start the transfer
 
 	priority common.JobPriority
@@ -329,6 +352,7 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 	}
 
 	jpm.putMd5 = dstData.PutMd5
+	jpm.compression = dstData.Compression
 	jpm.blockBlobTier = dstData.BlockBlobTier
 	jpm.pageBlobTier = dstData.PageBlobTier
 
@@ -342,6 +366,10 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 		}
 	}
 
+	blobTags, err := common.ToCommonBlobTags(string(dstData.BlobTags[:dstData.BlobTagsLength]))
+	common.PanicIfErr(err) // they were validated before the job was created
+	jpm.blobTags = blobTags
+
 	jpm.preserveLastModifiedTime = plan.DstLocalData.PreserveLastModifiedTime
 
 	jpm.blobTypeOverride = plan.DstBlobData.BlobType
@@ -438,7 +466,9 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 	var statsAccForSip *pipelineNetworkStats = nil // we don't accumulate stats on the source info provider
 
 	// Create source info provider's pipeline for S2S copy.
-	if fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFile() {
+	// Plain HTTP sources have no credential of their own, so the anonymous blob pipeline serves them just as well.
+	if fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFile() || fromTo == common.EFromTo.BlobS3() || fromTo == common.EFromTo.HttpBlob() ||
+		fromTo == common.EFromTo.BlobBlobFS() {
 		jpm.sourceProviderPipeline = NewBlobPipeline(
 			azblob.NewAnonymousCredential(),
 			azblob.PipelineOptions{
@@ -452,6 +482,21 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.jobMgr.HttpClient(),
 			statsAccForSip)
 	}
+	// The source of an ADLS Gen2 S2S copy is addressed by SAS, like the blob ones above, but through the DFS endpoint.
+	if fromTo == common.EFromTo.BlobFSBlob() || fromTo == common.EFromTo.BlobFSBlobFS() {
+		jpm.sourceProviderPipeline = NewBlobFSPipeline(
+			azbfs.NewAnonymousCredential(),
+			azbfs.PipelineOptions{
+				Log: jpm.jobMgr.PipelineLogInfo(),
+				Telemetry: azbfs.TelemetryOptions{
+					Value: userAgent,
+				},
+			},
+			xferRetryOption,
+			jpm.pacer,
+			jpm.jobMgr.HttpClient(),
+			statsAccForSip)
+	}
 	// Consider the file-local SDDL transfer case.
 	if fromTo == common.EFromTo.FileBlob() || fromTo == common.EFromTo.FileFile() || fromTo == common.EFromTo.FileLocal() {
 		jpm.sourceProviderPipeline = NewFilePipeline(
@@ -477,7 +522,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 	// Create pipeline for data transfer.
 	switch fromTo {
 	case common.EFromTo.BlobTrash(), common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BenchmarkBlob(),
-		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob():
+		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob(), common.EFromTo.GCPBlob(), common.EFromTo.HttpBlob(),
+		common.EFromTo.BlobFSBlob():
 		credential := common.CreateBlobCredential(ctx, credInfo, credOption)
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 		jpm.pipeline = NewBlobPipeline(
@@ -493,7 +539,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.jobMgr.HttpClient(),
 			jpm.jobMgr.PipelineNetworkStats())
 	// Create pipeline for Azure BlobFS.
-	case common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.BenchmarkBlobFS():
+	case common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.BenchmarkBlobFS(), common.EFromTo.BlobFSBlobFS(),
+		common.EFromTo.BlobBlobFS():
 		credential := common.CreateBlobFSCredential(ctx, credInfo, credOption)
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 
@@ -530,6 +577,12 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			jpm.jobMgr.PipelineNetworkStats())
+	case common.EFromTo.S3Local(), common.EFromTo.LocalS3(), common.EFromTo.BlobS3():
+		// S3 objects are read and written via the S3 client rather than an Azure Storage pipeline,
+		// and the other end is either local, or (for S2S) read through the source provider pipeline,
+		// so there is no pipeline to create.
+	case common.EFromTo.LocalLocal():
+		// nothing remote, so there is no pipeline to create
 	default:
 		panic(fmt.Errorf("Unrecognized from-to: %q", fromTo.String()))
 	}
@@ -567,6 +620,59 @@ func (jpm *jobPartMgr) AutoDecompress() bool {
 	return jpm.Plan().AutoDecompress
 }
 
+func (jpm *jobPartMgr) ShouldClientEncrypt() bool {
+	return jpm.Plan().ClientSideEncrypt
+}
+
+// ClientEncryptionKey returns the key given when the job was created, or nil if none was given.
+// Since the plan only keeps a hash of the key, the key is read again from its file or the environment.
+func (jpm *jobPartMgr) ClientEncryptionKey() (*common.ClientEncryptionKey, error) {
+	jpm.clientEncryptionKeyOnce.Do(func() {
+		plan := jpm.Plan()
+		if plan.ClientEncryptionKeyHash.IsZero() {
+			return
+		}
+
+		key, err := common.LoadClientEncryptionKey(string(plan.ClientEncryptionKeyFile[:plan.ClientEncryptionKeyFileLength]))
+		switch {
+		case err != nil:
+			jpm.clientEncryptionKeyErr = err
+		case key == nil:
+			jpm.clientEncryptionKeyErr = errors.New("the job needs a client-side encryption key, but none was found")
+		case key.Hash() != plan.ClientEncryptionKeyHash:
+			jpm.clientEncryptionKeyErr = errors.New("the client-side encryption key is not the one the job was created with")
+		default:
+			jpm.clientEncryptionKey = key
+		}
+	})
+	return jpm.clientEncryptionKey, jpm.clientEncryptionKeyErr
+}
+
+// CpkOptions returns the customer-provided key or encryption scope that the job was created with, if any.
+// Since the plan only keeps a hash of the key, the key is read again from the environment.
+func (jpm *jobPartMgr) CpkOptions() (common.CpkOptions, error) {
+	jpm.cpkOptionsOnce.Do(func() {
+		plan := jpm.Plan()
+		jpm.cpkOptions.EncryptionScope = string(plan.EncryptionScope[:plan.EncryptionScopeLength])
+		if plan.CpkKeyHash.IsZero() {
+			return
+		}
+
+		key, err := common.LoadCpkKey()
+		switch {
+		case err != nil:
+			jpm.cpkOptionsErr = err
+		case key == nil:
+			jpm.cpkOptionsErr = fmt.Errorf("the job needs a customer-provided key, but %s isn't set", common.EEnvironmentVariable.CpkEncryptionKey().Name)
+		case key.Hash() != plan.CpkKeyHash:
+			jpm.cpkOptionsErr = errors.New("the customer-provided key is not the one the job was created with")
+		default:
+			jpm.cpkOptions.Key = key
+		}
+	})
+	return jpm.cpkOptions, jpm.cpkOptionsErr
+}
+
 func (jpm *jobPartMgr) resourceDstData(fullFilePath string, dataFileToXfer []byte) (headers common.ResourceHTTPHeaders, metadata common.Metadata) {
 	if jpm.planMMF.Plan().DstBlobData.NoGuessMimeType {
 		return jpm.httpHeaders, jpm.metadata
@@ -632,6 +738,14 @@ func (jpm *jobPartMgr) ShouldPutMd5() bool {
 	return jpm.putMd5
 }
 
+func (jpm *jobPartMgr) CompressOnUpload() common.CompressionType {
+	return jpm.compression
+}
+
+func (jpm *jobPartMgr) BlobTags() common.BlobTags {
+	return jpm.blobTags
+}
+
 func (jpm *jobPartMgr) SAS() (string, string) {
 	return jpm.sourceSAS, jpm.destinationSAS
 }
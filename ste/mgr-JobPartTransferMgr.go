@@ -24,6 +24,7 @@ type IJobPartTransferMgr interface {
 	LastModifiedTime() time.Time
 	PreserveLastModifiedTime() (time.Time, bool)
 	ShouldPutMd5() bool
+	CompressOnUpload() common.CompressionType
 	MD5ValidationOption() common.HashValidationOption
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
@@ -39,6 +40,9 @@ type IJobPartTransferMgr interface {
 	GetOverwriteOption() common.OverwriteOption
 	GetForceIfReadOnly() bool
 	ShouldDecompress() bool
+	ShouldClientEncrypt() bool
+	ClientEncryptionKey() (*common.ClientEncryptionKey, error)
+	CpkInfo() (azblob.ClientProvidedKeyOptions, error)
 	GetSourceCompressionType() (common.CompressionType, error)
 	ReportChunkDone(id common.ChunkID) (lastChunk bool, chunksDone uint32)
 	TransferStatusIgnoringCancellation() common.TransferStatus
@@ -103,6 +107,7 @@ type TransferInfo struct {
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
 	S2SInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	S2SPreserveBlobTags            bool
 
 	// Blob
 	SrcBlobType    azblob.BlobType       // used for both S2S and for downloads to local from blob
@@ -145,6 +150,7 @@ func (i TransferInfo) entityTypeLogIndicator() string {
 type SrcProperties struct {
 	SrcHTTPHeaders common.ResourceHTTPHeaders // User for S2S copy, where per transfer's src properties need be set in destination.
 	SrcMetadata    common.Metadata
+	SrcBlobTags    common.BlobTags
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -225,6 +231,23 @@ func (jptm *jobPartTransferMgr) ShouldDecompress() bool {
 	return false
 }
 
+func (jptm *jobPartTransferMgr) ShouldClientEncrypt() bool {
+	return jptm.jobPartMgr.ShouldClientEncrypt()
+}
+
+func (jptm *jobPartTransferMgr) ClientEncryptionKey() (*common.ClientEncryptionKey, error) {
+	return jptm.jobPartMgr.ClientEncryptionKey()
+}
+
+// CpkInfo returns the customer-provided key or encryption scope, as azblob sends them
+func (jptm *jobPartTransferMgr) CpkInfo() (azblob.ClientProvidedKeyOptions, error) {
+	options, err := jptm.jobPartMgr.CpkOptions()
+	if err != nil {
+		return azblob.ClientProvidedKeyOptions{}, err
+	}
+	return options.ToClientProvidedKeyOptions(), nil
+}
+
 func (jptm *jobPartTransferMgr) GetSourceCompressionType() (common.CompressionType, error) {
 	encoding := jptm.Info().SrcHTTPHeaders.ContentEncoding
 	return common.GetCompressionType(encoding)
@@ -239,7 +262,7 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 	src, dst, _ := plan.TransferSrcDstStrings(jptm.transferIndex)
 	dstBlobData := plan.DstBlobData
 
-	srcHTTPHeaders, srcMetadata, srcBlobType, srcBlobTier, s2sGetPropertiesInBackend, DestLengthValidation, s2sSourceChangeValidation, s2sInvalidMetadataHandleOption, entityType, versionID :=
+	srcHTTPHeaders, srcMetadata, srcBlobType, srcBlobTier, s2sGetPropertiesInBackend, DestLengthValidation, s2sSourceChangeValidation, s2sInvalidMetadataHandleOption, entityType, versionID, srcBlobTags :=
 		plan.TransferSrcPropertiesAndMetadata(jptm.transferIndex)
 	srcSAS, dstSAS := jptm.jobPartMgr.SAS()
 	// If the length of destination SAS is greater than 0
@@ -292,21 +315,27 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		src = sURL.String()
 	}
 
+	// tags given for the whole job replace those of the source
+	if jobBlobTags := jptm.jobPartMgr.BlobTags(); len(jobBlobTags) != 0 {
+		srcBlobTags = jobBlobTags
+	}
+
 	sourceSize := plan.Transfer(jptm.transferIndex).SourceSize
 	var blockSize = dstBlobData.BlockSize
 	// If the blockSize is 0, then User didn't provide any blockSize
 	// We need to set the blockSize in such way that number of blocks per blob
-	// does not exceeds 50000 (max number of block per blob)
+	// does not exceeds 50000 (max number of block per blob), or 10000 (max number of parts per S3 object)
 	if blockSize == 0 {
+		maxNumberOfBlocks := common.Iffuint32(plan.FromTo.To() == common.ELocation.S3(), common.S3MaxNumberOfParts, common.MaxNumberOfBlocksPerBlob)
 		blockSize = common.DefaultBlockBlobBlockSize
-		for ; uint32(sourceSize/blockSize) > common.MaxNumberOfBlocksPerBlob; blockSize = 2 * blockSize {
+		for ; uint32(sourceSize/blockSize) > maxNumberOfBlocks; blockSize = 2 * blockSize {
 			if blockSize > common.BlockSizeThreshold {
 				/*
 				 * For a RAM usage of 0.5G/core, we would have 4G memory on typical 8 core device, meaning at a blockSize of 256M,
 				 * we can have 4 blocks in core, waiting for a disk or n/w operation. Any higher block size would *sort of*
 				 * serialize n/w and disk operations, and is better avoided.
 				 */
-				blockSize = sourceSize / common.MaxNumberOfBlocksPerBlob
+				blockSize = sourceSize / int64(maxNumberOfBlocks)
 				break
 			}
 		}
@@ -324,10 +353,12 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		S2SGetPropertiesInBackend:      s2sGetPropertiesInBackend,
 		S2SSourceChangeValidation:      s2sSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: s2sInvalidMetadataHandleOption,
+		S2SPreserveBlobTags:            plan.S2SPreserveBlobTags,
 		DestLengthValidation:           DestLengthValidation,
 		SrcProperties: SrcProperties{
 			SrcHTTPHeaders: srcHTTPHeaders,
 			SrcMetadata:    srcMetadata,
+			SrcBlobTags:    srcBlobTags,
 		},
 		SrcBlobType:    srcBlobType,
 		S2SSrcBlobTier: srcBlobTier,
@@ -416,8 +447,9 @@ func (jptm *jobPartTransferMgr) HoldsDestinationLock() bool {
 }
 
 func (jptm *jobPartTransferMgr) useFileCountLimiter() bool {
-	ft := jptm.FromTo()    // TODO: consider changing isDownload (and co) to have struct receiver instead of pointer receiver, so don't need variable like this
-	return ft.IsDownload() // count-based limits are only applied for download a present
+	ft := jptm.FromTo() // TODO: consider changing isDownload (and co) to have struct receiver instead of pointer receiver, so don't need variable like this
+	// count-based limits are only applied for download (and local copies, which write files the same way) at present
+	return ft.IsDownload() || ft == common.EFromTo.LocalLocal()
 }
 
 func (jptm *jobPartTransferMgr) RescheduleTransfer() {
@@ -451,6 +483,10 @@ func (jptm *jobPartTransferMgr) ShouldPutMd5() bool {
 	return jptm.jobPartMgr.ShouldPutMd5()
 }
 
+func (jptm *jobPartTransferMgr) CompressOnUpload() common.CompressionType {
+	return jptm.jobPartMgr.CompressOnUpload()
+}
+
 func (jptm *jobPartTransferMgr) MD5ValidationOption() common.HashValidationOption {
 	return jptm.jobPartMgr.(*jobPartMgr).localDstData().MD5VerificationOption
 }
@@ -44,6 +44,9 @@ type appendBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
+	blobTagsToApply azblob.BlobTagsMap
+	// the customer-provided key or encryption scope, if any, that the service encrypts the destination with
+	cpkToApply azblob.ClientProvidedKeyOptions
 
 	soleChunkFuncSemaphore *semaphore.Weighted
 }
@@ -77,6 +80,11 @@ func newAppendBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pip
 		return nil, err
 	}
 
+	cpk, err := jptm.CpkInfo()
+	if err != nil {
+		return nil, err
+	}
+
 	return &appendBlobSenderBase{
 		jptm:                   jptm,
 		destAppendBlobURL:      destAppendBlobURL,
@@ -85,6 +93,8 @@ func newAppendBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pip
 		pacer:                  pacer,
 		headersToApply:         props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
 		metadataToApply:        props.SrcMetadata.ToAzBlobMetadata(),
+		blobTagsToApply:        props.SrcBlobTags.ToAzBlobTagsMap(),
+		cpkToApply:             cpk,
 		soleChunkFuncSemaphore: semaphore.NewWeighted(1)}, nil
 }
 
@@ -101,7 +111,7 @@ func (s *appendBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *appendBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destAppendBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}))
+	return remoteObjectExists(s.destAppendBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, s.cpkToApply))
 }
 
 // Returns a chunk-func for sending append blob to remote
@@ -139,7 +149,7 @@ func (s *appendBlobSenderBase) Prologue(ps common.PrologueState) (destinationMod
 	s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
 
 	destinationModified = true
-	_, err := s.destAppendBlobURL.Create(s.jptm.Context(), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{})
+	_, err := s.destAppendBlobURL.Create(s.jptm.Context(), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}, s.blobTagsToApply, s.cpkToApply)
 	if err != nil {
 		s.jptm.FailActiveSend("Creating blob", err)
 		return
@@ -23,6 +23,7 @@ package ste
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/url"
 	"time"
 
@@ -145,19 +146,19 @@ func (u *blobFSSenderBase) Prologue(state common.PrologueState) (destinationModi
 	// folder-aware sources).
 	parentDir, err := u.fileURL().GetParentDir()
 	if err != nil {
-		u.jptm.FailActiveUpload("Getting parent directory URL", err)
+		u.jptm.FailActiveSend("Getting parent directory URL", err)
 		return
 	}
 	err = u.doEnsureDirExists(parentDir)
 	if err != nil {
-		u.jptm.FailActiveUpload("Ensuring parent directory exists", err)
+		u.jptm.FailActiveSend("Ensuring parent directory exists", err)
 		return
 	}
 
 	// Create file with the source size
 	_, err = u.fileURL().Create(u.jptm.Context(), *u.creationTimeHeaders) // "create" actually calls "create path", so if we didn't need to track folder creation, we could just let this call create the folder as needed
 	if err != nil {
-		u.jptm.FailActiveUpload("Creating file", err)
+		u.jptm.FailActiveSend("Creating file", err)
 		return
 	}
 	return
@@ -180,6 +181,26 @@ func (u *blobFSSenderBase) Cleanup() {
 	}
 }
 
+// flush commits the appended data, setting the given MD5 (if any) as the MD5 of the whole file
+func (u *blobFSSenderBase) flush(md5Hash []byte) {
+	jptm := u.jptm
+	ss := jptm.Info().SourceSize
+
+	// Flush incrementally to avoid timeouts on a full flush
+	for i := int64(math.Min(float64(ss), float64(u.flushThreshold))); ; i = int64(math.Min(float64(ss), float64(i+u.flushThreshold))) {
+		// Close only at the end of the file, keep all uncommitted data before then.
+		_, err := u.fileURL().FlushData(jptm.Context(), i, md5Hash, *u.creationTimeHeaders, i != ss, i == ss)
+		if err != nil {
+			jptm.FailActiveSend("Flushing data", err)
+			return // the caller's cleanup will deal with the partially-written file
+		}
+
+		if i == ss {
+			return
+		}
+	}
+}
+
 func (u *blobFSSenderBase) GetDestinationLength() (int64, error) {
 	prop, err := u.fileURL().GetProperties(u.jptm.Context())
 
@@ -0,0 +1,148 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// urlToBlobFSCopier copies from ADLS Gen2 or Blob storage to ADLS Gen2. There's no "append data from URL" in the DFS API,
+// so each chunk is read from the source and then appended to the destination.
+type urlToBlobFSCopier struct {
+	blobFSSenderBase
+
+	readSourceRange func(ctx context.Context, offset int64, count int64) (io.ReadCloser, error)
+	srcMD5          []byte
+}
+
+func newURLToBlobFSCopier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	srcInfoProvider := sip.(IRemoteSourceInfoProvider) // "downcast" to the type we know it really has
+
+	senderBase, err := newBlobFSSenderBase(jptm, destination, p, pacer, sip)
+	if err != nil {
+		return nil, err
+	}
+
+	srcURL, err := url.Parse(srcInfoProvider.RawSource())
+	if err != nil {
+		return nil, err
+	}
+
+	// an ADLS Gen2 source is read through the DFS endpoint, since the source provider pipeline is a BlobFS one in that case
+	var readSourceRange func(ctx context.Context, offset int64, count int64) (io.ReadCloser, error)
+	fromTo := jptm.FromTo()
+	if fromTo.From() == common.ELocation.Blob() {
+		srcBlobURL := azblob.NewBlobURL(*srcURL, jptm.SourceProviderPipeline())
+		cpk, err := jptm.CpkInfo()
+		if err != nil {
+			return nil, err
+		}
+		readSourceRange = func(ctx context.Context, offset int64, count int64) (io.ReadCloser, error) {
+			get, err := srcBlobURL.Download(ctx, offset, count, azblob.BlobAccessConditions{}, false, cpk)
+			if err != nil {
+				return nil, err
+			}
+			return get.Body(azblob.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody}), nil
+		}
+	} else {
+		srcFileURL := azbfs.NewFileURL(*srcURL, jptm.SourceProviderPipeline())
+		readSourceRange = func(ctx context.Context, offset int64, count int64) (io.ReadCloser, error) {
+			get, err := srcFileURL.Download(ctx, offset, count)
+			if err != nil {
+				return nil, err
+			}
+			return get.Body(azbfs.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody}), nil
+		}
+	}
+
+	props, err := sip.Properties()
+	if err != nil {
+		return nil, err
+	}
+
+	return &urlToBlobFSCopier{
+		blobFSSenderBase: *senderBase,
+		readSourceRange:  readSourceRange,
+		srcMD5:           props.SrcHTTPHeaders.ContentMD5,
+	}, nil
+}
+
+func (c *urlToBlobFSCopier) GenerateCopyFunc(id common.ChunkID, blockIndex int32, adjustedChunkSize int64, chunkIsWholeFile bool) chunkFunc {
+
+	return createSendToRemoteChunkFunc(c.jptm, id, func() {
+		jptm := c.jptm
+
+		if jptm.Info().SourceSize == 0 {
+			// nothing to do, since this is a dummy chunk in a zero-size file, and the prologue will have done all the real work
+			return
+		}
+
+		// Wait until we have enough RAM to hold the chunk. We buffer it (rather than streaming the response straight through)
+		// because AppendData must be able to rewind the body if it retries.
+		jptm.LogChunkStatus(id, common.EWaitReason.RAMToSchedule())
+		if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), adjustedChunkSize, func() bool { return false }); err != nil {
+			jptm.FailActiveS2SCopy("Waiting for memory", err)
+			return
+		}
+		defer jptm.CacheLimiter().Remove(adjustedChunkSize)
+		buffer := jptm.SlicePool().RentSlice(adjustedChunkSize)
+		defer jptm.SlicePool().ReturnSlice(buffer)
+
+		// read the range from the source
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		retryReader, err := c.readSourceRange(jptm.Context(), id.OffsetInFile(), adjustedChunkSize)
+		if err != nil {
+			jptm.FailActiveS2SCopy("Reading source range", err)
+			return
+		}
+
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		defer retryReader.Close()
+		if _, err = io.ReadFull(newPacedResponseBody(jptm.Context(), retryReader, c.pacer), buffer); err != nil {
+			jptm.FailActiveS2SCopy("Reading source body", err)
+			return
+		}
+
+		// append it to the destination
+		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		if _, err = c.fileURL().AppendData(jptm.Context(), id.OffsetInFile(), bytes.NewReader(buffer)); err != nil {
+			jptm.FailActiveS2SCopy("Appending data", err)
+			return
+		}
+	})
+}
+
+func (c *urlToBlobFSCopier) Epilogue() {
+	// Unlike an upload, we don't hash the data as it goes past (the chunks arrive in no particular order),
+	// so the source's own MD5, if it has one, is what we set on the destination.
+	if c.jptm.IsLive() {
+		c.flush(c.srcMD5)
+	}
+}
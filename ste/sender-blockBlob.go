@@ -52,6 +52,9 @@ type blockBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
+	blobTagsToApply azblob.BlobTagsMap
+	// the customer-provided key or encryption scope, if any, that the service encrypts the destination with
+	cpkToApply azblob.ClientProvidedKeyOptions
 
 	atomicPutListIndicator int32
 	muBlockIDs             *sync.Mutex
@@ -117,6 +120,11 @@ func newBlockBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipe
 		return nil, err
 	}
 
+	cpk, err := jptm.CpkInfo()
+	if err != nil {
+		return nil, err
+	}
+
 	// If user set blob tier explicitly, override any value that our caller
 	// may have guessed.
 	destBlobTier := inferredAccessTierType
@@ -134,6 +142,8 @@ func newBlockBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipe
 		blockIDs:         make([]string, numChunks),
 		headersToApply:   props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
 		metadataToApply:  props.SrcMetadata.ToAzBlobMetadata(),
+		blobTagsToApply:  props.SrcBlobTags.ToAzBlobTagsMap(),
+		cpkToApply:       cpk,
 		destBlobTier:     destBlobTier,
 		muBlockIDs:       &sync.Mutex{}}, nil
 }
@@ -151,7 +161,7 @@ func (s *blockBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *blockBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destBlockBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}))
+	return remoteObjectExists(s.destBlockBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, s.cpkToApply))
 }
 
 func (s *blockBlobSenderBase) Prologue(ps common.PrologueState) (destinationModified bool) {
@@ -179,7 +189,7 @@ func (s *blockBlobSenderBase) Epilogue() {
 		jptm.Log(pipeline.LogDebug, fmt.Sprintf("Conclude Transfer with BlockList %s", blockIDs))
 
 		// commit the blocks.
-		if _, err := s.destBlockBlobURL.CommitBlockList(jptm.Context(), blockIDs, s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}); err != nil {
+		if _, err := s.destBlockBlobURL.CommitBlockList(jptm.Context(), blockIDs, s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, s.blobTagsToApply, s.cpkToApply); err != nil {
 			jptm.FailActiveSend("Committing block list", err)
 			return
 		}
@@ -22,6 +22,12 @@ package ste
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"hash"
+	"io"
+	"sync"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -33,6 +39,14 @@ type blockBlobUploader struct {
 	blockBlobSenderBase
 
 	md5Channel chan []byte
+
+	// encryptor is nil unless the blob is to be encrypted, client-side
+	encryptor *common.ChunkEncryptor
+
+	// compression is None unless the blob is to be compressed as it's sent.
+	// Each chunk is compressed on its own, and since the compressed chunks are concatenated it's their MD5, in order, that's put.
+	compression   common.CompressionType
+	compressedMd5 *orderedChunkHasher
 }
 
 func newBlockBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
@@ -41,7 +55,48 @@ func newBlockBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeli
 		return nil, err
 	}
 
-	return &blockBlobUploader{blockBlobSenderBase: *senderBase, md5Channel: newMd5Channel()}, nil
+	compression := jptm.CompressOnUpload()
+	var compressedMd5 *orderedChunkHasher
+	if compression != common.ECompressionType.None() {
+		if !compression.CanConcatenate() || jptm.ShouldClientEncrypt() {
+			return nil, errors.New("cannot compress " + compression.String() + " while uploading")
+		}
+		if common.MaxCompressedSize(senderBase.chunkSize) > common.MaxBlockBlobBlockSize {
+			return nil, errors.New("the block size is too large for the block to be compressed, since a block that doesn't compress grows slightly")
+		}
+		senderBase.headersToApply.ContentEncoding = compression.ContentEncoding()
+		if jptm.ShouldPutMd5() {
+			compressedMd5 = newOrderedChunkHasher()
+		}
+	}
+
+	var encryptor *common.ChunkEncryptor
+	if jptm.ShouldClientEncrypt() {
+		key, err := jptm.ClientEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, errors.New("client-side encryption was requested, but there is no key")
+		}
+		if senderBase.chunkSize > common.MaxBlockBlobBlockSize-common.ClientEncryptionTagSize {
+			return nil, errors.New("the block size is too large for the block to be encrypted, client-side")
+		}
+		encryptor, err = key.NewChunkEncryptor(senderBase.chunkSize, jptm.Info().SourceSize)
+		if err != nil {
+			return nil, err
+		}
+		// there's no Content-MD5 until the end, since it's the plaintext's MD5. It goes in the envelope rather than the headers,
+		// because the service would take it to be the MD5 of the ciphertext.
+		senderBase.headersToApply.ContentMD5 = nil
+	}
+
+	return &blockBlobUploader{
+		blockBlobSenderBase: *senderBase,
+		md5Channel:          newMd5Channel(),
+		encryptor:           encryptor,
+		compression:         compression,
+		compressedMd5:       compressedMd5}, nil
 }
 
 func (u *blockBlobUploader) Md5Channel() chan<- []byte {
@@ -73,8 +128,14 @@ func (u *blockBlobUploader) generatePutBlock(id common.ChunkID, blockIndex int32
 
 		// step 3: put block to remote
 		u.jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		body := newPacedRequestBody(u.jptm.Context(), reader, u.pacer)
-		_, err := u.destBlockBlobURL.StageBlock(u.jptm.Context(), encodedBlockID, body, azblob.LeaseAccessConditions{}, nil)
+		chunk, release, err := u.transformIfNecessary(blockIndex, reader)
+		if err != nil {
+			u.jptm.FailActiveUpload("Compressing or encrypting block", err)
+			return
+		}
+		defer release()
+		body := newPacedRequestBody(u.jptm.Context(), chunk, u.pacer)
+		_, err = u.destBlockBlobURL.StageBlock(u.jptm.Context(), encodedBlockID, body, azblob.LeaseAccessConditions{}, nil, u.cpkToApply)
 		if err != nil {
 			u.jptm.FailActiveUpload("Staging block", err)
 			return
@@ -91,8 +152,12 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 		// Upload the blob
 		jptm.LogChunkStatus(id, common.EWaitReason.Body())
 		var err error
-		if jptm.Info().SourceSize == 0 {
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
+		if jptm.Info().SourceSize == 0 && u.compression == common.ECompressionType.None() {
+			if err = u.applyEncryptionEnvelope(nil); err != nil {
+				jptm.FailActiveUpload("Encrypting blob", err)
+				return
+			}
+			_, err = u.destBlockBlobURL.Upload(jptm.Context(), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, u.blobTagsToApply, u.cpkToApply)
 		} else {
 			// File with content
 
@@ -102,11 +167,19 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 				jptm.FailActiveUpload("Getting hash", errNoHash)
 				return
 			}
-			u.headersToApply.ContentMD5 = md5Hash
-
-			// Upload the file
-			body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
+			// Upload the file. (Even an empty one, if it's compressed, since an empty compressed stream isn't empty)
+			chunk, release, transformErr := u.transformIfNecessary(blockIndex, reader)
+			if transformErr != nil {
+				jptm.FailActiveUpload("Compressing or encrypting blob", transformErr)
+				return
+			}
+			defer release()
+			if err = u.applyMd5(md5Hash); err != nil {
+				jptm.FailActiveUpload("Encrypting blob", err)
+				return
+			}
+			body := newPacedRequestBody(jptm.Context(), chunk, u.pacer)
+			_, err = u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, u.blobTagsToApply, u.cpkToApply)
 		}
 
 		// if the put blob is a failure, update the transfer status to failed
@@ -125,23 +198,170 @@ func (u *blockBlobUploader) Epilogue() {
 	if jptm.IsLive() && shouldPutBlockList == putListNeeded {
 
 		md5Hash, ok := <-u.md5Channel
-		if ok {
-			u.headersToApply.ContentMD5 = md5Hash
-		} else {
+		if !ok {
 			jptm.FailActiveSend("Getting hash", errNoHash)
 			return
 		}
+		if err := u.applyMd5(md5Hash); err != nil {
+			jptm.FailActiveSend("Encrypting blob", err)
+			return
+		}
 	}
 
 	u.blockBlobSenderBase.Epilogue()
 }
 
+// applyMd5 puts the MD5 of the source where it belongs: in the headers, or in the envelope if the blob is encrypted.
+// If the blob is compressed, it's the MD5 of the compressed data that's put instead.
+func (u *blockBlobUploader) applyMd5(md5Hash []byte) error {
+	if u.compression != common.ECompressionType.None() {
+		if u.compressedMd5 != nil {
+			u.headersToApply.ContentMD5 = u.compressedMd5.Sum()
+		}
+		return nil
+	}
+	if u.encryptor == nil {
+		u.headersToApply.ContentMD5 = md5Hash
+		return nil
+	}
+	return u.applyEncryptionEnvelope(md5Hash)
+}
+
+func (u *blockBlobUploader) applyEncryptionEnvelope(plaintextMD5 []byte) error {
+	if u.encryptor == nil {
+		return nil
+	}
+	envelope, err := u.encryptor.Envelope(plaintextMD5)
+	if err != nil {
+		return err
+	}
+
+	// copy the metadata, rather than add to it, since the map came from the job part and is shared by its transfers
+	metadata := azblob.Metadata{common.ClientEncryptionMetadataKey: envelope}
+	for k, v := range u.metadataToApply {
+		if k != common.ClientEncryptionMetadataKey {
+			metadata[k] = v
+		}
+	}
+	u.metadataToApply = metadata
+	return nil
+}
+
+// transformIfNecessary returns what is to be sent for the chunk: the chunk itself, or its compressed data or ciphertext.
+// The release func must be called once it has been sent.
+func (u *blockBlobUploader) transformIfNecessary(blockIndex int32, reader common.SingleChunkReader) (chunk io.ReadSeeker, release func(), err error) {
+	release = func() {}
+	if u.compression != common.ECompressionType.None() {
+		chunk, err = u.compress(blockIndex, reader)
+		return chunk, release, err
+	}
+	if u.encryptor == nil {
+		return reader, release, nil
+	}
+
+	// The ciphertext is a little longer than the chunk, so it's sealed in a buffer of its own, which counts towards the RAM in use like the chunk does.
+	// Since the chunk's RAM isn't freed until the ciphertext is sent, the relaxed limit is used, as it is for retries, so that chunks can't deadlock waiting for each other.
+	ciphertextLength := reader.Length() + common.ClientEncryptionTagSize
+	if err = u.jptm.CacheLimiter().WaitUntilAdd(u.jptm.Context(), ciphertextLength, func() bool { return true }); err != nil {
+		return nil, release, err
+	}
+	buffer := u.jptm.SlicePool().RentSlice(ciphertextLength)
+	release = func() {
+		u.jptm.SlicePool().ReturnSlice(buffer)
+		u.jptm.CacheLimiter().Remove(ciphertextLength)
+	}
+
+	plaintext := buffer[:reader.Length()]
+	if _, err = io.ReadFull(reader, plaintext); err != nil {
+		release()
+		return nil, func() {}, err
+	}
+	return bytes.NewReader(u.encryptor.Seal(plaintext[:0], plaintext, int64(blockIndex))), release, nil
+}
+
 func (u *blockBlobUploader) GetDestinationLength() (int64, error) {
-	prop, err := u.destBlockBlobURL.GetProperties(u.jptm.Context(), azblob.BlobAccessConditions{})
+	prop, err := u.destBlockBlobURL.GetProperties(u.jptm.Context(), azblob.BlobAccessConditions{}, u.cpkToApply)
 
 	if err != nil {
 		return -1, err
 	}
 
+	if u.encryptor != nil {
+		// it's the length of the plaintext that is compared with the source, so long as the ciphertext has the length it should
+		envelope, err := common.ParseClientEncryptionEnvelope(common.FromAzBlobMetadataToCommonMetadata(prop.NewMetadata()))
+		if err != nil {
+			return -1, err
+		}
+		if envelope != nil && envelope.EncryptedSize() == prop.ContentLength() {
+			return envelope.PlaintextSize, nil
+		}
+	}
+
 	return prop.ContentLength(), nil
 }
+
+// compress compresses the chunk on its own. The blob's final size isn't known until all of its chunks have been compressed,
+// but that doesn't matter, because it's only when the block list is committed that the blob comes into being.
+func (u *blockBlobUploader) compress(blockIndex int32, reader common.SingleChunkReader) (io.ReadSeeker, error) {
+	compressed := &bytes.Buffer{}
+	compressor, err := common.NewCompressingWriter(compressed, u.compression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(compressor, reader); err != nil {
+		return nil, err
+	}
+	if err = compressor.Close(); err != nil {
+		return nil, err
+	}
+
+	if u.compressedMd5 != nil {
+		if err = u.compressedMd5.Write(u.jptm.Context(), blockIndex, compressed.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.NewReader(compressed.Bytes()), nil
+}
+
+// orderedChunkHasher hashes the data of chunks that are ready in any order, in the order of their indexes.
+// Each chunk waits for its turn. That can't wait forever, because if an earlier chunk is never hashed,
+// it's because the transfer has failed, or been cancelled, which cancels the context.
+type orderedChunkHasher struct {
+	mu        *sync.Mutex
+	hasher    hash.Hash
+	nextIndex int32
+	advanced  chan struct{} // closed, and replaced, each time nextIndex advances
+}
+
+func newOrderedChunkHasher() *orderedChunkHasher {
+	return &orderedChunkHasher{mu: &sync.Mutex{}, hasher: md5.New(), advanced: make(chan struct{})}
+}
+
+func (h *orderedChunkHasher) Write(ctx context.Context, index int32, data []byte) error {
+	for {
+		h.mu.Lock()
+		if index == h.nextIndex {
+			h.hasher.Write(data)
+			h.nextIndex++
+			close(h.advanced)
+			h.advanced = make(chan struct{})
+			h.mu.Unlock()
+			return nil
+		}
+		advanced := h.advanced
+		h.mu.Unlock()
+
+		select {
+		case <-advanced:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sum is only meaningful once every chunk has been written
+func (h *orderedChunkHasher) Sum() []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hasher.Sum(nil)
+}
@@ -48,7 +48,10 @@ type pageBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
-	destBlobTier    azblob.AccessTierType
+	blobTagsToApply azblob.BlobTagsMap
+	// the customer-provided key or encryption scope, if any, that the service encrypts the destination with
+	cpkToApply   azblob.ClientProvidedKeyOptions
+	destBlobTier azblob.AccessTierType
 	// filePacer is necessary because page blobs have per-blob throughput limits. The limits depend on
 	// what type of page blob it is (e.g. premium) and can be significantly lower than the blob account limit.
 	// Using a automatic pacer here lets us find the right rate for this particular page blob, at which
@@ -111,6 +114,11 @@ func newPageBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipel
 		return nil, err
 	}
 
+	cpk, err := jptm.CpkInfo()
+	if err != nil {
+		return nil, err
+	}
+
 	// If user set blob tier explicitly, override any value that our caller
 	// may have guessed.
 	destBlobTier := inferredAccessTierType
@@ -128,6 +136,8 @@ func newPageBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipel
 		pacer:                  pacer,
 		headersToApply:         props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
 		metadataToApply:        props.SrcMetadata.ToAzBlobMetadata(),
+		blobTagsToApply:        props.SrcBlobTags.ToAzBlobTagsMap(),
+		cpkToApply:             cpk,
 		destBlobTier:           destBlobTier,
 		filePacer:              newNullAutoPacer(), // defer creation of real one to Prologue
 		destPageRangeOptimizer: destRangeOptimizer,
@@ -171,7 +181,7 @@ func (s *pageBlobSenderBase) NumChunks() uint32 {
 }
 
 func (s *pageBlobSenderBase) RemoteFileExists() (bool, time.Time, error) {
-	return remoteObjectExists(s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}))
+	return remoteObjectExists(s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, s.cpkToApply))
 }
 
 var premiumPageBlobTierRegex = regexp.MustCompile(`P\d+`)
@@ -202,7 +212,7 @@ func (s *pageBlobSenderBase) Prologue(ps common.PrologueState) (destinationModif
 		// FileSize                : 1073742336  (equals our s.srcSize, i.e. the size of the disk file)
 		// Size                    : 1073741824
 
-		p, err := s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{})
+		p, err := s.destPageBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{}, s.cpkToApply)
 		if err != nil {
 			s.jptm.FailActiveSend("Checking size of managed disk blob", err)
 			return
@@ -232,7 +242,9 @@ func (s *pageBlobSenderBase) Prologue(ps common.PrologueState) (destinationModif
 		0,
 		s.headersToApply,
 		s.metadataToApply,
-		azblob.BlobAccessConditions{}); err != nil {
+		azblob.BlobAccessConditions{},
+		azblob.DefaultPremiumBlobAccessTier, // the tier is set afterwards, below
+		s.blobTagsToApply, s.cpkToApply); err != nil {
 		s.jptm.FailActiveSend("Creating blob", err)
 		return
 	}
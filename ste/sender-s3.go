@@ -0,0 +1,287 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	minio "github.com/minio/minio-go"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type s3SenderBase struct {
+	jptm      IJobPartTransferMgr
+	s3Client  *minio.Client
+	s3URLPart common.S3URLParts
+	chunkSize int64
+	numChunks uint32
+	pacer     pacer
+
+	// Headers and other info that we will apply to the destination object.
+	// For S2S, these come from the source service.
+	// When sending local data, they are computed based on
+	// the properties of the local file
+	headersToApply  common.ResourceHTTPHeaders
+	metadataToApply common.Metadata
+
+	// uploadID identifies the multipart upload, when the object is sent in more than one part
+	uploadID string
+	parts    []minio.CompletePart
+
+	atomicPutListIndicator int32
+	muParts                *sync.Mutex
+}
+
+func getVerifiedS3ChunkParams(transferInfo TransferInfo, memLimit int64) (chunkSize int64, numChunks uint32, err error) {
+	chunkSize = transferInfo.BlockSize
+	srcSize := transferInfo.SourceSize
+	numChunks = getNumChunks(srcSize, chunkSize)
+
+	toMiB := func(bytes int64) float64 {
+		return float64(bytes) / float64(1024*1024)
+	}
+
+	if chunkSize >= memLimit {
+		err = fmt.Errorf("Cannot use a block size of %.2fMiB. AzCopy is limited to use only %.2fMiB of memory",
+			toMiB(chunkSize), toMiB(memLimit))
+		return
+	}
+
+	if chunkSize > common.S3MaxPartSize {
+		err = fmt.Errorf("block size of %.2fMiB for file %s exceeds maximum allowed part size for S3", toMiB(chunkSize), transferInfo.Source)
+		return
+	}
+
+	if numChunks > 1 && chunkSize < common.S3MinPartSize {
+		err = fmt.Errorf("block size of %.2fMiB for file %s is less than the minimum allowed part size for S3 (%.2fMiB)",
+			toMiB(chunkSize), transferInfo.Source, toMiB(common.S3MinPartSize))
+		return
+	}
+
+	if numChunks > common.S3MaxNumberOfParts {
+		err = fmt.Errorf("Block size %d for source of size %d is not correct. Number of parts will exceed the limit", chunkSize, srcSize)
+		return
+	}
+
+	return
+}
+
+func newS3SenderBase(jptm IJobPartTransferMgr, destination string, pacer pacer, srcInfoProvider ISourceInfoProvider) (*s3SenderBase, error) {
+	// compute chunk count
+	chunkSize, numChunks, err := getVerifiedS3ChunkParams(jptm.Info(), jptm.CacheLimiter().Limit())
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, s3URLPart, err := newS3ClientForURL(jptm, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := srcInfoProvider.Properties()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3SenderBase{
+		jptm:            jptm,
+		s3Client:        s3Client,
+		s3URLPart:       s3URLPart,
+		chunkSize:       chunkSize,
+		numChunks:       numChunks,
+		pacer:           pacer,
+		headersToApply:  props.SrcHTTPHeaders,
+		metadataToApply: props.SrcMetadata,
+		parts:           make([]minio.CompletePart, numChunks),
+		muParts:         &sync.Mutex{}}, nil
+}
+
+func (s *s3SenderBase) SendableEntityType() common.EntityType {
+	return common.EEntityType.File()
+}
+
+func (s *s3SenderBase) ChunkSize() int64 {
+	return s.chunkSize
+}
+
+func (s *s3SenderBase) NumChunks() uint32 {
+	return s.numChunks
+}
+
+func (s *s3SenderBase) core() minio.Core {
+	return minio.Core{Client: s.s3Client}
+}
+
+func (s *s3SenderBase) RemoteFileExists() (bool, time.Time, error) {
+	objectInfo, err := s.s3Client.StatObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return false, time.Time{}, nil
+		}
+		return false, time.Time{}, err
+	}
+	return true, objectInfo.LastModified, nil
+}
+
+func (s *s3SenderBase) Prologue(ps common.PrologueState) (destinationModified bool) {
+	// sometimes, specifically when reading local files, we have more info
+	// about the file type at this time than what we had before.
+	// For S2S copies, keep the content type of the source.
+	if fromTo := s.jptm.FromTo(); fromTo.IsUpload() {
+		s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
+	}
+
+	// S3 requires the object's headers and metadata when the multipart upload is started, rather than when it is completed.
+	// Objects that fit in a single part are sent with a single PUT instead, so have nothing to do here.
+	if s.numChunks > 1 {
+		uploadID, err := s.core().NewMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.headersToApply.ToS3PutObjectOptions(s.metadataToApply))
+		if err != nil {
+			s.jptm.FailActiveSend("Creating multipart upload", err)
+			return false
+		}
+		s.uploadID = uploadID
+	}
+
+	// uncommitted parts are not visible, so the destination is not modified until the upload is completed
+	return false
+}
+
+// putWholeObject sends the object in a single PUT, for those that fit in one part.
+func (s *s3SenderBase) putWholeObject(body io.Reader, size int64) error {
+	md5Base64 := ""
+	if len(s.headersToApply.ContentMD5) != 0 {
+		md5Base64 = base64.StdEncoding.EncodeToString(s.headersToApply.ContentMD5)
+	}
+
+	_, err := s.core().PutObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, body, size, md5Base64, "",
+		s3HeadersToMetadata(s.headersToApply.ToS3PutObjectOptions(s.metadataToApply)), nil)
+	return err
+}
+
+// putPart sends one part of a multipart upload, and records it for completion in the Epilogue.
+func (s *s3SenderBase) putPart(blockIndex int32, body io.Reader, size int64) error {
+	partNumber := int(blockIndex) + 1 // S3 part numbers start at 1
+	part, err := s.core().PutObjectPart(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID, partNumber, body, size, "", "", nil)
+	if err != nil {
+		return err
+	}
+
+	s.setPart(blockIndex, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+	return nil
+}
+
+func (s *s3SenderBase) Epilogue() {
+	jptm := s.jptm
+
+	s.muParts.Lock()
+	parts := s.parts
+	s.parts = nil // so we know for sure that only this routine has access after we release the lock
+	s.muParts.Unlock()
+	shouldPutBlockList := getPutListNeed(&s.atomicPutListIndicator)
+	if shouldPutBlockList == putListNeedUnknown && !jptm.WasCanceled() {
+		panic(errors.New("'put list' need flag was never set"))
+	}
+
+	// complete the multipart upload if necessary
+	if jptm.IsLive() && shouldPutBlockList == putListNeeded {
+		jptm.Log(pipeline.LogDebug, fmt.Sprintf("Conclude Transfer with %d parts", len(parts)))
+
+		if _, err := s.core().CompleteMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID, parts); err != nil {
+			jptm.FailActiveSend("Completing multipart upload", err)
+			return
+		}
+		s.uploadID = ""
+	}
+}
+
+// replaceMetadata rewrites the headers and metadata of the completed object, by copying it onto itself.
+func (s *s3SenderBase) replaceMetadata() {
+	jptm := s.jptm
+
+	if jptm.Info().SourceSize > common.S3MaxPartSize {
+		// S3 can't copy objects this large in a single operation
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Object is too large for its MD5 hash to be stored in its metadata, so the hash has not been stored")
+		return
+	}
+
+	headers := s3HeadersToMetadata(s.headersToApply.ToS3PutObjectOptions(s.metadataToApply))
+	headers["x-amz-metadata-directive"] = "REPLACE"
+	if _, err := s.core().CopyObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, headers); err != nil {
+		jptm.FailActiveSend("Setting hash", err)
+	}
+}
+
+func (s *s3SenderBase) Cleanup() {
+	jptm := s.jptm
+
+	// Cleanup
+	if jptm.IsDeadInflight() {
+		// If the multipart upload was started but not completed, abort it, so the customer doesn't pay for storage
+		// of the parts that were already sent.
+		// Nothing else is needed if we were cancelled, since in that case the object that was there before our job
+		// started (if any) remains untouched.
+		if s.uploadID != "" {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Aborting multipart upload")
+			_ = s.core().AbortMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID)
+		} else if !jptm.WasCanceled() {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Deleting destination object due to failure")
+			_ = s.s3Client.RemoveObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey)
+		}
+	}
+}
+
+func (s *s3SenderBase) GetDestinationLength() (int64, error) {
+	objectInfo, err := s.s3Client.StatObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return -1, err
+	}
+
+	return objectInfo.Size, nil
+}
+
+func (s *s3SenderBase) setPart(index int32, part minio.CompletePart) {
+	s.muParts.Lock()
+	defer s.muParts.Unlock()
+	if s.parts[index].PartNumber != 0 {
+		panic(errors.New("part set twice for one chunk"))
+	}
+	s.parts[index] = part
+}
+
+// s3HeadersToMetadata flattens the given options into the form accepted by the minio Core API,
+// which takes headers and user-defined metadata in a single map.
+func s3HeadersToMetadata(opts minio.PutObjectOptions) map[string]string {
+	m := make(map[string]string)
+	for k, v := range opts.Header() {
+		if len(v) > 0 {
+			m[k] = v[0]
+		}
+	}
+	return m
+}
@@ -0,0 +1,136 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type s3Uploader struct {
+	s3SenderBase
+
+	md5Channel chan []byte
+}
+
+func newS3Uploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	senderBase, err := newS3SenderBase(jptm, destination, pacer, sip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Uploader{s3SenderBase: *senderBase, md5Channel: newMd5Channel()}, nil
+}
+
+func (u *s3Uploader) Md5Channel() chan<- []byte {
+	return u.md5Channel
+}
+
+// Returns a chunk-func for S3 uploads
+func (u *s3Uploader) GenerateUploadFunc(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader, chunkIsWholeFile bool) chunkFunc {
+	if chunkIsWholeFile {
+		if blockIndex > 0 {
+			panic("chunk cannot be whole file where there is more than one chunk")
+		}
+		setPutListNeed(&u.atomicPutListIndicator, putListNotNeeded)
+		return u.generatePutWholeObject(id, reader)
+	} else {
+		setPutListNeed(&u.atomicPutListIndicator, putListNeeded)
+		return u.generatePutPart(id, blockIndex, reader)
+	}
+}
+
+// generatePutPart generates a func to upload one part of a multipart upload.
+func (u *s3Uploader) generatePutPart(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader) chunkFunc {
+	return createSendToRemoteChunkFunc(u.jptm, id, func() {
+		defer reader.Close() // the minio client doesn't close request bodies
+
+		u.jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		body := newPacedRequestBody(u.jptm.Context(), reader, u.pacer)
+		if err := u.putPart(blockIndex, body, reader.Length()); err != nil {
+			u.jptm.FailActiveUpload("Uploading part", err)
+			return
+		}
+	})
+}
+
+// generates PUT Object (for an object that fits in a single put request)
+func (u *s3Uploader) generatePutWholeObject(id common.ChunkID, reader common.SingleChunkReader) chunkFunc {
+	return createSendToRemoteChunkFunc(u.jptm, id, func() {
+		defer reader.Close() // the minio client doesn't close request bodies
+		jptm := u.jptm
+
+		// Upload the object
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		var err error
+		if jptm.Info().SourceSize == 0 {
+			err = u.putWholeObject(bytes.NewReader(nil), 0)
+		} else {
+			// File with content
+
+			// Get the MD5 that was computed as we read the file
+			md5Hash, ok := <-u.md5Channel
+			if !ok {
+				jptm.FailActiveUpload("Getting hash", errNoHash)
+				return
+			}
+			u.headersToApply.ContentMD5 = md5Hash
+
+			// Upload the file
+			body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
+			err = u.putWholeObject(body, reader.Length())
+		}
+
+		// if the put object is a failure, update the transfer status to failed
+		if err != nil {
+			jptm.FailActiveUpload("Uploading object", err)
+			return
+		}
+	})
+}
+
+func (u *s3Uploader) Epilogue() {
+	jptm := u.jptm
+
+	shouldPutBlockList := getPutListNeed(&u.atomicPutListIndicator)
+
+	if jptm.IsLive() && shouldPutBlockList == putListNeeded {
+
+		md5Hash, ok := <-u.md5Channel
+		if ok {
+			u.headersToApply.ContentMD5 = md5Hash
+		} else {
+			jptm.FailActiveSend("Getting hash", errNoHash)
+			return
+		}
+	}
+
+	u.s3SenderBase.Epilogue()
+
+	// The hash isn't known until all parts have been read, which is after the multipart upload was started with the object's metadata.
+	// So replace the metadata now, using a server-side copy of the object onto itself.
+	if jptm.IsLive() && shouldPutBlockList == putListNeeded && len(u.headersToApply.ContentMD5) != 0 {
+		u.replaceMetadata()
+	}
+}
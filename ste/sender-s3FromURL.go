@@ -0,0 +1,131 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// urlToS3Copier copies blobs to S3. Unlike Azure Storage, S3 can't read the source itself,
+// so each chunk is downloaded from the source and then uploaded as a part of a multipart upload.
+type urlToS3Copier struct {
+	s3SenderBase
+
+	srcBlobURL azblob.BlobURL
+	srcCpk     azblob.ClientProvidedKeyOptions // since the source is downloaded, it may be encrypted with a customer-provided key
+}
+
+func newURLToS3Copier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	srcInfoProvider := sip.(IRemoteSourceInfoProvider) // "downcast" to the type we know it really has
+
+	senderBase, err := newS3SenderBase(jptm, destination, pacer, srcInfoProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	srcURL, err := srcInfoProvider.PreSignedSourceURL()
+	if err != nil {
+		return nil, err
+	}
+
+	srcCpk, err := jptm.CpkInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return &urlToS3Copier{
+		s3SenderBase: *senderBase,
+		srcBlobURL:   azblob.NewBlobURL(*srcURL, jptm.SourceProviderPipeline()),
+		srcCpk:       srcCpk}, nil
+}
+
+// Returns a chunk-func for copies to S3
+func (c *urlToS3Copier) GenerateCopyFunc(id common.ChunkID, blockIndex int32, adjustedChunkSize int64, chunkIsWholeFile bool) chunkFunc {
+	if chunkIsWholeFile {
+		if blockIndex > 0 {
+			panic("chunk cannot be whole file where there is more than one chunk")
+		}
+		setPutListNeed(&c.atomicPutListIndicator, putListNotNeeded)
+	} else {
+		setPutListNeed(&c.atomicPutListIndicator, putListNeeded)
+	}
+
+	return createSendToRemoteChunkFunc(c.jptm, id, func() {
+		jptm := c.jptm
+
+		if adjustedChunkSize == 0 {
+			if err := c.putWholeObject(bytes.NewReader(nil), 0); err != nil {
+				jptm.FailActiveSend("Creating empty object", err)
+			}
+			return
+		}
+
+		// Wait until we have enough RAM to hold the chunk, since S3 needs to know the length of each part up front,
+		// and buffering it (rather than streaming the response straight through) lets the S3 client retry the part.
+		jptm.LogChunkStatus(id, common.EWaitReason.RAMToSchedule())
+		if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), adjustedChunkSize, func() bool { return false }); err != nil {
+			jptm.FailActiveSend("Waiting for memory", err)
+			return
+		}
+		defer jptm.CacheLimiter().Remove(adjustedChunkSize)
+		buffer := jptm.SlicePool().RentSlice(adjustedChunkSize)
+		defer jptm.SlicePool().ReturnSlice(buffer)
+
+		// read the range from the source, protecting against changes-while-being-read
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		accessConditions := azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfUnmodifiedSince: jptm.LastModifiedTime()}}
+		get, err := c.srcBlobURL.Download(jptm.Context(), id.OffsetInFile(), adjustedChunkSize, accessConditions, false, c.srcCpk)
+		if err != nil {
+			jptm.FailActiveS2SCopy("Reading source range", err)
+			return
+		}
+
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		u := c.srcBlobURL.URL()
+		retryReader := get.Body(azblob.RetryReaderOptions{
+			MaxRetryRequests: MaxRetryPerDownloadBody,
+			NotifyFailedRead: common.NewReadLogFunc(jptm, &u),
+		})
+		defer retryReader.Close()
+		if _, err = io.ReadFull(newPacedResponseBody(jptm.Context(), retryReader, c.pacer), buffer); err != nil {
+			jptm.FailActiveS2SCopy("Reading source body", err)
+			return
+		}
+
+		// write it to the destination
+		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		if chunkIsWholeFile {
+			err = c.putWholeObject(bytes.NewReader(buffer), adjustedChunkSize)
+		} else {
+			err = c.putPart(blockIndex, bytes.NewReader(buffer), adjustedChunkSize)
+		}
+		if err != nil {
+			jptm.FailActiveSend(common.IffString(chunkIsWholeFile, "Uploading object", "Uploading part"), err)
+			return
+		}
+	})
+}
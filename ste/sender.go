@@ -22,6 +22,7 @@ package ste
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -192,6 +193,13 @@ func newBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pi
 		// TODO: Perhaps we should log it only if it isn't a block blob?
 	}
 
+	if jptm.ShouldClientEncrypt() && intendedType != azblob.BlobBlockBlob && intendedType != azblob.BlobNone {
+		return nil, fmt.Errorf("client-side encryption is only supported for block blobs, but this would be a %s", intendedType)
+	}
+	if jptm.CompressOnUpload() != common.ECompressionType.None() && intendedType != azblob.BlobBlockBlob && intendedType != azblob.BlobNone {
+		return nil, fmt.Errorf("compression is only supported for block blobs, but this would be a %s", intendedType)
+	}
+
 	switch intendedType {
 	case azblob.BlobBlockBlob:
 		return newBlockBlobUploader(jptm, destination, p, pacer, sip)
@@ -21,9 +21,16 @@
 package ste
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
 	"fmt"
+	"io/ioutil"
+	"sync"
 
 	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 type blockBlobSuite struct{}
@@ -58,3 +65,102 @@ func (s *blockBlobSuite) TestGetVerifiedChunkParams(c *chk.C) {
 	c.Assert(err.Error(), chk.Equals, expectedErr)
 
 }
+
+func (s *blockBlobSuite) TestOrderedChunkHasher(c *chk.C) {
+	chunks := [][]byte{[]byte("zero"), []byte("one"), []byte("two"), []byte("three"), []byte("four")}
+	expected := md5.Sum([]byte("zeroonetwothreefour"))
+
+	// chunks that are ready out of order are still hashed in order
+	h := newOrderedChunkHasher()
+	wg := &sync.WaitGroup{}
+	for _, i := range []int32{4, 2, 3, 0, 1} {
+		wg.Add(1)
+		go func(i int32) {
+			defer wg.Done()
+			c.Check(h.Write(context.Background(), i, chunks[i]), chk.IsNil)
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(h.Sum(), chk.DeepEquals, expected[:])
+
+	// and a chunk that's waiting for one that'll never come gives up when the transfer is cancelled
+	h = newOrderedChunkHasher()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(h.Write(ctx, 1, chunks[1]), chk.NotNil)
+}
+
+// countingCacheLimiter keeps count of the RAM that's been added to it, without any limit
+type countingCacheLimiter struct {
+	value int64
+}
+
+func (l *countingCacheLimiter) TryAdd(count int64, useRelaxedLimit bool) bool {
+	l.value += count
+	return true
+}
+func (l *countingCacheLimiter) WaitUntilAdd(ctx context.Context, count int64, useRelaxedLimit common.Predicate) error {
+	l.value += count
+	return nil
+}
+func (l *countingCacheLimiter) Remove(count int64) { l.value -= count }
+func (l *countingCacheLimiter) Limit() int64       { return 1 << 30 }
+
+// countingSlicePool keeps count of the slices that are rented from it and not yet returned
+type countingSlicePool struct {
+	rented int
+}
+
+func (p *countingSlicePool) RentSlice(desiredLength int64) []byte {
+	p.rented++
+	return make([]byte, desiredLength)
+}
+func (p *countingSlicePool) ReturnSlice(slice []byte) { p.rented-- }
+func (p *countingSlicePool) Prune()                   {}
+
+// testPooledTransferMgr is a transfer manager that only has a pool and a cache limiter
+type testPooledTransferMgr struct {
+	IJobPartTransferMgr
+	slicePool    *countingSlicePool
+	cacheLimiter *countingCacheLimiter
+}
+
+func (t *testPooledTransferMgr) Context() context.Context          { return context.Background() }
+func (t *testPooledTransferMgr) SlicePool() common.ByteSlicePooler { return t.slicePool }
+func (t *testPooledTransferMgr) CacheLimiter() common.CacheLimiter { return t.cacheLimiter }
+
+// testChunkReader is a chunk that's already in RAM
+type testChunkReader struct {
+	common.SingleChunkReader
+	data *bytes.Reader
+}
+
+func (r *testChunkReader) Read(p []byte) (int, error) { return r.data.Read(p) }
+func (r *testChunkReader) Length() int64              { return r.data.Size() }
+
+func (s *blockBlobSuite) TestEncryptedChunkIsPooledAndCounted(c *chk.C) {
+	key, err := common.NewClientEncryptionKey(bytes.Repeat([]byte{7}, 32))
+	c.Assert(err, chk.IsNil)
+	plaintext := bytes.Repeat([]byte("some text to be encrypted"), 100)
+	encryptor, err := key.NewChunkEncryptor(int64(len(plaintext)), int64(len(plaintext)))
+	c.Assert(err, chk.IsNil)
+
+	jptm := &testPooledTransferMgr{slicePool: &countingSlicePool{}, cacheLimiter: &countingCacheLimiter{}}
+	u := &blockBlobUploader{blockBlobSenderBase: blockBlobSenderBase{jptm: jptm}, encryptor: encryptor, compression: common.ECompressionType.None()}
+
+	chunk, release, err := u.transformIfNecessary(0, &testChunkReader{data: bytes.NewReader(plaintext)})
+	c.Assert(err, chk.IsNil)
+
+	// the ciphertext is in a slice from the pool, which counts towards the RAM in use until it's released
+	ciphertextLength := int64(len(plaintext) + common.ClientEncryptionTagSize)
+	c.Assert(jptm.slicePool.rented, chk.Equals, 1)
+	c.Assert(jptm.cacheLimiter.value, chk.Equals, ciphertextLength)
+	ciphertext, err := ioutil.ReadAll(chunk)
+	c.Assert(err, chk.IsNil)
+	c.Assert(int64(len(ciphertext)), chk.Equals, ciphertextLength)
+	c.Assert(bytes.Contains(ciphertext, plaintext[:25]), chk.Equals, false)
+
+	release()
+	c.Assert(jptm.slicePool.rented, chk.Equals, 0)
+	c.Assert(jptm.cacheLimiter.value, chk.Equals, int64(0))
+}
@@ -0,0 +1,66 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+
+	chk "gopkg.in/check.v1"
+)
+
+type s3SenderSuite struct{}
+
+var _ = chk.Suite(&s3SenderSuite{})
+
+func (s *s3SenderSuite) TestGetVerifiedS3ChunkParams(c *chk.C) {
+	memLimit := int64(8388608000) // 8000MiB
+
+	// A small file fits in a single part, even though the block size is below the S3 minimum part size
+	transferInfo := TransferInfo{
+		BlockSize:  1048576, // 1MiB
+		Source:     "tmpSrc",
+		SourceSize: 1024,
+	}
+	chunkSize, numChunks, err := getVerifiedS3ChunkParams(transferInfo, memLimit)
+	c.Assert(err, chk.IsNil)
+	c.Assert(chunkSize, chk.Equals, int64(1048576))
+	c.Assert(numChunks, chk.Equals, uint32(1))
+
+	// But multipart uploads must respect it
+	transferInfo.SourceSize = 4194304 // 4MiB
+	expectedErr := fmt.Sprintf("block size of 1.00MiB for file tmpSrc is less than the minimum allowed part size for S3 (5.00MiB)")
+	_, _, err = getVerifiedS3ChunkParams(transferInfo, memLimit)
+	c.Assert(err.Error(), chk.Equals, expectedErr)
+
+	// Verify large block Size
+	transferInfo.BlockSize = 6442450944  // 6GiB
+	transferInfo.SourceSize = 6442450944 // 6GiB
+	expectedErr = fmt.Sprintf("block size of 6144.00MiB for file tmpSrc exceeds maximum allowed part size for S3")
+	_, _, err = getVerifiedS3ChunkParams(transferInfo, memLimit)
+	c.Assert(err.Error(), chk.Equals, expectedErr)
+
+	// High part count
+	transferInfo.BlockSize = 5242880      // 5MiB
+	transferInfo.SourceSize = 64424509440 // 60GiB
+	expectedErr = fmt.Sprintf("Block size 5242880 for source of size 64424509440 is not correct. Number of parts will exceed the limit")
+	_, _, err = getVerifiedS3ChunkParams(transferInfo, memLimit)
+	c.Assert(err.Error(), chk.Equals, expectedErr)
+}
@@ -24,6 +24,8 @@ import (
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
 )
 
 // Source info provider for Azure blob
@@ -54,8 +56,17 @@ func (p *blobSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, erro
 		return time.Time{}, err
 	}
 
+	// When the destination is Blob Storage too, the customer-provided key is the destination's,
+	// since the service can't copy from a blob that's encrypted with one
+	cpk := azblob.ClientProvidedKeyOptions{}
+	if fromTo := p.jptm.FromTo(); fromTo.To() != common.ELocation.Blob() {
+		if cpk, err = p.jptm.CpkInfo(); err != nil {
+			return time.Time{}, err
+		}
+	}
+
 	blobURL := azblob.NewBlobURL(*presignedURL, p.jptm.SourceProviderPipeline())
-	properties, err := blobURL.GetProperties(p.jptm.Context(), azblob.BlobAccessConditions{})
+	properties, err := blobURL.GetProperties(p.jptm.Context(), azblob.BlobAccessConditions{}, cpk)
 	if err != nil {
 		return time.Time{}, err
 	}
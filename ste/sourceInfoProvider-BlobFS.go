@@ -0,0 +1,156 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/azbfs"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Source info provider for ADLS Gen2 (BlobFS)
+type blobFSSourceInfoProvider struct {
+	cacheOnce        *sync.Once
+	cachedProperties *azbfs.PathGetPropertiesResponse
+	cachedErr        error
+	defaultRemoteSourceInfoProvider
+}
+
+func newBlobFSSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	base, err := newDefaultRemoteSourceInfoProvider(jptm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blobFSSourceInfoProvider{defaultRemoteSourceInfoProvider: *base, cacheOnce: &sync.Once{}}, nil
+}
+
+// PreSignedSourceURL returns the Blob endpoint equivalent of the source.
+// Services that copy from a URL (e.g. Put Block From URL) read through the Blob endpoint,
+// which serves the very same data as the DFS endpoint on a hierarchical namespace account.
+func (p *blobFSSourceInfoProvider) PreSignedSourceURL() (*url.URL, error) {
+	srcURL, err := p.defaultRemoteSourceInfoProvider.PreSignedSourceURL()
+	if err != nil {
+		return nil, err
+	}
+
+	srcURL.Host = blobEndpointHostForBlobFS(srcURL.Host)
+	return srcURL, nil
+}
+
+// blobEndpointHostForBlobFS converts e.g. account.dfs.core.windows.net to account.blob.core.windows.net
+func blobEndpointHostForBlobFS(host string) string {
+	if i := strings.Index(strings.ToLower(host), ".dfs."); i >= 0 {
+		return host[:i] + ".blob." + host[i+len(".dfs."):]
+	}
+	return host
+}
+
+func (p *blobFSSourceInfoProvider) getFreshProperties() (*azbfs.PathGetPropertiesResponse, error) {
+	// unlike PreSignedSourceURL, we talk to the DFS endpoint here, since that's what our pipeline is for
+	srcURL, err := url.Parse(p.transferInfo.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	return azbfs.NewFileURL(*srcURL, p.jptm.SourceProviderPipeline()).GetProperties(p.jptm.Context())
+}
+
+// cached because the BlobFS senders ask for the properties more than once
+func (p *blobFSSourceInfoProvider) getCachedProperties() (*azbfs.PathGetPropertiesResponse, error) {
+	p.cacheOnce.Do(func() {
+		p.cachedProperties, p.cachedErr = p.getFreshProperties()
+	})
+
+	return p.cachedProperties, p.cachedErr
+}
+
+// the root of a file system isn't a path, so it has no properties of its own
+func (p *blobFSSourceInfoProvider) isFileSystemRoot() bool {
+	srcURL, err := url.Parse(p.transferInfo.Source)
+	if err != nil {
+		return false
+	}
+	return azbfs.NewBfsURLParts(*srcURL).DirectoryOrFilePath == ""
+}
+
+func (p *blobFSSourceInfoProvider) Properties() (*SrcProperties, error) {
+	srcProperties, err := p.defaultRemoteSourceInfoProvider.Properties()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get properties in backend.
+	// The BlobFS traverser doesn't get full properties or metadata (the listing doesn't return them), so this is the only way to preserve them.
+	if p.transferInfo.S2SGetPropertiesInBackend && !p.isFileSystemRoot() {
+		props, err := p.getCachedProperties()
+		if err != nil {
+			return nil, err
+		}
+
+		metadata, err := common.FromBlobFSPropertiesToCommonMetadata(props.XMsProperties())
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.EntityType() {
+		case common.EEntityType.File():
+			srcProperties = &SrcProperties{
+				SrcHTTPHeaders: common.ResourceHTTPHeaders{
+					ContentType:        props.ContentType(),
+					ContentEncoding:    props.ContentEncoding(),
+					ContentDisposition: props.ContentDisposition(),
+					ContentLanguage:    props.ContentLanguage(),
+					CacheControl:       props.CacheControl(),
+					ContentMD5:         props.ContentMD5(),
+				},
+				SrcMetadata: metadata,
+				SrcBlobTags: p.transferInfo.SrcBlobTags,
+			}
+		case common.EEntityType.Folder():
+			srcProperties = &SrcProperties{
+				SrcHTTPHeaders: common.ResourceHTTPHeaders{}, // no contentType etc for folders
+				SrcMetadata:    metadata,
+			}
+		default:
+			panic("unsupported entity type")
+		}
+	}
+
+	return srcProperties, nil
+}
+
+func (p *blobFSSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	if p.EntityType() != common.EEntityType.File() {
+		panic("unsupported. Cannot get modification time on non-file object") // nothing should ever call this for a non-file
+	}
+
+	props, err := p.getFreshProperties()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return newBlobFSLastModifiedTimeProvider(props).LastModified(), nil
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	minio "github.com/minio/minio-go"
+)
+
+// Source info provider for Google Cloud Storage.
+// GCS is accessed through its S3-compatible XML API, so the client is the same as for S3.
+type gcpSourceInfoProvider struct {
+	jptm         IJobPartTransferMgr
+	transferInfo TransferInfo
+
+	gcpClient   *minio.Client
+	gcpURLParts common.GCPURLParts
+
+	// set when authenticating with a service account, since its key signs the URLs rather than the client
+	serviceAccountKey *common.GCPServiceAccountKey
+}
+
+func newGCPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	var err error
+	p := gcpSourceInfoProvider{jptm: jptm, transferInfo: jptm.Info()}
+
+	u, err := url.Parse(p.transferInfo.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	p.gcpURLParts, err = common.NewGCPURLParts(*u)
+	if err != nil {
+		return nil, err
+	}
+
+	credType, err := common.GetGCPCredentialType()
+	if err != nil {
+		return nil, err
+	}
+	if credType == common.ECredentialType.GCPServiceAccount() {
+		if p.serviceAccountKey, err = common.GetGCPServiceAccountKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.gcpClient, err = s3ClientFactory.GetS3Client(
+		jptm.Context(),
+		common.CredentialInfo{
+			CredentialType:   credType,
+			S3CredentialInfo: p.gcpURLParts.S3CredentialInfo(),
+		},
+		common.CredentialOpOptions{
+			LogInfo:  func(str string) { jptm.Log(pipeline.LogInfo, str) },
+			LogError: func(str string) { jptm.Log(pipeline.LogError, str) },
+			Panic:    func(err error) { panic(err) },
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *gcpSourceInfoProvider) PreSignedSourceURL() (*url.URL, error) {
+	if p.serviceAccountKey != nil {
+		return p.serviceAccountKey.PresignGetObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, defaultPresignExpires)
+	}
+	return p.gcpClient.PresignedGetObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, defaultPresignExpires, url.Values{})
+}
+
+func (p *gcpSourceInfoProvider) Properties() (*SrcProperties, error) {
+	srcProperties := SrcProperties{
+		SrcHTTPHeaders: p.transferInfo.SrcHTTPHeaders,
+		SrcMetadata:    p.transferInfo.SrcMetadata,
+		SrcBlobTags:    p.transferInfo.SrcBlobTags,
+	}
+
+	// Get properties in backend.
+	if p.transferInfo.S2SGetPropertiesInBackend {
+		objectInfo, err := p.gcpClient.StatObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, err
+		}
+		oie := common.GCPObjectInfoExtension{ObjectInfo: objectInfo}
+
+		srcProperties = SrcProperties{
+			SrcHTTPHeaders: common.ResourceHTTPHeaders{
+				ContentType:        oie.ContentType(),
+				ContentEncoding:    oie.ContentEncoding(),
+				ContentDisposition: oie.ContentDisposition(),
+				ContentLanguage:    oie.ContentLanguage(),
+				CacheControl:       oie.CacheControl(),
+				ContentMD5:         oie.ContentMD5(),
+			},
+			SrcMetadata: oie.NewCommonMetadata(),
+			SrcBlobTags: p.transferInfo.SrcBlobTags,
+		}
+	}
+
+	// Handle invalid metadata, in the same way as for S3.
+	resolvedMetadata, err := handleInvalidMetadataKeys(p.jptm, p.transferInfo, srcProperties.SrcMetadata)
+	if err != nil {
+		return nil, err
+	}
+	srcProperties.SrcMetadata = resolvedMetadata
+
+	return &srcProperties, nil
+}
+
+func (p *gcpSourceInfoProvider) SourceSize() int64 {
+	return p.transferInfo.SourceSize
+}
+
+func (p *gcpSourceInfoProvider) RawSource() string {
+	return p.transferInfo.Source
+}
+
+func (p *gcpSourceInfoProvider) IsLocal() bool {
+	return false
+}
+
+func (p *gcpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	objectInfo, err := p.gcpClient.StatObject(p.gcpURLParts.BucketName, p.gcpURLParts.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return objectInfo.LastModified, nil
+}
+
+func (p *gcpSourceInfoProvider) EntityType() common.EntityType {
+	return common.EEntityType.File() // no real folders exist in GCS
+}
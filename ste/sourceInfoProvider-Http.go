@@ -0,0 +1,93 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Source info provider for resources served by a plain HTTP(S) web server.
+// The service pulls the data directly from the source URL, so all that's needed here are the properties,
+// which are obtained with a HEAD request.
+type httpSourceInfoProvider struct {
+	defaultRemoteSourceInfoProvider
+}
+
+func newHttpSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	base, err := newDefaultRemoteSourceInfoProvider(jptm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpSourceInfoProvider{defaultRemoteSourceInfoProvider: *base}, nil
+}
+
+func (p *httpSourceInfoProvider) getFreshProperties() (common.HTTPResourceProperties, error) {
+	sourceURL, err := url.Parse(p.transferInfo.Source)
+	if err != nil {
+		return common.HTTPResourceProperties{}, err
+	}
+
+	return common.GetHTTPResourceProperties(p.jptm.Context(), p.jptm.SourceProviderPipeline(), *sourceURL)
+}
+
+func (p *httpSourceInfoProvider) Properties() (*SrcProperties, error) {
+	srcProperties := SrcProperties{
+		SrcHTTPHeaders: p.transferInfo.SrcHTTPHeaders,
+		SrcMetadata:    p.transferInfo.SrcMetadata,
+		SrcBlobTags:    p.transferInfo.SrcBlobTags,
+	}
+
+	// Get properties in backend.
+	if p.transferInfo.S2SGetPropertiesInBackend {
+		props, err := p.getFreshProperties()
+		if err != nil {
+			return nil, err
+		}
+
+		srcProperties.SrcHTTPHeaders = common.ResourceHTTPHeaders{
+			ContentType:        props.ContentType(),
+			ContentEncoding:    props.ContentEncoding(),
+			ContentDisposition: props.ContentDisposition(),
+			ContentLanguage:    props.ContentLanguage(),
+			CacheControl:       props.CacheControl(),
+			ContentMD5:         props.ContentMD5(),
+		}
+	}
+
+	return &srcProperties, nil
+}
+
+func (p *httpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	props, err := p.getFreshProperties()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return props.LastModified(), nil
+}
+
+func (p *httpSourceInfoProvider) EntityType() common.EntityType {
+	return common.EEntityType.File() // a web server has no folders we can see
+}
@@ -52,6 +52,7 @@ func (f localFileSourceInfoProvider) Properties() (*SrcProperties, error) {
 			CacheControl:       headers.CacheControl,
 		},
 		SrcMetadata: metadata,
+		SrcBlobTags: f.transferInfo.SrcBlobTags,
 	}, nil
 }
 
@@ -47,34 +47,46 @@ const defaultPresignExpires = time.Hour * 7 * 24
 
 var s3ClientFactory = common.NewS3ClientFactory()
 
-func newS3SourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
-	var err error
-	p := s3SourceInfoProvider{jptm: jptm, transferInfo: jptm.Info()}
-
-	p.rawSourceURL, err = url.Parse(p.transferInfo.Source)
+// newS3ClientForURL parses the given S3 URL, and returns a (cached) client suitable for accessing it.
+func newS3ClientForURL(jptm IJobPartTransferMgr, rawURL string) (*minio.Client, common.S3URLParts, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, err
+		return nil, common.S3URLParts{}, err
 	}
 
-	p.s3URLPart, err = common.NewS3URLParts(*p.rawSourceURL)
+	s3URLPart, err := common.NewS3URLParts(*u)
 	if err != nil {
-		return nil, err
+		return nil, common.S3URLParts{}, err
 	}
 
-	p.s3Client, err = s3ClientFactory.GetS3Client(
-		p.jptm.Context(),
+	s3Client, err := s3ClientFactory.GetS3Client(
+		jptm.Context(),
 		common.CredentialInfo{
-			CredentialType: common.ECredentialType.S3AccessKey(),
-			S3CredentialInfo: common.S3CredentialInfo{
-				Endpoint: p.s3URLPart.Endpoint,
-				Region:   p.s3URLPart.Region,
-			},
+			CredentialType:   common.ECredentialType.S3AccessKey(),
+			S3CredentialInfo: s3URLPart.S3CredentialInfo(),
 		},
 		common.CredentialOpOptions{
-			LogInfo:  func(str string) { p.jptm.Log(pipeline.LogInfo, str) },
-			LogError: func(str string) { p.jptm.Log(pipeline.LogError, str) },
+			LogInfo:  func(str string) { jptm.Log(pipeline.LogInfo, str) },
+			LogError: func(str string) { jptm.Log(pipeline.LogError, str) },
 			Panic:    func(err error) { panic(err) },
 		})
+	if err != nil {
+		return nil, common.S3URLParts{}, err
+	}
+
+	return s3Client, s3URLPart, nil
+}
+
+func newS3SourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	var err error
+	p := s3SourceInfoProvider{jptm: jptm, transferInfo: jptm.Info()}
+
+	p.rawSourceURL, err = url.Parse(p.transferInfo.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	p.s3Client, p.s3URLPart, err = newS3ClientForURL(jptm, p.transferInfo.Source)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +102,7 @@ func (p *s3SourceInfoProvider) Properties() (*SrcProperties, error) {
 	srcProperties := SrcProperties{
 		SrcHTTPHeaders: p.transferInfo.SrcHTTPHeaders,
 		SrcMetadata:    p.transferInfo.SrcMetadata,
+		SrcBlobTags:    p.transferInfo.SrcBlobTags,
 	}
 
 	// Get properties in backend.
@@ -100,6 +113,12 @@ func (p *s3SourceInfoProvider) Properties() (*SrcProperties, error) {
 		}
 		oie := common.ObjectInfoExtension{ObjectInfo: objectInfo}
 
+		// the ETag is the MD5 of most objects, and here, unlike in a listing, we know the headers that tell whether it is
+		contentMD5 := oie.ContentMD5()
+		if len(contentMD5) == 0 {
+			contentMD5 = oie.ETagMD5()
+		}
+
 		srcProperties = SrcProperties{
 			SrcHTTPHeaders: common.ResourceHTTPHeaders{
 				ContentType:        objectInfo.ContentType,
@@ -107,15 +126,31 @@ func (p *s3SourceInfoProvider) Properties() (*SrcProperties, error) {
 				ContentDisposition: oie.ContentDisposition(),
 				ContentLanguage:    oie.ContentLanguage(),
 				CacheControl:       oie.CacheControl(),
-				ContentMD5:         oie.ContentMD5(),
+				ContentMD5:         contentMD5,
 			},
 			SrcMetadata: oie.NewCommonMetadata(),
+			SrcBlobTags: p.transferInfo.SrcBlobTags,
+		}
+	}
+
+	// Preserve the object's tags, unless tags were given for the whole job
+	fromTo := p.jptm.FromTo()
+	if p.transferInfo.S2SPreserveBlobTags && len(srcProperties.SrcBlobTags) == 0 && fromTo.To() == common.ELocation.Blob() {
+		tags, err := common.GetS3ObjectTags(p.jptm.Context(), p.s3Client, p.s3URLPart.BucketName, p.s3URLPart.ObjectKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the object's tags, to preserve them. "+
+				"If it's not possible to get them, use --s2s-preserve-blob-tags=false to copy the object without them. %w", err)
+		}
+		if err = tags.Validate(); err != nil {
+			return nil, fmt.Errorf("cannot preserve the object's tags, since they are not valid blob tags. "+
+				"Use --s2s-preserve-blob-tags=false to copy the object without them, or --blob-tags to give it other tags. %w", err)
 		}
+		srcProperties.SrcBlobTags = tags
 	}
 
 	// Handle invalid metadata.
 	// Note: Only handle metadata's key, as metadata's value must conform to US-ASCII for both S3 and Azure.
-	resolvedMetadata, err := p.handleInvalidMetadataKeys(srcProperties.SrcMetadata)
+	resolvedMetadata, err := handleInvalidMetadataKeys(p.jptm, p.transferInfo, srcProperties.SrcMetadata)
 	if err != nil {
 		return nil, err
 	}
@@ -124,18 +159,18 @@ func (p *s3SourceInfoProvider) Properties() (*SrcProperties, error) {
 	return &srcProperties, nil
 }
 
-// handleInvalidMetadataKeys handles invalid metadata for S3 source.
-func (p *s3SourceInfoProvider) handleInvalidMetadataKeys(m common.Metadata) (common.Metadata, error) {
+// handleInvalidMetadataKeys handles invalid metadata for S3 (and GCP) sources.
+func handleInvalidMetadataKeys(jptm IJobPartTransferMgr, transferInfo TransferInfo, m common.Metadata) (common.Metadata, error) {
 	if m == nil {
 		return m, nil
 	}
 
-	switch p.transferInfo.S2SInvalidMetadataHandleOption {
+	switch transferInfo.S2SInvalidMetadataHandleOption {
 	case common.EInvalidMetadataHandleOption.ExcludeIfInvalid():
 		retainedMetadata, excludedMetadata, invalidKeyExists := m.ExcludeInvalidKey()
-		if invalidKeyExists && p.jptm.ShouldLog(pipeline.LogWarning) {
-			p.jptm.Log(pipeline.LogWarning,
-				fmt.Sprintf("METADATAWARNING: For source %q, invalid metadata with keys %s are excluded", p.transferInfo.Source, excludedMetadata.ConcatenatedKeys()))
+		if invalidKeyExists && jptm.ShouldLog(pipeline.LogWarning) {
+			jptm.Log(pipeline.LogWarning,
+				fmt.Sprintf("METADATAWARNING: For source %q, invalid metadata with keys %s are excluded", transferInfo.Source, excludedMetadata.ConcatenatedKeys()))
 		}
 		return retainedMetadata, nil
 
@@ -0,0 +1,40 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type blobFSSourceInfoProviderSuite struct{}
+
+var _ = chk.Suite(&blobFSSourceInfoProviderSuite{})
+
+func (s *blobFSSourceInfoProviderSuite) TestBlobEndpointHostForBlobFS(c *chk.C) {
+	c.Assert(blobEndpointHostForBlobFS("account.dfs.core.windows.net"), chk.Equals, "account.blob.core.windows.net")
+	c.Assert(blobEndpointHostForBlobFS("account.DFS.core.chinacloudapi.cn"), chk.Equals, "account.blob.core.chinacloudapi.cn")
+
+	// only the endpoint label is replaced, not a similar-looking account name
+	c.Assert(blobEndpointHostForBlobFS("dfs.dfs.core.windows.net"), chk.Equals, "dfs.blob.core.windows.net")
+
+	// anything else (e.g. an emulator addressed by IP) is left alone
+	c.Assert(blobEndpointHostForBlobFS("127.0.0.1:10000"), chk.Equals, "127.0.0.1:10000")
+}
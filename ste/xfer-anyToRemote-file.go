@@ -522,7 +522,8 @@ func epilogueWithCleanupSendToRemote(jptm IJobPartTransferMgr, s sender, sip ISo
 	//  or should we redefine epilogue to be success-path only, and only call it in that case?
 	s.Epilogue() // Perform service-specific cleanup before jptm cleanup. Some services may actually require setup to make the file actually appear.
 
-	if jptm.IsLive() && info.DestLengthValidation {
+	// (the length of a blob that was compressed as it was uploaded can't be compared with the source's)
+	if jptm.IsLive() && info.DestLengthValidation && jptm.CompressOnUpload() == common.ECompressionType.None() {
 		_, isS2SCopier := s.(s2sCopier)
 		shouldCheckLength := true
 		destLength, err := s.GetDestinationLength()
@@ -93,10 +93,19 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		}
 	}
 
+	// if the source was encrypted client-side, it's decrypted as it's written, and it's the plaintext that's validated
+	envelope, decryptionKey, err := getClientEncryptionEnvelopeAndKey(jptm, info)
+	if err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, "Client-side decryption error "+err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
 	if jptm.MD5ValidationOption() == common.EHashValidationOption.FailIfDifferentOrMissing() {
 		// We can make a check early on MD5 existence and fail the transfer if it's not present.
 		// This will save hours in the event a user has say, a several hundred gigabyte file.
-		if len(info.SrcHTTPHeaders.ContentMD5) == 0 {
+		if len(expectedMd5(info, envelope)) == 0 {
 			jptm.LogDownloadError(info.Source, info.Destination, errExpectedMd5Missing.Error(), 0)
 			jptm.SetStatus(common.ETransferStatus.Failed())
 			jptm.ReportTransferDone()
@@ -149,7 +158,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		epilogueWithCleanupDownload(jptm, dl, nil, nil)
 	}
 	// block until we can safely use a file handle
-	err := jptm.WaitUntilLockDestination(jptm.Context())
+	err = jptm.WaitUntilLockDestination(jptm.Context())
 	if err != nil {
 		failFileCreation(err)
 		return
@@ -165,13 +174,28 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		// file creations are running at any given instant, for perf diagnostics
 		pseudoId := common.NewPseudoChunkIDForWholeFile(info.Source)
 		jptm.LogChunkStatus(pseudoId, common.EWaitReason.CreateLocalFile())
-		dstFile, err = createDestinationFile(jptm, info.Destination, fileSize, writeThrough)
+		plaintextSize := fileSize
+		if envelope != nil {
+			plaintextSize = envelope.PlaintextSize
+		}
+		dstFile, err = createDestinationFile(jptm, info.Destination, plaintextSize, writeThrough)
 		jptm.LogChunkStatus(pseudoId, common.EWaitReason.ChunkDone()) // normal setting to done doesn't apply to these pseudo ids
 		if err != nil {
 			failFileCreation(err)
 			return
 		}
 	}
+	if envelope != nil {
+		// wrap for decryption. This goes outside any decompression, since it was the compressed data that was encrypted
+		decryptingWriter, err := decryptionKey.NewDecryptingWriter(dstFile, envelope)
+		if err != nil {
+			_ = dstFile.Close()
+			failFileCreation(err)
+			return
+		}
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "will be decrypted")
+		dstFile = decryptingWriter
+	}
 
 	// TODO: Question: do we need to Stat the file, to check its size, after explicitly making it with the desired size?
 	// That was what the old xfer-blobToLocal code used to do
@@ -200,7 +224,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 
 	// step 5b: create destination writer
 	chunkLogger := jptm.ChunkStatusLogger()
-	sourceMd5Exists := len(info.SrcHTTPHeaders.ContentMD5) > 0
+	sourceMd5Exists := len(info.SrcHTTPHeaders.ContentMD5) > 0 && envelope == nil // the decrypting writer hashes the plaintext itself
 	dstWriter := common.NewChunkedFileWriter(
 		jptm.Context(),
 		jptm.SlicePool(),
@@ -263,6 +287,43 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 
 }
 
+// getClientEncryptionEnvelopeAndKey returns the envelope of a source that was encrypted client-side, and the key to decrypt it with.
+// Both are nil if the source isn't encrypted.
+func getClientEncryptionEnvelopeAndKey(jptm IJobPartTransferMgr, info TransferInfo) (*common.ClientEncryptionEnvelope, *common.ClientEncryptionKey, error) {
+	envelope, err := common.ParseClientEncryptionEnvelope(info.SrcMetadata)
+	if err != nil || envelope == nil {
+		return nil, nil, err
+	}
+	if envelope.EncryptedSize() != info.SourceSize {
+		return nil, nil, errors.New("the length of the source does not match its client-side encryption metadata")
+	}
+
+	key, err := jptm.ClientEncryptionKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		return nil, nil, errors.New("the source was encrypted client-side, but no key was given to decrypt it with")
+	}
+	return envelope, key, nil
+}
+
+// expectedMd5 is the MD5 of the source. If the source was encrypted client-side, that's the MD5 of its plaintext.
+func expectedMd5(info TransferInfo, envelope *common.ClientEncryptionEnvelope) []byte {
+	if envelope != nil {
+		return envelope.GetPlaintextMD5()
+	}
+	return info.SrcHTTPHeaders.ContentMD5
+}
+
+// expectedLength is the length the destination should have, once downloaded
+func expectedLength(info TransferInfo) int64 {
+	if envelope, err := common.ParseClientEncryptionEnvelope(info.SrcMetadata); err == nil && envelope != nil {
+		return envelope.PlaintextSize
+	}
+	return info.SourceSize
+}
+
 func createDestinationFile(jptm IJobPartTransferMgr, destination string, size int64, writeThrough bool) (file io.WriteCloser, err error) {
 	ct := common.ECompressionType.None()
 	if jptm.ShouldDecompress() {
@@ -314,6 +375,10 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 		// wait until all received chunks are flushed out
 		md5OfFileAsWritten, flushError := cw.Flush(jptm.Context())
 		closeErr := activeDstFile.Close() // always try to close if, even if flush failed
+		envelope, _ := common.ParseClientEncryptionEnvelope(info.SrcMetadata)
+		if decryptingWriter, ok := activeDstFile.(*common.DecryptingWriter); ok {
+			md5OfFileAsWritten = decryptingWriter.PlaintextMD5()
+		}
 		if flushError != nil {
 			jptm.FailActiveDownload("Flushing file", flushError)
 		}
@@ -325,7 +390,7 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 		// Check MD5 (but only if file was fully flushed and saved - else no point and may not have actualAsSaved hash anyway)
 		if jptm.IsLive() {
 			comparison := md5Comparer{
-				expected:         info.SrcHTTPHeaders.ContentMD5, // the MD5 that came back from Service when we enumerated the source
+				expected:         expectedMd5(info, envelope), // the MD5 that came back from Service when we enumerated the source
 				actualAsSaved:    md5OfFileAsWritten,
 				validationOption: jptm.MD5ValidationOption(),
 				logger:           jptm}
@@ -347,7 +412,7 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 
 			if err != nil {
 				jptm.FailActiveDownload("Download length check", err)
-			} else if fi.Size() != info.SourceSize {
+			} else if fi.Size() != expectedLength(info) {
 				jptm.FailActiveDownload("Download length check", errors.New("destination length did not match source length"))
 			}
 		}
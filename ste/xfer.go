@@ -81,8 +81,6 @@ func parameterizeSend(targetFunction newJobXferWithSenderFactory, sf senderFacto
 // the xfer factory is generated based on the type of source and destination
 func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 
-	const blobFSNotS2S = "blobFS not supported as S2S source"
-
 	//local helper functions
 
 	getDownloader := func(sourceType common.Location) downloaderFactory {
@@ -93,6 +91,8 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 			return newAzureFilesDownloader
 		case common.ELocation.BlobFS():
 			return newBlobFSDownloader
+		case common.ELocation.S3():
+			return newS3Downloader
 		default:
 			panic("unexpected source type")
 		}
@@ -103,13 +103,14 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		if isFromRemote {
 			// sending from remote = doing an S2S copy
 			switch fromTo.To() {
-			case common.ELocation.Blob(),
-				common.ELocation.S3():
+			case common.ELocation.Blob():
 				return newURLToBlobCopier
+			case common.ELocation.S3():
+				return newURLToS3Copier
 			case common.ELocation.File():
 				return newURLToAzureFileCopier
 			case common.ELocation.BlobFS():
-				panic(blobFSNotS2S)
+				return newURLToBlobFSCopier
 			default:
 				panic("unexpected target location type")
 			}
@@ -122,6 +123,8 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 				return newAzureFilesUploader
 			case common.ELocation.BlobFS():
 				return newBlobFSUploader
+			case common.ELocation.S3():
+				return newS3Uploader
 			default:
 				panic("unexpected target location type")
 			}
@@ -139,9 +142,13 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		case common.ELocation.File():
 			return newFileSourceInfoProvider
 		case common.ELocation.BlobFS():
-			panic(blobFSNotS2S)
+			return newBlobFSSourceInfoProvider
 		case common.ELocation.S3():
 			return newS3SourceInfoProvider
+		case common.ELocation.GCP():
+			return newGCPSourceInfoProvider
+		case common.ELocation.Http():
+			return newHttpSourceInfoProvider
 		default:
 			panic("unexpected source type")
 		}
@@ -153,6 +160,9 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		return DeleteBlob
 	case fromTo == common.EFromTo.FileTrash():
 		return DeleteFile
+	case fromTo == common.EFromTo.LocalLocal():
+		// the destination end of a local copy is just like a download
+		return parameterizeDownload(remoteToLocal, newLocalDownloader)
 	default:
 		if fromTo.IsDownload() {
 			return parameterizeDownload(remoteToLocal, getDownloader(fromTo.From()))
@@ -255,7 +255,9 @@ func createBlob(blobURL string, blobSize uint32, metadata azblob.Metadata, blobH
 		strings.NewReader(randomString),
 		blobHTTPHeaders,
 		metadata,
-		azblob.BlobAccessConditions{})
+		azblob.BlobAccessConditions{},
+		azblob.DefaultAccessTier,
+		nil, azblob.ClientProvidedKeyOptions{})
 	if err != nil {
 		fmt.Println(fmt.Sprintf("error uploading the blob %v", err))
 		os.Exit(1)